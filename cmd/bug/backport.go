@@ -1,16 +1,8 @@
 package bug
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
-	"log"
-	"os/exec"
-	"reflect"
-	"runtime"
-	"strconv"
-	"strings"
-	"text/tabwriter"
 
 	"github.com/jinzhu/copier"
 	"github.com/manifoldco/promptui"
@@ -18,7 +10,10 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/zalando/go-keyring"
 
+	"github.com/ecordell/cop/cmd/login"
 	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/view"
+	"github.com/ecordell/cop/pkg/workflow"
 )
 
 const (
@@ -70,9 +65,12 @@ var backportCmd = &cobra.Command{
 		}
 
 		endpoint := "https://bugzilla.redhat.com/"
-		backportOpts.client = bugzilla.NewClient(func() []byte {
+		backportOpts.client, err = login.Journaled(bugzilla.NewClient(func() []byte {
 			return []byte(apikey)
-		}, endpoint)
+		}, endpoint))
+		if err != nil {
+			return err
+		}
 
 		// TODO check BZ API key - api returns an error if wrong
 		query := baseQuery
@@ -84,20 +82,14 @@ var backportCmd = &cobra.Command{
 			return err
 		}
 
-		sbs := []CLIMarshaller{}
+		sbs := []view.CLIMarshaller{}
 		for _, bug := range bs {
 			sbs = append(sbs, NewSimpleBugView(*bug))
 		}
-		return NewMultiSelectView(sbs).Prompt()
+		return view.NewMultiSelectView(sbs).Prompt()
 	},
 }
 
-const cliTag = "cli"
-
-type CLIMarshaller interface {
-	MarshallCLI() ([]string, error)
-}
-
 type SimpleBugView struct {
 	bugzilla.Bug
 	// ID is the unique numeric ID of this bug.
@@ -120,9 +112,8 @@ type SimpleBugView struct {
 
 func NewSimpleBugView(bug bugzilla.Bug) *SimpleBugView {
 	backport := "⚠️"
-	whiteboard := strings.Split(bug.InternalWhiteboard, ":")
-	if len(whiteboard) > 1 {
-		backport = strings.Trim(whiteboard[1], " ")
+	if to := workflow.Backport(bug); to != "" {
+		backport = to
 	}
 	view := &SimpleBugView{
 		Bug: bug,
@@ -135,117 +126,10 @@ func NewSimpleBugView(bug bugzilla.Bug) *SimpleBugView {
 }
 
 func (b SimpleBugView) MarshallCLI() ([]string, error) {
-	values := []string{}
-	var err error
-	val := reflect.ValueOf(b)
-	for i := 0; i < val.Type().NumField(); i++ {
-		tag := val.Type().Field(i).Tag.Get(cliTag)
-
-		var maxLen int
-		parts := strings.Split(tag, ",")
-		if len(parts) > 2 {
-			return nil, fmt.Errorf("too many parts to field tag %s", tag)
-		}
-		if len(parts) == 2 {
-			maxLen, err = strconv.Atoi(parts[1])
-			if err != nil {
-				return nil, fmt.Errorf("couldn't get length from struct tag %s: %v", tag, err)
-			}
-		}
-
-		// Skip if tag is not defined or ignored
-		if tag == "" || tag == "-" {
-			continue
-		}
-
-		if maxLen == 0 {
-			values = append(values, fmt.Sprintf("%v", val.Field(i).Interface()))
-			continue
-		}
-
-		// if maxlen, assume string
-		if maxLen > len(val.Field(i).String()) {
-			maxLen = len(val.Field(i).String())
-		}
-
-		values = append(values, val.Field(i).String()[:maxLen])
-	}
-	return values, err
+	return view.MarshallCLI(b)
 }
 
-func Fields(b CLIMarshaller) ([]string) {
-	fields := []string{}
-
-	val := reflect.ValueOf(b).Elem()
-	for i := 0; i < val.Type().NumField(); i++ {
-		tag := val.Type().Field(i).Tag.Get(cliTag)
-
-		// Skip if tag is not defined or ignored
-		if tag == "" || tag == "-" {
-			continue
-		}
-		parts := strings.Split(tag, ",")
-		fields = append(fields, parts[0])
-	}
-	return fields
-}
-
-var _ CLIMarshaller = &SimpleBugView{}
-
-func TabHeader(s []string) string {
-	return "  " + strings.Join(s, "\t  ") + "\n"
-}
-
-func TabLine(s []string) string {
-	return strings.Join(s, "\t") + "\n"
-}
-
-type MultiSelectView struct {
-	options []CLIMarshaller
-}
-
-func NewMultiSelectView(options []CLIMarshaller) *MultiSelectView {
-	return &MultiSelectView{
-		options: options,
-	}
-}
-
-func (v *MultiSelectView) Prompt() error {
-	var buffer bytes.Buffer
-	w := tabwriter.NewWriter(&buffer, 0, 0, 2, ' ', 0)
-	if len(v.options) == 0 {
-		return fmt.Errorf("can't prompt with no options")
-	}
-	if _, err := fmt.Fprint(w, TabHeader(Fields(v.options[0]))); err != nil {
-		return err
-	}
-	for _, o := range v.options {
-		values, err := o.MarshallCLI()
-		if err != nil {
-			return err
-		}
-		if _, err := fmt.Fprint(w, TabLine(values)); err != nil {
-			logrus.Error(err)
-		}
-	}
-	if err := w.Flush(); err != nil {
-		return err
-	}
-	lines := strings.Split(buffer.String(), "\n")
-	prompt := promptui.Select{
-		Label: lines[0],
-		Size: 10,
-		Items: lines[1:],
-		HideSelected: true,
-	}
-
-	row, _, err := prompt.Run()
-	if err != nil {
-		return err
-	}
-	fmt.Println(row)
-	return nil
-}
+var _ view.CLIMarshaller = &SimpleBugView{}
 
 func bugPrompt(bug *bugzilla.Bug, bugs []*bugzilla.Bug) error {
 	options := []string{
@@ -259,7 +143,9 @@ func bugPrompt(bug *bugzilla.Bug, bugs []*bugzilla.Bug) error {
 	}
 	actions := []func() error {
 		func() error {
-			openbrowser(fmt.Sprintf("https://bugzilla.redhat.com/show_bug.cgi?id=%d",bug.ID))
+			if err := view.OpenBrowser(fmt.Sprintf("https://bugzilla.redhat.com/show_bug.cgi?id=%d",bug.ID)); err != nil {
+				return err
+			}
 			return bugPrompt(bug, bugs)
 		},
 		func() error {
@@ -304,22 +190,3 @@ func backportSelect(bug *bugzilla.Bug, bugs []*bugzilla.Bug) error {
 	//printBZs(bugs)
 	return nil
 }
-
-// have not tested outside of macos
-func openbrowser(url string) {
-	var err error
-
-	switch runtime.GOOS {
-	case "linux":
-		err = exec.Command("xdg-open", url).Start()
-	case "windows":
-		err = exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
-	case "darwin":
-		err = exec.Command("open", url).Start()
-	default:
-		err = fmt.Errorf("unsupported platform")
-	}
-	if err != nil {
-		log.Fatal(err)
-	}
-}
\ No newline at end of file
@@ -10,6 +10,7 @@ type bugOptions struct {
 	apiKey string
 	jiraUser string
 	jiraPass string
+	githubToken string
 }
 
 var bugOpts bugOptions
@@ -25,4 +26,5 @@ func init() {
 	BugCmd.PersistentFlags().StringVarP(&bugOpts.apiKey, "bz-apikey", "k", "", "apikey for bugzilla")
 	BugCmd.PersistentFlags().StringVarP(&bugOpts.jiraUser, "jira-user", "u", "", "username for jboss jira")
 	BugCmd.PersistentFlags().StringVarP(&bugOpts.jiraPass, "jira-pass", "p", "", "password for jboss jira")
+	BugCmd.PersistentFlags().StringVar(&bugOpts.githubToken, "github-token", "", "token for github, defaults to the stored token or $GITHUB_TOKEN")
 }
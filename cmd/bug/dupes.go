@@ -0,0 +1,179 @@
+package bug
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/ecordell/cop/cmd/login"
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/dupes"
+	"github.com/ecordell/cop/pkg/view"
+	"github.com/ecordell/cop/pkg/workflow"
+)
+
+type dupesOptions struct {
+	query     string
+	threshold float64
+	limit     int
+	workers   int
+	output    string
+	markOf    int
+	vars      map[string]string
+}
+
+var dupesOpts dupesOptions
+
+var dupesCmd = &cobra.Command{
+	Use:   "dupes [ID]",
+	Short: "Find likely duplicate bugs",
+	Long: `Find likely duplicate bugs by comparing their summaries and descriptions.
+
+With a bug ID, the bugs most like it are ranked. Without one, every pair of bugs scoring
+above the threshold is listed. Bugs are compared with those of --query (a saved @NAME, raw
+search parameters or a buglist.cgi url), defaulting to the team's open OLM bugs.
+
+Scores run from 0 to 1, mixing how many rare words the bugs share with how much of their
+text, such as error messages, they have in common word for word.
+
+Once you're sure, --mark-dupe-of closes the bug as a DUPLICATE of another.`,
+	Example: `  cop bz dupes 1812345
+  cop bz dupes --threshold 0.5 --query @mine
+  cop bz dupes 1812345 --mark-dupe-of 1809876`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if bugOpts.debug {
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+		id := 0
+		if len(args) > 0 {
+			var err error
+			if id, err = strconv.Atoi(args[0]); err != nil {
+				return fmt.Errorf("invalid bug id %q: %v", args[0], err)
+			}
+		}
+		client, err := login.NewBugzillaClient(bugOpts.apiKey)
+		if err != nil {
+			return err
+		}
+		if dupesOpts.markOf != 0 {
+			if id == 0 {
+				return fmt.Errorf("--mark-dupe-of needs the ID of the duplicate bug")
+			}
+			return markDuplicate(client, id, dupesOpts.markOf)
+		}
+
+		query, err := resolveQuery(dupesOpts.query, nil, dupesOpts.vars)
+		if err != nil {
+			return err
+		}
+		bugs, err := client.SearchBugs(query)
+		if err != nil {
+			return err
+		}
+		byID := map[int]*bugzilla.Bug{}
+		for _, b := range bugs {
+			byID[b.ID] = b
+		}
+		if _, ok := byID[id]; id != 0 && !ok {
+			// the bug may not match the search, e.g. if it is already closed
+			b, err := client.GetBug(id)
+			if err != nil {
+				return err
+			}
+			bugs = append(bugs, b)
+			byID[id] = b
+		}
+		docs, err := dupes.Fetch(client, bugs, dupesOpts.workers)
+		if err != nil {
+			return err
+		}
+		idx := dupes.NewIndex(docs)
+
+		views := []view.CLIMarshaller{}
+		if id != 0 {
+			matches, err := idx.Similar(id, dupesOpts.threshold, dupesOpts.limit)
+			if err != nil {
+				return err
+			}
+			for _, m := range matches {
+				b := byID[m.ID]
+				views = append(views, &DupeView{ID: b.ID, Score: score(m.Score), Status: b.Status, Assignee: b.AssignedTo, Summary: b.Summary})
+			}
+		} else {
+			for _, p := range idx.Pairs(dupesOpts.threshold) {
+				views = append(views, &DupePairView{ID: p.ID, Duplicate: p.Duplicate.ID, Score: score(p.Duplicate.Score), Summary: byID[p.Duplicate.ID].Summary})
+			}
+		}
+		if len(views) == 0 && dupesOpts.output != view.FormatJSON {
+			fmt.Printf("No likely duplicates among %d bugs.\n", len(bugs))
+			return nil
+		}
+		return view.Print(os.Stdout, dupesOpts.output, views)
+	},
+}
+
+func score(s float64) string {
+	return strconv.FormatFloat(s, 'f', 2, 64)
+}
+
+func markDuplicate(client bugzilla.Client, id, of int) error {
+	if id == of {
+		return fmt.Errorf("a bug can't be a duplicate of itself")
+	}
+	if err := client.UpdateBug(id, bugzilla.BugUpdate{Status: workflow.StatusClosed, Resolution: "DUPLICATE", DupeOf: of}); err != nil {
+		return fmt.Errorf("could not mark bug %d a duplicate of %d: %v", id, of, err)
+	}
+	fmt.Printf("Closed bug %d as a duplicate of bug %d.\n", id, of)
+	return nil
+}
+
+type DupeView struct {
+	// ID is the unique numeric ID of the bug.
+	ID int `cli:"ID"`
+	// Score is how similar the bug is, from 0 to 1.
+	Score string `cli:"Score"`
+	// Status is the current status of the bug.
+	Status string `cli:"Status"`
+	// Assignee is the login name of the user to whom the bug is assigned.
+	Assignee string `cli:"Assignee"`
+	// Summary is the summary of the bug.
+	Summary string `cli:"Summary,60"`
+}
+
+func (v DupeView) MarshallCLI() ([]string, error) {
+	return view.MarshallCLI(v)
+}
+
+var _ view.CLIMarshaller = &DupeView{}
+
+type DupePairView struct {
+	// ID is the older bug of the pair.
+	ID int `cli:"ID"`
+	// Duplicate is the newer bug of the pair, the likely duplicate.
+	Duplicate int `cli:"Duplicate"`
+	// Score is how similar the bugs are, from 0 to 1.
+	Score string `cli:"Score"`
+	// Summary is the summary of the duplicate.
+	Summary string `cli:"Summary,60"`
+}
+
+func (v DupePairView) MarshallCLI() ([]string, error) {
+	return view.MarshallCLI(v)
+}
+
+var _ view.CLIMarshaller = &DupePairView{}
+
+func init() {
+	dupesCmd.Flags().StringVar(&dupesOpts.query, "query", "", "bugzilla search query or saved @query of the bugs to compare with, defaults to open OLM bugs")
+	dupesCmd.Flags().Float64Var(&dupesOpts.threshold, "threshold", 0.3, "lowest score to list")
+	dupesCmd.Flags().IntVar(&dupesOpts.limit, "limit", 10, "most similar bugs to list for a bug, 0 for all")
+	dupesCmd.Flags().IntVar(&dupesOpts.workers, "workers", 8, "how many bug descriptions to fetch at once")
+	dupesCmd.Flags().StringVarP(&dupesOpts.output, "output", "o", view.FormatTable, "output format, table or json")
+	dupesCmd.Flags().IntVar(&dupesOpts.markOf, "mark-dupe-of", 0, "close the bug as a DUPLICATE of this bug")
+	dupesCmd.Flags().StringToStringVar(&dupesOpts.vars, "set", nil, "values for templated queries, e.g. Release=4.4")
+	BugCmd.AddCommand(dupesCmd)
+}
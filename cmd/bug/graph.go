@@ -0,0 +1,74 @@
+package bug
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/ecordell/cop/cmd/login"
+	"github.com/ecordell/cop/pkg/graph"
+)
+
+const (
+	graphFormatTree    = "tree"
+	graphFormatDOT     = "dot"
+	graphFormatMermaid = "mermaid"
+)
+
+type graphOptions struct {
+	depth   int
+	workers int
+	format  string
+	noColor bool
+}
+
+var graphOpts graphOptions
+
+var graphCmd = &cobra.Command{
+	Use:   "graph ID",
+	Short: "Show the dependency, duplicate and clone graph around a bug",
+	Long: `Show the dependency, duplicate and clone graph around a bug.
+
+Links are followed both ways: blocks and depends on, and the bugs a bug duplicates or was
+cloned from as well as its own duplicates and clones, which are searched for.
+
+Renders as an ascii tree, Graphviz DOT (pipe into "dot -Tsvg") or a Mermaid flowchart.
+Nodes are colored by status, and outlined by target release in DOT and Mermaid.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if bugOpts.debug {
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return err
+		}
+		client, err := login.NewBugzillaClient(bugOpts.apiKey)
+		if err != nil {
+			return err
+		}
+
+		g := graph.Crawl(client, id, graphOpts.depth, graphOpts.workers)
+		switch graphOpts.format {
+		case graphFormatTree:
+			return g.Tree(os.Stdout, !graphOpts.noColor)
+		case graphFormatDOT:
+			return g.DOT(os.Stdout)
+		case graphFormatMermaid:
+			return g.Mermaid(os.Stdout)
+		default:
+			return fmt.Errorf("unknown format %q, must be one of %s|%s|%s", graphOpts.format, graphFormatTree, graphFormatDOT, graphFormatMermaid)
+		}
+	},
+}
+
+func init() {
+	graphCmd.Flags().IntVar(&graphOpts.depth, "depth", 2, "how many relationships away from the bug to crawl")
+	graphCmd.Flags().IntVar(&graphOpts.workers, "workers", 8, "how many bugs to fetch at once")
+	graphCmd.Flags().StringVarP(&graphOpts.format, "format", "f", graphFormatTree, "output format, one of tree|dot|mermaid")
+	graphCmd.Flags().BoolVar(&graphOpts.noColor, "no-color", false, "don't color the tree output")
+	BugCmd.AddCommand(graphCmd)
+}
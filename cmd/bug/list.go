@@ -0,0 +1,186 @@
+package bug
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/ecordell/cop/cmd/login"
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/view"
+	"github.com/ecordell/cop/pkg/workflow"
+)
+
+type listOptions struct {
+	flags   queryFlags
+	vars    map[string]string
+	output  string
+	columns []string
+	sortBy  []string
+	groupBy string
+}
+
+var listOpts listOptions
+
+// defaultColumns match the columns of SimpleBugView
+var defaultColumns = []view.Column{
+	{Name: "ID", Path: "id"},
+	{Name: "Status", Path: "status"},
+	{Name: "Assignee", Path: "assigned_to"},
+	{Name: "Summary", Path: "summary", Width: 50},
+	{Name: "Priority", Path: "priority"},
+	{Name: "Severity", Path: "severity"},
+	{Name: "Backport", Path: "backport"},
+}
+
+// fieldAliases are short names for commonly used bug fields
+var fieldAliases = map[string]string{
+	"assignee": "assigned_to",
+	"release":  "target_release",
+}
+
+func fieldPath(name string) string {
+	if path, ok := fieldAliases[name]; ok {
+		return path
+	}
+	return name
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list [@NAME|QUERY]",
+	Short: "List bugs matching a search",
+	Long: `List bugs matching a search.
+
+The search is a saved query (@NAME), raw search parameters or a buglist.cgi url,
+narrowed by any search flags. With no search the team's open OLM bugs are listed.
+
+Columns may be any field of a bug as returned by bugzilla, including nested fields
+like assigned_to_detail.real_name, and can be truncated with a width, e.g. summary:60.
+Sort keys prefixed with - sort descending. Statuses sort in workflow order, and
+priority and severity from most to least urgent.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if bugOpts.debug {
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+		columns := defaultColumns
+		if len(listOpts.columns) > 0 {
+			var err error
+			if columns, err = view.ParseColumns(listOpts.columns); err != nil {
+				return err
+			}
+			for i := range columns {
+				columns[i].Path = fieldPath(columns[i].Path)
+			}
+		}
+		var arg string
+		if len(args) > 0 {
+			arg = args[0]
+		}
+		query, err := resolveQuery(arg, &listOpts.flags, listOpts.vars)
+		if err != nil {
+			return err
+		}
+		client, err := login.NewBugzillaClient(bugOpts.apiKey)
+		if err != nil {
+			return err
+		}
+		bugs, err := client.SearchBugs(query)
+		if err != nil {
+			return err
+		}
+
+		docs, err := bugDocuments(bugs)
+		if err != nil {
+			return err
+		}
+		view.Sort(docs, sortKeys(listOpts.sortBy))
+		if listOpts.groupBy == "" {
+			return view.Print(os.Stdout, listOpts.output, rows(docs, columns))
+		}
+		return printGroups(os.Stdout, view.GroupBy(docs, fieldPath(listOpts.groupBy)), columns)
+	},
+}
+
+// bugDocuments converts bugs for display, adding the derived backport field
+func bugDocuments(bugs []*bugzilla.Bug) ([]view.Document, error) {
+	docs := make([]view.Document, 0, len(bugs))
+	for _, bug := range bugs {
+		doc, err := view.NewDocument(bug)
+		if err != nil {
+			return nil, err
+		}
+		doc["backport"] = NewSimpleBugView(*bug).Backport
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+func sortKeys(specs []string) []view.SortKey {
+	keys := view.ParseSortKeys(specs)
+	for i := range keys {
+		keys[i].Path = fieldPath(keys[i].Path)
+		switch keys[i].Path {
+		case "status":
+			keys[i].Less = workflow.Before
+		case "priority", "severity":
+			keys[i].Less = bugzilla.MoreUrgent
+		}
+	}
+	return keys
+}
+
+func rows(docs []view.Document, columns []view.Column) []view.CLIMarshaller {
+	rows := make([]view.CLIMarshaller, 0, len(docs))
+	for _, d := range docs {
+		rows = append(rows, view.Row{Document: d, Columns: columns})
+	}
+	return rows
+}
+
+type groupJSON struct {
+	Group string              `json:"group"`
+	Count int                 `json:"count"`
+	Bugs  []map[string]string `json:"bugs"`
+}
+
+func printGroups(w io.Writer, groups []view.Group, columns []view.Column) error {
+	if listOpts.output == view.FormatJSON {
+		out := []groupJSON{}
+		for _, g := range groups {
+			objects, err := view.Objects(rows(g.Documents, columns))
+			if err != nil {
+				return err
+			}
+			out = append(out, groupJSON{Group: g.Value, Count: len(g.Documents), Bugs: objects})
+		}
+		return view.PrintJSON(w, out)
+	}
+	for i, g := range groups {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		name := g.Value
+		if name == "" {
+			name = "(none)"
+		}
+		fmt.Fprintf(w, "%s (%d)\n", name, len(g.Documents))
+		if err := view.Print(w, listOpts.output, rows(g.Documents, columns)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	listOpts.flags.register(listCmd.Flags())
+	listCmd.Flags().StringToStringVar(&listOpts.vars, "set", nil, "values for templated queries, e.g. Release=4.4")
+	listCmd.Flags().StringVarP(&listOpts.output, "output", "o", view.FormatTable, "output format, table or json")
+	listCmd.Flags().StringSliceVar(&listOpts.columns, "columns", nil, "bug fields to show, e.g. id,status,assigned_to_detail.real_name,summary:60")
+	listCmd.Flags().StringSliceVar(&listOpts.sortBy, "sort-by", nil, "bug fields to sort by, e.g. status,-priority")
+	listCmd.Flags().StringVar(&listOpts.groupBy, "group-by", "", "bug field to group by, e.g. component, assignee or release")
+	BugCmd.AddCommand(listCmd)
+}
@@ -0,0 +1,246 @@
+package bug
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/ecordell/cop/cmd/login"
+	"github.com/ecordell/cop/pkg/backport"
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/git"
+	"github.com/ecordell/cop/pkg/github"
+	"github.com/ecordell/cop/pkg/transport"
+	"github.com/ecordell/cop/pkg/view"
+	"github.com/ecordell/cop/pkg/workflow"
+)
+
+type pickOptions struct {
+	repoDir       string
+	remote        string
+	pushRemote    string
+	forkOwner     string
+	githubRepo    string
+	branches      []string
+	masterRelease string
+	openPR        bool
+}
+
+var pickOpts pickOptions
+
+var pickCmd = &cobra.Command{
+	Use:   "pick ID",
+	Short: "Cherry-pick a bug's fix onto the release branches of its clones",
+	Long: `Cherry-pick the merged PRs linked to a bug onto a new branch for each of its backport clones.
+
+The PRs' commits are fetched from the remote's pull request refs, so squashed or rebased merges can
+be picked too. Each clone's target release decides the release branch. Picking refuses to run with
+uncommitted changes or to replace an existing branch. Conflicts are reported and the branch is left
+with the commits that applied. With --push-remote the branches are pushed, and with --open-pr a PR
+titled "Bug <clone>: ..." is opened for each. With --dry-run the branches are still picked locally, but
+nothing is pushed or opened.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if bugOpts.debug {
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return err
+		}
+		if pickOpts.openPR && (pickOpts.pushRemote == "" || pickOpts.forkOwner == "") {
+			return fmt.Errorf("--open-pr requires --push-remote and --fork-owner")
+		}
+		repo, err := git.NewRepo(pickOpts.repoDir)
+		if err != nil {
+			return err
+		}
+		client, err := login.NewBugzillaClient(bugOpts.apiKey)
+		if err != nil {
+			return err
+		}
+		gh, err := login.NewGitHubClient(bugOpts.githubToken)
+		if err != nil {
+			return err
+		}
+
+		prs, err := mergedPRs(client, gh, id)
+		if err != nil {
+			return err
+		}
+		var commits []string
+		for _, pr := range prs {
+			// the PR's own commits aren't on any branch if it was squashed or rebased
+			if err := repo.FetchPull(pickOpts.remote, pr.Num); err != nil {
+				return err
+			}
+			cs, err := gh.ListPullRequestCommits(pr.Org, pr.Repo, pr.Num)
+			if err != nil {
+				return err
+			}
+			for _, c := range cs {
+				// merge commits from syncing the PR branch don't belong in the backport
+				if len(c.Parents) > 1 {
+					continue
+				}
+				commits = append(commits, c.SHA)
+			}
+		}
+
+		targets, err := cloneTargets(client, id)
+		if err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			return fmt.Errorf("bug %d has no clones targeting a release branch", id)
+		}
+
+		views := []view.CLIMarshaller{}
+		for _, target := range targets {
+			result := backport.Pick(repo, pickOpts.remote, commits, target)
+			v := &PickView{Clone: target.Clone.ID, Branch: target.Branch, LocalBranch: result.LocalBranch}
+			switch {
+			case result.Err != nil:
+				v.Result = result.Err.Error()
+			case pickOpts.pushRemote != "":
+				v.Result = publish(repo, gh, prs[0], result)
+			default:
+				v.Result = fmt.Sprintf("picked %d commits", result.Picked)
+			}
+			views = append(views, v)
+		}
+		return view.Print(os.Stdout, view.FormatTable, views)
+	},
+}
+
+// mergedPRs returns the merged PRs linked to a bug, oldest first, requiring
+// that they come from a single repo.
+func mergedPRs(client bugzilla.Client, gh github.Client, id int) ([]workflow.LinkedPR, error) {
+	linked, err := workflow.LinkedPRs(client, gh, id)
+	if err != nil {
+		return nil, err
+	}
+	var prs []workflow.LinkedPR
+	repos := map[string]bool{}
+	for _, pr := range linked {
+		name := pr.Org + "/" + pr.Repo
+		if pickOpts.githubRepo != "" && name != pickOpts.githubRepo {
+			continue
+		}
+		if pr.Err != nil {
+			// a commit could be missed
+			return nil, fmt.Errorf("could not look up %s#%d: %v", name, pr.Num, pr.Err)
+		}
+		if pr.PullRequest == nil || !pr.Merged || pr.MergedAt == nil {
+			continue
+		}
+		repos[name] = true
+		prs = append(prs, pr)
+	}
+	if len(prs) == 0 {
+		return nil, fmt.Errorf("bug %d has no merged pull requests linked", id)
+	}
+	if len(repos) > 1 {
+		return nil, fmt.Errorf("bug %d has merged pull requests in several repos, choose one with --github-repo", id)
+	}
+	sort.Slice(prs, func(i, j int) bool {
+		return prs[i].MergedAt.Before(*prs[j].MergedAt)
+	})
+	return prs, nil
+}
+
+// cloneTargets finds the clones of a bug and the branch each should be fixed on.
+func cloneTargets(client bugzilla.Client, id int) ([]backport.Target, error) {
+	query := url.Values{
+		"f1": {"cf_clone_of"},
+		"o1": {"equals"},
+		"v1": {strconv.Itoa(id)},
+	}
+	clones, err := client.SearchBugs(query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	wanted := map[string]bool{}
+	for _, b := range pickOpts.branches {
+		wanted[b] = true
+	}
+	branches := workflow.Branches{MasterRelease: pickOpts.masterRelease}
+	var targets []backport.Target
+	for _, clone := range clones {
+		for _, release := range clone.TargetRelease {
+			branch := branches.Expected(release)
+			if branch == "" || (len(wanted) > 0 && !wanted[branch]) {
+				continue
+			}
+			targets = append(targets, backport.Target{Clone: clone, Branch: branch})
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].Branch > targets[j].Branch
+	})
+	return targets, nil
+}
+
+// publish pushes a picked branch and opens its PR if asked, describing the outcome.
+// In a dry run nothing is pushed or opened, only described.
+func publish(repo *git.Repo, gh github.Client, original workflow.LinkedPR, result backport.Result) string {
+	pull := github.NewPullRequest{
+		Title: backport.PRTitle(result.Clone.ID, original.Title),
+		Body:  fmt.Sprintf("Backport of %s for %s.", strings.TrimPrefix(original.HTMLURL, "https://github.com/"), bugzilla.BugURL(bugzilla.DefaultEndpoint, result.Clone.ID)),
+		Head:  pickOpts.forkOwner + ":" + result.LocalBranch,
+		Base:  result.Branch,
+	}
+	if transport.DryRun() {
+		outcome := fmt.Sprintf("picked %d commits, would push %s to %s", result.Picked, result.LocalBranch, pickOpts.pushRemote)
+		if pickOpts.openPR {
+			outcome += fmt.Sprintf(" and open %q from %s against %s/%s:%s", pull.Title, pull.Head, original.Org, original.Repo, pull.Base)
+		}
+		return outcome
+	}
+	if err := repo.Push(pickOpts.pushRemote, result.LocalBranch); err != nil {
+		return err.Error()
+	}
+	if !pickOpts.openPR {
+		return fmt.Sprintf("picked %d commits, pushed to %s", result.Picked, pickOpts.pushRemote)
+	}
+	pr, err := gh.CreatePullRequest(original.Org, original.Repo, pull)
+	if err != nil {
+		return fmt.Sprintf("picked %d commits, could not open PR: %v", result.Picked, err)
+	}
+	return fmt.Sprintf("opened %s", pr.HTMLURL)
+}
+
+type PickView struct {
+	// Clone is the ID of the backport clone.
+	Clone int `cli:"Clone"`
+	// Branch is the release branch the fix is picked onto.
+	Branch string `cli:"Branch"`
+	// LocalBranch is the branch holding the picked commits.
+	LocalBranch string `cli:"Local Branch"`
+	// Result describes what happened.
+	Result string `cli:"Result"`
+}
+
+func (v PickView) MarshallCLI() ([]string, error) {
+	return view.MarshallCLI(v)
+}
+
+var _ view.CLIMarshaller = &PickView{}
+
+func init() {
+	pickCmd.Flags().StringVar(&pickOpts.repoDir, "repo-dir", ".", "local checkout of the repo to pick in")
+	pickCmd.Flags().StringVar(&pickOpts.remote, "remote", "origin", "remote with the release branches")
+	pickCmd.Flags().StringVar(&pickOpts.pushRemote, "push-remote", "", "remote to push the backport branches to, usually your fork")
+	pickCmd.Flags().StringVar(&pickOpts.forkOwner, "fork-owner", "", "github owner of the push remote, used to open PRs")
+	pickCmd.Flags().StringVar(&pickOpts.githubRepo, "github-repo", "", "only pick PRs from this org/repo")
+	pickCmd.Flags().StringSliceVar(&pickOpts.branches, "branches", nil, "only pick onto these release branches")
+	pickCmd.Flags().StringVar(&pickOpts.masterRelease, "master-release", "4.5", "release currently developed on master")
+	pickCmd.Flags().BoolVar(&pickOpts.openPR, "open-pr", false, "open a PR for each pushed branch")
+	backportCmd.AddCommand(pickCmd)
+}
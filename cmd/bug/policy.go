@@ -0,0 +1,203 @@
+package bug
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/ecordell/cop/cmd/exitcode"
+	"github.com/ecordell/cop/cmd/login"
+	"github.com/ecordell/cop/pkg/config"
+	"github.com/ecordell/cop/pkg/policy"
+	"github.com/ecordell/cop/pkg/view"
+)
+
+// exitViolations is the exit code of a policy check that found violations,
+// to tell them apart from errors, which exit with 1.
+const exitViolations = 2
+
+type policyOptions struct {
+	file  string
+	json  bool
+	apply bool
+	vars  map[string]string
+}
+
+var policyOpts policyOptions
+
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Check bugs against the team's SLA policy",
+}
+
+var policyCheckCmd = &cobra.Command{
+	Use:   "check [@NAME|QUERY]",
+	Short: "Report bugs violating the SLA policy",
+	Long: `Report bugs violating the SLA policy.
+
+Every open bug of the search (a saved @NAME, raw search parameters or a buglist.cgi url,
+defaulting to the team's open OLM bugs) is checked against each rule of the policy file.
+With --apply, the remediation of violated rules is carried out: a rule comments at most
+once on a bug, and doesn't request needinfo from an assignee who already has one pending.
+
+The command exits with 0 if there are no violations, 2 if there are and 1 on errors. Every
+violation is remediated even if some fail, and the failures are reported with the violations.
+
+A policy file looks like:
+
+  rules:
+  - name: urgent-unassigned
+    description: urgent bugs must be ASSIGNED within 1 day
+    match:
+      severity: [urgent]
+      status: [NEW]
+      older_than: 1d
+    remediate:
+      comment: "This urgent bug has been NEW for {{.Age}}, please triage it."
+      needinfo_assignee: true
+  - name: urgent-without-pr
+    description: urgent bugs must have a PR within 7 days
+    match:
+      severity: [urgent]
+      status_before: POST
+      older_than: 7d
+
+Matches can also select on priority, keywords, flags and unchanged_for, the time since
+the bug last changed. Keywords and flags are negated with a leading !, and flags may have
+a status, e.g. flags: ["blocker?", "!needinfo"].`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if bugOpts.debug {
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+		path := policyOpts.file
+		if path == "" {
+			var err error
+			if path, err = config.Path("policy.yaml"); err != nil {
+				return err
+			}
+		}
+		p, err := policy.Load(path)
+		if err != nil {
+			return err
+		}
+		var arg string
+		if len(args) > 0 {
+			arg = args[0]
+		}
+		query, err := resolveQuery(arg, nil, policyOpts.vars)
+		if err != nil {
+			return err
+		}
+		client, err := login.NewBugzillaClient(bugOpts.apiKey)
+		if err != nil {
+			return err
+		}
+		bugs, err := client.SearchBugs(query)
+		if err != nil {
+			return err
+		}
+		violations, err := p.Check(bugs, time.Now())
+		if err != nil {
+			return err
+		}
+
+		var (
+			remediated   []int
+			remediateErr error
+		)
+		if policyOpts.apply {
+			// failures are reported along with the violations
+			remediated, remediateErr = policy.Remediate(client, violations)
+		}
+
+		if policyOpts.json {
+			if violations == nil {
+				violations = []*policy.Violation{}
+			}
+			if err := view.PrintJSON(os.Stdout, struct {
+				Checked    int                 `json:"checked"`
+				Violations []*policy.Violation `json:"violations"`
+				Remediated []int               `json:"remediated,omitempty"`
+			}{len(bugs), violations, remediated}); err != nil {
+				return err
+			}
+		} else {
+			views := []view.CLIMarshaller{}
+			for _, v := range violations {
+				views = append(views, NewViolationView(v))
+			}
+			if err := view.Print(os.Stdout, view.FormatTable, views); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "%d violations in %d bugs checked.\n", len(violations), len(bugs))
+			if policyOpts.apply {
+				fmt.Fprintf(os.Stderr, "Remediated %d violations.\n", len(remediated))
+			}
+		}
+		if remediateErr != nil {
+			return remediateErr
+		}
+		if len(violations) > 0 {
+			// the violations were reported above, there's nothing to add
+			cmd.SilenceErrors = true
+			cmd.SilenceUsage = true
+			return &exitcode.Error{Code: exitViolations, Message: fmt.Sprintf("%d policy violations", len(violations))}
+		}
+		return nil
+	},
+}
+
+type ViolationView struct {
+	// ID is the unique numeric ID of the bug.
+	ID int `cli:"ID"`
+	// Rule is the name of the violated rule.
+	Rule string `cli:"Rule"`
+	// Status is the current status of the bug.
+	Status string `cli:"Status"`
+	// Severity is the current severity of the bug.
+	Severity string `cli:"Severity"`
+	// Assignee is the login name of the user to whom the bug is assigned.
+	Assignee string `cli:"Assignee"`
+	// Age is how long ago the bug was filed.
+	Age string `cli:"Age"`
+	// Summary is the summary of the bug.
+	Summary string `cli:"Summary,50"`
+	// Remediation is what --apply does about the violation, and why it failed if it did.
+	Remediation string `cli:"Remediation"`
+}
+
+func NewViolationView(v *policy.Violation) *ViolationView {
+	remediation := v.Remediation
+	if v.RemediationError != "" {
+		remediation = fmt.Sprintf("%s (failed: %s)", remediation, v.RemediationError)
+	}
+	return &ViolationView{
+		ID:          v.ID,
+		Rule:        v.Rule,
+		Status:      v.Status,
+		Severity:    v.Severity,
+		Assignee:    v.AssignedTo,
+		Age:         v.Age,
+		Summary:     v.Summary,
+		Remediation: remediation,
+	}
+}
+
+func (v ViolationView) MarshallCLI() ([]string, error) {
+	return view.MarshallCLI(v)
+}
+
+var _ view.CLIMarshaller = &ViolationView{}
+
+func init() {
+	policyCheckCmd.Flags().StringVar(&policyOpts.file, "policy", "", "policy file, defaults to policy.yaml in the cop data dir")
+	policyCheckCmd.Flags().BoolVar(&policyOpts.json, "json", false, "print the violations as json")
+	policyCheckCmd.Flags().BoolVar(&policyOpts.apply, "apply", false, "carry out the remediation of violated rules")
+	policyCheckCmd.Flags().StringToStringVar(&policyOpts.vars, "set", nil, "values for templated queries, e.g. Release=4.4")
+	policyCmd.AddCommand(policyCheckCmd)
+	BugCmd.AddCommand(policyCmd)
+}
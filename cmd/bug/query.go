@@ -0,0 +1,290 @@
+package bug
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/config"
+	"github.com/ecordell/cop/pkg/notify"
+	"github.com/ecordell/cop/pkg/view"
+)
+
+// queryFlags build up a bugzilla search from individual fields
+type queryFlags struct {
+	assignee       string
+	status         []string
+	product        string
+	components     []string
+	targetReleases []string
+	keywords       string
+	query          string
+}
+
+func (f *queryFlags) register(fs *pflag.FlagSet) {
+	fs.StringVar(&f.assignee, "assignee", "", `assignee's email, or "me" for whoever is logged in`)
+	fs.StringSliceVar(&f.status, "status", nil, "statuses to include, e.g. NEW,ASSIGNED")
+	fs.StringVar(&f.product, "product", "", "product to search")
+	fs.StringSliceVar(&f.components, "component", nil, "components to search")
+	fs.StringSliceVar(&f.targetReleases, "target-release", nil, "target releases to search, may use {{.Release}}")
+	fs.StringVar(&f.keywords, "keywords", "", "keywords the bugs must all have")
+	fs.StringVar(&f.query, "query", "", "raw search parameters, or a buglist.cgi url copied from the browser")
+}
+
+// params returns the search parameters from the flags, on top of base
+func (f *queryFlags) params(base url.Values) (url.Values, error) {
+	params := url.Values{}
+	for k, v := range base {
+		params[k] = append([]string{}, v...)
+	}
+	if f.query != "" {
+		raw, err := bugzilla.ParseQueryURL(f.query)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range raw {
+			params[k] = v
+		}
+	}
+	set := func(key string, values ...string) {
+		if len(values) > 0 && values[0] != "" {
+			params[key] = values
+		}
+	}
+	assignee := f.assignee
+	if assignee == "me" {
+		assignee = bugzilla.CurrentUser
+	}
+	set("assigned_to", assignee)
+	set("bug_status", f.status...)
+	set("product", f.product)
+	set("component", f.components...)
+	set("target_release", f.targetReleases...)
+	if f.keywords != "" {
+		params.Set("keywords", f.keywords)
+		params.Set("keywords_type", "allwords")
+	}
+	return params, nil
+}
+
+func (f *queryFlags) empty() bool {
+	return f.assignee == "" && len(f.status) == 0 && f.product == "" && len(f.components) == 0 &&
+		len(f.targetReleases) == 0 && f.keywords == "" && f.query == ""
+}
+
+// resolveQuery turns a query argument into a search for SearchBugs. An
+// argument of @name runs the saved query with that name, anything else is
+// taken as raw search parameters. Flags narrow the search further, and
+// templated values are filled in from vars. Without an argument, flags search
+// the default product and component unless they name their own.
+func resolveQuery(arg string, flags *queryFlags, vars map[string]string) (string, error) {
+	var base url.Values
+	var err error
+	switch {
+	case strings.HasPrefix(arg, "@"):
+		cfg, err := config.Load()
+		if err != nil {
+			return "", err
+		}
+		q, err := cfg.Query(strings.TrimPrefix(arg, "@"))
+		if err != nil {
+			return "", err
+		}
+		base = q.Params
+	case arg != "":
+		base, err = bugzilla.ParseQueryURL(arg)
+	case flags == nil || flags.empty():
+		base, err = url.ParseQuery(baseQuery)
+	case flags.product == "" && len(flags.components) == 0 && flags.query == "":
+		// keep searching the default product and component unless the
+		// flags say where to search
+		base, err = scope(baseQuery)
+	}
+	if err != nil {
+		return "", err
+	}
+	params := base
+	if flags != nil {
+		if params, err = flags.params(base); err != nil {
+			return "", err
+		}
+	}
+	return bugzilla.ExpandQuery(params, vars)
+}
+
+// scope returns just the classification, product and component of a query.
+func scope(query string) (url.Values, error) {
+	all, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	for _, k := range []string{"classification", "product", "component"} {
+		if v, ok := all[k]; ok {
+			params[k] = v
+		}
+	}
+	return params, nil
+}
+
+// ResolveQuery turns a saved @query name or raw search parameters into a
+// search for SearchBugs, filling in templated values from vars.
+func ResolveQuery(arg string, vars map[string]string) (string, error) {
+	return resolveQuery(arg, nil, vars)
+}
+
+// queryNotifier returns the notifier configured for a saved @query, or nil
+// if arg isn't a saved query or it has no notifications configured.
+func queryNotifier(arg, endpoint string) (*notify.Notifier, error) {
+	if !strings.HasPrefix(arg, "@") {
+		return nil, nil
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	q, err := cfg.Query(strings.TrimPrefix(arg, "@"))
+	if err != nil {
+		return nil, err
+	}
+	if q.Notify == nil {
+		return nil, nil
+	}
+	return notify.New(*q.Notify, arg, endpoint)
+}
+
+type queryOptions struct {
+	flags       queryFlags
+	description string
+}
+
+var queryOpts queryOptions
+
+var queryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "Manage saved queries",
+	Long: `Manage saved queries.
+
+Saved queries can be run with "cop bz list @NAME". Values may be templated, e.g.
+--target-release '{{.Release}}.z', and filled in with "cop bz list @NAME --set Release=4.4".
+
+Queries are kept in config.yaml in the cop data dir. Changes to a query's bugs can be
+sent to Slack, a webhook, email or desktop notifications by adding a notify section:
+
+  queries:
+    mine:
+      params: ...
+      notify:
+        digest: 15m        # send at most one batch of changes this often
+        max_per_hour: 4
+        sinks:
+        - slack: {webhook: "https://hooks.slack.com/services/..."}
+        - webhook: {url: "https://example.com/hook", body: '{"text": {{json .Title}}}'}
+        - email: {server: "smtp.example.com:25", from: "cop@example.com", to: ["team@example.com"]}
+        - desktop: {}
+
+Notifications are sent by "cop bz watch @NAME" and "cop bz reconcile --query @NAME --apply".`,
+}
+
+var querySaveCmd = &cobra.Command{
+	Use:   "save NAME",
+	Short: "Save a query for later",
+	Long: `Save a query for later.
+
+The query is built from the flags, or imported from a buglist.cgi url with --query.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if queryOpts.flags.empty() {
+			return fmt.Errorf("nothing to save, pass some search flags or --query")
+		}
+		params, err := queryOpts.flags.params(nil)
+		if err != nil {
+			return err
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(args[0], "@")
+		q := config.Query{Description: queryOpts.description, Params: params}
+		if existing, ok := cfg.Queries[name]; ok {
+			// notifications are configured by hand, don't lose them
+			q.Notify = existing.Notify
+		}
+		cfg.SetQuery(name, q)
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("Saved query @%s.\n", name)
+		return nil
+	},
+}
+
+var queryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved queries",
+	Long:  `List saved queries`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		views := []view.CLIMarshaller{}
+		for _, name := range cfg.QueryNames() {
+			q := cfg.Queries[name]
+			params, err := url.QueryUnescape(q.Params.Encode())
+			if err != nil {
+				return err
+			}
+			views = append(views, &QueryView{Name: "@" + name, Description: q.Description, Params: params})
+		}
+		if len(views) == 0 {
+			fmt.Println("No saved queries, add one with `cop bz query save`.")
+			return nil
+		}
+		return view.Print(os.Stdout, view.FormatTable, views)
+	},
+}
+
+var queryDeleteCmd = &cobra.Command{
+	Use:   "delete NAME",
+	Short: "Delete a saved query",
+	Long:  `Delete a saved query`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(args[0], "@")
+		if _, err := cfg.Query(name); err != nil {
+			return err
+		}
+		delete(cfg.Queries, name)
+		return cfg.Save()
+	},
+}
+
+type QueryView struct {
+	Name        string `cli:"Name"`
+	Description string `cli:"Description,40"`
+	Params      string `cli:"Query,80"`
+}
+
+func (q QueryView) MarshallCLI() ([]string, error) {
+	return view.MarshallCLI(q)
+}
+
+var _ view.CLIMarshaller = &QueryView{}
+
+func init() {
+	queryOpts.flags.register(querySaveCmd.Flags())
+	querySaveCmd.Flags().StringVar(&queryOpts.description, "description", "", "what the query is for")
+	queryCmd.AddCommand(querySaveCmd, queryListCmd, queryDeleteCmd)
+	BugCmd.AddCommand(queryCmd)
+}
@@ -0,0 +1,163 @@
+package bug
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/config"
+)
+
+func TestQueryFlagsParams(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags queryFlags
+		base  url.Values
+		want  url.Values
+	}{
+		{
+			name: "no flags keep the base",
+			base: url.Values{"product": {"OCP"}, "bug_status": {"NEW"}},
+			want: url.Values{"product": {"OCP"}, "bug_status": {"NEW"}},
+		},
+		{
+			name:  "flags override the base",
+			flags: queryFlags{status: []string{"POST", "MODIFIED"}, components: []string{"OLM", "Catalog"}},
+			base:  url.Values{"product": {"OCP"}, "bug_status": {"NEW"}},
+			want:  url.Values{"product": {"OCP"}, "bug_status": {"POST", "MODIFIED"}, "component": {"OLM", "Catalog"}},
+		},
+		{
+			name:  "me is the logged in user",
+			flags: queryFlags{assignee: "me"},
+			want:  url.Values{"assigned_to": {bugzilla.CurrentUser}},
+		},
+		{
+			name:  "keywords must all match",
+			flags: queryFlags{keywords: "Regression TestBlocker"},
+			want:  url.Values{"keywords": {"Regression TestBlocker"}, "keywords_type": {"allwords"}},
+		},
+		{
+			name:  "raw query under the other flags",
+			flags: queryFlags{query: "https://bugzilla.redhat.com/buglist.cgi?product=OCP&bug_status=NEW", status: []string{"ON_QA"}},
+			base:  url.Values{"product": {"Other"}, "component": {"OLM"}},
+			want:  url.Values{"product": {"OCP"}, "component": {"OLM"}, "bug_status": {"ON_QA"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := tt.flags.params(tt.base)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, params)
+		})
+	}
+}
+
+func TestQueryFlagsParamsLeavesBaseAlone(t *testing.T) {
+	base := url.Values{"bug_status": {"NEW"}}
+	flags := queryFlags{status: []string{"POST"}}
+	_, err := flags.params(base)
+	require.NoError(t, err)
+	require.Equal(t, url.Values{"bug_status": {"NEW"}}, base)
+}
+
+func TestResolveQuery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cop-query")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer os.Setenv("COP_DATA_DIR", os.Getenv("COP_DATA_DIR"))
+	os.Setenv("COP_DATA_DIR", dir)
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	cfg.SetQuery("zstream", config.Query{Params: url.Values{
+		"bug_status":     {"NEW"},
+		"target_release": {"{{.Release}}.z"},
+	}})
+	require.NoError(t, cfg.Save())
+
+	defaults, err := url.ParseQuery(baseQuery)
+	require.NoError(t, err)
+	defaultScope := url.Values{
+		"classification": defaults["classification"],
+		"product":        defaults["product"],
+		"component":      defaults["component"],
+	}
+	with := func(params url.Values, extra url.Values) url.Values {
+		out := url.Values{}
+		for k, v := range params {
+			out[k] = v
+		}
+		for k, v := range extra {
+			out[k] = v
+		}
+		return out
+	}
+
+	tests := []struct {
+		name  string
+		arg   string
+		flags *queryFlags
+		vars  map[string]string
+		want  url.Values
+	}{
+		{
+			name: "default query without flags",
+			want: defaults,
+		},
+		{
+			name:  "flags search the default product and component",
+			flags: &queryFlags{status: []string{"ON_QA"}},
+			want:  with(defaultScope, url.Values{"bug_status": {"ON_QA"}}),
+		},
+		{
+			name:  "a product flag drops the default scope",
+			flags: &queryFlags{product: "OCP", status: []string{"ON_QA"}},
+			want:  url.Values{"product": {"OCP"}, "bug_status": {"ON_QA"}},
+		},
+		{
+			name:  "a component flag drops the default scope",
+			flags: &queryFlags{components: []string{"Catalog"}},
+			want:  url.Values{"component": {"Catalog"}},
+		},
+		{
+			name:  "a raw query drops the default scope",
+			flags: &queryFlags{query: "product=OCP&bug_status=NEW"},
+			want:  url.Values{"product": {"OCP"}, "bug_status": {"NEW"}},
+		},
+		{
+			name:  "raw argument",
+			arg:   "product=OCP&bug_status=NEW",
+			flags: &queryFlags{},
+			want:  url.Values{"product": {"OCP"}, "bug_status": {"NEW"}},
+		},
+		{
+			name:  "saved query narrowed by flags",
+			arg:   "@zstream",
+			flags: &queryFlags{assignee: "me"},
+			vars:  map[string]string{"Release": "4.4"},
+			want:  url.Values{"bug_status": {"NEW"}, "target_release": {"4.4.z"}, "assigned_to": {bugzilla.CurrentUser}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := resolveQuery(tt.arg, tt.flags, tt.vars)
+			require.NoError(t, err)
+			params, err := url.ParseQuery(query)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, params)
+		})
+	}
+
+	t.Run("unknown saved query", func(t *testing.T) {
+		_, err := resolveQuery("@missing", nil, nil)
+		require.Error(t, err)
+	})
+	t.Run("missing template value", func(t *testing.T) {
+		_, err := resolveQuery("@zstream", nil, nil)
+		require.Error(t, err)
+	})
+}
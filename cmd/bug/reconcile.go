@@ -0,0 +1,187 @@
+package bug
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/ecordell/cop/cmd/login"
+	"github.com/ecordell/cop/pkg/transport"
+	"github.com/ecordell/cop/pkg/view"
+	"github.com/ecordell/cop/pkg/watch"
+	"github.com/ecordell/cop/pkg/workflow"
+)
+
+type reconcileOptions struct {
+	query          string
+	targetVersions []string
+	masterRelease  string
+	apply          bool
+}
+
+var reconcileOpts reconcileOptions
+
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Move bugs along the workflow based on their linked PRs",
+	Long: `Move bugs along the workflow based on their linked PRs.
+
+Bugs move to POST when a PR against the branch for their target release is linked,
+and to MODIFIED once all such PRs have merged. NEW bugs are moved to ASSIGNED on the way.
+Bugs with a linked PR that can't be looked up are left alone.
+
+Without --apply, or with --dry-run, the transitions are only printed. A bug that fails to move
+doesn't stop the rest.
+Applied transitions are sent to the notification sinks of a saved --query, if it has any.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if bugOpts.debug {
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+		client, err := login.NewBugzillaClient(bugOpts.apiKey)
+		if err != nil {
+			return err
+		}
+		gh, err := login.NewGitHubClient(bugOpts.githubToken)
+		if err != nil {
+			return err
+		}
+
+		query := reconcileOpts.query
+		if query == "" {
+			query = baseQuery
+			for _, v := range reconcileOpts.targetVersions {
+				query = query + "&target_release=" + v
+			}
+		} else if query, err = resolveQuery(query, nil, nil); err != nil {
+			return err
+		}
+		bugs, err := client.SearchBugs(query)
+		if err != nil {
+			return err
+		}
+
+		branches := workflow.Branches{MasterRelease: reconcileOpts.masterRelease}
+		var transitions []*workflow.Transition
+		for _, bug := range bugs {
+			prs, err := workflow.LinkedPRs(client, gh, bug.ID)
+			if err != nil {
+				return err
+			}
+			result := workflow.Reconcile(bug, prs, branches)
+			for _, w := range result.Warnings {
+				fmt.Fprintf(os.Stderr, "Bug %d: %s\n", bug.ID, w)
+			}
+			if result.Transition != nil {
+				transitions = append(transitions, result.Transition)
+			}
+		}
+		if len(transitions) == 0 {
+			fmt.Println("All bugs are up to date.")
+			return nil
+		}
+
+		views := []view.CLIMarshaller{}
+		for _, t := range transitions {
+			views = append(views, NewTransitionView(t))
+		}
+		if err := view.Print(os.Stdout, view.FormatTable, views); err != nil {
+			return err
+		}
+		if !reconcileOpts.apply {
+			fmt.Println("\nRerun with --apply to make these changes.")
+			return nil
+		}
+		if transport.DryRun() {
+			// not every sink sends over http, so the dry run can't just skip the requests
+			for _, t := range transitions {
+				fmt.Printf("Would move bug %d to %s.\n", t.Bug.ID, t.To)
+			}
+			return nil
+		}
+		notifier, err := queryNotifier(reconcileOpts.query, client.Endpoint())
+		if err != nil {
+			return err
+		}
+		var failed []string
+		for _, t := range transitions {
+			if err := workflow.Apply(client, t); err != nil {
+				fmt.Fprintf(os.Stderr, "Could not move bug %d: %v\n", t.Bug.ID, err)
+				failed = append(failed, strconv.Itoa(t.Bug.ID))
+				continue
+			}
+			fmt.Printf("Moved bug %d to %s.\n", t.Bug.ID, t.To)
+			if notifier != nil {
+				notifier.Add(transitionEvent(t))
+			}
+		}
+		// the bugs that did move are notified of regardless
+		var flushErr error
+		if notifier != nil {
+			flushErr = notifier.Flush(context.Background(), true)
+		}
+		if len(failed) > 0 {
+			if flushErr != nil {
+				logrus.WithError(flushErr).Warn("could not send notifications")
+			}
+			return fmt.Errorf("could not move %d of %d bugs: %s", len(failed), len(transitions), strings.Join(failed, ", "))
+		}
+		return flushErr
+	},
+}
+
+// transitionEvent describes an applied transition for notifications
+func transitionEvent(t *workflow.Transition) watch.Event {
+	return watch.Event{
+		Time:    time.Now().UTC().Format(time.RFC3339),
+		Bug:     t.Bug.ID,
+		Summary: t.Bug.Summary,
+		Kind:    watch.KindChanged,
+		Field:   "status",
+		From:    t.From,
+		To:      t.To,
+		Text:    t.Reason,
+	}
+}
+
+type TransitionView struct {
+	// ID is the unique numeric ID of the bug.
+	ID int `cli:"ID"`
+	// From is the current status of the bug.
+	From string `cli:"From"`
+	// To is the status the bug should move to, after any it passes through.
+	To string `cli:"To"`
+	// Reason explains why the bug should move.
+	Reason string `cli:"Reason,60"`
+	// Summary is the summary of the bug.
+	Summary string `cli:"Summary,50"`
+}
+
+func NewTransitionView(t *workflow.Transition) *TransitionView {
+	return &TransitionView{
+		ID:      t.Bug.ID,
+		From:    t.From,
+		To:      strings.Join(t.Steps(), " → "),
+		Reason:  t.Reason,
+		Summary: t.Bug.Summary,
+	}
+}
+
+func (v TransitionView) MarshallCLI() ([]string, error) {
+	return view.MarshallCLI(v)
+}
+
+var _ view.CLIMarshaller = &TransitionView{}
+
+func init() {
+	reconcileCmd.Flags().StringVar(&reconcileOpts.query, "query", "", "bugzilla search query or saved @query selecting the bugs to reconcile, defaults to open OLM bugs")
+	reconcileCmd.Flags().StringSliceVarP(&reconcileOpts.targetVersions, "versions", "v", []string{"4.5.0"}, "target versions to query when no query is given")
+	reconcileCmd.Flags().StringVar(&reconcileOpts.masterRelease, "master-release", "4.5", "release currently developed on master")
+	reconcileCmd.Flags().BoolVar(&reconcileOpts.apply, "apply", false, "make the proposed transitions")
+	BugCmd.AddCommand(reconcileCmd)
+}
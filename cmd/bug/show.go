@@ -0,0 +1,107 @@
+package bug
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/ecordell/cop/cmd/login"
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/github"
+	"github.com/ecordell/cop/pkg/view"
+)
+
+var showCmd = &cobra.Command{
+	Use:   "show ID",
+	Short: "Show a bug and the state of its linked PRs",
+	Long:  `Show a bug and the state of its linked PRs`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if bugOpts.debug {
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return err
+		}
+		client, err := login.NewBugzillaClient(bugOpts.apiKey)
+		if err != nil {
+			return err
+		}
+		gh, err := login.NewGitHubClient(bugOpts.githubToken)
+		if err != nil {
+			return err
+		}
+
+		bug, err := client.GetBug(id)
+		if err != nil {
+			return err
+		}
+		prs, err := client.GetExternalBugPRsOnBug(id)
+		if err != nil {
+			return err
+		}
+
+		v := NewSimpleBugView(*bug)
+		fmt.Printf("Bug %d: %s\n", v.ID, v.Summary)
+		fmt.Printf("URL:      %s\n", bugzilla.BugURL(client.Endpoint(), v.ID))
+		fmt.Printf("Status:   %s\n", v.Status)
+		fmt.Printf("Assignee: %s\n", v.AssignedTo)
+		fmt.Printf("Target:   %s\n", strings.Join(bug.TargetRelease, ", "))
+		fmt.Printf("Severity: %s  Priority: %s\n", v.Severity, v.Priority)
+		fmt.Printf("Backport: %s\n", v.Backport)
+		if len(prs) == 0 {
+			fmt.Println("No linked pull requests.")
+			return nil
+		}
+		fmt.Println("Pull Requests:")
+		for _, ext := range prs {
+			description, err := describePR(gh, ext)
+			if err != nil {
+				logrus.WithError(err).Warnf("could not get %s", ext.ExternalBugID)
+				description = "unknown state"
+			}
+			fmt.Printf("  %s/%s#%d %s\n", ext.Org, ext.Repo, ext.Num, description)
+		}
+		return nil
+	},
+}
+
+// describePR summarizes a linked pull request, e.g.
+// "merged into release-4.4 2 days ago" or "open against master, CI pending".
+func describePR(gh github.Client, ext bugzilla.GithubExternalBug) (string, error) {
+	pr, err := gh.GetPullRequest(ext.Org, ext.Repo, ext.Num)
+	if err != nil {
+		return "", err
+	}
+	if pr.Merged && pr.MergedAt != nil {
+		return fmt.Sprintf("merged into %s %s", pr.Base.Ref, view.Ago(*pr.MergedAt)), nil
+	}
+	if pr.State == "closed" {
+		return fmt.Sprintf("closed without merging into %s", pr.Base.Ref), nil
+	}
+
+	parts := []string{"open against " + pr.Base.Ref}
+	status, err := gh.GetCombinedStatus(ext.Org, ext.Repo, pr.Head.SHA)
+	if err != nil {
+		return "", err
+	}
+	if len(status.Statuses) > 0 {
+		parts = append(parts, "CI "+status.State)
+	}
+	var labels []string
+	for _, l := range pr.Labels {
+		labels = append(labels, l.Name)
+	}
+	if len(labels) > 0 {
+		parts = append(parts, "labels: "+strings.Join(labels, " "))
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+func init() {
+	BugCmd.AddCommand(showCmd)
+}
@@ -1,13 +1,14 @@
 package bug
 
 import (
+	"context"
 	"fmt"
-	"gopkg.in/andygrunwald/go-jira.v1"
 	"strconv"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
+	"github.com/ecordell/cop/cmd/login"
 	"github.com/ecordell/cop/pkg/bugzilla"
 	jiraclient "github.com/ecordell/cop/pkg/jira"
 )
@@ -41,7 +42,7 @@ var syncCmd = &cobra.Command{
 			return []byte(bugOpts.apiKey)
 		}, endpoint)
 
-		client, err := jiraclient.NewClient(bugOpts.jiraUser, bugOpts.jiraPass)
+		client, err := login.NewJiraClient(jiraclient.DefaultEndpoint, bugOpts.jiraUser, bugOpts.jiraPass)
 		if err != nil {
 			return err
 		}
@@ -57,9 +58,9 @@ var syncCmd = &cobra.Command{
 	},
 }
 
-func printBug(jiraIssueId string, client *jira.Client) error {
+func printBug(jiraIssueId string, client jiraclient.Client) error {
 	fmt.Printf("JIRA issue link found: %s\n", jiraIssueId)
-	issue, _, err := client.Issue.Get(jiraIssueId, nil)
+	issue, err := client.GetIssue(context.Background(), jiraIssueId)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,173 @@
+package bug
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/ecordell/cop/cmd/login"
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/config"
+	"github.com/ecordell/cop/pkg/transport"
+	"github.com/ecordell/cop/pkg/triage"
+	"github.com/ecordell/cop/pkg/view"
+	"github.com/ecordell/cop/pkg/workflow"
+)
+
+type triageOptions struct {
+	rules string
+	audit string
+	flags queryFlags
+	vars  map[string]string
+}
+
+var triageOpts triageOptions
+
+var triageCmd = &cobra.Command{
+	Use:   "triage [@NAME|QUERY]",
+	Short: "Route bugs with triage rules",
+	Long: `Route bugs with triage rules, setting their assignee, sub component, priority
+and status whiteboard tokens by what they are about.
+
+The search is a saved @NAME, raw search parameters or a buglist.cgi url, narrowed by the
+flags, and defaults to the team's NEW bugs. Every rule applied is logged to the audit
+log, and a rule is never applied to the same bug twice, so reruns leave bugs that were
+rerouted by hand alone.
+
+A rules file looks like:
+
+  rules:
+  - name: catalog
+    match:
+      summary: (?i)catalog|index image|grpc
+      component: [OLM]
+    set:
+      assignee: catalog-owner@redhat.com
+      sub_component: OperatorHub
+      whiteboard: [catalog]
+  - name: webhooks
+    match:
+      summary: (?i)webhook
+      description: (?i)(validating|mutating|conversion) webhook
+    set:
+      assignee: webhook-owner@redhat.com
+      priority: high
+
+Rules can also match on reporter and keywords. Every matching rule applies, but a field
+is set by the first rule setting it.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if bugOpts.debug {
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+		if triageOpts.rules == "" {
+			return fmt.Errorf("--rules is required")
+		}
+		rules, err := triage.Load(triageOpts.rules)
+		if err != nil {
+			return err
+		}
+		auditPath := triageOpts.audit
+		if auditPath == "" {
+			if auditPath, err = config.Path("triage.log"); err != nil {
+				return err
+			}
+		}
+		audit, err := triage.OpenAudit(auditPath)
+		if err != nil {
+			return err
+		}
+
+		var arg string
+		if len(args) > 0 {
+			arg = args[0]
+		} else if triageOpts.flags.empty() {
+			params, err := url.ParseQuery(baseQuery)
+			if err != nil {
+				return err
+			}
+			params["bug_status"] = []string{workflow.StatusNew}
+			arg = params.Encode()
+		}
+		query, err := resolveQuery(arg, &triageOpts.flags, triageOpts.vars)
+		if err != nil {
+			return err
+		}
+		client, err := login.NewBugzillaClient(bugOpts.apiKey)
+		if err != nil {
+			return err
+		}
+		bugs, err := client.SearchBugs(query)
+		if err != nil {
+			return err
+		}
+
+		var decisions []*triage.Decision
+		for _, bug := range bugs {
+			var description string
+			if rules.NeedsDescription() {
+				if description, err = bugzilla.GetDescription(client, bug.ID); err != nil {
+					return err
+				}
+			}
+			if d := rules.Triage(bug, description, audit.Applied(bug.ID)); d != nil {
+				decisions = append(decisions, d)
+			}
+		}
+		if len(decisions) == 0 {
+			fmt.Println("Nothing to triage.")
+			return nil
+		}
+
+		views := []view.CLIMarshaller{}
+		for _, d := range decisions {
+			for _, c := range d.Applied {
+				views = append(views, &TriageView{ID: d.Bug.ID, Rule: c.Rule, Changes: strings.Join(c.Changes, ", "), Summary: d.Bug.Summary})
+			}
+		}
+		if err := view.Print(os.Stdout, view.FormatTable, views); err != nil {
+			return err
+		}
+		if transport.DryRun() {
+			fmt.Println("\nDry run, nothing was changed.")
+			return nil
+		}
+		for _, d := range decisions {
+			if err := triage.Apply(client, d, audit, time.Now()); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("\nTriaged %d bugs, logged to %s.\n", len(decisions), auditPath)
+		return nil
+	},
+}
+
+type TriageView struct {
+	// ID is the unique numeric ID of the bug.
+	ID int `cli:"ID"`
+	// Rule is the name of the applied rule.
+	Rule string `cli:"Rule"`
+	// Changes are what the rule changes.
+	Changes string `cli:"Changes,60"`
+	// Summary is the summary of the bug.
+	Summary string `cli:"Summary,50"`
+}
+
+func (v TriageView) MarshallCLI() ([]string, error) {
+	return view.MarshallCLI(v)
+}
+
+var _ view.CLIMarshaller = &TriageView{}
+
+func init() {
+	triageCmd.Flags().StringVar(&triageOpts.rules, "rules", "", "triage rules file")
+	triageCmd.Flags().StringVar(&triageOpts.audit, "audit-log", "", "file applied rules are logged to, defaults to triage.log in the cop data dir")
+	triageCmd.Flags().StringToStringVar(&triageOpts.vars, "set", nil, "values for templated queries, e.g. Release=4.4")
+	triageOpts.flags.register(triageCmd.Flags())
+	BugCmd.AddCommand(triageCmd)
+}
@@ -0,0 +1,165 @@
+package bug
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/ecordell/cop/cmd/login"
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/github"
+	"github.com/ecordell/cop/pkg/view"
+)
+
+type unlinkedOptions struct {
+	repos []string
+	since string
+	yes   bool
+}
+
+var unlinkedOpts unlinkedOptions
+
+var unlinkedCmd = &cobra.Command{
+	Use:   "find-unlinked",
+	Short: "Find PRs that reference bugs that don't link back to them",
+	Long: `Find PRs whose title or commits reference a bug, e.g. "Bug NNNNNN", "BZ#NNNNNN" or a bugzilla link, but
+aren't linked from that bug's external trackers, and offer to add the missing links.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if bugOpts.debug {
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+		age, err := view.ParseAge(unlinkedOpts.since)
+		if err != nil {
+			return err
+		}
+		client, err := login.NewBugzillaClient(bugOpts.apiKey)
+		if err != nil {
+			return err
+		}
+		gh, err := login.NewGitHubClient(bugOpts.githubToken)
+		if err != nil {
+			return err
+		}
+
+		var missing []*UnlinkedView
+		for _, r := range unlinkedOpts.repos {
+			parts := strings.Split(r, "/")
+			if len(parts) != 2 {
+				return fmt.Errorf("invalid repo %q, must be org/repo", r)
+			}
+			found, err := findUnlinked(client, gh, parts[0], parts[1], time.Now().Add(-age))
+			if err != nil {
+				return err
+			}
+			missing = append(missing, found...)
+		}
+		if len(missing) == 0 {
+			fmt.Println("All referenced bugs link to their PRs.")
+			return nil
+		}
+
+		views := []view.CLIMarshaller{}
+		for _, m := range missing {
+			views = append(views, m)
+		}
+		if err := view.Print(os.Stdout, view.FormatTable, views); err != nil {
+			return err
+		}
+		if !unlinkedOpts.yes {
+			prompt := promptui.Prompt{
+				Label:     fmt.Sprintf("Add %d missing links", len(missing)),
+				IsConfirm: true,
+			}
+			if _, err := prompt.Run(); err != nil {
+				return nil
+			}
+		}
+		for _, m := range missing {
+			if _, err := client.AddPullRequestAsExternalBug(m.Bug, m.org, m.repo, m.num); err != nil {
+				return fmt.Errorf("could not link %s to bug %d: %v", m.PR, m.Bug, err)
+			}
+			fmt.Printf("Linked %s to bug %d.\n", m.PR, m.Bug)
+		}
+		return nil
+	},
+}
+
+// findUnlinked returns the references from PRs in a repo to bugs that don't
+// have the PR as an external bug.
+func findUnlinked(client bugzilla.Client, gh github.Client, org, repo string, since time.Time) ([]*UnlinkedView, error) {
+	prs, err := gh.ListPullRequests(org, repo, since)
+	if err != nil {
+		return nil, err
+	}
+	// linked caches the PR identifiers linked from each bug
+	linked := map[int]map[string]bool{}
+	var missing []*UnlinkedView
+	for _, pr := range prs {
+		text := []string{pr.Title}
+		commits, err := gh.ListPullRequestCommits(org, repo, pr.Number)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range commits {
+			text = append(text, c.Commit.Message)
+		}
+
+		identifier := bugzilla.IdentifierForPull(org, repo, pr.Number)
+		for _, id := range bugzilla.References(strings.Join(text, "\n")) {
+			if _, ok := linked[id]; !ok {
+				exts, err := client.GetExternalBugPRsOnBug(id)
+				if err != nil {
+					logrus.WithError(err).Warnf("could not get external bugs for bug %d referenced by %s", id, identifier)
+					linked[id] = nil
+					continue
+				}
+				linked[id] = map[string]bool{}
+				for _, ext := range exts {
+					linked[id][bugzilla.IdentifierForPull(ext.Org, ext.Repo, ext.Num)] = true
+				}
+			}
+			if linked[id] == nil || linked[id][identifier] {
+				continue
+			}
+			missing = append(missing, &UnlinkedView{
+				Bug:   id,
+				PR:    fmt.Sprintf("%s/%s#%d", org, repo, pr.Number),
+				Title: pr.Title,
+				org:   org,
+				repo:  repo,
+				num:   pr.Number,
+			})
+		}
+	}
+	return missing, nil
+}
+
+type UnlinkedView struct {
+	// Bug is the ID of the referenced bug.
+	Bug int `cli:"Bug"`
+	// PR is the pull request that references the bug.
+	PR string `cli:"PR"`
+	// Title is the title of the pull request.
+	Title string `cli:"Title,60"`
+
+	org, repo string
+	num       int
+}
+
+func (v UnlinkedView) MarshallCLI() ([]string, error) {
+	return view.MarshallCLI(v)
+}
+
+var _ view.CLIMarshaller = &UnlinkedView{}
+
+func init() {
+	unlinkedCmd.Flags().StringSliceVar(&unlinkedOpts.repos, "repo", []string{"operator-framework/operator-lifecycle-manager"}, "github repos to scan, as org/repo")
+	unlinkedCmd.Flags().StringVar(&unlinkedOpts.since, "since", "2w", "how far back to look at PRs, e.g. 3d or 2w")
+	unlinkedCmd.Flags().BoolVarP(&unlinkedOpts.yes, "yes", "y", false, "add the missing links without asking")
+	BugCmd.AddCommand(unlinkedCmd)
+}
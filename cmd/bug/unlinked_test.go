@@ -0,0 +1,62 @@
+package bug
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/github"
+)
+
+func TestFindUnlinked(t *testing.T) {
+	now := time.Now()
+	client := &bugzilla.Fake{
+		Bugs: map[int]bugzilla.Bug{
+			1812345: {ID: 1812345},
+			1812346: {ID: 1812346},
+			1812347: {ID: 1812347},
+		},
+		BugErrors: map[int]bool{1812347: true},
+	}
+	// 1812345 already links back to #1
+	_, err := client.AddPullRequestAsExternalBug(1812345, "operator-framework", "operator-lifecycle-manager", 1)
+	require.NoError(t, err)
+
+	gh := &github.Fake{
+		PullRequests: map[string][]github.PullRequest{
+			"operator-framework/operator-lifecycle-manager": {
+				{Number: 1, Title: "Bug 1812345: fix catalog pod", UpdatedAt: now},
+				{Number: 2, Title: "Bug 1812345: fix catalog pod on 4.4", UpdatedAt: now},
+				{Number: 3, Title: "fix install plans", UpdatedAt: now},
+				{Number: 4, Title: "BZ#1812347: can't look this one up", UpdatedAt: now},
+				{Number: 5, Title: "Bug 1812346: too old", UpdatedAt: now.Add(-48 * time.Hour)},
+			},
+		},
+		Commits: map[string][]github.Commit{
+			"operator-framework/operator-lifecycle-manager#3": {
+				{SHA: "a", Commit: github.GitCommit{Message: "fix install plans\n\nSee https://bugzilla.redhat.com/show_bug.cgi?id=1812346"}},
+				{SHA: "b", Commit: github.GitCommit{Message: "Bug 1812346: and again"}},
+			},
+		},
+	}
+
+	missing, err := findUnlinked(client, gh, "operator-framework", "operator-lifecycle-manager", now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	var found []UnlinkedView
+	for _, m := range missing {
+		found = append(found, *m)
+	}
+	require.Equal(t, []UnlinkedView{
+		{Bug: 1812345, PR: "operator-framework/operator-lifecycle-manager#2", Title: "Bug 1812345: fix catalog pod on 4.4", org: "operator-framework", repo: "operator-lifecycle-manager", num: 2},
+		{Bug: 1812346, PR: "operator-framework/operator-lifecycle-manager#3", Title: "fix install plans", org: "operator-framework", repo: "operator-lifecycle-manager", num: 3},
+	}, found)
+}
+
+func TestFindUnlinkedGitHubFails(t *testing.T) {
+	gh := &github.Fake{Err: errors.New("API rate limit exceeded")}
+	_, err := findUnlinked(&bugzilla.Fake{}, gh, "operator-framework", "operator-lifecycle-manager", time.Now())
+	require.EqualError(t, err, "API rate limit exceeded")
+}
@@ -0,0 +1,116 @@
+package bug
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/ecordell/cop/cmd/login"
+	"github.com/ecordell/cop/pkg/notify"
+	"github.com/ecordell/cop/pkg/signals"
+	"github.com/ecordell/cop/pkg/transport"
+	"github.com/ecordell/cop/pkg/view"
+	"github.com/ecordell/cop/pkg/watch"
+)
+
+type watchOptions struct {
+	interval time.Duration
+	json     bool
+	noNotify bool
+	me       string
+	vars     map[string]string
+}
+
+var watchOpts watchOptions
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [@NAME|QUERY]",
+	Short: "Watch bugs for changes",
+	Long: `Watch bugs for changes.
+
+Polls the search (a saved @NAME, raw search parameters or a buglist.cgi url) for bugs
+changed since the last poll and prints what changed: field changes, new comments, flags
+and bugs newly matching the search. Stops on interrupt.
+
+If the search is a saved query with notifications configured in the cop config,
+changes are also sent to its notification sinks (see "cop bz query --help").`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if bugOpts.debug {
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+		var arg string
+		if len(args) > 0 {
+			arg = args[0]
+		}
+		ctx := signals.Context()
+		query, err := resolveQuery(arg, nil, watchOpts.vars)
+		if err != nil {
+			return err
+		}
+		client, err := login.NewBugzillaClient(bugOpts.apiKey)
+		if err != nil {
+			return err
+		}
+
+		print := func(e watch.Event) error {
+			_, err := fmt.Printf("%s %s\n", e.Time, e)
+			return err
+		}
+		if watchOpts.json {
+			print = func(e watch.Event) error {
+				return view.PrintJSONLine(os.Stdout, e)
+			}
+		}
+		var notifier *notify.Notifier
+		if !watchOpts.noNotify {
+			if notifier, err = queryNotifier(arg, client.Endpoint()); err != nil {
+				return err
+			}
+			if notifier != nil && transport.DryRun() {
+				// not every sink sends over http, so the dry run can't just skip the requests
+				fmt.Fprintln(os.Stderr, "Dry run, not sending notifications.")
+				notifier = nil
+			}
+		}
+		handle := func(events []watch.Event) error {
+			for _, e := range events {
+				if err := print(e); err != nil {
+					return err
+				}
+			}
+			if notifier == nil {
+				return nil
+			}
+			notifier.Add(events...)
+			if err := notifier.Flush(ctx, false); err != nil {
+				// the events a sink failed to send are retried with its next notification
+				logrus.WithError(err).Warn("could not send notifications")
+			}
+			return nil
+		}
+
+		fmt.Fprintf(os.Stderr, "Watching for changes every %s.\n", watchOpts.interval)
+		if err := watch.NewWatcher(client, query, watchOpts.me).Run(ctx, watchOpts.interval, handle); err != nil {
+			return err
+		}
+		if notifier != nil {
+			// send anything still held back by the digest or rate limit
+			return notifier.Flush(context.Background(), true)
+		}
+		return nil
+	},
+}
+
+func init() {
+	watchCmd.Flags().DurationVar(&watchOpts.interval, "interval", time.Minute, "how often to poll bugzilla")
+	watchCmd.Flags().BoolVar(&watchOpts.json, "json", false, "print events as json lines")
+	watchCmd.Flags().BoolVar(&watchOpts.noNotify, "no-notify", false, "don't send notifications configured for the saved query")
+	watchCmd.Flags().StringVar(&watchOpts.me, "me", "", "your bugzilla login, to point out flags requested of you")
+	watchCmd.Flags().StringToStringVar(&watchOpts.vars, "set", nil, "values for templated queries, e.g. Release=4.4")
+	BugCmd.AddCommand(watchCmd)
+}
@@ -0,0 +1,30 @@
+package docs
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type docsOptions struct {
+	debug bool
+
+	apiKey string
+}
+
+var docsOpts docsOptions
+
+var DocsCmd = &cobra.Command{
+	Use:   "docs",
+	Short: "Manage docs and release notes",
+	Long:  `Manage docs and release notes`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if docsOpts.debug {
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+	},
+}
+
+func init() {
+	DocsCmd.PersistentFlags().BoolVarP(&docsOpts.debug, "debug", "d", false, "enable debug logging")
+	DocsCmd.PersistentFlags().StringVarP(&docsOpts.apiKey, "bz-apikey", "k", "", "apikey for bugzilla")
+}
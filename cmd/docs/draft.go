@@ -0,0 +1,84 @@
+package docs
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ecordell/cop/cmd/login"
+	"github.com/ecordell/cop/pkg/docs"
+)
+
+type draftOptions struct {
+	release      string
+	product      string
+	components   []string
+	repo         string
+	undocumented bool
+}
+
+var draftOpts draftOptions
+
+var draftCmd = &cobra.Command{
+	Use:   "draft",
+	Short: "Generate a release notes draft",
+	Long: `Generate a release notes draft from the bugs targeted at a release that need docs.
+
+A bug needs docs if it has a doc type other than "No Doc Update", has doc text, or has the docs keyword.
+Bugs closed without a fix, e.g. as NOTABUG or DUPLICATE, are left out.
+If a docs repo is given, entries note where each bug is already documented.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if draftOpts.release == "" {
+			return fmt.Errorf("--release is required")
+		}
+		client, err := login.NewBugzillaClient(docsOpts.apiKey)
+		if err != nil {
+			return err
+		}
+
+		var documented map[int][]docs.Reference
+		if draftOpts.repo != "" {
+			if documented, err = docs.Scan(draftOpts.repo); err != nil {
+				return err
+			}
+		}
+
+		query := url.Values{
+			"classification": {"Red Hat"},
+			"product":        {draftOpts.product},
+			"component":      draftOpts.components,
+			"target_release": {draftOpts.release},
+		}
+		bugs, err := client.SearchBugs(query.Encode())
+		if err != nil {
+			return err
+		}
+
+		var entries []docs.Entry
+		for _, bug := range bugs {
+			if docs.Unfixed(bug) || !docs.NeedsDocs(bug) {
+				continue
+			}
+			if draftOpts.undocumented && len(documented[bug.ID]) > 0 {
+				continue
+			}
+			prs, err := client.GetExternalBugPRsOnBug(bug.ID)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, docs.Entry{Bug: bug, PRs: prs, Documented: documented[bug.ID]})
+		}
+		return docs.NewDraft(draftOpts.release, client.Endpoint(), entries).Markdown(os.Stdout)
+	},
+}
+
+func init() {
+	draftCmd.Flags().StringVarP(&draftOpts.release, "release", "r", "", "target release to draft notes for, e.g. 4.5.0")
+	draftCmd.Flags().StringVar(&draftOpts.product, "product", "OpenShift Container Platform", "bugzilla product to query")
+	draftCmd.Flags().StringSliceVar(&draftOpts.components, "components", []string{"OLM"}, "bugzilla components to query")
+	draftCmd.Flags().StringVar(&draftOpts.repo, "repo", "", "path to a docs repo to check for existing docs")
+	draftCmd.Flags().BoolVar(&draftOpts.undocumented, "undocumented", false, "only include bugs not yet mentioned in the docs repo")
+	DocsCmd.AddCommand(draftCmd)
+}
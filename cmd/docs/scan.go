@@ -0,0 +1,38 @@
+package docs
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ecordell/cop/pkg/docs"
+)
+
+var scanCmd = &cobra.Command{
+	Use:   "scan PATH",
+	Short: "List the bugs referenced in a docs repo",
+	Long:  `List the bugs referenced from Markdown and AsciiDoc files in a docs repo`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		refs, err := docs.Scan(args[0])
+		if err != nil {
+			return err
+		}
+		ids := make([]int, 0, len(refs))
+		for id := range refs {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			for _, ref := range refs[id] {
+				fmt.Printf("%d\t%s:%d\n", id, ref.Path, ref.Line)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	DocsCmd.AddCommand(scanCmd)
+}
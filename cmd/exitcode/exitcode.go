@@ -0,0 +1,13 @@
+// Package exitcode lets commands choose the code cop exits with.
+package exitcode
+
+// Error is returned by a command that has reported its outcome and only
+// needs cop to exit with Code.
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
@@ -0,0 +1,144 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/ecordell/cop/pkg/journal"
+	"github.com/ecordell/cop/pkg/view"
+)
+
+type historyOptions struct {
+	debug bool
+
+	bug    int
+	issue  string
+	limit  int
+	output string
+}
+
+var historyOpts historyOptions
+
+var HistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List the changes cop made to bugs and jira issues",
+	Long: `List the changes cop made to bugs and jira issues, newest first.
+
+Every change made through cop is recorded in a journal in cop's data directory, with the old
+and new value of each field, when it was made and the command that made it. A change can be
+reverted with cop undo and the ID of its entry.`,
+	Example: `  cop history
+  cop history --bug 1812345
+  cop history --issue OLM-1234
+  cop undo 42`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if historyOpts.debug {
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+		j, err := journal.Default()
+		if err != nil {
+			return err
+		}
+		all, err := j.Entries()
+		if err != nil {
+			return err
+		}
+		var entries []journal.Entry
+		for i := len(all) - 1; i >= 0; i-- {
+			if historyOpts.bug != 0 && all[i].Bug != historyOpts.bug {
+				continue
+			}
+			if historyOpts.issue != "" && !strings.EqualFold(all[i].Issue, historyOpts.issue) {
+				continue
+			}
+			entries = append(entries, all[i])
+			if historyOpts.limit > 0 && len(entries) == historyOpts.limit {
+				break
+			}
+		}
+		if historyOpts.output == view.FormatJSON {
+			return view.PrintJSON(os.Stdout, entries)
+		}
+		if len(entries) == 0 {
+			fmt.Println("cop hasn't changed any bugs or issues yet.")
+			return nil
+		}
+		views := []view.CLIMarshaller{}
+		for _, e := range entries {
+			for _, v := range NewHistoryViews(e) {
+				views = append(views, v)
+			}
+		}
+		return view.Print(os.Stdout, historyOpts.output, views)
+	},
+}
+
+type HistoryView struct {
+	// ID is the ID of the journal entry, for cop undo.
+	ID int `cli:"ID"`
+	// Time is when the change was made.
+	Time string `cli:"Time"`
+	// Bug is the ID of the bug or the key of the jira issue changed.
+	Bug string `cli:"Bug/Issue"`
+	// Field is the field that changed.
+	Field string `cli:"Field"`
+	// Old is the value of the field before the change.
+	Old string `cli:"Old,30"`
+	// New is the value of the field after the change.
+	New string `cli:"New,30"`
+	// Command is the command that made the change.
+	Command string `cli:"Command,40"`
+}
+
+// NewHistoryViews returns a row for each field an entry changed.
+func NewHistoryViews(e journal.Entry) []*HistoryView {
+	command := e.Command
+	if e.Undoes != 0 {
+		command = fmt.Sprintf("undo of %d: %s", e.Undoes, command)
+	}
+	var views []*HistoryView
+	for _, c := range e.Changes {
+		views = append(views, &HistoryView{
+			ID:      e.ID,
+			Time:    e.Time.Local().Format("2006-01-02 15:04"),
+			Bug:     changed(e),
+			Field:   c.Field,
+			Old:     oneLine(c.Old),
+			New:     oneLine(c.New),
+			Command: command,
+		})
+	}
+	return views
+}
+
+// changed is the bug or issue an entry changed
+func changed(e journal.Entry) string {
+	if e.Issue != "" {
+		return e.Issue
+	}
+	return fmt.Sprintf("%d", e.Bug)
+}
+
+// oneLine keeps multiline values, like comments, to a single row
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func (v HistoryView) MarshallCLI() ([]string, error) {
+	return view.MarshallCLI(v)
+}
+
+var _ view.CLIMarshaller = &HistoryView{}
+
+func init() {
+	HistoryCmd.Flags().BoolVarP(&historyOpts.debug, "debug", "d", false, "enable debug logging")
+	HistoryCmd.Flags().IntVar(&historyOpts.bug, "bug", 0, "only list changes to this bug")
+	HistoryCmd.Flags().StringVar(&historyOpts.issue, "issue", "", "only list changes to this jira issue")
+	HistoryCmd.Flags().IntVarP(&historyOpts.limit, "limit", "n", 50, "most entries to list, 0 for all")
+	HistoryCmd.Flags().StringVarP(&historyOpts.output, "output", "o", view.FormatTable, "output format, table or json")
+}
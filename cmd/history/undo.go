@@ -0,0 +1,111 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/ecordell/cop/cmd/login"
+	"github.com/ecordell/cop/pkg/jira"
+	"github.com/ecordell/cop/pkg/journal"
+	"github.com/ecordell/cop/pkg/view"
+)
+
+type undoOptions struct {
+	debug    bool
+	apiKey   string
+	jiraUser string
+	jiraPass string
+}
+
+var undoOpts undoOptions
+
+var UndoCmd = &cobra.Command{
+	Use:   "undo ENTRY",
+	Short: "Revert a change cop made to a bug or jira issue",
+	Long: `Revert a change cop made to a bug or jira issue, by setting the old values of the fields it changed.
+
+ENTRY is the ID of the change in cop history. cop undo refuses if any of those fields has
+changed since, or if the change was already undone. Comments can't be deleted, and flags
+and linked pull requests are left as they are. Of jira issues, the status, assignee and fix
+versions are put back. The undo is itself recorded in the history, so it can't be dry run.`,
+	Example: `  cop history --bug 1812345
+  cop undo 42`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if undoOpts.debug {
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid entry %q: %v", args[0], err)
+		}
+		j, err := journal.Default()
+		if err != nil {
+			return err
+		}
+		entry, err := j.Entry(id)
+		if err != nil {
+			return err
+		}
+		var undone *journal.Undone
+		if entry.Issue != "" {
+			undone, err = undoIssue(j, id)
+		} else {
+			undone, err = undoBug(j, id)
+		}
+		if err != nil {
+			return err
+		}
+		views := []view.CLIMarshaller{}
+		for _, v := range NewHistoryViews(*undone.Entry) {
+			views = append(views, v)
+		}
+		if err := view.Print(os.Stdout, view.FormatTable, views); err != nil {
+			return err
+		}
+		for _, c := range undone.Skipped {
+			fmt.Fprintf(os.Stderr, "Not undoing the %s change, it can't be reverted.\n", c.Field)
+		}
+		fmt.Printf("\nUndid entry %d on %s as entry %d.\n", id, changed(*undone.Entry), undone.Entry.ID)
+		return nil
+	},
+}
+
+func undoBug(j *journal.Journal, id int) (*journal.Undone, error) {
+	client, err := login.NewBugzillaClient(undoOpts.apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return journal.Undo(client, j, id, journal.CommandLine(os.Args), time.Now())
+}
+
+func undoIssue(j *journal.Journal, id int) (*journal.Undone, error) {
+	user, pass := undoOpts.jiraUser, undoOpts.jiraPass
+	if user == "" {
+		var err error
+		if user, pass, err = login.JiraCredentials(); err != nil {
+			return nil, err
+		}
+	}
+	if user == "" {
+		return nil, fmt.Errorf("must provide jira credentials or login with `cop login jira`")
+	}
+	client, err := login.NewJiraClient(jira.DefaultEndpoint, user, pass)
+	if err != nil {
+		return nil, err
+	}
+	return journal.UndoIssue(context.Background(), client, j, id, journal.CommandLine(os.Args), time.Now())
+}
+
+func init() {
+	UndoCmd.Flags().BoolVarP(&undoOpts.debug, "debug", "d", false, "enable debug logging")
+	UndoCmd.Flags().StringVarP(&undoOpts.apiKey, "bz-apikey", "k", "", "apikey for bugzilla")
+	UndoCmd.Flags().StringVarP(&undoOpts.jiraUser, "jira-user", "u", "", "username for jboss jira")
+	UndoCmd.Flags().StringVarP(&undoOpts.jiraPass, "jira-pass", "p", "", "password for jboss jira")
+}
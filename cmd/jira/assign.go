@@ -0,0 +1,58 @@
+package jira
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	jiraclient "github.com/ecordell/cop/pkg/jira"
+)
+
+type assignOptions struct {
+	unassign bool
+}
+
+var assignOpts assignOptions
+
+var assignCmd = &cobra.Command{
+	Use:   "assign KEY [USER]",
+	Short: "Assign an issue",
+	Long:  `Assign an issue to a user, or to yourself if no user is given.`,
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var assignee string
+		switch {
+		case assignOpts.unassign:
+		case len(args) == 2:
+			assignee = args[1]
+		default:
+			var err error
+			if assignee, err = username(); err != nil {
+				return err
+			}
+		}
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		return assign(context.Background(), client, args[0], assignee)
+	},
+}
+
+func assign(ctx context.Context, client jiraclient.Client, key, assignee string) error {
+	if err := client.AssignIssue(ctx, key, assignee); err != nil {
+		return err
+	}
+	if assignee == "" {
+		fmt.Printf("Unassigned %s.\n", key)
+		return nil
+	}
+	fmt.Printf("Assigned %s to %s.\n", key, assignee)
+	return nil
+}
+
+func init() {
+	assignCmd.Flags().BoolVar(&assignOpts.unassign, "unassign", false, "remove the assignee instead")
+	JiraCmd.AddCommand(assignCmd)
+}
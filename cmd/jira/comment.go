@@ -0,0 +1,59 @@
+package jira
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+
+	jiraclient "github.com/ecordell/cop/pkg/jira"
+)
+
+var commentCmd = &cobra.Command{
+	Use:   "comment KEY [TEXT]",
+	Short: "Comment on an issue",
+	Long:  `Comment on an issue. The comment is prompted for if it is not given.`,
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		body := strings.Join(args[1:], " ")
+		if body == "" {
+			var err error
+			if body, err = promptText("Comment: "); err != nil {
+				return err
+			}
+		}
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		return comment(context.Background(), client, args[0], body)
+	},
+}
+
+func comment(ctx context.Context, client jiraclient.Client, key, body string) error {
+	if _, err := client.AddComment(ctx, key, body); err != nil {
+		return err
+	}
+	fmt.Printf("Commented on %s.\n", key)
+	return nil
+}
+
+func promptText(label string) (string, error) {
+	prompt := promptui.Prompt{
+		Label: label,
+	}
+	text, err := prompt.Run()
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("no input given")
+	}
+	return text, nil
+}
+
+func init() {
+	JiraCmd.AddCommand(commentCmd)
+}
@@ -0,0 +1,113 @@
+package jira
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/ecordell/cop/cmd/login"
+	jiraclient "github.com/ecordell/cop/pkg/jira"
+	"github.com/ecordell/cop/pkg/view"
+)
+
+type jiraOptions struct {
+	debug bool
+
+	endpoint string
+	jiraUser string
+	jiraPass string
+	output   string
+}
+
+var jiraOpts jiraOptions
+
+var JiraCmd = &cobra.Command{
+	Use:   "jira",
+	Short: "Manage issues in Jira",
+	Long:  `Manage issues in Jira`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if jiraOpts.debug {
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+	},
+}
+
+func init() {
+	JiraCmd.PersistentFlags().BoolVarP(&jiraOpts.debug, "debug", "d", false, "enable debug logging")
+	JiraCmd.PersistentFlags().StringVar(&jiraOpts.endpoint, "endpoint", jiraclient.DefaultEndpoint, "jira endpoint")
+	JiraCmd.PersistentFlags().StringVarP(&jiraOpts.jiraUser, "jira-user", "u", "", "username for jboss jira")
+	JiraCmd.PersistentFlags().StringVarP(&jiraOpts.jiraPass, "jira-pass", "p", "", "password for jboss jira")
+	JiraCmd.PersistentFlags().StringVarP(&jiraOpts.output, "output", "o", view.FormatTable, "output format, one of table|json")
+}
+
+// username returns the jira user cop acts as, from flags or stored credentials.
+func username() (string, error) {
+	if jiraOpts.jiraUser != "" {
+		return jiraOpts.jiraUser, nil
+	}
+	user, _, err := login.JiraCredentials()
+	return user, err
+}
+
+// newClient returns a jira client using the credentials from flags, falling
+// back to those stored by `cop login jira`.
+func newClient() (jiraclient.Client, error) {
+	user, pass := jiraOpts.jiraUser, jiraOpts.jiraPass
+	if user == "" {
+		var err error
+		if user, pass, err = login.JiraCredentials(); err != nil {
+			return nil, err
+		}
+	}
+	if user == "" {
+		return nil, fmt.Errorf("must provide jira credentials or login with `cop login jira`")
+	}
+	return login.NewJiraClient(jiraOpts.endpoint, user, pass)
+}
+
+// browseURL is where an issue can be viewed in a browser.
+func browseURL(key string) string {
+	return fmt.Sprintf("%s/browse/%s", jiraOpts.endpoint, key)
+}
+
+type IssueView struct {
+	// Key is the human readable ID of the issue, e.g. OLM-1234.
+	Key string `cli:"Key"`
+	// Type is the name of the issue type, e.g. Epic.
+	Type string `cli:"Type"`
+	// Status is the name of the current status of the issue.
+	Status string `cli:"Status"`
+	// Priority is the name of the priority of the issue.
+	Priority string `cli:"Priority"`
+	// Assignee is the username the issue is assigned to.
+	Assignee string `cli:"Assignee"`
+	// Summary is the summary of the issue.
+	Summary string `cli:"Summary,50"`
+}
+
+func NewIssueView(issue jiraclient.Issue) *IssueView {
+	v := &IssueView{Key: issue.Key}
+	f := issue.Fields
+	if f == nil {
+		return v
+	}
+	v.Type = f.Type.Name
+	v.Summary = f.Summary
+	if f.Status != nil {
+		v.Status = f.Status.Name
+	}
+	if f.Priority != nil {
+		v.Priority = f.Priority.Name
+	}
+	if f.Assignee != nil {
+		v.Assignee = f.Assignee.Name
+	}
+	return v
+}
+
+func (v IssueView) MarshallCLI() ([]string, error) {
+	return view.MarshallCLI(v)
+}
+
+var _ view.CLIMarshaller = &IssueView{}
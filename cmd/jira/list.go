@@ -0,0 +1,89 @@
+package jira
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	jiraclient "github.com/ecordell/cop/pkg/jira"
+	"github.com/ecordell/cop/pkg/view"
+)
+
+const defaultJQL = "project = OLM AND resolution = Unresolved ORDER BY updated DESC"
+
+type listOptions struct {
+	jql         string
+	interactive bool
+}
+
+var listOpts listOptions
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List issues matching a JQL query",
+	Long:  `List issues matching a JQL query`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		ctx := context.Background()
+		issues, err := client.SearchIssues(ctx, listOpts.jql)
+		if err != nil {
+			return err
+		}
+
+		views := []view.CLIMarshaller{}
+		for _, issue := range issues {
+			views = append(views, NewIssueView(issue))
+		}
+		if !listOpts.interactive {
+			return view.Print(os.Stdout, jiraOpts.output, views)
+		}
+		i, err := view.NewMultiSelectView(views).Select()
+		if err != nil {
+			return err
+		}
+		return issuePrompt(ctx, client, issues[i].Key)
+	},
+}
+
+// issuePrompt offers the actions that can be taken on a single issue.
+func issuePrompt(ctx context.Context, client jiraclient.Client, key string) error {
+	return view.PromptActions("Select Action", []view.Action{
+		{Label: "Show Issue", Do: func() error {
+			return show(ctx, client, key)
+		}},
+		{Label: "View Issue on Jira", Do: func() error {
+			return view.OpenBrowser(browseURL(key))
+		}},
+		{Label: "Transition", Do: func() error {
+			status, err := promptText("Status: ")
+			if err != nil {
+				return err
+			}
+			return transition(ctx, client, key, status)
+		}},
+		{Label: "Comment", Do: func() error {
+			body, err := promptText("Comment: ")
+			if err != nil {
+				return err
+			}
+			return comment(ctx, client, key, body)
+		}},
+		{Label: "Assign to Me", Do: func() error {
+			user, err := username()
+			if err != nil {
+				return err
+			}
+			return assign(ctx, client, key, user)
+		}},
+	})
+}
+
+func init() {
+	listCmd.Flags().StringVar(&listOpts.jql, "jql", defaultJQL, "JQL query selecting the issues to list")
+	listCmd.Flags().BoolVarP(&listOpts.interactive, "interactive", "i", false, "select an issue and act on it")
+	JiraCmd.AddCommand(listCmd)
+}
@@ -0,0 +1,81 @@
+package jira
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	jiraclient "github.com/ecordell/cop/pkg/jira"
+	"github.com/ecordell/cop/pkg/view"
+)
+
+// shownComments is how many of the most recent comments show prints.
+const shownComments = 5
+
+var showCmd = &cobra.Command{
+	Use:   "show KEY",
+	Short: "Show the details of an issue",
+	Long:  `Show the details of an issue`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		return show(context.Background(), client, args[0])
+	},
+}
+
+func show(ctx context.Context, client jiraclient.Client, key string) error {
+	issue, err := client.GetIssue(ctx, key)
+	if err != nil {
+		return err
+	}
+	if jiraOpts.output == view.FormatJSON {
+		return view.PrintJSON(os.Stdout, issue)
+	}
+	links, err := client.GetRemoteLinks(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	v := NewIssueView(*issue)
+	fmt.Printf("%s: %s\n", v.Key, v.Summary)
+	fmt.Printf("URL:      %s\n", browseURL(v.Key))
+	fmt.Printf("Type:     %s\n", v.Type)
+	fmt.Printf("Status:   %s\n", v.Status)
+	fmt.Printf("Priority: %s\n", v.Priority)
+	fmt.Printf("Assignee: %s\n", v.Assignee)
+	if issue.Fields == nil {
+		return nil
+	}
+	var versions []string
+	for _, fv := range issue.Fields.FixVersions {
+		versions = append(versions, fv.Name)
+	}
+	fmt.Printf("Fix Versions: %s\n", strings.Join(versions, ", "))
+	for _, l := range links {
+		fmt.Printf("Link:     %s (%s)\n", l.Object.Title, l.Object.URL)
+	}
+	if issue.Fields.Description != "" {
+		fmt.Printf("\n%s\n", issue.Fields.Description)
+	}
+	if issue.Fields.Comments == nil {
+		return nil
+	}
+	comments := issue.Fields.Comments.Comments
+	if len(comments) > shownComments {
+		comments = comments[len(comments)-shownComments:]
+	}
+	for _, c := range comments {
+		fmt.Printf("\n--- %s (%s)\n%s\n", c.Author.Name, c.Created, c.Body)
+	}
+	return nil
+}
+
+func init() {
+	JiraCmd.AddCommand(showCmd)
+}
@@ -0,0 +1,37 @@
+package jira
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	jiraclient "github.com/ecordell/cop/pkg/jira"
+)
+
+var transitionCmd = &cobra.Command{
+	Use:   "transition KEY STATUS",
+	Short: "Move an issue to a new status",
+	Long:  `Move an issue to a new status, e.g. "cop jira transition OLM-1234 In Progress"`,
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		return transition(context.Background(), client, args[0], strings.Join(args[1:], " "))
+	},
+}
+
+func transition(ctx context.Context, client jiraclient.Client, key, status string) error {
+	if err := client.TransitionIssue(ctx, key, status); err != nil {
+		return err
+	}
+	fmt.Printf("Moved %s to %s.\n", key, status)
+	return nil
+}
+
+func init() {
+	JiraCmd.AddCommand(transitionCmd)
+}
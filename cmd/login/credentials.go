@@ -0,0 +1,131 @@
+package login
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/zalando/go-keyring"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/github"
+	"github.com/ecordell/cop/pkg/jira"
+	"github.com/ecordell/cop/pkg/journal"
+	"github.com/ecordell/cop/pkg/metrics"
+	"github.com/ecordell/cop/pkg/transport"
+)
+
+// clientMetrics, if set, record the requests of the clients built here.
+var clientMetrics *metrics.ClientMetrics
+
+// Instrument records the latency and errors of the bugzilla and jira clients
+// built from now on in m.
+func Instrument(m *metrics.ClientMetrics) {
+	clientMetrics = m
+}
+
+// BugzillaAPIKey returns the api key stored by `cop login bugzilla`, or an
+// empty string if there is none.
+func BugzillaAPIKey() (string, error) {
+	return lookup(service)
+}
+
+// JiraCredentials returns the credentials stored by `cop login jira`, or empty
+// strings if there are none.
+func JiraCredentials() (username, password string, err error) {
+	if username, err = lookup(jiraUserService); err != nil {
+		return "", "", err
+	}
+	if password, err = lookup(jiraPassService); err != nil {
+		return "", "", err
+	}
+	return username, password, nil
+}
+
+// GitHubToken returns the token stored by `cop login github`, falling back to
+// the GITHUB_TOKEN environment variable.
+func GitHubToken() (string, error) {
+	token, err := lookup(githubService)
+	if err != nil || token != "" {
+		return token, err
+	}
+	return os.Getenv("GITHUB_TOKEN"), nil
+}
+
+// NewGitHubClient returns a client for the public GitHub API using token,
+// falling back to the stored token. Without any token requests are anonymous.
+func NewGitHubClient(token string) (github.Client, error) {
+	if token == "" {
+		var err error
+		if token, err = GitHubToken(); err != nil {
+			return nil, err
+		}
+	}
+	return github.NewClient(func() []byte {
+		return []byte(token)
+	}, github.DefaultEndpoint), nil
+}
+
+func lookup(svc string) (string, error) {
+	value, err := keyring.Get(svc, user)
+	if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return "", err
+	}
+	return value, nil
+}
+
+// NewBugzillaClient returns a client for the default bugzilla using apiKey,
+// falling back to the key stored by `cop login bugzilla`.
+func NewBugzillaClient(apiKey string) (bugzilla.Client, error) {
+	if apiKey == "" {
+		var err error
+		if apiKey, err = BugzillaAPIKey(); err != nil {
+			return nil, err
+		}
+	}
+	if apiKey == "" && !transport.Replaying() {
+		// replayed requests don't need a key
+		return nil, fmt.Errorf("must provide apikey or login with `cop login bugzilla`")
+	}
+	client := bugzilla.NewClient(func() []byte {
+		return []byte(apiKey)
+	}, bugzilla.DefaultEndpoint)
+	if clientMetrics != nil {
+		client = metrics.InstrumentBugzilla(client, clientMetrics)
+	}
+	return Journaled(client)
+}
+
+// Journaled records the changes made through client in the journal, for
+// `cop history` and `cop undo`.
+func Journaled(client bugzilla.Client) (bugzilla.Client, error) {
+	if transport.DryRun() || transport.Replaying() {
+		// nothing really changes
+		return client, nil
+	}
+	j, err := journal.Default()
+	if err != nil {
+		return nil, err
+	}
+	return journal.Wrap(client, j, journal.CommandLine(os.Args)), nil
+}
+
+// NewJiraClient returns a client for the jira at endpoint, logged in as
+// username, that records its changes in the journal.
+func NewJiraClient(endpoint, username, password string) (jira.Client, error) {
+	client, err := jira.NewClient(endpoint, username, password)
+	if err != nil {
+		return nil, err
+	}
+	if clientMetrics != nil {
+		client = metrics.InstrumentJira(client, clientMetrics)
+	}
+	if transport.DryRun() || transport.Replaying() {
+		return client, nil
+	}
+	j, err := journal.Default()
+	if err != nil {
+		return nil, err
+	}
+	return journal.WrapJira(client, j, journal.CommandLine(os.Args)), nil
+}
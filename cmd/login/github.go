@@ -0,0 +1,37 @@
+package login
+
+import (
+	"fmt"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/zalando/go-keyring"
+)
+
+const githubService = "github"
+
+var GitHubLoginCmd = &cobra.Command{
+	Use:   "github",
+	Short: "github login",
+	Long:  `set the personal access token for github`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		prompt := promptui.Prompt{
+			Label: "Token: ",
+			Mask:  '*',
+		}
+
+		token, err := prompt.Run()
+		if err != nil {
+			return fmt.Errorf("Failed to get token: %v\n", err)
+		}
+		if err := keyring.Set(githubService, user, token); err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	LoginCmd.AddCommand(GitHubLoginCmd)
+}
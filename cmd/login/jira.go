@@ -6,6 +6,8 @@ import (
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
 	"github.com/zalando/go-keyring"
+
+	"github.com/ecordell/cop/pkg/jira"
 )
 
 const (
@@ -18,6 +20,10 @@ var JiraLoginCmd = &cobra.Command{
 	Short: "jira login",
 	Long:  `log in to jira`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if jiraRefresh {
+			return refreshJiraSession()
+		}
+
 		prompt := promptui.Prompt{
 			Label: "Username: ",
 		}
@@ -47,6 +53,30 @@ var JiraLoginCmd = &cobra.Command{
 	},
 }
 
+var jiraRefresh bool
+
+// refreshJiraSession logs in again with the stored credentials, replacing
+// whatever session was saved for them.
+func refreshJiraSession() error {
+	username, pass, err := JiraCredentials()
+	if err != nil {
+		return err
+	}
+	if username == "" || pass == "" {
+		return fmt.Errorf("no stored jira credentials, run `cop login jira` first")
+	}
+	session, err := jira.NewSession(jira.DefaultEndpoint, username, pass)
+	if err != nil {
+		return err
+	}
+	if err := session.Refresh(); err != nil {
+		return err
+	}
+	fmt.Printf("Refreshed jira session for %s\n", username)
+	return nil
+}
+
 func init() {
+	JiraLoginCmd.Flags().BoolVar(&jiraRefresh, "refresh", false, "log in again with the stored credentials, replacing the saved session")
 	LoginCmd.AddCommand(JiraLoginCmd)
 }
@@ -0,0 +1,125 @@
+package releasenotes
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/ecordell/cop/cmd/login"
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/docs"
+)
+
+const (
+	formatMarkdown = "markdown"
+	formatAsciiDoc = "asciidoc"
+)
+
+type releaseNotesOptions struct {
+	debug bool
+
+	apiKey     string
+	release    string
+	product    string
+	components []string
+	format     string
+	prs        bool
+}
+
+var releaseNotesOpts releaseNotesOptions
+
+var ReleaseNotesCmd = &cobra.Command{
+	Use:   "release-notes",
+	Short: "Generate release notes from bugzilla doc text",
+	Long: `Generate release notes for the VERIFIED and CLOSED bugs of a release from their doc type and doc text.
+
+Bugs that need release notes but have no doc text are listed on stderr.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if releaseNotesOpts.debug {
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+		if releaseNotesOpts.release == "" {
+			return fmt.Errorf("--release is required")
+		}
+		client, err := login.NewBugzillaClient(releaseNotesOpts.apiKey)
+		if err != nil {
+			return err
+		}
+
+		query := url.Values{
+			"bug_status":     {"VERIFIED", "CLOSED"},
+			"classification": {"Red Hat"},
+			"product":        {releaseNotesOpts.product},
+			"component":      releaseNotesOpts.components,
+			"target_release": {targetRelease(releaseNotesOpts.release)},
+		}
+		bugs, err := client.SearchBugs(query.Encode())
+		if err != nil {
+			return err
+		}
+
+		var entries []docs.Entry
+		var missing []*bugzilla.Bug
+		for _, bug := range bugs {
+			if docs.Unfixed(bug) || bug.DocType == bugzilla.DocTypeNoDocUpdate {
+				continue
+			}
+			if docs.MissingDocText(bug) {
+				missing = append(missing, bug)
+				continue
+			}
+			entry := docs.Entry{Bug: bug}
+			if releaseNotesOpts.prs {
+				if entry.PRs, err = client.GetExternalBugPRsOnBug(bug.ID); err != nil {
+					return err
+				}
+			}
+			entries = append(entries, entry)
+		}
+
+		notes := docs.NewDraft(releaseNotesOpts.release, client.Endpoint(), entries)
+		notes.Title = fmt.Sprintf("Release notes for %s", releaseNotesOpts.release)
+		switch releaseNotesOpts.format {
+		case formatMarkdown:
+			err = notes.Markdown(os.Stdout)
+		case formatAsciiDoc:
+			err = notes.AsciiDoc(os.Stdout)
+		default:
+			err = fmt.Errorf("unknown format %q, must be %s or %s", releaseNotesOpts.format, formatMarkdown, formatAsciiDoc)
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(missing) > 0 {
+			fmt.Fprintf(os.Stderr, "\n%d bugs need doc text:\n", len(missing))
+			for _, bug := range missing {
+				fmt.Fprintf(os.Stderr, "  %s  [%s] %s\n", bugzilla.BugURL(client.Endpoint(), bug.ID), bug.DocType, bug.Summary)
+			}
+		}
+		return nil
+	},
+}
+
+// targetRelease turns a minor version like 4.5 into the bugzilla target
+// release of its GA, 4.5.0. Anything more specific is used as-is.
+func targetRelease(release string) string {
+	if strings.Count(release, ".") == 1 {
+		return release + ".0"
+	}
+	return release
+}
+
+func init() {
+	ReleaseNotesCmd.Flags().BoolVarP(&releaseNotesOpts.debug, "debug", "d", false, "enable debug logging")
+	ReleaseNotesCmd.Flags().StringVarP(&releaseNotesOpts.apiKey, "bz-apikey", "k", "", "apikey for bugzilla")
+	ReleaseNotesCmd.Flags().StringVarP(&releaseNotesOpts.release, "release", "r", "", "release to generate notes for, e.g. 4.5 or 4.5.z")
+	ReleaseNotesCmd.Flags().StringVar(&releaseNotesOpts.product, "product", "OpenShift Container Platform", "bugzilla product to query")
+	ReleaseNotesCmd.Flags().StringSliceVar(&releaseNotesOpts.components, "components", []string{"OLM"}, "bugzilla components to query")
+	ReleaseNotesCmd.Flags().StringVarP(&releaseNotesOpts.format, "format", "f", formatMarkdown, "output format, one of markdown|asciidoc")
+	ReleaseNotesCmd.Flags().BoolVar(&releaseNotesOpts.prs, "prs", false, "link the pull requests that fixed each bug")
+}
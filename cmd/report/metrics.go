@@ -0,0 +1,111 @@
+package report
+
+import (
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ecordell/cop/cmd/login"
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/report"
+	"github.com/ecordell/cop/pkg/view"
+	"github.com/ecordell/cop/pkg/workflow"
+)
+
+const (
+	formatCSV        = "csv"
+	formatPrometheus = "prometheus"
+)
+
+type metricsOptions struct {
+	since   string
+	workers int
+}
+
+var metricsOpts metricsOptions
+
+var metricsCmd = &cobra.Command{
+	Use:   "metrics",
+	Short: "Report how long bugs take to move through the workflow",
+	Long: `Report how long bugs take to move through the workflow, from their history.
+
+Metrics cover the bugs changed during the period and every open bug:
+  - time in status: how long bugs stayed in each status
+  - cycle time: how long bugs took from being filed to first reaching MODIFIED and VERIFIED
+  - reopen rate: how many bugs fixed during the period moved back before MODIFIED
+  - load: the open bugs assigned to each person
+
+Durations are in hours in csv and seconds in prometheus output.`,
+	Example: `  cop report metrics --since 90d -f csv > metrics.csv
+  cop report metrics -f prometheus > /var/lib/node_exporter/cop.prom`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkFormat(formatMarkdown, formatCSV, formatPrometheus, formatJSON); err != nil {
+			return err
+		}
+		age, err := view.ParseAge(metricsOpts.since)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		since := now.Add(-age)
+		client, err := login.NewBugzillaClient(reportOpts.apiKey)
+		if err != nil {
+			return err
+		}
+
+		changed, err := search(client, url.Values{"last_change_time": {since.UTC().Format(time.RFC3339)}})
+		if err != nil {
+			return err
+		}
+		open, err := search(client, url.Values{"bug_status": {
+			workflow.StatusNew, workflow.StatusAssigned, workflow.StatusOnDev,
+			workflow.StatusPost, workflow.StatusModified, workflow.StatusOnQA,
+		}})
+		if err != nil {
+			return err
+		}
+		bugs := merge(changed, open)
+		history, err := report.FetchHistory(client, bugs, metricsOpts.workers)
+		if err != nil {
+			return err
+		}
+		m, err := report.NewMetrics(report.MetricsInput{Bugs: bugs, History: history, Since: since, Now: now})
+		if err != nil {
+			return err
+		}
+
+		switch reportOpts.format {
+		case formatCSV:
+			return m.CSV(os.Stdout)
+		case formatPrometheus:
+			return m.Prometheus(os.Stdout)
+		case formatJSON:
+			return view.PrintJSON(os.Stdout, m)
+		default:
+			return m.Markdown(os.Stdout)
+		}
+	},
+}
+
+// merge combines search results, dropping bugs found more than once
+func merge(results ...[]*bugzilla.Bug) []*bugzilla.Bug {
+	seen := map[int]bool{}
+	var bugs []*bugzilla.Bug
+	for _, result := range results {
+		for _, b := range result {
+			if !seen[b.ID] {
+				seen[b.ID] = true
+				bugs = append(bugs, b)
+			}
+		}
+	}
+	return bugs
+}
+
+func init() {
+	metricsCmd.Flags().StringVar(&metricsOpts.since, "since", "90d", "how far back to measure, e.g. 90d, 12w or 720h")
+	metricsCmd.Flags().IntVar(&metricsOpts.workers, "workers", 8, "how many bug histories to fetch at once")
+	ReportCmd.AddCommand(metricsCmd)
+}
@@ -0,0 +1,117 @@
+package report
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/ecordell/cop/cmd/bug"
+	"github.com/ecordell/cop/cmd/login"
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/report"
+	"github.com/ecordell/cop/pkg/view"
+	"github.com/ecordell/cop/pkg/workflow"
+)
+
+type releaseOptions struct {
+	release string
+	noPRs   bool
+}
+
+var releaseOpts releaseOptions
+
+var releaseCmd = &cobra.Command{
+	Use:   "release",
+	Short: "Report how ready a release is to ship",
+	Long: `Report how ready a target release is to ship.
+
+All bugs targeting the release are bucketed by status, and the open ones (before
+VERIFIED) by severity and by the state of their linked pull requests. Open blocker+
+and blocker? bugs are listed first. Each run is recorded in the cop data dir, so
+later runs chart the burndown of open bugs one point per day.`,
+	Example: `  cop report release --release 4.5.0
+  cop report release --release 4.4.z -f html > 4.4.z.html`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkFormat(formatMarkdown, formatHTML, formatJSON); err != nil {
+			return err
+		}
+		if releaseOpts.release == "" {
+			return fmt.Errorf("--release is required")
+		}
+		client, err := login.NewBugzillaClient(reportOpts.apiKey)
+		if err != nil {
+			return err
+		}
+		bugs, err := search(client, url.Values{"target_release": {releaseOpts.release}})
+		if err != nil {
+			return err
+		}
+
+		in := report.ReleaseInput{
+			Release:  releaseOpts.release,
+			Endpoint: client.Endpoint(),
+			Bugs:     bugs,
+			RowView: func(b bugzilla.Bug) view.CLIMarshaller {
+				return bug.NewSimpleBugView(b)
+			},
+			Now: time.Now(),
+		}
+		if !releaseOpts.noPRs {
+			gh, err := login.NewGitHubClient(reportOpts.githubToken)
+			if err != nil {
+				return err
+			}
+			in.PRStates = map[int]string{}
+			for _, b := range bugs {
+				if !report.IsOpen(b) {
+					continue
+				}
+				prs, err := workflow.LinkedPRs(client, gh, b.ID)
+				if err != nil {
+					logrus.WithField("bug", b.ID).Warnf("could not look up linked PRs: %v", err)
+					in.PRStates[b.ID] = workflow.PRStateUnknown
+					continue
+				}
+				for _, pr := range prs {
+					if pr.Err != nil {
+						logrus.WithFields(logrus.Fields{"bug": b.ID, "pr": fmt.Sprintf("%s/%s#%d", pr.Org, pr.Repo, pr.Num)}).Warnf("could not look up linked PR: %v", pr.Err)
+					}
+				}
+				in.PRStates[b.ID] = workflow.PRState(prs)
+			}
+		}
+
+		r, err := report.NewRelease(in)
+		if err != nil {
+			return err
+		}
+		history, err := report.LoadHistory(releaseOpts.release)
+		if err != nil {
+			return err
+		}
+		history.Record(report.Snapshot{Time: in.Now, Total: r.Total, Open: r.Open, Blockers: len(r.Blockers.Rows)})
+		if err := history.Save(); err != nil {
+			return err
+		}
+		r.Burndown = history.Snapshots
+
+		switch reportOpts.format {
+		case formatHTML:
+			return r.HTML(os.Stdout)
+		case formatJSON:
+			return view.PrintJSON(os.Stdout, r)
+		default:
+			return r.Markdown(os.Stdout)
+		}
+	},
+}
+
+func init() {
+	releaseCmd.Flags().StringVarP(&releaseOpts.release, "release", "r", "", "target release to report on, e.g. 4.5.0 or 4.4.z")
+	releaseCmd.Flags().BoolVar(&releaseOpts.noPRs, "no-prs", false, "don't look up the state of linked pull requests")
+	ReportCmd.AddCommand(releaseCmd)
+}
@@ -0,0 +1,65 @@
+package report
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+)
+
+const (
+	formatMarkdown = "markdown"
+	formatHTML     = "html"
+	formatJSON     = "json"
+)
+
+type reportOptions struct {
+	debug bool
+
+	apiKey      string
+	githubToken string
+	product     string
+	components  []string
+	format      string
+}
+
+var reportOpts reportOptions
+
+var ReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Reports on the team's bugs",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if reportOpts.debug {
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+	},
+}
+
+func init() {
+	ReportCmd.PersistentFlags().BoolVarP(&reportOpts.debug, "debug", "d", false, "enable debug logging")
+	ReportCmd.PersistentFlags().StringVarP(&reportOpts.apiKey, "bz-apikey", "k", "", "apikey for bugzilla")
+	ReportCmd.PersistentFlags().StringVar(&reportOpts.githubToken, "github-token", "", "token for the github api, defaults to the one stored by cop login github")
+	ReportCmd.PersistentFlags().StringVar(&reportOpts.product, "product", "OpenShift Container Platform", "bugzilla product to query")
+	ReportCmd.PersistentFlags().StringSliceVar(&reportOpts.components, "components", []string{"OLM"}, "bugzilla components to query")
+	ReportCmd.PersistentFlags().StringVarP(&reportOpts.format, "format", "f", formatMarkdown, "output format, one of markdown|html|json, or csv|prometheus for metrics")
+}
+
+// search finds the team's bugs matching params
+func search(client bugzilla.Client, params url.Values) ([]*bugzilla.Bug, error) {
+	params.Set("classification", "Red Hat")
+	params.Set("product", reportOpts.product)
+	params["component"] = reportOpts.components
+	return client.SearchBugs(params.Encode())
+}
+
+func checkFormat(formats ...string) error {
+	for _, f := range formats {
+		if f == reportOpts.format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown format %q, must be one of %v", reportOpts.format, formats)
+}
@@ -1,9 +1,19 @@
 package cmd
 
 import (
+  "errors"
   "fmt"
   "github.com/ecordell/cop/cmd/bug"
+  "github.com/ecordell/cop/cmd/docs"
+  "github.com/ecordell/cop/cmd/exitcode"
+  "github.com/ecordell/cop/cmd/history"
+  "github.com/ecordell/cop/cmd/jira"
   "github.com/ecordell/cop/cmd/login"
+  "github.com/ecordell/cop/cmd/releasenotes"
+  "github.com/ecordell/cop/cmd/report"
+  "github.com/ecordell/cop/cmd/serve"
+  "github.com/ecordell/cop/cmd/tui"
+  "github.com/ecordell/cop/pkg/transport"
   "os"
 
   "github.com/spf13/cobra"
@@ -11,6 +21,8 @@ import (
 
 var cfgFile string
 
+var transportOpts transport.Options
+
 // rootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
   Use:   "cop",
@@ -18,10 +30,34 @@ var RootCmd = &cobra.Command{
   Long: `A set of tools that can be used to manage bugs and docs for operator-framework.`,
 }
 
+func init() {
+  RootCmd.PersistentFlags().BoolVar(&transportOpts.DryRun, "dry-run", false, "send requests that only read, and log and skip those that would change anything")
+  RootCmd.PersistentFlags().StringVar(&transportOpts.Record, "record", "", "save every request and response to this directory, with credentials scrubbed")
+  RootCmd.PersistentFlags().StringVar(&transportOpts.Replay, "replay", "", "answer requests with the responses saved by --record in this directory, without sending them")
+  cobra.OnInitialize(func() {
+    if err := transport.Install(transportOpts); err != nil {
+      fmt.Println(err)
+      os.Exit(1)
+    }
+  })
+}
+
 func Execute() {
   RootCmd.AddCommand(bug.BugCmd)
+  RootCmd.AddCommand(docs.DocsCmd)
+  RootCmd.AddCommand(history.HistoryCmd)
+  RootCmd.AddCommand(jira.JiraCmd)
   RootCmd.AddCommand(login.LoginCmd)
+  RootCmd.AddCommand(releasenotes.ReleaseNotesCmd)
+  RootCmd.AddCommand(report.ReportCmd)
+  RootCmd.AddCommand(serve.ServeCmd)
+  RootCmd.AddCommand(tui.TuiCmd)
+  RootCmd.AddCommand(history.UndoCmd)
   if err := RootCmd.Execute(); err != nil {
+    var exit *exitcode.Error
+    if errors.As(err, &exit) {
+      os.Exit(exit.Code)
+    }
     fmt.Println(err)
     os.Exit(1)
   }
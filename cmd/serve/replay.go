@@ -0,0 +1,140 @@
+package serve
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/ecordell/cop/cmd/login"
+	"github.com/ecordell/cop/pkg/transport"
+	"github.com/ecordell/cop/pkg/view"
+	"github.com/ecordell/cop/pkg/webhook"
+	"github.com/ecordell/cop/pkg/workflow"
+)
+
+type replayOptions struct {
+	event string
+}
+
+var replayOpts replayOptions
+
+var replayCmd = &cobra.Command{
+	Use:   "replay FILE...",
+	Short: "Handle recorded GitHub webhook deliveries",
+	Long: `Handle recorded GitHub webhook deliveries as cop serve would, without a server.
+
+Deliveries saved by cop serve --webhook-record are replayed as they were received. Bare
+payloads, e.g. copied from the recent deliveries of a webhook on GitHub, need their --event.
+Signatures aren't checked. With --dry-run bugs are read but nothing is changed.`,
+	Example: `  cop serve replay --dry-run deliveries/*.json
+  cop serve replay --event pull_request payload.json`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if serveOpts.debug {
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+		client, err := login.NewBugzillaClient(serveOpts.apiKey)
+		if err != nil {
+			return err
+		}
+		h := &webhook.Handler{
+			Client:   client,
+			Branches: workflow.Branches{MasterRelease: serveOpts.masterRelease},
+			DryRun:   transport.DryRun(),
+		}
+		if !serveOpts.noGitHub {
+			if h.GitHub, err = login.NewGitHubClient(serveOpts.githubToken); err != nil {
+				return err
+			}
+		}
+
+		views := []view.CLIMarshaller{}
+		failed := 0
+		for _, path := range args {
+			d, err := webhook.ReadDelivery(path, replayOpts.event)
+			if err != nil {
+				return err
+			}
+			result, err := h.Handle(d)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+				failed++
+				if result == nil {
+					continue
+				}
+			}
+			views = append(views, newDeliveryViews(filepath.Base(path), result)...)
+		}
+		if err := view.Print(os.Stdout, view.FormatTable, views); err != nil {
+			return err
+		}
+		if transport.DryRun() {
+			fmt.Println("\nRerun without --dry-run to make these changes.")
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d deliveries failed", failed, len(args))
+		}
+		return nil
+	},
+}
+
+type DeliveryView struct {
+	// File is the file the delivery was read from.
+	File string `cli:"File,30"`
+	// PR is the pull request of the event.
+	PR string `cli:"PR,40"`
+	// Action is what happened to the pull request.
+	Action string `cli:"Action"`
+	// Bug is the ID of the bug referenced, if any.
+	Bug string `cli:"Bug"`
+	// Outcome is what was done to the bug, or why nothing was.
+	Outcome string `cli:"Outcome,60"`
+}
+
+func newDeliveryViews(file string, result *webhook.Result) []view.CLIMarshaller {
+	base := DeliveryView{File: file, PR: result.PR, Action: result.Action}
+	if result.Action == "" {
+		base.Action = result.Event
+	}
+	if len(result.Bugs) == 0 {
+		base.Outcome = "ignored: " + result.Ignored
+		return []view.CLIMarshaller{&base}
+	}
+	var views []view.CLIMarshaller
+	for _, b := range result.Bugs {
+		v := base
+		v.Bug = fmt.Sprintf("%d", b.ID)
+		var outcome []string
+		if b.Linked {
+			outcome = append(outcome, "linked PR")
+		}
+		if b.To != "" {
+			outcome = append(outcome, fmt.Sprintf("moved %s to %s", b.From, b.To))
+		}
+		outcome = append(outcome, b.Warnings...)
+		if b.Error != "" {
+			outcome = append(outcome, "error: "+b.Error)
+		}
+		if len(outcome) == 0 {
+			outcome = append(outcome, "up to date")
+		}
+		v.Outcome = strings.Join(outcome, "; ")
+		views = append(views, &v)
+	}
+	return views
+}
+
+func (v DeliveryView) MarshallCLI() ([]string, error) {
+	return view.MarshallCLI(v)
+}
+
+var _ view.CLIMarshaller = &DeliveryView{}
+
+func init() {
+	replayCmd.Flags().StringVar(&replayOpts.event, "event", "", "event of bare payloads, e.g. pull_request")
+	ServeCmd.AddCommand(replayCmd)
+}
@@ -0,0 +1,196 @@
+package serve
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/ecordell/cop/cmd/bug"
+	"github.com/ecordell/cop/cmd/login"
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/config"
+	"github.com/ecordell/cop/pkg/metrics"
+	"github.com/ecordell/cop/pkg/server"
+	"github.com/ecordell/cop/pkg/signals"
+	"github.com/ecordell/cop/pkg/view"
+	"github.com/ecordell/cop/pkg/webhook"
+	"github.com/ecordell/cop/pkg/workflow"
+)
+
+type serveOptions struct {
+	debug bool
+
+	apiKey      string
+	githubToken string
+	noGitHub    bool
+	addr        string
+	refresh     time.Duration
+	token       string
+	product     string
+	components  []string
+
+	metrics        bool
+	metricsQueries []string
+
+	webhookSecret string
+	webhookRecord string
+	masterRelease string
+}
+
+var serveOpts serveOptions
+
+var ServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve cop's queries and reports over a JSON API",
+	Long: `Serve cop's queries and reports over a JSON API, for bots and dashboards to share.
+
+  GET /healthz                              always ok, without a token
+  GET /api/queries                          the saved queries
+  GET /api/queries/NAME/bugs?Release=4.4    the bugs of a saved query, with templated values
+  GET /api/bugs/ID                          a bug and its linked pull requests
+  GET /api/backports?release=4.5.0          the backport each bug of a release wants
+  GET /api/reports/release/4.5.0?prs=true   a release readiness report
+  GET /metrics                              Prometheus metrics, with --metrics
+  POST /webhooks/github                     GitHub webhook deliveries, with --webhook-secret
+
+With --metrics, the bugs of saved queries (all of them, or those named by --metrics-queries)
+are counted every --refresh into the cop_bugs gauge, by query, status, severity, target
+release and component. The latency and errors of cop's calls to bugzilla and jira are
+exported as well.
+
+With a webhook secret from --webhook-secret or $COP_WEBHOOK_SECRET, signed pull_request
+deliveries whose title references "Bug NNNNNNN" link the PR to the bug, and move the bug to
+POST when the PR is opened or to MODIFIED once its PRs have merged. Point a GitHub webhook
+for pull requests at /webhooks/github, with content type application/json. Deliveries can be
+saved with --webhook-record and fed back in with "cop serve replay".
+
+Responses are cached and refreshed in the background. If a token is set with --token or
+$COP_SERVE_TOKEN, API requests must send it as "Authorization: Bearer TOKEN".
+The server shuts down gracefully on interrupt.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if serveOpts.debug {
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+		var registry *prometheus.Registry
+		if serveOpts.metrics {
+			registry = prometheus.NewRegistry()
+			login.Instrument(metrics.NewClientMetrics(registry))
+		}
+		client, err := login.NewBugzillaClient(serveOpts.apiKey)
+		if err != nil {
+			return err
+		}
+		ctx := signals.Context()
+		if registry != nil {
+			queries, err := metricsQueries(serveOpts.metricsQueries)
+			if err != nil {
+				return err
+			}
+			refresh := serveOpts.refresh
+			if refresh <= 0 {
+				refresh = 5 * time.Minute
+			}
+			go metrics.NewBugs(registry, client, queries).Run(ctx, refresh)
+		}
+		opts := server.Options{
+			Client:     client,
+			Refresh:    serveOpts.refresh,
+			Token:      serveOpts.token,
+			Product:    serveOpts.product,
+			Components: serveOpts.components,
+			RowView: func(b bugzilla.Bug) view.CLIMarshaller {
+				return bug.NewSimpleBugView(b)
+			},
+		}
+		if !serveOpts.noGitHub {
+			if opts.GitHub, err = login.NewGitHubClient(serveOpts.githubToken); err != nil {
+				return err
+			}
+		}
+		if registry != nil {
+			opts.Metrics = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+		}
+		secret := serveOpts.webhookSecret
+		if secret == "" {
+			secret = os.Getenv("COP_WEBHOOK_SECRET")
+		}
+		var hook *webhook.Handler
+		if secret != "" {
+			hook = &webhook.Handler{
+				Client:   client,
+				GitHub:   opts.GitHub,
+				Secret:   []byte(secret),
+				Branches: workflow.Branches{MasterRelease: serveOpts.masterRelease},
+				Record:   serveOpts.webhookRecord,
+			}
+			opts.Webhook = hook
+		}
+		if opts.Token == "" {
+			opts.Token = os.Getenv("COP_SERVE_TOKEN")
+		}
+		if opts.Token == "" {
+			logrus.Warn("serving without a token, anyone who can reach the server can use your bugzilla credentials to read bugs")
+		}
+		srv := server.New(opts)
+		if hook != nil {
+			hook.Changed = srv.BugChanged
+		}
+		return srv.Run(ctx, serveOpts.addr)
+	},
+}
+
+// metricsQueries expands the saved queries named, or all of them, into
+// bugzilla searches by name.
+func metricsQueries(names []string) (map[string]string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	all := len(names) == 0
+	if all {
+		names = cfg.QueryNames()
+	}
+	queries := map[string]string{}
+	for _, name := range names {
+		q, err := cfg.Query(name)
+		if err != nil {
+			return nil, err
+		}
+		query, err := bugzilla.ExpandQuery(q.Params, nil)
+		if err != nil {
+			if all {
+				// templated queries need values there is nowhere to give
+				logrus.WithField("query", name).WithError(err).Warn("not counting bugs of templated query")
+				continue
+			}
+			return nil, fmt.Errorf("could not expand query %q: %v", name, err)
+		}
+		queries[name] = query
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("no saved queries to count bugs of, see `cop bz query save`")
+	}
+	return queries, nil
+}
+
+func init() {
+	ServeCmd.PersistentFlags().BoolVarP(&serveOpts.debug, "debug", "d", false, "enable debug logging")
+	ServeCmd.PersistentFlags().StringVarP(&serveOpts.apiKey, "bz-apikey", "k", "", "apikey for bugzilla")
+	ServeCmd.PersistentFlags().StringVar(&serveOpts.githubToken, "github-token", "", "token for github, defaults to the stored token or $GITHUB_TOKEN")
+	ServeCmd.PersistentFlags().BoolVar(&serveOpts.noGitHub, "no-github", false, "don't look up linked pull requests")
+	ServeCmd.PersistentFlags().StringVar(&serveOpts.masterRelease, "master-release", "4.5", "release currently developed on master, for webhooks")
+	ServeCmd.Flags().StringVar(&serveOpts.addr, "addr", "127.0.0.1:8080", "address to listen on")
+	ServeCmd.Flags().DurationVar(&serveOpts.refresh, "refresh", 5*time.Minute, "how often to refresh cached responses, 0 to never")
+	ServeCmd.Flags().StringVar(&serveOpts.token, "token", "", "bearer token API requests must send, defaults to $COP_SERVE_TOKEN")
+	ServeCmd.Flags().StringVar(&serveOpts.product, "product", "OpenShift Container Platform", "bugzilla product of release reports")
+	ServeCmd.Flags().StringSliceVar(&serveOpts.components, "components", []string{"OLM"}, "bugzilla components of release reports")
+	ServeCmd.Flags().BoolVar(&serveOpts.metrics, "metrics", false, "serve Prometheus metrics on /metrics")
+	ServeCmd.Flags().StringSliceVar(&serveOpts.metricsQueries, "metrics-queries", nil, "saved queries to count bugs of for metrics, defaults to all of them")
+	ServeCmd.Flags().StringVar(&serveOpts.webhookSecret, "webhook-secret", "", "secret GitHub webhook deliveries are signed with, defaults to $COP_WEBHOOK_SECRET")
+	ServeCmd.Flags().StringVar(&serveOpts.webhookRecord, "webhook-record", "", "directory to save webhook deliveries to, for cop serve replay")
+}
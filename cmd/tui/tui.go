@@ -0,0 +1,87 @@
+package tui
+
+import (
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/ecordell/cop/cmd/bug"
+	"github.com/ecordell/cop/cmd/login"
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/config"
+	"github.com/ecordell/cop/pkg/signals"
+	tuiapp "github.com/ecordell/cop/pkg/tui"
+	"github.com/ecordell/cop/pkg/view"
+)
+
+type tuiOptions struct {
+	debug     bool
+	apiKey    string
+	refresh   time.Duration
+	backports []string
+	vars      map[string]string
+}
+
+var tuiOpts tuiOptions
+
+var TuiCmd = &cobra.Command{
+	Use:   "tui [@NAME|QUERY]",
+	Short: "Triage bugs in a full screen terminal interface",
+	Long: `Triage bugs in a full screen terminal interface.
+
+The search is a saved query (@NAME), raw search parameters or a buglist.cgi url,
+defaulting to the team's open OLM bugs. Bugs can be filtered with /, selected with
+space, and assigned (a), prioritized (p), marked for backport (b), sent a needinfo (n)
+or opened in the browser (o) one at a time or all selected at once.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// logs would draw over the interface, so they go to a file if wanted
+		logrus.SetOutput(ioutil.Discard)
+		if tuiOpts.debug {
+			path, err := config.Path("tui.log")
+			if err != nil {
+				return err
+			}
+			f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			logrus.SetOutput(f)
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+
+		var arg string
+		if len(args) > 0 {
+			arg = args[0]
+		}
+		query, err := bug.ResolveQuery(arg, tuiOpts.vars)
+		if err != nil {
+			return err
+		}
+		client, err := login.NewBugzillaClient(tuiOpts.apiKey)
+		if err != nil {
+			return err
+		}
+		return tuiapp.Run(signals.Context(), tuiapp.Options{
+			Client:    client,
+			Query:     query,
+			Refresh:   tuiOpts.refresh,
+			Backports: tuiOpts.backports,
+			RowView: func(b bugzilla.Bug) view.CLIMarshaller {
+				return bug.NewSimpleBugView(b)
+			},
+		})
+	},
+}
+
+func init() {
+	TuiCmd.Flags().BoolVarP(&tuiOpts.debug, "debug", "d", false, "enable debug logging to tui.log in the cop data dir")
+	TuiCmd.Flags().StringVarP(&tuiOpts.apiKey, "bz-apikey", "k", "", "apikey for bugzilla")
+	TuiCmd.Flags().DurationVar(&tuiOpts.refresh, "refresh", 2*time.Minute, "how often to reload the bugs, 0 to only reload on demand")
+	TuiCmd.Flags().StringSliceVar(&tuiOpts.backports, "backports", []string{"4.1", "4.2", "4.3", "4.4", "4.5"}, "versions offered when setting a backport")
+	TuiCmd.Flags().StringToStringVar(&tuiOpts.vars, "set", nil, "values for templated queries, e.g. Release=4.4")
+}
@@ -0,0 +1,69 @@
+package backport
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/git"
+)
+
+// Target is a clone of a bug and the release branch its fix goes to.
+type Target struct {
+	Clone  *bugzilla.Bug
+	Branch string
+}
+
+// Result is the outcome of picking a fix onto one Target.
+type Result struct {
+	Target
+	// LocalBranch is the branch the commits were picked onto.
+	LocalBranch string
+	// Picked is how many commits applied.
+	Picked int
+	// Err is set if the pick failed, and is a *git.ConflictError on conflicts.
+	Err error
+}
+
+// BranchName is the local branch a fix for clone is picked onto.
+func BranchName(clone int, branch string) string {
+	return fmt.Sprintf("bug-%d-%s", clone, branch)
+}
+
+// Pick fetches the target's release branch from remote, starts a new branch
+// from it and cherry-picks commits onto it.
+func Pick(repo *git.Repo, remote string, commits []string, target Target) Result {
+	result := Result{Target: target, LocalBranch: BranchName(target.Clone.ID, target.Branch)}
+	if err := repo.Fetch(remote, target.Branch); err != nil {
+		result.Err = err
+		return result
+	}
+	for _, sha := range commits {
+		if !repo.HasCommit(sha) {
+			result.Err = fmt.Errorf("commit %s is not available locally, fetch the pull request it came from", sha)
+			return result
+		}
+	}
+	if err := repo.CheckoutNewBranch(result.LocalBranch, remote+"/"+target.Branch); err != nil {
+		result.Err = err
+		return result
+	}
+	for _, sha := range commits {
+		if err := repo.CherryPick(sha); err != nil {
+			result.Err = err
+			return result
+		}
+		result.Picked++
+	}
+	return result
+}
+
+// bugPrefix matches the "Bug 1812345: " a PR title starts with.
+var bugPrefix = regexp.MustCompile(`(?i)^\s*bug\s+\d+\s*:\s*`)
+
+// PRTitle is the title of the backport PR for clone, reusing the original
+// PR's title with the clone's ID in place of the original bug.
+func PRTitle(clone int, original string) string {
+	return fmt.Sprintf("Bug %d: %s", clone, strings.TrimSpace(bugPrefix.ReplaceAllString(original, "")))
+}
@@ -0,0 +1,173 @@
+package backport
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/git"
+)
+
+// fixture is a bare repo acting as the remote, and a clone of it to pick in.
+type fixture struct {
+	t      *testing.T
+	root   string
+	remote string
+	work   string
+}
+
+func newFixture(t *testing.T) *fixture {
+	root, err := ioutil.TempDir("", "backport")
+	require.NoError(t, err)
+	f := &fixture{t: t, root: root, remote: filepath.Join(root, "remote.git"), work: filepath.Join(root, "work")}
+
+	f.git(root, "init", "--bare", f.remote)
+	f.clone(f.work)
+	f.git(f.work, "checkout", "-b", "master")
+	f.commit("catalog.go", "package catalog\n", "initial")
+	f.git(f.work, "push", "origin", "master")
+	f.git(f.work, "push", "origin", "master:release-4.4")
+	return f
+}
+
+// clone clones the remote into dir, with an identity for the commits
+// cherry-picked there.
+func (f *fixture) clone(dir string) {
+	f.git(f.root, "clone", f.remote, dir)
+	f.git(dir, "config", "user.name", "cop")
+	f.git(dir, "config", "user.email", "cop@example.com")
+}
+
+func (f *fixture) git(dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=cop",
+		"GIT_AUTHOR_EMAIL=cop@example.com",
+		"GIT_COMMITTER_NAME=cop",
+		"GIT_COMMITTER_EMAIL=cop@example.com",
+	)
+	out, err := cmd.CombinedOutput()
+	require.NoError(f.t, err, string(out))
+	return strings.TrimSpace(string(out))
+}
+
+func (f *fixture) commit(file, content, message string) string {
+	require.NoError(f.t, ioutil.WriteFile(filepath.Join(f.work, file), []byte(content), 0644))
+	f.git(f.work, "add", file)
+	f.git(f.work, "commit", "-m", message)
+	return f.git(f.work, "rev-parse", "HEAD")
+}
+
+func TestPick(t *testing.T) {
+	f := newFixture(t)
+	defer os.RemoveAll(f.root)
+
+	fix := f.commit("catalog.go", "package catalog\n\n// fixed\n", "Bug 1812345: fix catalog pod")
+	f.git(f.work, "push", "origin", "master")
+
+	repo, err := git.NewRepo(f.work)
+	require.NoError(t, err)
+	result := Pick(repo, "origin", []string{fix}, Target{Clone: &bugzilla.Bug{ID: 1812346}, Branch: "release-4.4"})
+	require.NoError(t, result.Err)
+	require.Equal(t, 1, result.Picked)
+	require.Equal(t, "bug-1812346-release-4.4", result.LocalBranch)
+
+	require.Equal(t, "bug-1812346-release-4.4", f.git(f.work, "rev-parse", "--abbrev-ref", "HEAD"))
+	message := f.git(f.work, "log", "-1", "--format=%B")
+	require.Contains(t, message, "(cherry picked from commit "+fix+")")
+}
+
+func TestPickConflict(t *testing.T) {
+	f := newFixture(t)
+	defer os.RemoveAll(f.root)
+
+	// the release branch has diverged from master
+	f.git(f.work, "checkout", "-b", "release-4.4", "origin/release-4.4")
+	f.commit("catalog.go", "package catalog\n\n// release only\n", "release change")
+	f.git(f.work, "push", "origin", "release-4.4")
+	f.git(f.work, "checkout", "master")
+	fix := f.commit("catalog.go", "package catalog\n\n// fixed\n", "Bug 1812345: fix catalog pod")
+
+	repo, err := git.NewRepo(f.work)
+	require.NoError(t, err)
+	result := Pick(repo, "origin", []string{fix}, Target{Clone: &bugzilla.Bug{ID: 1812346}, Branch: "release-4.4"})
+	require.True(t, git.IsConflict(result.Err), "expected conflict, got %v", result.Err)
+	require.Equal(t, []string{"catalog.go"}, result.Err.(*git.ConflictError).Files)
+	require.Equal(t, 0, result.Picked)
+
+	// the checkout is left clean after aborting
+	require.Equal(t, "", f.git(f.work, "status", "--porcelain"))
+}
+
+func TestPickMissingCommit(t *testing.T) {
+	f := newFixture(t)
+	defer os.RemoveAll(f.root)
+
+	repo, err := git.NewRepo(f.work)
+	require.NoError(t, err)
+	result := Pick(repo, "origin", []string{"0123456789abcdef0123456789abcdef01234567"}, Target{Clone: &bugzilla.Bug{ID: 1}, Branch: "release-4.4"})
+	require.Error(t, result.Err)
+	require.False(t, git.IsConflict(result.Err))
+}
+
+func TestPickSquashed(t *testing.T) {
+	f := newFixture(t)
+	defer os.RemoveAll(f.root)
+
+	// someone else's PR, squashed into a different commit when it merged
+	contributor := filepath.Join(f.root, "contributor")
+	f.clone(contributor)
+	f.git(contributor, "checkout", "-b", "fix", "origin/master")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(contributor, "catalog.go"), []byte("package catalog\n\n// fixed\n"), 0644))
+	f.git(contributor, "commit", "-am", "fix catalog pod")
+	fix := f.git(contributor, "rev-parse", "HEAD")
+	f.git(contributor, "push", "origin", "fix:refs/pull/7/head")
+	f.git(contributor, "checkout", "master")
+	f.git(contributor, "merge", "--squash", "fix")
+	f.git(contributor, "commit", "-m", "Bug 1812345: fix catalog pod (#7)")
+	f.git(contributor, "push", "origin", "master")
+
+	repo, err := git.NewRepo(f.work)
+	require.NoError(t, err)
+	require.False(t, repo.HasCommit(fix))
+	require.NoError(t, repo.FetchPull("origin", 7))
+	require.True(t, repo.HasCommit(fix))
+
+	result := Pick(repo, "origin", []string{fix}, Target{Clone: &bugzilla.Bug{ID: 1812346}, Branch: "release-4.4"})
+	require.NoError(t, result.Err)
+	require.Equal(t, 1, result.Picked)
+}
+
+func TestPickRefusesToLoseWork(t *testing.T) {
+	f := newFixture(t)
+	defer os.RemoveAll(f.root)
+	fix := f.commit("catalog.go", "package catalog\n\n// fixed\n", "Bug 1812345: fix catalog pod")
+	target := Target{Clone: &bugzilla.Bug{ID: 1812346}, Branch: "release-4.4"}
+	repo, err := git.NewRepo(f.work)
+	require.NoError(t, err)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(f.work, "catalog.go"), []byte("package catalog\n\n// wip\n"), 0644))
+	result := Pick(repo, "origin", []string{fix}, target)
+	require.Error(t, result.Err)
+	require.Contains(t, result.Err.Error(), "uncommitted changes")
+	require.Equal(t, "master", f.git(f.work, "rev-parse", "--abbrev-ref", "HEAD"))
+	f.git(f.work, "checkout", "--", "catalog.go")
+
+	f.git(f.work, "branch", BranchName(1812346, "release-4.4"))
+	result = Pick(repo, "origin", []string{fix}, target)
+	require.Error(t, result.Err)
+	require.Contains(t, result.Err.Error(), "already exists")
+	require.Equal(t, fix, f.git(f.work, "rev-parse", BranchName(1812346, "release-4.4")))
+}
+
+func TestPRTitle(t *testing.T) {
+	require.Equal(t, "Bug 1812346: fix catalog pod", PRTitle(1812346, "Bug 1812345: fix catalog pod"))
+	require.Equal(t, "Bug 1812346: fix catalog pod", PRTitle(1812346, "fix catalog pod"))
+}
@@ -0,0 +1,53 @@
+package bugzilla
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ForEach calls f with each of ids, with up to workers calls at once. Every
+// call is made, and the first error returned by one is returned.
+func ForEach(ids []int, workers int, f func(id int) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	var (
+		firstErr error
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, workers)
+	)
+	for _, id := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(id int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := f(id); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(id)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// GetDescription returns the text a bug was filed with, its first comment.
+func GetDescription(client Client, id int) (string, error) {
+	comments, err := client.GetCommentsOnBug(id)
+	if err != nil {
+		return "", fmt.Errorf("could not get description of bug %d: %v", id, err)
+	}
+	for _, c := range comments {
+		if c.Count == 0 {
+			return c.Text, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,50 @@
+package bugzilla
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestForEach(t *testing.T) {
+	var (
+		mu               sync.Mutex
+		seen             []int
+		running, busiest int
+	)
+	err := ForEach([]int{1, 2, 3, 4, 5}, 2, func(id int) error {
+		mu.Lock()
+		seen = append(seen, id)
+		running++
+		if running > busiest {
+			busiest = running
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+		if id == 3 {
+			return fmt.Errorf("bug %d failed", id)
+		}
+		return nil
+	})
+	require.EqualError(t, err, "bug 3 failed")
+	require.ElementsMatch(t, []int{1, 2, 3, 4, 5}, seen)
+	require.True(t, busiest <= 2)
+}
+
+func TestGetDescription(t *testing.T) {
+	client := &Fake{BugComments: map[int][]Comment{
+		1: {{BugID: 1, Count: 0, Text: "catalog crashes"}, {BugID: 1, Count: 1, Text: "seen again"}},
+	}}
+	description, err := GetDescription(client, 1)
+	require.NoError(t, err)
+	require.Equal(t, "catalog crashes", description)
+	description, err = GetDescription(client, 2)
+	require.NoError(t, err)
+	require.Equal(t, "", description)
+}
@@ -12,6 +12,9 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// DefaultEndpoint is the bugzilla instance operator-framework bugs are filed in.
+const DefaultEndpoint = "https://bugzilla.redhat.com/"
+
 type Client interface {
 	Endpoint() string
 	GetBug(id int) (*Bug, error)
@@ -20,8 +23,9 @@ type Client interface {
 	SearchBugs(query string) ([]*Bug, error)
 	UpdateInternalWhiteboard(id int, value string) (*Bug, error)
 	GetCommentsOnBug(id int) ([]Comment, error)
-	//UpdateBug(id int, update BugUpdate) error
-	//AddPullRequestAsExternalBug(id int, org, repo string, num int) (bool, error)
+	GetBugHistory(id int) ([]History, error)
+	UpdateBug(id int, update BugUpdate) error
+	AddPullRequestAsExternalBug(id int, org, repo string, num int) (bool, error)
 }
 
 func NewClient(getAPIKey func() []byte, endpoint string) Client {
@@ -111,21 +115,57 @@ func (c *client) GetBug(id int) (*Bug, error) {
 
 func (c *client) UpdateInternalWhiteboard(id int, value string) (*Bug, error) {
 	logger := c.logger.WithFields(logrus.Fields{"method": "UpdateInternalWhiteboard", "id": id})
-	body, err := json.Marshal(Bug{InternalWhiteboard: value})
+	// not a Bug, which would leave out an empty value instead of clearing it
+	body, err := json.Marshal(map[string]string{"cf_internal_whiteboard": value})
 	if err != nil {
 		return nil, err
 	}
 	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/rest/bug/%d", c.endpoint, id), bytes.NewBuffer(body))
-	req.Header.Add("Content-Type", "application/json")
 	if err != nil {
 		return nil, err
 	}
-	_, err = c.request(req, logger)
+	req.Header.Add("Content-Type", "application/json")
+	raw, err := c.request(req, logger)
 	if err != nil {
 		return nil, err
 	}
-	//fmt.Println(string(raw))
-	return nil, nil
+	return nil, updateError(raw)
+}
+
+// UpdateBug changes the fields set in update on a bug.
+// https://bugzilla.readthedocs.io/en/latest/api/core/v1/bug.html#update-bug
+func (c *client) UpdateBug(id int, update BugUpdate) error {
+	logger := c.logger.WithFields(logrus.Fields{"method": "UpdateBug", "id": id})
+	body, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/rest/bug/%d", c.endpoint, id), bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	raw, err := c.request(req, logger)
+	if err != nil {
+		return err
+	}
+	return updateError(raw)
+}
+
+// updateError returns the error in the response to an update, as bugzilla
+// reports some failures with a 200 and an error in the body.
+func updateError(raw []byte) error {
+	var parsedResponse struct {
+		Error   bool   `json:"error,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	if err := json.Unmarshal(raw, &parsedResponse); err != nil {
+		return fmt.Errorf("could not unmarshal response body: %v", err)
+	}
+	if parsedResponse.Error {
+		return &requestError{statusCode: http.StatusOK, message: parsedResponse.Message}
+	}
+	return nil
 }
 
 func (c *client) SearchBugs(query string) ([]*Bug, error) {
@@ -235,6 +275,89 @@ func (c *client) GetExternalBugPRsOnBug(id int) ([]GithubExternalBug, error) {
 	return prs, nil
 }
 
+// AddPullRequestAsExternalBug attempts to add a PR to the external tracker list.
+// External bugs are assumed to fall under the type identified by their hostname,
+// so we will provide https://github.com/ here for the URL identifier. We return
+// any error as well as whether a change was actually made.
+// This will be done via JSONRPC:
+// https://bugzilla.redhat.com/docs/en/html/integrating/api/Bugzilla/Extension/ExternalBugs/WebService.html#add-external-bug
+func (c *client) AddPullRequestAsExternalBug(id int, org, repo string, num int) (bool, error) {
+	logger := c.logger.WithFields(logrus.Fields{"method": "AddExternalBug", "id": id, "org": org, "repo": repo, "num": num})
+	pullIdentifier := IdentifierForPull(org, repo, num)
+	rpcPayload := struct {
+		// Version is the version of JSONRPC to use. All Bugzilla servers
+		// support 1.0. Some support 1.1 and some support 2.0
+		Version string `json:"jsonrpc"`
+		Method  string `json:"method"`
+		// Parameters must be specified in JSONRPC 1.0 as a structure in the first
+		// index of this slice
+		Parameters []AddExternalBugParameters `json:"params"`
+		ID         string                     `json:"id"`
+	}{
+		Version: "1.0", // some Bugzilla servers support 2.0 but not all
+		Method:  "ExternalBugs.add_external_bug",
+		ID:      "identifier", // this is useful when fielding asynchronous responses, but not here
+		Parameters: []AddExternalBugParameters{{
+			APIKey: string(c.getAPIKey()),
+			BugIDs: []int{id},
+			ExternalBugs: []NewExternalBugIdentifier{{
+				Type: "https://github.com/",
+				ID:   pullIdentifier,
+			}},
+		}},
+	}
+	body, err := json.Marshal(rpcPayload)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal JSONRPC payload: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/jsonrpc.cgi", strings.TrimSuffix(c.endpoint, "/")), bytes.NewBuffer(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.request(req, logger)
+	if err != nil {
+		return false, err
+	}
+	var response struct {
+		Error *struct {
+			Code    int    `json:"code,omitempty"`
+			Message string `json:"message,omitempty"`
+		} `json:"error,omitempty"`
+		ID     string `json:"id,omitempty"`
+		Result *struct {
+			Bugs []struct {
+				ID      int `json:"id,omitempty"`
+				Changes struct {
+					ExternalBugs struct {
+						Added   string `json:"added,omitempty"`
+						Removed string `json:"removed,omitempty"`
+					} `json:"ext_bz_bug_map.ext_bz_bug_id,omitempty"`
+				} `json:"changes,omitempty"`
+			} `json:"bugs,omitempty"`
+		} `json:"result,omitempty"`
+	}
+	if err := json.Unmarshal(resp, &response); err != nil {
+		return false, fmt.Errorf("failed to unmarshal JSONRPC response: %v", err)
+	}
+	if response.Error != nil {
+		return false, fmt.Errorf("JSONRPC error %d: %v", response.Error.Code, response.Error.Message)
+	}
+	if response.ID != rpcPayload.ID {
+		return false, fmt.Errorf("JSONRPC returned mismatched identifier, expected %s but got %s", rpcPayload.ID, response.ID)
+	}
+	changed := false
+	if response.Result != nil {
+		for _, bug := range response.Result.Bugs {
+			if bug.ID == id {
+				changed = changed || bug.Changes.ExternalBugs.Added == pullIdentifier
+			}
+		}
+	}
+	return changed, nil
+}
+
 // GetCommentsOnBug retrieves comments for a particular bug.
 // https://bugzilla.readthedocs.io/en/latest/api/core/v1/comment.html#get-comments
 func (c *client) GetCommentsOnBug(id int) ([]Comment, error) {
@@ -261,6 +384,45 @@ func (c *client) GetCommentsOnBug(id int) ([]Comment, error) {
 	return nil, nil
 }
 
+// GetBugHistory retrieves the changes made to a bug, oldest first.
+// https://bugzilla.readthedocs.io/en/latest/api/core/v1/bug.html#bug-history
+func (c *client) GetBugHistory(id int) ([]History, error) {
+	logger := c.logger.WithFields(logrus.Fields{"method": "GetBugHistory", "id": id})
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/rest/bug/%d/history", c.endpoint, id), nil)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := c.request(req, logger)
+	if err != nil {
+		return nil, err
+	}
+	var parsedResponse struct {
+		Bugs []struct {
+			ID      int       `json:"id"`
+			History []History `json:"history"`
+		} `json:"bugs"`
+	}
+	if err := json.Unmarshal(raw, &parsedResponse); err != nil {
+		return nil, fmt.Errorf("could not unmarshal response body: %v", err)
+	}
+	for _, bug := range parsedResponse.Bugs {
+		if bug.ID == id {
+			return bug.History, nil
+		}
+	}
+	return nil, nil
+}
+
+// BugURL returns the address a bug can be viewed at in a browser.
+func BugURL(endpoint string, id int) string {
+	return fmt.Sprintf("%s/show_bug.cgi?id=%d", strings.TrimSuffix(endpoint, "/"), id)
+}
+
+// IdentifierForPull is the external bug identifier of a GitHub pull request.
+func IdentifierForPull(org, repo string, num int) string {
+	return fmt.Sprintf("%s/%s/pull/%d", org, repo, num)
+}
+
 func PullFromIdentifier(identifier string) (org, repo string, num int, err error) {
 	parts := strings.Split(identifier, "/")
 	if len(parts) != 4 {
@@ -0,0 +1,220 @@
+package bugzilla
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recorded is a request the test server got
+type recorded struct {
+	method string
+	path   string
+	query  string
+	header http.Header
+	body   string
+}
+
+// testServer answers every request with status and response, recording what
+// it was sent.
+func testServer(t *testing.T, status int, response string) (*httptest.Server, *[]recorded) {
+	var requests []recorded
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("could not read request body: %v", err)
+		}
+		requests = append(requests, recorded{
+			method: r.Method,
+			path:   r.URL.Path,
+			query:  r.URL.RawQuery,
+			header: r.Header,
+			body:   string(body),
+		})
+		w.WriteHeader(status)
+		fmt.Fprint(w, response)
+	}))
+	return server, &requests
+}
+
+func testClient(endpoint string) Client {
+	return NewClient(func() []byte { return []byte("s3cret") }, endpoint)
+}
+
+func TestUpdateBug(t *testing.T) {
+	whiteboard := ""
+	tests := []struct {
+		name   string
+		update BugUpdate
+		body   string
+	}{
+		{
+			name:   "status only",
+			update: BugUpdate{Status: "POST"},
+			body:   `{"status":"POST"}`,
+		},
+		{
+			name:   "close as a duplicate",
+			update: BugUpdate{Status: "CLOSED", Resolution: "DUPLICATE", DupeOf: 2, Comment: &BugComment{Body: "dupe"}},
+			body:   `{"status":"CLOSED","resolution":"DUPLICATE","dupe_of":2,"comment":{"body":"dupe"}}`,
+		},
+		{
+			name:   "clear the whiteboard",
+			update: BugUpdate{Whiteboard: &whiteboard},
+			body:   `{"whiteboard":""}`,
+		},
+		{
+			name: "flags",
+			update: BugUpdate{Flags: []FlagChange{
+				{Name: "needinfo", Status: "?", Requestee: "someone@redhat.com"},
+				{Name: "blocker", Status: "X"},
+			}},
+			body: `{"flags":[{"name":"needinfo","status":"?","requestee":"someone@redhat.com"},{"name":"blocker","status":"X"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := testServer(t, http.StatusOK, `{"bugs":[{"id":1}]}`)
+			defer server.Close()
+			require.NoError(t, testClient(server.URL).UpdateBug(1, tt.update))
+			require.Len(t, *requests, 1)
+			req := (*requests)[0]
+			require.Equal(t, http.MethodPut, req.method)
+			require.Equal(t, "/rest/bug/1", req.path)
+			require.Equal(t, "api_key=s3cret", req.query)
+			require.Equal(t, "s3cret", req.header.Get("X-BUGZILLA-API-KEY"))
+			require.Equal(t, "application/json", req.header.Get("Content-Type"))
+			require.JSONEq(t, tt.body, req.body)
+		})
+	}
+}
+
+func TestUpdateBugErrors(t *testing.T) {
+	server, _ := testServer(t, http.StatusOK, `{"error":true,"message":"You are not allowed to change the status."}`)
+	defer server.Close()
+	err := testClient(server.URL).UpdateBug(1, BugUpdate{Status: "VERIFIED"})
+	require.EqualError(t, err, "You are not allowed to change the status.")
+
+	server, _ = testServer(t, http.StatusNotFound, `{"error":true,"message":"Bug #1 does not exist."}`)
+	defer server.Close()
+	err = testClient(server.URL).UpdateBug(1, BugUpdate{Status: "POST"})
+	require.Error(t, err)
+	require.True(t, IsNotFound(err))
+}
+
+func TestUpdateError(t *testing.T) {
+	require.NoError(t, updateError([]byte(`{"bugs":[{"id":1,"changes":{}}]}`)))
+	require.NoError(t, updateError([]byte(`{"error":false}`)))
+	require.EqualError(t, updateError([]byte(`{"error":true,"message":"bad flag"}`)), "bad flag")
+	require.Error(t, updateError([]byte(`<html>`)))
+}
+
+func TestAddPullRequestAsExternalBug(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		changed  bool
+		err      string
+	}{
+		{
+			name:     "added",
+			status:   http.StatusOK,
+			response: `{"id":"identifier","result":{"bugs":[{"id":1,"changes":{"ext_bz_bug_map.ext_bz_bug_id":{"added":"org/repo/pull/2"}}}]}}`,
+			changed:  true,
+		},
+		{
+			name:     "already linked",
+			status:   http.StatusOK,
+			response: `{"id":"identifier","result":{"bugs":[{"id":1,"changes":{}}]}}`,
+		},
+		{
+			name:     "rpc error",
+			status:   http.StatusOK,
+			response: `{"id":"identifier","error":{"code":100,"message":"Invalid Bug ID"}}`,
+			err:      "JSONRPC error 100: Invalid Bug ID",
+		},
+		{
+			name:     "mismatched identifier",
+			status:   http.StatusOK,
+			response: `{"id":"other","result":{}}`,
+			err:      "JSONRPC returned mismatched identifier, expected identifier but got other",
+		},
+		{
+			name:     "not json",
+			status:   http.StatusOK,
+			response: `<html>`,
+			err:      "failed to unmarshal JSONRPC response: invalid character '<' looking for beginning of value",
+		},
+		{
+			name:   "server error",
+			status: http.StatusInternalServerError,
+			err:    "response code 500 not 200",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := testServer(t, tt.status, tt.response)
+			defer server.Close()
+			changed, err := testClient(server.URL+"/").AddPullRequestAsExternalBug(1, "org", "repo", 2)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.changed, changed)
+
+			require.Len(t, *requests, 1)
+			req := (*requests)[0]
+			require.Equal(t, http.MethodPost, req.method)
+			require.Equal(t, "/jsonrpc.cgi", req.path)
+			require.Equal(t, "application/json", req.header.Get("Content-Type"))
+			require.JSONEq(t, `{
+				"jsonrpc": "1.0",
+				"method": "ExternalBugs.add_external_bug",
+				"id": "identifier",
+				"params": [{
+					"api_key": "s3cret",
+					"bug_ids": [1],
+					"external_bugs": [{"ext_type_url": "https://github.com/", "ext_bz_bug_id": "org/repo/pull/2"}]
+				}]
+			}`, req.body)
+		})
+	}
+}
+
+func TestGetBugHistory(t *testing.T) {
+	server, requests := testServer(t, http.StatusOK, `{"bugs":[
+		{"id":2,"history":[]},
+		{"id":1,"alias":[],"history":[{
+			"when":"2020-05-01T12:00:00Z",
+			"who":"someone@redhat.com",
+			"changes":[
+				{"field_name":"status","removed":"NEW","added":"ASSIGNED"},
+				{"field_name":"flagtypes.name","removed":"","added":"needinfo?(other@redhat.com)","attachment_id":7}
+			]
+		}]}
+	]}`)
+	defer server.Close()
+
+	history, err := testClient(server.URL).GetBugHistory(1)
+	require.NoError(t, err)
+	require.Equal(t, "/rest/bug/1/history", (*requests)[0].path)
+	require.Equal(t, []History{{
+		When: time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC),
+		Who:  "someone@redhat.com",
+		Changes: []Change{
+			{FieldName: "status", Removed: "NEW", Added: "ASSIGNED"},
+			{FieldName: "flagtypes.name", Added: "needinfo?(other@redhat.com)", AttachmentID: 7},
+		},
+	}}, history)
+
+	history, err = testClient(server.URL).GetBugHistory(3)
+	require.NoError(t, err)
+	require.Empty(t, history)
+}
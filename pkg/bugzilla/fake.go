@@ -0,0 +1,198 @@
+package bugzilla
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Fake is a fake Bugzilla client with injectable fields, safe for concurrent use.
+type Fake struct {
+	EndpointString string
+	Bugs           map[int]Bug
+	BugComments    map[int][]Comment
+	BugHistory     map[int][]History
+	BugErrors      map[int]bool
+	ExternalBugs   map[int][]ExternalBug
+	// SearchResults are returned from SearchBugs by query, or all Bugs if the
+	// query isn't present.
+	SearchResults map[string][]int
+	// Updates records every update made, in order.
+	Updates []FakeUpdate
+
+	mu sync.Mutex
+}
+
+// FakeUpdate is an update recorded by the Fake.
+type FakeUpdate struct {
+	ID     int
+	Update BugUpdate
+}
+
+// the Fake is a Client impl
+var _ Client = &Fake{}
+
+func (c *Fake) Endpoint() string {
+	return c.EndpointString
+}
+
+func (c *Fake) GetBug(id int) (*Bug, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.BugErrors[id] {
+		return nil, errors.New("injected error getting bug")
+	}
+	if bug, exists := c.Bugs[id]; exists {
+		return &bug, nil
+	}
+	return nil, &requestError{statusCode: http.StatusNotFound, message: "bug not registered in the fake"}
+}
+
+func (c *Fake) GetExternalBugPRsOnBug(id int) ([]GithubExternalBug, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.BugErrors[id] {
+		return nil, errors.New("injected error getting bug")
+	}
+	var prs []GithubExternalBug
+	for _, ext := range c.ExternalBugs[id] {
+		if ext.Type.URL != "https://github.com/" {
+			continue
+		}
+		org, repo, num, err := PullFromIdentifier(ext.ExternalBugID)
+		if err != nil {
+			continue
+		}
+		prs = append(prs, NewGithubExternalBug(ext, org, repo, num))
+	}
+	return prs, nil
+}
+
+func (c *Fake) GetJiraIssueForBug(id int) ([]JiraExternalBug, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var issues []JiraExternalBug
+	for _, ext := range c.ExternalBugs[id] {
+		if ext.Type.URL == "https://issues.redhat.com/" {
+			issues = append(issues, NewJiraExternalBug(ext))
+		}
+	}
+	return issues, nil
+}
+
+func (c *Fake) SearchBugs(query string) ([]*Bug, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var bugs []*Bug
+	if ids, ok := c.SearchResults[query]; ok {
+		for _, id := range ids {
+			bug := c.Bugs[id]
+			bugs = append(bugs, &bug)
+		}
+		return bugs, nil
+	}
+	for id := range c.Bugs {
+		bug := c.Bugs[id]
+		bugs = append(bugs, &bug)
+	}
+	return bugs, nil
+}
+
+func (c *Fake) UpdateInternalWhiteboard(id int, value string) (*Bug, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	bug, exists := c.Bugs[id]
+	if !exists {
+		return nil, fmt.Errorf("bug %d not registered in the fake", id)
+	}
+	bug.InternalWhiteboard = value
+	c.Bugs[id] = bug
+	return nil, nil
+}
+
+func (c *Fake) GetCommentsOnBug(id int) ([]Comment, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.BugComments[id], nil
+}
+
+func (c *Fake) GetBugHistory(id int) ([]History, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.BugErrors[id] {
+		return nil, errors.New("injected error getting history")
+	}
+	return c.BugHistory[id], nil
+}
+
+func (c *Fake) UpdateBug(id int, update BugUpdate) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.BugErrors[id] {
+		return errors.New("injected error updating bug")
+	}
+	bug, exists := c.Bugs[id]
+	if !exists {
+		return &requestError{statusCode: http.StatusNotFound, message: "bug not registered in the fake"}
+	}
+	if update.Status != "" {
+		bug.Status = update.Status
+	}
+	if update.Resolution != "" {
+		bug.Resolution = update.Resolution
+	}
+	if update.AssignedTo != "" {
+		bug.AssignedTo = update.AssignedTo
+	}
+	if update.Priority != "" {
+		bug.Priority = update.Priority
+	}
+	if update.Severity != "" {
+		bug.Severity = update.Severity
+	}
+	if update.Whiteboard != nil {
+		bug.Whiteboard = *update.Whiteboard
+	}
+	if update.SubComponents != nil {
+		bug.SubComponents = update.SubComponents
+	}
+	if update.DupeOf != 0 {
+		bug.DupeOf = update.DupeOf
+	}
+	for _, f := range update.Flags {
+		bug.Flags = append(bug.Flags, Flag{Name: f.Name, Status: f.Status, Requestee: f.Requestee})
+	}
+	if update.Comment != nil {
+		if c.BugComments == nil {
+			c.BugComments = map[int][]Comment{}
+		}
+		c.BugComments[id] = append(c.BugComments[id], Comment{BugID: id, Count: len(c.BugComments[id]), Text: update.Comment.Body})
+	}
+	c.Bugs[id] = bug
+	c.Updates = append(c.Updates, FakeUpdate{ID: id, Update: update})
+	return nil
+}
+
+func (c *Fake) AddPullRequestAsExternalBug(id int, org, repo string, num int) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.BugErrors[id] {
+		return false, errors.New("injected error adding external bug")
+	}
+	identifier := IdentifierForPull(org, repo, num)
+	for _, ext := range c.ExternalBugs[id] {
+		if ext.ExternalBugID == identifier {
+			return false, nil
+		}
+	}
+	if c.ExternalBugs == nil {
+		c.ExternalBugs = map[int][]ExternalBug{}
+	}
+	c.ExternalBugs[id] = append(c.ExternalBugs[id], ExternalBug{
+		Type:          ExternalBugType{URL: "https://github.com/"},
+		BugzillaBugID: id,
+		ExternalBugID: identifier,
+	})
+	return true, nil
+}
@@ -0,0 +1,194 @@
+package bugzilla
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// customFieldPrefix is the prefix bugzilla gives to fields a site has added.
+const customFieldPrefix = "cf_"
+
+// Known values of the cf_doc_type field.
+const (
+	DocTypeBugFix      = "Bug Fix"
+	DocTypeEnhancement = "Enhancement"
+	DocTypeFeature     = "Feature"
+	DocTypeKnownIssue  = "Known Issue"
+	DocTypeTechPreview = "Technology Preview"
+	DocTypeDeprecated  = "Deprecated Functionality"
+	DocTypeRemoved     = "Removed Functionality"
+	DocTypeReleaseNote = "Release Note"
+	DocTypeNoDocUpdate = "No Doc Update"
+	// DocTypeUnset is the default value, before anyone has decided on docs.
+	DocTypeUnset = "If docs needed, set a value"
+)
+
+// Priorities are the values of the priority and severity fields, most
+// urgent first.
+var Priorities = []string{"urgent", "high", "medium", "low", "unspecified"}
+
+// MoreUrgent reports whether priority or severity a is more urgent than b.
+// Unknown values are not ordered.
+func MoreUrgent(a, b string) bool {
+	ia, ib := -1, -1
+	for i, p := range Priorities {
+		if p == a {
+			ia = i
+		}
+		if p == b {
+			ib = i
+		}
+	}
+	return ia >= 0 && ib >= 0 && ia < ib
+}
+
+var (
+	modeledFieldsOnce sync.Once
+	modeledFields     map[string]bool
+)
+
+// bugFields returns the json names of the fields Bug models directly.
+func bugFields() map[string]bool {
+	modeledFieldsOnce.Do(func() {
+		modeledFields = map[string]bool{}
+		t := reflect.TypeOf(Bug{})
+		for i := 0; i < t.NumField(); i++ {
+			name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+			if name != "" && name != "-" {
+				modeledFields[name] = true
+			}
+		}
+	})
+	return modeledFields
+}
+
+// bugAlias has Bug's fields without its methods, so it can be (un)marshalled
+// without recursing into the custom (un)marshallers.
+type bugAlias Bug
+
+// UnmarshalJSON decodes a bug, keeping any custom fields that Bug doesn't
+// model in CustomFields.
+func (b *Bug) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*bugAlias)(b)); err != nil {
+		return err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	known := bugFields()
+	b.CustomFields = nil
+	for name, value := range raw {
+		if !strings.HasPrefix(name, customFieldPrefix) || known[name] {
+			continue
+		}
+		if b.CustomFields == nil {
+			b.CustomFields = map[string]json.RawMessage{}
+		}
+		b.CustomFields[name] = value
+	}
+	return nil
+}
+
+// MarshalJSON encodes a bug, including any CustomFields alongside the
+// modeled fields.
+func (b Bug) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(bugAlias(b))
+	if err != nil || len(b.CustomFields) == 0 {
+		return data, err
+	}
+	var merged map[string]json.RawMessage
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+	for name, value := range b.CustomFields {
+		if _, ok := merged[name]; !ok {
+			merged[name] = value
+		}
+	}
+	return json.Marshal(merged)
+}
+
+// CustomField decodes the custom field name into out, reporting whether the
+// bug had a value for it.
+func (b *Bug) CustomField(name string, out interface{}) (bool, error) {
+	raw, ok := b.CustomFields[name]
+	if !ok || string(raw) == "null" {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, out)
+}
+
+// customString returns a string custom field, or "" if it isn't set or isn't a string.
+func (b *Bug) customString(name string) string {
+	var value string
+	if _, err := b.CustomField(name, &value); err != nil {
+		return ""
+	}
+	return value
+}
+
+// customStrings returns a custom field that may be sent as a single string or a list.
+func (b *Bug) customStrings(name string) []string {
+	var values []string
+	if _, err := b.CustomField(name, &values); err == nil {
+		return values
+	}
+	if value := b.customString(name); value != "" {
+		return []string{value}
+	}
+	return nil
+}
+
+// customInt returns a numeric custom field, which bugzilla often sends as a string.
+func (b *Bug) customInt(name string) int {
+	var value int
+	if _, err := b.CustomField(name, &value); err == nil {
+		return value
+	}
+	value, _ = strconv.Atoi(b.customString(name))
+	return value
+}
+
+// PMScore is the product management score used to rank bugs.
+func (b *Bug) PMScore() int {
+	return b.customInt("cf_pm_score")
+}
+
+// CloneOf is the ID of the bug this bug was cloned from, or 0.
+func (b *Bug) CloneOf() int {
+	return b.customInt("cf_clone_of")
+}
+
+// Verified lists how the fix was verified, e.g. "Tested".
+func (b *Bug) Verified() []string {
+	return b.customStrings("cf_verified")
+}
+
+// Environment describes the environment the bug was seen in.
+func (b *Bug) Environment() string {
+	return b.customString("cf_environment")
+}
+
+// FixedInVersion is the version the bug was fixed in.
+func (b *Bug) FixedInVersion() string {
+	return b.customString("cf_fixed_in")
+}
+
+// TargetUpstreamVersion is the upstream version the fix is expected in.
+func (b *Bug) TargetUpstreamVersion() string {
+	return b.customString("cf_target_upstream_version")
+}
+
+// LastClosed is when the bug was last closed.
+func (b *Bug) LastClosed() string {
+	return b.customString("cf_last_closed")
+}
+
+// CustomerFacing is whether the bug was reported by or affects customers.
+func (b *Bug) CustomerFacing() string {
+	return b.customString("cf_cust_facing")
+}
@@ -0,0 +1,52 @@
+package bugzilla
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBugCustomFields(t *testing.T) {
+	raw := []byte(`{
+		"id": 1812345,
+		"cf_doc_type": "Bug Fix",
+		"cf_release_notes": "Catalog pods no longer restart.",
+		"cf_internal_whiteboard": "backport-to: 4.4",
+		"cf_pm_score": "42",
+		"cf_clone_of": 1800000,
+		"cf_verified": ["Tested"],
+		"cf_environment": "",
+		"cf_fixed_in": null
+	}`)
+	var bug Bug
+	require.NoError(t, json.Unmarshal(raw, &bug))
+
+	require.Equal(t, 1812345, bug.ID)
+	require.Equal(t, DocTypeBugFix, bug.DocType)
+	require.Equal(t, "Catalog pods no longer restart.", bug.ReleaseNotes)
+	require.Equal(t, "backport-to: 4.4", bug.InternalWhiteboard)
+
+	// modeled fields aren't duplicated into the generic map
+	require.NotContains(t, bug.CustomFields, "cf_doc_type")
+	require.NotContains(t, bug.CustomFields, "id")
+	require.Len(t, bug.CustomFields, 5)
+
+	require.Equal(t, 42, bug.PMScore())
+	require.Equal(t, 1800000, bug.CloneOf())
+	require.Equal(t, []string{"Tested"}, bug.Verified())
+	require.Equal(t, "", bug.Environment())
+	require.Equal(t, "", bug.FixedInVersion())
+
+	var fixedIn string
+	set, err := bug.CustomField("cf_fixed_in", &fixedIn)
+	require.NoError(t, err)
+	require.False(t, set)
+
+	// custom fields survive a round trip
+	out, err := json.Marshal(bug)
+	require.NoError(t, err)
+	var again Bug
+	require.NoError(t, json.Unmarshal(out, &again))
+	require.Equal(t, bug, again)
+}
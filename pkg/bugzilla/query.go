@@ -0,0 +1,62 @@
+package bugzilla
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"text/template"
+)
+
+// CurrentUser is the bugzilla pronoun for the logged in user, usable as a
+// value in searches like assigned_to=%user%.
+const CurrentUser = "%user%"
+
+// buglist.cgi parameters that only affect how the browser shows results
+var uiParams = []string{"list_id", "known_name", "query_based_on", "columnlist", "cmdtype", "remaction", "ctype"}
+
+// ParseQueryURL parses search parameters out of a buglist.cgi url as copied
+// from the browser. A bare query string is accepted as well.
+func ParseQueryURL(raw string) (url.Values, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse query url: %v", err)
+	}
+	query := u.RawQuery
+	if u.Scheme == "" && u.RawQuery == "" {
+		query = raw
+	}
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse query %q: %v", query, err)
+	}
+	for _, p := range uiParams {
+		params.Del(p)
+	}
+	if len(params) == 0 {
+		return nil, fmt.Errorf("no search parameters found in %q", raw)
+	}
+	return params, nil
+}
+
+// ExpandQuery fills in any templated parameter values, like {{.Release}},
+// from data and encodes the result for SearchBugs. Values that expand to
+// nothing are dropped.
+func ExpandQuery(params url.Values, data map[string]string) (string, error) {
+	expanded := url.Values{}
+	for key, values := range params {
+		for _, v := range values {
+			t, err := template.New(key).Option("missingkey=error").Parse(v)
+			if err != nil {
+				return "", fmt.Errorf("invalid template in %s=%s: %v", key, v, err)
+			}
+			var buf bytes.Buffer
+			if err := t.Execute(&buf, data); err != nil {
+				return "", fmt.Errorf("could not fill in %s=%s, pass the value with --set: %v", key, v, err)
+			}
+			if buf.Len() > 0 {
+				expanded.Add(key, buf.String())
+			}
+		}
+	}
+	return expanded.Encode(), nil
+}
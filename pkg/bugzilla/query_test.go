@@ -0,0 +1,40 @@
+package bugzilla
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQueryURL(t *testing.T) {
+	params, err := ParseQueryURL("https://bugzilla.redhat.com/buglist.cgi?bug_status=NEW&bug_status=ASSIGNED&classification=Red%20Hat&component=OLM&list_id=10943355&query_format=advanced")
+	require.NoError(t, err)
+	require.Equal(t, url.Values{
+		"bug_status":     {"NEW", "ASSIGNED"},
+		"classification": {"Red Hat"},
+		"component":      {"OLM"},
+		"query_format":   {"advanced"},
+	}, params)
+
+	params, err = ParseQueryURL("component=OLM&target_release=4.5.0")
+	require.NoError(t, err)
+	require.Equal(t, url.Values{"component": {"OLM"}, "target_release": {"4.5.0"}}, params)
+
+	_, err = ParseQueryURL("https://bugzilla.redhat.com/buglist.cgi?list_id=1")
+	require.Error(t, err)
+}
+
+func TestExpandQuery(t *testing.T) {
+	params := url.Values{
+		"component":      {"OLM"},
+		"target_release": {"{{.Release}}.0", "{{.Release}}.z"},
+		"keywords":       {"{{.Keywords}}"},
+	}
+	query, err := ExpandQuery(params, map[string]string{"Release": "4.4", "Keywords": ""})
+	require.NoError(t, err)
+	require.Equal(t, "component=OLM&target_release=4.4.0&target_release=4.4.z", query)
+
+	_, err = ExpandQuery(params, map[string]string{"Keywords": ""})
+	require.Error(t, err)
+}
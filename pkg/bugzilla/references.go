@@ -0,0 +1,27 @@
+package bugzilla
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// bugReference matches the ways PRs, commits and docs refer to a bug: the
+// "Bug 1812345" convention, "BZ#1812345", "BZ 1812345" and links to
+// show_bug.cgi.
+var bugReference = regexp.MustCompile(`(?i)(?:show_bug\.cgi\?id=|\bBZ\s*#?\s*|\bBug\s+#?)(\d{6,8})\b`)
+
+// References returns the IDs of bugs referenced in text, in order of first
+// appearance and without duplicates.
+func References(text string) []int {
+	var ids []int
+	seen := map[int]bool{}
+	for _, m := range bugReference.FindAllStringSubmatch(text, -1) {
+		id, err := strconv.Atoi(m[1])
+		if err != nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
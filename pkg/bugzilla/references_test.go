@@ -0,0 +1,27 @@
+package bugzilla
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferences(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []int
+	}{
+		{name: "pr title", text: "Bug 1812345: fix catalog pod", want: []int{1812345}},
+		{name: "in order without duplicates", text: "bug 1812345: fix\n\nAlso fixes BUG 1800001 and Bug 1812345", want: []int{1812345, 1800001}},
+		{name: "numbered", text: "Bug #1812345", want: []int{1812345}},
+		{name: "bz", text: "BZ#1800001, BZ 1800002 and bz1800003", want: []int{1800001, 1800002, 1800003}},
+		{name: "link", text: "see https://bugzilla.redhat.com/show_bug.cgi?id=1812345", want: []int{1812345}},
+		{name: "not references", text: "Debug 1812345, bug 12, bugs 1812345, Bug 123456789"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, References(tt.text))
+		})
+	}
+}
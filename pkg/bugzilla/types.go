@@ -1,6 +1,7 @@
 package bugzilla
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -35,6 +36,8 @@ type Bug struct {
 	Deadline string `json:"deadline,omitempty"`
 	// DependsOn is the IDs of bugs that this bug "depends on".
 	DependsOn []int `json:"depends_on,omitempty"`
+	// DocType is the kind of documentation this bug needs, e.g. "Bug Fix" or "No Doc Update".
+	DocType string `json:"cf_doc_type,omitempty"`
 	// DupeOf is the bug ID of the bug that this bug is a duplicate of. If this bug isn't a duplicate of any bug, this will be null.
 	DupeOf int `json:"dupe_of,omitempty"`
 	// EstimatedTime is the number of hours that it was estimated that this bug would take. If you are not in the time-tracking group, this field will not be included in the return value.
@@ -71,6 +74,8 @@ type Bug struct {
 	QAContact string `json:"qa_contact,omitempty"`
 	// QAContactDetail is an object containing detailed user information for the qa_contact. To see the keys included in the user detail object, see below.
 	QAContactDetail *User `json:"qa_contact_detail,omitempty"`
+	// ReleaseNotes is the "Doc Text" of the bug, used to write release notes.
+	ReleaseNotes string `json:"cf_release_notes,omitempty"`
 	// RemainingTime is the number of hours of work remaining until work on this bug is complete. If you are not in the time-tracking group, this field will not be included in the return value.
 	RemainingTime int `json:"remaining_time,omitempty"`
 	// Resolution is the current resolution of the bug, or an empty string if the bug is open.
@@ -81,6 +86,8 @@ type Bug struct {
 	Severity string `json:"severity,omitempty"`
 	// Status is the current status of the bug.
 	Status string `json:"status,omitempty"`
+	// SubComponents are the current sub components of the bug, by component.
+	SubComponents map[string][]string `json:"sub_components,omitempty"`
 	// Summary is the summary of this bug.
 	Summary string `json:"summary,omitempty"`
 	// TargetMilestone is the milestone that this bug is supposed to be fixed by, or for closed bugs, the milestone that it was fixed for.
@@ -95,6 +102,8 @@ type Bug struct {
 	Version []string `json:"version,omitempty"`
 	// Whiteboard is he value of the "status whiteboard" field on the bug.
 	Whiteboard string `json:"whiteboard,omitempty"`
+	// CustomFields holds the site-specific cf_ fields that aren't modeled above.
+	CustomFields map[string]json.RawMessage `json:"-"`
 }
 
 // User holds information about a user
@@ -135,6 +144,42 @@ type BugUpdate struct {
 	// Status is the current status of the bug.
 	Status     string `json:"status,omitempty"`
 	Resolution string `json:"resolution,omitempty"`
+	// AssignedTo is the login name of the user to assign the bug to.
+	AssignedTo string `json:"assigned_to,omitempty"`
+	// Priority is the new priority of the bug.
+	Priority string `json:"priority,omitempty"`
+	// Severity is the new severity of the bug.
+	Severity string `json:"severity,omitempty"`
+	// Whiteboard is the new value of the status whiteboard, which may be
+	// empty to clear it. Nil leaves it as is.
+	Whiteboard *string `json:"whiteboard,omitempty"`
+	// SubComponents sets the sub component of the bug, by component.
+	SubComponents map[string][]string `json:"sub_components,omitempty"`
+	// DupeOf marks the bug a duplicate of another. The status and resolution
+	// should be set to close the bug as DUPLICATE along with it.
+	DupeOf int `json:"dupe_of,omitempty"`
+	// Flags are flags to set, such as needinfo requests.
+	Flags []FlagChange `json:"flags,omitempty"`
+	// Comment is added to the bug along with the update.
+	Comment *BugComment `json:"comment,omitempty"`
+}
+
+// FlagChange sets a flag on a bug as part of a BugUpdate.
+type FlagChange struct {
+	// Name is the name of the flag, e.g. needinfo.
+	Name string `json:"name"`
+	// Status is one of ?, + or -, or X to clear the flag.
+	Status string `json:"status"`
+	// Requestee is the login name of the user the flag is requested of.
+	Requestee string `json:"requestee,omitempty"`
+}
+
+// BugComment is a comment added as part of a BugUpdate.
+type BugComment struct {
+	// Body is the text of the comment.
+	Body string `json:"body"`
+	// IsPrivate restricts the comment to the insidergroup.
+	IsPrivate bool `json:"is_private,omitempty"`
 }
 
 type JiraExternalBug struct {
@@ -226,3 +271,26 @@ type Comment struct {
 	// Tags is an array of comment tags currently set for the comment.
 	Tags []string `json:"tags,omitempty"`
 }
+
+// History is a set of changes made to a bug by one person at one time.
+// https://bugzilla.readthedocs.io/en/latest/api/core/v1/bug.html#bug-history
+type History struct {
+	// When is the date the bug activity/change happened.
+	When time.Time `json:"when"`
+	// Who is the login name of the user who performed the bug change.
+	Who string `json:"who"`
+	// Changes are the changes that were performed on the bug at this time.
+	Changes []Change `json:"changes"`
+}
+
+// Change is a change to a single field of a bug.
+type Change struct {
+	// FieldName is the name of the bug field that has changed.
+	FieldName string `json:"field_name"`
+	// Removed is the previous value for the bug field (or empty string if there was no value).
+	Removed string `json:"removed"`
+	// Added is the new value for the bug field (or empty string if there is no value).
+	Added string `json:"added"`
+	// AttachmentID is the ID of the attachment that was changed, if the change was to an attachment.
+	AttachmentID int `json:"attachment_id,omitempty"`
+}
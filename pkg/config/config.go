@@ -0,0 +1,39 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const appName = "cop"
+
+// DataDir returns the directory cop stores its local state in, creating it
+// with owner-only permissions if it does not exist yet.
+// It honors COP_DATA_DIR, and otherwise lives under the user config dir.
+func DataDir() (string, error) {
+	dir := os.Getenv("COP_DATA_DIR")
+	if dir == "" {
+		base, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(base, appName)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// Path joins elem onto the data dir, creating any intermediate directories.
+func Path(elem ...string) (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(append([]string{dir}, elem...)...)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+const fileName = "config.yaml"
+
+// Config is the user's cop configuration, stored as yaml in the data dir.
+type Config struct {
+	// Queries are saved bugzilla searches, referred to on the command line as @name.
+	Queries map[string]Query `yaml:"queries,omitempty"`
+}
+
+// Query is a saved bugzilla search.
+type Query struct {
+	Description string `yaml:"description,omitempty"`
+	// Params are buglist.cgi search parameters. Values may use text/template
+	// syntax, like {{.Release}}, which is filled in when the query is run.
+	Params url.Values `yaml:"params"`
+	// Notify sends changes to the query's bugs elsewhere, see package notify.
+	Notify *NotifyConfig `yaml:"notify,omitempty"`
+}
+
+// Load reads the config file, returning an empty config if there isn't one yet.
+func Load() (*Config, error) {
+	path, err := Path(fileName)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Save writes the config file.
+func (c *Config) Save() error {
+	path, err := Path(fileName)
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// Query returns the saved query with the given name.
+func (c *Config) Query(name string) (Query, error) {
+	q, ok := c.Queries[name]
+	if !ok {
+		return Query{}, fmt.Errorf("no saved query named %q, see `cop bz query list`", name)
+	}
+	return q, nil
+}
+
+// SetQuery saves q under name, replacing any existing query with that name.
+func (c *Config) SetQuery(name string, q Query) {
+	if c.Queries == nil {
+		c.Queries = map[string]Query{}
+	}
+	c.Queries[name] = q
+}
+
+// QueryNames returns the names of the saved queries in order.
+func (c *Config) QueryNames() []string {
+	names := make([]string, 0, len(c.Queries))
+	for name := range c.Queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadAndSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cop-config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer os.Setenv("COP_DATA_DIR", os.Getenv("COP_DATA_DIR"))
+	os.Setenv("COP_DATA_DIR", dir)
+
+	c, err := Load()
+	require.NoError(t, err)
+	require.Empty(t, c.Queries)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, fileName), []byte(`
+queries:
+  mine:
+    params:
+      bug_status: [NEW, ASSIGNED]
+      target_release: ['{{.Release}}.z']
+    notify:
+      digest: 15m
+      sinks:
+      - slack: {webhook: "https://hooks.slack.com/services/x"}
+`), 0600))
+	c, err = Load()
+	require.NoError(t, err)
+	q, err := c.Query("mine")
+	require.NoError(t, err)
+	require.Equal(t, url.Values{"bug_status": {"NEW", "ASSIGNED"}, "target_release": {"{{.Release}}.z"}}, q.Params)
+	require.Equal(t, 15*time.Minute, q.Notify.Digest)
+	require.Equal(t, []SinkConfig{{Slack: &SlackConfig{Webhook: "https://hooks.slack.com/services/x"}}}, q.Notify.Sinks)
+
+	c.SetQuery("theirs", Query{Params: url.Values{"assigned_to": {"%user%"}}})
+	require.NoError(t, c.Save())
+	c, err = Load()
+	require.NoError(t, err)
+	require.Equal(t, []string{"mine", "theirs"}, c.QueryNames())
+	_, err = c.Query("missing")
+	require.Error(t, err)
+}
@@ -0,0 +1,49 @@
+package config
+
+import "time"
+
+// NotifyConfig configures where and how often package notify sends a saved
+// query's changes.
+type NotifyConfig struct {
+	// Digest batches events, sending at most one notification per period.
+	Digest time.Duration `yaml:"digest,omitempty"`
+	// MaxPerHour limits how many notifications are sent in any hour. Events
+	// over the limit are held until the next notification can be sent.
+	MaxPerHour int          `yaml:"max_per_hour,omitempty"`
+	Sinks      []SinkConfig `yaml:"sinks"`
+}
+
+// SinkConfig configures one sink. Exactly one of its fields is set.
+type SinkConfig struct {
+	Slack   *SlackConfig   `yaml:"slack,omitempty"`
+	Webhook *WebhookConfig `yaml:"webhook,omitempty"`
+	Email   *EmailConfig   `yaml:"email,omitempty"`
+	Desktop *DesktopConfig `yaml:"desktop,omitempty"`
+}
+
+// SlackConfig configures a Slack incoming webhook.
+type SlackConfig struct {
+	Webhook string `yaml:"webhook"`
+}
+
+// WebhookConfig configures a generic webhook.
+type WebhookConfig struct {
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Body is a text/template rendered with the notify.Notification, with a json
+	// function for quoting values. It defaults to the notification as json.
+	Body string `yaml:"body,omitempty"`
+}
+
+// EmailConfig configures sending email over SMTP.
+type EmailConfig struct {
+	// Server is the host:port of the SMTP server.
+	Server   string   `yaml:"server"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+	Username string   `yaml:"username,omitempty"`
+	Password string   `yaml:"password,omitempty"`
+}
+
+// DesktopConfig configures desktop notifications. It has no options yet.
+type DesktopConfig struct{}
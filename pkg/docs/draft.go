@@ -0,0 +1,192 @@
+package docs
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/view"
+)
+
+const (
+	// unspecifiedType groups bugs that need docs but have no doc type yet.
+	unspecifiedType = "Unspecified"
+	// docsKeyword marks bugs that need documentation regardless of doc type.
+	docsKeyword = "docs"
+)
+
+// unfixedResolutions are resolutions of closed bugs that didn't ship a change.
+var unfixedResolutions = map[string]bool{
+	"NOTABUG":           true,
+	"WONTFIX":           true,
+	"DUPLICATE":         true,
+	"WORKSFORME":        true,
+	"CANTFIX":           true,
+	"INSUFFICIENT_DATA": true,
+	"DEFERRED":          true,
+}
+
+// Unfixed reports whether a bug was closed without shipping a change, so it
+// doesn't belong in release notes whatever its doc type.
+func Unfixed(bug *bugzilla.Bug) bool {
+	return unfixedResolutions[bug.Resolution]
+}
+
+// NeedsDocs reports whether a bug has been marked as needing documentation,
+// either through its doc type, its doc text, or a docs keyword.
+func NeedsDocs(bug *bugzilla.Bug) bool {
+	for _, k := range bug.Keywords {
+		if strings.EqualFold(k, docsKeyword) || strings.EqualFold(k, "documentation") {
+			return true
+		}
+	}
+	switch bug.DocType {
+	case bugzilla.DocTypeNoDocUpdate:
+		return false
+	case "", bugzilla.DocTypeUnset:
+		return bug.ReleaseNotes != ""
+	}
+	return true
+}
+
+// MissingDocText reports whether a bug needs release notes but nobody has
+// written its doc text yet.
+func MissingDocText(bug *bugzilla.Bug) bool {
+	if strings.TrimSpace(bug.ReleaseNotes) != "" {
+		return false
+	}
+	return bug.DocType != bugzilla.DocTypeNoDocUpdate
+}
+
+// Entry is a single bug in the release notes.
+type Entry struct {
+	Bug *bugzilla.Bug
+	// PRs are the pull requests that fix the bug.
+	PRs []bugzilla.GithubExternalBug
+	// Documented lists where the bug is already mentioned in the docs.
+	Documented []Reference
+}
+
+// Section holds the entries of one bug type within a component.
+type Section struct {
+	Component string
+	Type      string
+	Entries   []Entry
+}
+
+// Draft is a set of release notes, grouped by component and bug type.
+type Draft struct {
+	Title    string
+	Release  string
+	Endpoint string
+	Sections []Section
+}
+
+// NewDraft groups entries by component and bug type. Sections and the entries
+// in them are sorted so that drafts are stable across runs.
+func NewDraft(release, endpoint string, entries []Entry) *Draft {
+	grouped := map[[2]string][]Entry{}
+	for _, e := range entries {
+		component := "Unknown"
+		if len(e.Bug.Component) > 0 {
+			component = e.Bug.Component[0]
+		}
+		docType := e.Bug.DocType
+		if docType == "" || docType == bugzilla.DocTypeUnset {
+			docType = unspecifiedType
+		}
+		key := [2]string{component, docType}
+		grouped[key] = append(grouped[key], e)
+	}
+
+	d := &Draft{
+		Title:    fmt.Sprintf("Release notes draft for %s", release),
+		Release:  release,
+		Endpoint: endpoint,
+	}
+	for key, entries := range grouped {
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Bug.ID < entries[j].Bug.ID
+		})
+		d.Sections = append(d.Sections, Section{Component: key[0], Type: key[1], Entries: entries})
+	}
+	sort.Slice(d.Sections, func(i, j int) bool {
+		if d.Sections[i].Component != d.Sections[j].Component {
+			return d.Sections[i].Component < d.Sections[j].Component
+		}
+		return d.Sections[i].Type < d.Sections[j].Type
+	})
+	return d
+}
+
+// Markdown writes the draft as Markdown.
+func (d *Draft) Markdown(w io.Writer) error {
+	p := view.NewPrinter(w)
+	p.Printf("# %s\n", d.Title)
+	component := ""
+	for _, s := range d.Sections {
+		if s.Component != component {
+			component = s.Component
+			p.Printf("\n## %s\n", component)
+		}
+		p.Printf("\n### %s\n\n", s.Type)
+		for _, e := range s.Entries {
+			p.Printf("* [Bug %d](%s): %s\n", e.Bug.ID, bugzilla.BugURL(d.Endpoint, e.Bug.ID), e.Bug.Summary)
+			if e.Bug.ReleaseNotes != "" {
+				p.Printf("\n%s\n\n", indent(e.Bug.ReleaseNotes, "  "))
+			}
+			for _, pr := range e.PRs {
+				p.Printf("  * Fixed by [%s/%s#%d](%s)\n", pr.Org, pr.Repo, pr.Num, PullURL(pr))
+			}
+			for _, ref := range e.Documented {
+				p.Printf("  * Documented in `%s:%d`\n", ref.Path, ref.Line)
+			}
+		}
+	}
+	return p.Err()
+}
+
+// AsciiDoc writes the draft as AsciiDoc, in the style of the OpenShift docs.
+func (d *Draft) AsciiDoc(w io.Writer) error {
+	p := view.NewPrinter(w)
+	p.Printf("= %s\n", d.Title)
+	component := ""
+	for _, s := range d.Sections {
+		if s.Component != component {
+			component = s.Component
+			p.Printf("\n== %s\n", component)
+		}
+		p.Printf("\n=== %s\n\n", s.Type)
+		for _, e := range s.Entries {
+			p.Printf("* %s (link:%s[*BZ#%d*])\n", e.Bug.Summary, bugzilla.BugURL(d.Endpoint, e.Bug.ID), e.Bug.ID)
+			if e.Bug.ReleaseNotes != "" {
+				// a + continues the list item across the paragraph break
+				p.Printf("+\n%s\n", strings.TrimSpace(e.Bug.ReleaseNotes))
+			}
+			for _, pr := range e.PRs {
+				p.Printf("** Fixed by link:%s[%s/%s#%d]\n", PullURL(pr), pr.Org, pr.Repo, pr.Num)
+			}
+			for _, ref := range e.Documented {
+				p.Printf("** Documented in `%s:%d`\n", ref.Path, ref.Line)
+			}
+		}
+	}
+	return p.Err()
+}
+
+// PullURL returns the GitHub address of a linked pull request.
+func PullURL(pr bugzilla.GithubExternalBug) string {
+	return fmt.Sprintf("https://github.com/%s/%s/pull/%d", pr.Org, pr.Repo, pr.Num)
+}
+
+func indent(text, prefix string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, l := range lines {
+		if l != "" {
+			lines[i] = prefix + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,183 @@
+package docs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+)
+
+func TestNeedsDocs(t *testing.T) {
+	tests := []struct {
+		name string
+		bug  bugzilla.Bug
+		want bool
+	}{
+		{name: "no doc type", bug: bugzilla.Bug{}, want: false},
+		{name: "unset doc type", bug: bugzilla.Bug{DocType: bugzilla.DocTypeUnset}, want: false},
+		{name: "unset doc type with doc text", bug: bugzilla.Bug{DocType: bugzilla.DocTypeUnset, ReleaseNotes: "Fixed it."}, want: true},
+		{name: "no doc update", bug: bugzilla.Bug{DocType: bugzilla.DocTypeNoDocUpdate, ReleaseNotes: "Fixed it."}, want: false},
+		{name: "bug fix", bug: bugzilla.Bug{DocType: bugzilla.DocTypeBugFix}, want: true},
+		{name: "docs keyword", bug: bugzilla.Bug{DocType: bugzilla.DocTypeNoDocUpdate, Keywords: []string{"Docs"}}, want: true},
+		{name: "documentation keyword", bug: bugzilla.Bug{Keywords: []string{"Regression", "documentation"}}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, NeedsDocs(&tt.bug))
+		})
+	}
+}
+
+func TestMissingDocText(t *testing.T) {
+	tests := []struct {
+		name string
+		bug  bugzilla.Bug
+		want bool
+	}{
+		{name: "bug fix without doc text", bug: bugzilla.Bug{DocType: bugzilla.DocTypeBugFix}, want: true},
+		{name: "blank doc text", bug: bugzilla.Bug{DocType: bugzilla.DocTypeBugFix, ReleaseNotes: " \n"}, want: true},
+		{name: "bug fix with doc text", bug: bugzilla.Bug{DocType: bugzilla.DocTypeBugFix, ReleaseNotes: "Fixed it."}, want: false},
+		{name: "no doc update", bug: bugzilla.Bug{DocType: bugzilla.DocTypeNoDocUpdate}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, MissingDocText(&tt.bug))
+		})
+	}
+}
+
+func TestUnfixed(t *testing.T) {
+	tests := []struct {
+		resolution string
+		want       bool
+	}{
+		{resolution: "", want: false},
+		{resolution: "CURRENTRELEASE", want: false},
+		{resolution: "ERRATA", want: false},
+		{resolution: "NOTABUG", want: true},
+		{resolution: "DUPLICATE", want: true},
+		{resolution: "WONTFIX", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.resolution, func(t *testing.T) {
+			require.Equal(t, tt.want, Unfixed(&bugzilla.Bug{Resolution: tt.resolution}))
+		})
+	}
+}
+
+func TestNewDraft(t *testing.T) {
+	entry := func(id int, component, docType string) Entry {
+		bug := &bugzilla.Bug{ID: id, DocType: docType}
+		if component != "" {
+			bug.Component = []string{component}
+		}
+		return Entry{Bug: bug}
+	}
+	tests := []struct {
+		name    string
+		entries []Entry
+		want    []Section
+	}{
+		{
+			name: "no entries",
+			want: nil,
+		},
+		{
+			name: "grouped by component and type, sorted",
+			entries: []Entry{
+				entry(3, "OLM", bugzilla.DocTypeBugFix),
+				entry(1, "OLM", bugzilla.DocTypeBugFix),
+				entry(2, "OLM", bugzilla.DocTypeEnhancement),
+				entry(4, "Catalog", bugzilla.DocTypeBugFix),
+			},
+			want: []Section{
+				{Component: "Catalog", Type: bugzilla.DocTypeBugFix, Entries: []Entry{entry(4, "Catalog", bugzilla.DocTypeBugFix)}},
+				{Component: "OLM", Type: bugzilla.DocTypeBugFix, Entries: []Entry{entry(1, "OLM", bugzilla.DocTypeBugFix), entry(3, "OLM", bugzilla.DocTypeBugFix)}},
+				{Component: "OLM", Type: bugzilla.DocTypeEnhancement, Entries: []Entry{entry(2, "OLM", bugzilla.DocTypeEnhancement)}},
+			},
+		},
+		{
+			name: "missing component and doc type",
+			entries: []Entry{
+				entry(1, "", ""),
+				entry(2, "", bugzilla.DocTypeUnset),
+			},
+			want: []Section{
+				{Component: "Unknown", Type: unspecifiedType, Entries: []Entry{entry(1, "", ""), entry(2, "", bugzilla.DocTypeUnset)}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDraft("4.5.0", "https://bugzilla.redhat.com", tt.entries)
+			require.Equal(t, "Release notes draft for 4.5.0", d.Title)
+			require.Equal(t, "4.5.0", d.Release)
+			require.Equal(t, tt.want, d.Sections)
+		})
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	tests := []struct {
+		name  string
+		draft Draft
+		want  string
+	}{
+		{
+			name:  "empty",
+			draft: Draft{Title: "Release notes draft for 4.5.0"},
+			want:  "# Release notes draft for 4.5.0\n",
+		},
+		{
+			name: "entries",
+			draft: Draft{
+				Title:    "Release notes draft for 4.5.0",
+				Endpoint: "https://bugzilla.redhat.com/",
+				Sections: []Section{
+					{Component: "OLM", Type: bugzilla.DocTypeBugFix, Entries: []Entry{
+						{
+							Bug: &bugzilla.Bug{ID: 1, Summary: "catalog pods restart", ReleaseNotes: "Cause: x\n\nResult: y\n"},
+							PRs: []bugzilla.GithubExternalBug{{Org: "operator-framework", Repo: "operator-lifecycle-manager", Num: 42}},
+						},
+						{
+							Bug:        &bugzilla.Bug{ID: 2, Summary: "installs hang"},
+							Documented: []Reference{{Path: "olm/index.md", Line: 3}},
+						},
+					}},
+					{Component: "OLM", Type: bugzilla.DocTypeEnhancement, Entries: []Entry{
+						{Bug: &bugzilla.Bug{ID: 3, Summary: "faster resolution"}},
+					}},
+				},
+			},
+			want: `# Release notes draft for 4.5.0
+
+## OLM
+
+### Bug Fix
+
+* [Bug 1](https://bugzilla.redhat.com/show_bug.cgi?id=1): catalog pods restart
+
+  Cause: x
+
+  Result: y
+
+  * Fixed by [operator-framework/operator-lifecycle-manager#42](https://github.com/operator-framework/operator-lifecycle-manager/pull/42)
+* [Bug 2](https://bugzilla.redhat.com/show_bug.cgi?id=2): installs hang
+  * Documented in ` + "`olm/index.md:3`" + `
+
+### Enhancement
+
+* [Bug 3](https://bugzilla.redhat.com/show_bug.cgi?id=3): faster resolution
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			require.NoError(t, tt.draft.Markdown(&out))
+			require.Equal(t, tt.want, out.String())
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package docs
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+)
+
+// docExtensions are the file types scanned for bug references.
+var docExtensions = map[string]bool{
+	".md":       true,
+	".markdown": true,
+	".adoc":     true,
+	".asciidoc": true,
+}
+
+// Reference is a place in the docs that mentions a bug.
+type Reference struct {
+	// Path is the file the reference is in, relative to the scanned root.
+	Path string
+	// Line is the 1-indexed line the reference is on.
+	Line int
+}
+
+// Scan walks root for Markdown and AsciiDoc files and returns the places each
+// bug is referenced, keyed by bug ID.
+func Scan(root string) (map[int][]Reference, error) {
+	refs := map[int][]Reference{}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if strings.HasPrefix(info.Name(), ".") && path != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !docExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		return scanFile(path, rel, refs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+func scanFile(path, rel string, refs map[int][]Reference) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		// a line often both links and names a bug, References counts it once
+		for _, id := range bugzilla.References(scanner.Text()) {
+			refs[id] = append(refs[id], Reference{Path: rel, Line: line})
+		}
+	}
+	return scanner.Err()
+}
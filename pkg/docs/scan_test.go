@@ -0,0 +1,37 @@
+package docs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScan(t *testing.T) {
+	root, err := ioutil.TempDir("", "docs")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		"olm/index.md":        "# OLM\n\nFixed in Bug 1812345: catalog pods restart.\n",
+		"release/notes.adoc":  "* link:https://bugzilla.redhat.com/show_bug.cgi?id=1800001[BZ#1800001]\n* BZ 1800002\n",
+		"olm/ignored.txt":     "Bug 1899999\n",
+		".git/COMMIT_EDITMSG": "Bug 1888888\n",
+		".git/notes.md":       "Bug 1888888\n",
+	}
+	for path, content := range files {
+		full := filepath.Join(root, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+		require.NoError(t, ioutil.WriteFile(full, []byte(content), 0644))
+	}
+
+	refs, err := Scan(root)
+	require.NoError(t, err)
+	require.Equal(t, map[int][]Reference{
+		1812345: {{Path: filepath.Join("olm", "index.md"), Line: 3}},
+		1800001: {{Path: filepath.Join("release", "notes.adoc"), Line: 1}},
+		1800002: {{Path: filepath.Join("release", "notes.adoc"), Line: 2}},
+	}, refs)
+}
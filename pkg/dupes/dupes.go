@@ -0,0 +1,248 @@
+// Package dupes finds bugs that are likely duplicates of each other by how
+// similar their summaries and descriptions are.
+//
+// Two measures are combined: the cosine similarity of TF-IDF weighted words,
+// which finds bugs about the same things, and the Jaccard similarity of word
+// shingles, which finds bugs quoting the same error messages.
+package dupes
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+)
+
+const (
+	// shingleSize is how many words make up a shingle.
+	shingleSize = 3
+	// cosineWeight is how much the TF-IDF similarity counts towards a score,
+	// the rest is the shingle similarity.
+	cosineWeight = 0.6
+)
+
+// stopWords are too common in bug reports to tell them apart.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "but": true,
+	"by": true, "for": true, "from": true, "has": true, "have": true, "in": true, "is": true, "it": true,
+	"not": true, "of": true, "on": true, "or": true, "that": true, "the": true, "this": true, "to": true,
+	"was": true, "when": true, "with": true, "description": true, "problem": true, "version": true,
+	"steps": true, "reproduce": true, "actual": true, "expected": true, "results": true, "additional": true,
+	"info": true, "how": true, "reproducible": true,
+}
+
+// Doc is the text of a bug that is compared.
+type Doc struct {
+	ID      int
+	Summary string
+	// Description is the bug's first comment.
+	Description string
+}
+
+// Tokenize splits text into lower case words, dropping punctuation and stop
+// words. Dots, dashes, underscores and slashes within words are kept, so that
+// names like quay.io/foo and catalog-operator stay whole.
+func Tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("._-/", r)
+	})
+	var tokens []string
+	for _, f := range fields {
+		f = strings.Trim(f, "._-/")
+		if len(f) < 2 || stopWords[f] {
+			continue
+		}
+		tokens = append(tokens, f)
+	}
+	return tokens
+}
+
+// doc is a Doc prepared for comparison
+type doc struct {
+	Doc
+	weights  map[string]float64
+	norm     float64
+	shingles map[string]bool
+}
+
+// Index compares a set of bugs with each other.
+type Index struct {
+	docs map[int]*doc
+	ids  []int
+}
+
+// NewIndex prepares docs for comparison, weighing words by how rare they are
+// among them.
+func NewIndex(docs []Doc) *Index {
+	idx := &Index{docs: map[int]*doc{}}
+	counts := map[int]map[string]int{}
+	df := map[string]int{}
+	for _, d := range docs {
+		if _, ok := idx.docs[d.ID]; ok {
+			continue
+		}
+		tokens := Tokenize(d.Summary + "\n" + d.Description)
+		tf := map[string]int{}
+		for _, t := range tokens {
+			tf[t]++
+		}
+		for t := range tf {
+			df[t]++
+		}
+		counts[d.ID] = tf
+		idx.docs[d.ID] = &doc{Doc: d, shingles: shingles(tokens)}
+		idx.ids = append(idx.ids, d.ID)
+	}
+	sort.Ints(idx.ids)
+
+	n := float64(len(idx.docs))
+	for id, d := range idx.docs {
+		d.weights = map[string]float64{}
+		for t, c := range counts[id] {
+			// smoothed so that words in every doc still count a little
+			w := (1 + math.Log(float64(c))) * math.Log(1+n/float64(df[t]))
+			d.weights[t] = w
+			d.norm += w * w
+		}
+		d.norm = math.Sqrt(d.norm)
+	}
+	return idx
+}
+
+func shingles(tokens []string) map[string]bool {
+	s := map[string]bool{}
+	if len(tokens) < shingleSize {
+		if len(tokens) > 0 {
+			s[strings.Join(tokens, " ")] = true
+		}
+		return s
+	}
+	for i := 0; i+shingleSize <= len(tokens); i++ {
+		s[strings.Join(tokens[i:i+shingleSize], " ")] = true
+	}
+	return s
+}
+
+// Match is a bug similar to another.
+type Match struct {
+	ID int `json:"id"`
+	// Score combines the two similarities, from 0 to 1.
+	Score float64 `json:"score"`
+	// Cosine is the TF-IDF cosine similarity.
+	Cosine float64 `json:"cosine"`
+	// Shingle is the Jaccard similarity of word shingles.
+	Shingle float64 `json:"shingle"`
+}
+
+func (idx *Index) compare(a, b *doc) Match {
+	m := Match{ID: b.ID}
+	if a.norm > 0 && b.norm > 0 {
+		var dot float64
+		for t, w := range a.weights {
+			dot += w * b.weights[t]
+		}
+		m.Cosine = dot / (a.norm * b.norm)
+	}
+	if len(a.shingles) > 0 && len(b.shingles) > 0 {
+		shared := 0
+		for s := range a.shingles {
+			if b.shingles[s] {
+				shared++
+			}
+		}
+		m.Shingle = float64(shared) / float64(len(a.shingles)+len(b.shingles)-shared)
+	}
+	m.Score = cosineWeight*m.Cosine + (1-cosineWeight)*m.Shingle
+	return m
+}
+
+// Similar ranks the bugs most like the bug with id, best first, leaving out
+// those scoring below min.
+func (idx *Index) Similar(id int, min float64, limit int) ([]Match, error) {
+	d, ok := idx.docs[id]
+	if !ok {
+		return nil, fmt.Errorf("bug %d is not indexed", id)
+	}
+	var matches []Match
+	for _, other := range idx.ids {
+		if other == id {
+			continue
+		}
+		if m := idx.compare(d, idx.docs[other]); m.Score >= min {
+			matches = append(matches, m)
+		}
+	}
+	sortMatches(matches)
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// Pair is two bugs that are likely duplicates.
+type Pair struct {
+	// ID is the older bug of the two, which the other would duplicate.
+	ID int `json:"id"`
+	// Duplicate is the newer bug and how similar it is.
+	Duplicate Match `json:"duplicate"`
+}
+
+// Pairs finds every pair of bugs scoring at least min, best first.
+func (idx *Index) Pairs(min float64) []Pair {
+	var pairs []Pair
+	for i, a := range idx.ids {
+		for _, b := range idx.ids[i+1:] {
+			if m := idx.compare(idx.docs[a], idx.docs[b]); m.Score >= min {
+				pairs = append(pairs, Pair{ID: a, Duplicate: m})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		if pairs[i].Duplicate.Score != pairs[j].Duplicate.Score {
+			return pairs[i].Duplicate.Score > pairs[j].Duplicate.Score
+		}
+		return pairs[i].ID < pairs[j].ID
+	})
+	return pairs
+}
+
+func sortMatches(matches []Match) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+}
+
+// Fetch gets the description of each bug to build its Doc, with up to workers
+// requests at once.
+func Fetch(client bugzilla.Client, bugs []*bugzilla.Bug, workers int) ([]Doc, error) {
+	var (
+		ids          []int
+		descriptions = map[int]string{}
+		mu           sync.Mutex
+	)
+	for _, b := range bugs {
+		ids = append(ids, b.ID)
+	}
+	err := bugzilla.ForEach(ids, workers, func(id int) error {
+		description, err := bugzilla.GetDescription(client, id)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		descriptions[id] = description
+		mu.Unlock()
+		return nil
+	})
+	docs := make([]Doc, 0, len(bugs))
+	for _, b := range bugs {
+		docs = append(docs, Doc{ID: b.ID, Summary: b.Summary, Description: descriptions[b.ID]})
+	}
+	return docs, err
+}
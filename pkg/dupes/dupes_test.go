@@ -0,0 +1,62 @@
+package dupes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+)
+
+var testDocs = []Doc{
+	{ID: 1, Summary: "catalog-operator crashes on startup", Description: `Description of problem:
+catalog-operator pod is in CrashLoopBackOff.
+panic: runtime error: invalid memory address or nil pointer dereference
+	at pkg/controller/registry/reconciler.go:120`},
+	{ID: 2, Summary: "CrashLoopBackOff for catalog operator after upgrade", Description: `after upgrading to 4.5 the catalog operator restarts:
+panic: runtime error: invalid memory address or nil pointer dereference
+	at pkg/controller/registry/reconciler.go:120`},
+	{ID: 3, Summary: "Subscription stuck in UpgradePending", Description: "The subscription never installs the new CSV because the install plan requires approval."},
+	{ID: 4, Summary: "Install plan needs approval but subscription is automatic", Description: "Automatic subscription is stuck waiting for install plan approval."},
+}
+
+func TestTokenize(t *testing.T) {
+	require.Equal(t, []string{"pull", "quay.io/foo/bar", "v1", "failed", "catalog-operator"},
+		Tokenize("Pull of quay.io/foo/bar:v1 failed (catalog-operator)."))
+}
+
+func TestSimilar(t *testing.T) {
+	idx := NewIndex(testDocs)
+	matches, err := idx.Similar(1, 0.2, 0)
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	require.Equal(t, 2, matches[0].ID)
+	require.True(t, matches[0].Shingle > 0.2, "shared panic message should count: %v", matches[0])
+
+	matches, err = idx.Similar(3, 0, 2)
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	require.Equal(t, 4, matches[0].ID)
+
+	_, err = idx.Similar(5, 0, 0)
+	require.Error(t, err)
+}
+
+func TestPairs(t *testing.T) {
+	pairs := NewIndex(testDocs).Pairs(0.2)
+	require.Len(t, pairs, 2)
+	require.Equal(t, 1, pairs[0].ID)
+	require.Equal(t, 2, pairs[0].Duplicate.ID)
+	require.Equal(t, 3, pairs[1].ID)
+	require.Equal(t, 4, pairs[1].Duplicate.ID)
+	require.True(t, pairs[0].Duplicate.Score > pairs[1].Duplicate.Score)
+}
+
+func TestFetch(t *testing.T) {
+	client := &bugzilla.Fake{BugComments: map[int][]bugzilla.Comment{
+		1: {{Count: 0, Text: "it broke"}, {Count: 1, Text: "still broken"}},
+	}}
+	docs, err := Fetch(client, []*bugzilla.Bug{{ID: 1, Summary: "broken"}, {ID: 2, Summary: "quiet"}}, 2)
+	require.NoError(t, err)
+	require.Equal(t, []Doc{{ID: 1, Summary: "broken", Description: "it broke"}, {ID: 2, Summary: "quiet"}}, docs)
+}
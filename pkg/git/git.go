@@ -0,0 +1,128 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Repo runs git commands in a local checkout.
+type Repo struct {
+	logger *logrus.Entry
+	dir    string
+}
+
+// NewRepo returns a Repo for the checkout at dir.
+func NewRepo(dir string) (*Repo, error) {
+	r := &Repo{
+		logger: logrus.WithFields(logrus.Fields{"client": "git", "dir": dir}),
+		dir:    dir,
+	}
+	if _, err := r.run("rev-parse", "--git-dir"); err != nil {
+		return nil, fmt.Errorf("%s is not a git checkout: %v", dir, err)
+	}
+	return r, nil
+}
+
+// Dir is the directory of the checkout.
+func (r *Repo) Dir() string {
+	return r.dir
+}
+
+func (r *Repo) run(args ...string) (string, error) {
+	r.logger.WithField("args", args).Debug("Running git.")
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.String(), fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// Fetch fetches a branch from a remote.
+func (r *Repo) Fetch(remote, branch string) error {
+	_, err := r.run("fetch", remote, branch)
+	return err
+}
+
+// FetchPull fetches the head of a GitHub pull request from a remote, so that
+// its commits are available even if it was squashed or rebased when merged.
+func (r *Repo) FetchPull(remote string, num int) error {
+	_, err := r.run("fetch", remote, fmt.Sprintf("refs/pull/%d/head", num))
+	return err
+}
+
+// HasCommit reports whether the commit is available locally.
+func (r *Repo) HasCommit(sha string) bool {
+	_, err := r.run("cat-file", "-e", sha+"^{commit}")
+	return err == nil
+}
+
+// CheckoutNewBranch creates branch at start and checks it out. It refuses to
+// if the checkout has uncommitted changes or the branch already exists, rather
+// than lose either.
+func (r *Repo) CheckoutNewBranch(branch, start string) error {
+	changes, err := r.run("status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return err
+	}
+	if changes != "" {
+		return fmt.Errorf("%s has uncommitted changes, commit or stash them first", r.dir)
+	}
+	if _, err := r.run("rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err == nil {
+		return fmt.Errorf("branch %s already exists, delete it to pick again", branch)
+	}
+	_, err = r.run("checkout", "-b", branch, start)
+	return err
+}
+
+// ConflictError is returned when a cherry-pick doesn't apply cleanly.
+type ConflictError struct {
+	// SHA is the commit that failed to apply.
+	SHA string
+	// Files are the paths with conflicts.
+	Files []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("cherry-pick of %s conflicts in %s", e.SHA, strings.Join(e.Files, ", "))
+}
+
+// IsConflict reports whether err is a cherry-pick conflict.
+func IsConflict(err error) bool {
+	_, ok := err.(*ConflictError)
+	return ok
+}
+
+// CherryPick applies commits in order onto the current branch, recording the
+// original commit in each message. If a commit conflicts the cherry-pick is
+// aborted, leaving the branch with the commits that applied, and a
+// *ConflictError is returned.
+func (r *Repo) CherryPick(shas ...string) error {
+	for _, sha := range shas {
+		if _, err := r.run("cherry-pick", "-x", sha); err != nil {
+			conflicts, diffErr := r.run("diff", "--name-only", "--diff-filter=U")
+			if _, abortErr := r.run("cherry-pick", "--abort"); abortErr != nil {
+				r.logger.WithError(abortErr).Warn("could not abort cherry-pick")
+			}
+			if diffErr != nil || conflicts == "" {
+				return err
+			}
+			return &ConflictError{SHA: sha, Files: strings.Split(conflicts, "\n")}
+		}
+	}
+	return nil
+}
+
+// Push pushes a local branch to a remote, replacing the remote branch only if
+// it hasn't changed since it was last fetched.
+func (r *Repo) Push(remote, branch string) error {
+	_, err := r.run("push", "--force-with-lease", remote, branch+":"+branch)
+	return err
+}
@@ -0,0 +1,297 @@
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultEndpoint is the public GitHub API.
+const DefaultEndpoint = "https://api.github.com"
+
+const (
+	// perPage is the page size requested from list endpoints.
+	perPage = 100
+	// maxRateLimitWait is the longest we'll sleep waiting for a rate limit to
+	// reset before giving up on a request.
+	maxRateLimitWait = 5 * time.Minute
+	// maxRetries bounds how many times a rate limited request is retried.
+	maxRetries = 3
+)
+
+type Client interface {
+	Endpoint() string
+	GetPullRequest(org, repo string, num int) (*PullRequest, error)
+	GetCombinedStatus(org, repo, ref string) (*CombinedStatus, error)
+	ListPullRequests(org, repo string, since time.Time) ([]PullRequest, error)
+	ListPullRequestCommits(org, repo string, num int) ([]Commit, error)
+	CreatePullRequest(org, repo string, pr NewPullRequest) (*PullRequest, error)
+}
+
+// NewClient returns a client for the GitHub API at endpoint. Requests are
+// anonymous if getToken returns nothing, which GitHub heavily rate limits.
+func NewClient(getToken func() []byte, endpoint string) Client {
+	return &client{
+		logger:   logrus.WithField("client", "github"),
+		client:   &http.Client{},
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		getToken: getToken,
+		sleep:    time.Sleep,
+		now:      time.Now,
+	}
+}
+
+type client struct {
+	logger   *logrus.Entry
+	client   *http.Client
+	endpoint string
+	getToken func() []byte
+
+	// injected for tests
+	sleep func(time.Duration)
+	now   func() time.Time
+}
+
+// the client is a Client impl
+var _ Client = &client{}
+
+func (c *client) Endpoint() string {
+	return c.endpoint
+}
+
+// request sends a request to url, waiting out rate limits, and returns the
+// body along with the URL of the next page, if there is one.
+func (c *client) request(method, url string, body []byte, logger *logrus.Entry) ([]byte, string, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, "", err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Accept", "application/vnd.github.v3+json")
+		if token := c.getToken(); len(token) > 0 {
+			req.Header.Set("Authorization", "token "+string(token))
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, "", &requestError{statusCode: -1, message: err.Error()}
+		}
+		logger.WithFields(logrus.Fields{
+			"response":  resp.StatusCode,
+			"remaining": resp.Header.Get("X-RateLimit-Remaining"),
+		}).Debug("Got response from GitHub.")
+		raw, err := ioutil.ReadAll(resp.Body)
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			logger.WithError(closeErr).Warn("could not close response body")
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("could not read response body: %v", err)
+		}
+
+		if wait, limited := c.rateLimitWait(resp); limited {
+			if attempt >= maxRetries || wait > maxRateLimitWait {
+				return nil, "", &requestError{statusCode: resp.StatusCode, message: fmt.Sprintf("rate limited by github, resets in %s", wait.Round(time.Second))}
+			}
+			logger.WithField("wait", wait).Info("Rate limited by GitHub, waiting for reset.")
+			c.sleep(wait)
+			continue
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return nil, "", &requestError{statusCode: resp.StatusCode, message: fmt.Sprintf("response code %d not %d: %s", resp.StatusCode, http.StatusOK, githubMessage(raw))}
+		}
+		return raw, nextPage(resp.Header.Get("Link")), nil
+	}
+}
+
+// rateLimitWait reports whether a response was rate limited and how long to
+// wait before retrying.
+// https://developer.github.com/v3/#rate-limiting
+func (c *client) rateLimitWait(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	// secondary rate limits say how long to back off directly
+	if retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return time.Duration(retryAfter) * time.Second, true
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return 0, false
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	wait := time.Unix(reset, 0).Sub(c.now())
+	if wait < 0 {
+		wait = 0
+	}
+	// the reset time has one second resolution
+	return wait + time.Second, true
+}
+
+var nextLink = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// nextPage extracts the next page URL from a Link header.
+// https://developer.github.com/v3/#pagination
+func nextPage(link string) string {
+	if m := nextLink.FindStringSubmatch(link); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+func githubMessage(raw []byte) string {
+	var parsed struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(raw, &parsed); err != nil || parsed.Message == "" {
+		return string(raw)
+	}
+	return parsed.Message
+}
+
+// get decodes the response for a single object at path into out.
+func (c *client) get(path string, logger *logrus.Entry, out interface{}) error {
+	raw, _, err := c.request(http.MethodGet, c.endpoint+path, nil, logger)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("could not unmarshal response body: %v", err)
+	}
+	return nil
+}
+
+// list follows pagination of a list endpoint, calling page with each page.
+// page returns false to stop early.
+func (c *client) list(path string, logger *logrus.Entry, page func(raw []byte) (bool, error)) error {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	url := fmt.Sprintf("%s%s%sper_page=%d", c.endpoint, path, sep, perPage)
+	for url != "" {
+		raw, next, err := c.request(http.MethodGet, url, nil, logger)
+		if err != nil {
+			return err
+		}
+		more, err := page(raw)
+		if err != nil {
+			return fmt.Errorf("could not unmarshal response body: %v", err)
+		}
+		if !more {
+			return nil
+		}
+		url = next
+	}
+	return nil
+}
+
+// GetPullRequest retrieves a pull request.
+// https://developer.github.com/v3/pulls/#get-a-single-pull-request
+func (c *client) GetPullRequest(org, repo string, num int) (*PullRequest, error) {
+	logger := c.logger.WithFields(logrus.Fields{"method": "GetPullRequest", "pr": fmt.Sprintf("%s/%s#%d", org, repo, num)})
+	var pr PullRequest
+	if err := c.get(fmt.Sprintf("/repos/%s/%s/pulls/%d", org, repo, num), logger, &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// GetCombinedStatus retrieves the overall CI state of a commit or branch.
+// https://developer.github.com/v3/repos/statuses/#get-the-combined-status-for-a-specific-ref
+func (c *client) GetCombinedStatus(org, repo, ref string) (*CombinedStatus, error) {
+	logger := c.logger.WithFields(logrus.Fields{"method": "GetCombinedStatus", "repo": org + "/" + repo, "ref": ref})
+	var combined *CombinedStatus
+	err := c.list(fmt.Sprintf("/repos/%s/%s/commits/%s/status", org, repo, ref), logger, func(raw []byte) (bool, error) {
+		var page CombinedStatus
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return false, err
+		}
+		if combined == nil {
+			combined = &page
+			return len(page.Statuses) > 0, nil
+		}
+		combined.Statuses = append(combined.Statuses, page.Statuses...)
+		return len(page.Statuses) > 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return combined, nil
+}
+
+// ListPullRequests lists the pull requests in a repo, open or closed, that
+// have been updated since the given time.
+// https://developer.github.com/v3/pulls/#list-pull-requests
+func (c *client) ListPullRequests(org, repo string, since time.Time) ([]PullRequest, error) {
+	logger := c.logger.WithFields(logrus.Fields{"method": "ListPullRequests", "repo": org + "/" + repo})
+	var prs []PullRequest
+	// the pulls API has no since filter, so walk them newest first and stop
+	// at the first one that's too old
+	err := c.list(fmt.Sprintf("/repos/%s/%s/pulls?state=all&sort=updated&direction=desc", org, repo), logger, func(raw []byte) (bool, error) {
+		var page []PullRequest
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return false, err
+		}
+		for _, pr := range page {
+			if pr.UpdatedAt.Before(since) {
+				return false, nil
+			}
+			prs = append(prs, pr)
+		}
+		return len(page) > 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prs, nil
+}
+
+// ListPullRequestCommits lists the commits in a pull request.
+// https://developer.github.com/v3/pulls/#list-commits-on-a-pull-request
+func (c *client) ListPullRequestCommits(org, repo string, num int) ([]Commit, error) {
+	logger := c.logger.WithFields(logrus.Fields{"method": "ListPullRequestCommits", "pr": fmt.Sprintf("%s/%s#%d", org, repo, num)})
+	var commits []Commit
+	err := c.list(fmt.Sprintf("/repos/%s/%s/pulls/%d/commits", org, repo, num), logger, func(raw []byte) (bool, error) {
+		var page []Commit
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return false, err
+		}
+		commits = append(commits, page...)
+		return len(page) > 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// CreatePullRequest opens a pull request.
+// https://developer.github.com/v3/pulls/#create-a-pull-request
+func (c *client) CreatePullRequest(org, repo string, pr NewPullRequest) (*PullRequest, error) {
+	logger := c.logger.WithFields(logrus.Fields{"method": "CreatePullRequest", "repo": org + "/" + repo, "head": pr.Head})
+	body, err := json.Marshal(pr)
+	if err != nil {
+		return nil, err
+	}
+	raw, _, err := c.request(http.MethodPost, fmt.Sprintf("%s/repos/%s/%s/pulls", c.endpoint, org, repo), body, logger)
+	if err != nil {
+		return nil, err
+	}
+	var created PullRequest
+	if err := json.Unmarshal(raw, &created); err != nil {
+		return nil, fmt.Errorf("could not unmarshal response body: %v", err)
+	}
+	return &created, nil
+}
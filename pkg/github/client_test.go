@@ -0,0 +1,145 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(handler http.HandlerFunc) (*client, *[]time.Duration, *httptest.Server) {
+	server := httptest.NewServer(handler)
+	c := NewClient(func() []byte { return []byte("secret") }, server.URL).(*client)
+	var slept []time.Duration
+	c.sleep = func(d time.Duration) { slept = append(slept, d) }
+	c.now = func() time.Time { return time.Unix(1000, 0) }
+	return c, &slept, server
+}
+
+func TestGetPullRequest(t *testing.T) {
+	c, _, server := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "token secret", r.Header.Get("Authorization"))
+		require.Equal(t, "/repos/operator-framework/operator-lifecycle-manager/pulls/1234", r.URL.Path)
+		fmt.Fprint(w, `{
+			"number": 1234,
+			"title": "Bug 1812345: fix catalog pod",
+			"state": "closed",
+			"merged": true,
+			"merged_at": "2020-03-10T12:00:00Z",
+			"base": {"ref": "release-4.4"},
+			"head": {"ref": "fix", "sha": "abc123"},
+			"labels": [{"name": "lgtm"}, {"name": "cherry-pick-approved"}]
+		}`)
+	})
+	defer server.Close()
+
+	pr, err := c.GetPullRequest("operator-framework", "operator-lifecycle-manager", 1234)
+	require.NoError(t, err)
+	require.True(t, pr.Merged)
+	require.Equal(t, "release-4.4", pr.Base.Ref)
+	require.Equal(t, time.Date(2020, 3, 10, 12, 0, 0, 0, time.UTC), *pr.MergedAt)
+	require.True(t, pr.HasLabel("cherry-pick-approved"))
+	require.False(t, pr.HasLabel("approved"))
+}
+
+func TestGetPullRequestNotFound(t *testing.T) {
+	c, _, server := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Not Found"}`)
+	})
+	defer server.Close()
+
+	_, err := c.GetPullRequest("org", "repo", 1)
+	require.True(t, IsNotFound(err))
+}
+
+func TestGetCombinedStatusPaginates(t *testing.T) {
+	var endpoint string
+	c, _, server := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		require.Equal(t, "100", r.URL.Query().Get("per_page"))
+		if page < 2 {
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=%d&per_page=100>; rel="next", <%s%s?page=2&per_page=100>; rel="last"`, endpoint, r.URL.Path, page+1, endpoint, r.URL.Path))
+		}
+		fmt.Fprintf(w, `{"state": "pending", "sha": "abc123", "statuses": [{"context": "ci/prow/e2e-%d", "state": "pending"}]}`, page)
+	})
+	defer server.Close()
+	endpoint = c.endpoint
+
+	status, err := c.GetCombinedStatus("org", "repo", "abc123")
+	require.NoError(t, err)
+	require.Equal(t, StatusPending, status.State)
+	require.Len(t, status.Statuses, 3)
+	require.Equal(t, "ci/prow/e2e-2", status.Statuses[2].Context)
+}
+
+func TestRateLimitWaitsForReset(t *testing.T) {
+	calls := 0
+	c, slept, server := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", "1030")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, `{"number": 1}`)
+	})
+	defer server.Close()
+
+	pr, err := c.GetPullRequest("org", "repo", 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, pr.Number)
+	require.Equal(t, []time.Duration{31 * time.Second}, *slept)
+}
+
+func TestRateLimitGivesUpOnLongWaits(t *testing.T) {
+	c, slept, server := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", "4600")
+		w.WriteHeader(http.StatusForbidden)
+	})
+	defer server.Close()
+
+	_, err := c.GetPullRequest("org", "repo", 1)
+	require.Error(t, err)
+	require.Empty(t, *slept)
+}
+
+func TestForbiddenWithoutRateLimit(t *testing.T) {
+	c, slept, server := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "4000")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message": "Resource not accessible by integration"}`)
+	})
+	defer server.Close()
+
+	_, err := c.GetPullRequest("org", "repo", 1)
+	require.EqualError(t, err, "response code 403 not 200: Resource not accessible by integration")
+	require.Empty(t, *slept)
+}
+
+func TestListPullRequestsStopsAtSince(t *testing.T) {
+	pages := 0
+	c, _, server := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		pages++
+		require.Equal(t, "all", r.URL.Query().Get("state"))
+		require.Equal(t, "updated", r.URL.Query().Get("sort"))
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=2>; rel="next"`, "http://"+r.Host, r.URL.Path))
+		fmt.Fprint(w, `[
+			{"number": 3, "updated_at": "2020-03-10T00:00:00Z"},
+			{"number": 2, "updated_at": "2020-03-05T00:00:00Z"},
+			{"number": 1, "updated_at": "2020-02-01T00:00:00Z"}
+		]`)
+	})
+	defer server.Close()
+
+	prs, err := c.ListPullRequests("org", "repo", time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, prs, 2)
+	require.Equal(t, 1, pages)
+}
@@ -0,0 +1,22 @@
+package github
+
+import (
+	"net/http"
+)
+
+type requestError struct {
+	statusCode int
+	message    string
+}
+
+func (e requestError) Error() string {
+	return e.message
+}
+
+func IsNotFound(err error) bool {
+	reqError, ok := err.(*requestError)
+	if !ok {
+		return false
+	}
+	return reqError.statusCode == http.StatusNotFound
+}
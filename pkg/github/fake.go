@@ -0,0 +1,108 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Fake is a fake GitHub client with injectable fields, safe for concurrent use.
+type Fake struct {
+	EndpointString string
+	// PullRequests are the pull requests of each repo, keyed by org/repo.
+	PullRequests map[string][]PullRequest
+	// Commits are the commits of each pull request, keyed by org/repo#num.
+	Commits map[string][]Commit
+	// Statuses are the combined statuses of each ref, keyed by org/repo@ref.
+	Statuses map[string]*CombinedStatus
+	// Err is returned from every call if set.
+	Err error
+	// Created records every pull request opened, in order.
+	Created []NewPullRequest
+
+	mu sync.Mutex
+}
+
+// the Fake is a Client impl
+var _ Client = &Fake{}
+
+func (c *Fake) Endpoint() string {
+	return c.EndpointString
+}
+
+func (c *Fake) GetPullRequest(org, repo string, num int) (*PullRequest, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	for _, pr := range c.PullRequests[org+"/"+repo] {
+		if pr.Number == num {
+			return &pr, nil
+		}
+	}
+	return nil, &requestError{statusCode: http.StatusNotFound, message: "pull request not registered in the fake"}
+}
+
+func (c *Fake) GetCombinedStatus(org, repo, ref string) (*CombinedStatus, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	if status, ok := c.Statuses[fmt.Sprintf("%s/%s@%s", org, repo, ref)]; ok {
+		return status, nil
+	}
+	return &CombinedStatus{}, nil
+}
+
+// ListPullRequests returns the pull requests of a repo updated since the
+// given time.
+func (c *Fake) ListPullRequests(org, repo string, since time.Time) ([]PullRequest, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	var prs []PullRequest
+	for _, pr := range c.PullRequests[org+"/"+repo] {
+		if !pr.UpdatedAt.Before(since) {
+			prs = append(prs, pr)
+		}
+	}
+	return prs, nil
+}
+
+func (c *Fake) ListPullRequestCommits(org, repo string, num int) ([]Commit, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	return c.Commits[fmt.Sprintf("%s/%s#%d", org, repo, num)], nil
+}
+
+func (c *Fake) CreatePullRequest(org, repo string, pr NewPullRequest) (*PullRequest, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	c.Created = append(c.Created, pr)
+	num := len(c.PullRequests[org+"/"+repo]) + 1
+	created := PullRequest{
+		Number:  num,
+		Title:   pr.Title,
+		Body:    pr.Body,
+		State:   "open",
+		Base:    Ref{Ref: pr.Base},
+		Head:    Ref{Ref: pr.Head},
+		HTMLURL: fmt.Sprintf("https://github.com/%s/%s/pull/%d", org, repo, num),
+	}
+	if c.PullRequests == nil {
+		c.PullRequests = map[string][]PullRequest{}
+	}
+	c.PullRequests[org+"/"+repo] = append(c.PullRequests[org+"/"+repo], created)
+	return &created, nil
+}
@@ -0,0 +1,122 @@
+package github
+
+import (
+	"time"
+)
+
+// PullRequest is a GitHub pull request. See API documentation at:
+// https://developer.github.com/v3/pulls/#get-a-single-pull-request
+type PullRequest struct {
+	// Number is the number of the pull request within its repo.
+	Number int `json:"number"`
+	// Title is the title of the pull request.
+	Title string `json:"title"`
+	// Body is the description of the pull request.
+	Body string `json:"body,omitempty"`
+	// State is "open" or "closed". Merged pull requests are closed.
+	State string `json:"state"`
+	// Merged is true if the pull request has been merged.
+	Merged bool `json:"merged"`
+	// MergedAt is when the pull request was merged, if it was.
+	MergedAt *time.Time `json:"merged_at,omitempty"`
+	// MergeCommitSHA is the commit the pull request was merged as.
+	MergeCommitSHA string `json:"merge_commit_sha,omitempty"`
+	// CreatedAt is when the pull request was opened.
+	CreatedAt time.Time `json:"created_at"`
+	// UpdatedAt is when the pull request last changed.
+	UpdatedAt time.Time `json:"updated_at"`
+	// Base is the branch the pull request merges into.
+	Base Ref `json:"base"`
+	// Head is the branch the pull request merges from.
+	Head Ref `json:"head"`
+	// Labels are the labels set on the pull request.
+	Labels []Label `json:"labels,omitempty"`
+	// User is the author of the pull request.
+	User User `json:"user"`
+	// HTMLURL is where the pull request can be viewed in a browser.
+	HTMLURL string `json:"html_url"`
+}
+
+// NewPullRequest holds the fields used to open a pull request.
+type NewPullRequest struct {
+	// Title is the title of the pull request.
+	Title string `json:"title"`
+	// Body is the description of the pull request.
+	Body string `json:"body,omitempty"`
+	// Head is the branch to merge from, as owner:branch for forks.
+	Head string `json:"head"`
+	// Base is the branch to merge into.
+	Base string `json:"base"`
+}
+
+// Ref is one side of a pull request.
+type Ref struct {
+	// Ref is the branch name.
+	Ref string `json:"ref"`
+	// SHA is the commit the branch pointed at.
+	SHA string `json:"sha"`
+}
+
+// Label is a label on an issue or pull request.
+type Label struct {
+	Name string `json:"name"`
+}
+
+// User is a GitHub user.
+type User struct {
+	Login string `json:"login"`
+}
+
+// Commit is a commit in a pull request. See API documentation at:
+// https://developer.github.com/v3/pulls/#list-commits-on-a-pull-request
+type Commit struct {
+	// SHA is the ID of the commit.
+	SHA string `json:"sha"`
+	// Commit holds the git data of the commit.
+	Commit GitCommit `json:"commit"`
+	// Parents are the commits this commit follows.
+	Parents []Ref `json:"parents,omitempty"`
+}
+
+// GitCommit is the git data of a Commit.
+type GitCommit struct {
+	// Message is the full commit message.
+	Message string `json:"message"`
+}
+
+// Status values of commit statuses
+const (
+	StatusSuccess = "success"
+	StatusPending = "pending"
+	StatusFailure = "failure"
+	StatusError   = "error"
+)
+
+// CombinedStatus is the overall CI state of a commit. See API documentation at:
+// https://developer.github.com/v3/repos/statuses/#get-the-combined-status-for-a-specific-ref
+type CombinedStatus struct {
+	// State is one of success, pending, failure or error.
+	State string `json:"state"`
+	// SHA is the commit the status is for.
+	SHA string `json:"sha"`
+	// Statuses are the latest status of each context.
+	Statuses []Status `json:"statuses"`
+}
+
+// Status is the state of a single CI context on a commit.
+type Status struct {
+	State       string `json:"state"`
+	Context     string `json:"context"`
+	Description string `json:"description,omitempty"`
+	TargetURL   string `json:"target_url,omitempty"`
+}
+
+// HasLabel reports whether the pull request has the named label.
+func (pr *PullRequest) HasLabel(name string) bool {
+	for _, l := range pr.Labels {
+		if l.Name == name {
+			return true
+		}
+	}
+	return false
+}
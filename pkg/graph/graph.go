@@ -0,0 +1,182 @@
+package graph
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+)
+
+// Kinds of relationship between bugs. Edges point from the bug that has to
+// be dealt with first to the bug that follows from it.
+const (
+	// KindBlocks is an edge from a bug to a bug it blocks.
+	KindBlocks = "blocks"
+	// KindDuplicate is an edge from a bug to a duplicate of it.
+	KindDuplicate = "duplicate"
+	// KindClone is an edge from a bug to a clone of it, e.g. a backport.
+	KindClone = "clone"
+)
+
+// Edge is a relationship between two bugs.
+type Edge struct {
+	From, To int
+	Kind     string
+}
+
+// Graph is the neighbourhood of a bug.
+type Graph struct {
+	Root  int
+	Nodes map[int]*bugzilla.Bug
+	// Missing holds bugs that are referenced but couldn't be fetched, e.g.
+	// because they are private.
+	Missing map[int]error
+	Edges   []Edge
+}
+
+// Crawl fetches root and the bugs related to it, following relationships up
+// to depth hops away. Duplicates and clones only record the bug they
+// duplicate or were cloned from, so they are searched for. Each hop is
+// fetched concurrently with up to workers requests in flight. Bugs are only
+// fetched once, so cycles end the crawl.
+func Crawl(client bugzilla.Client, root, depth, workers int) *Graph {
+	g := &Graph{Root: root, Nodes: map[int]*bugzilla.Bug{}, Missing: map[int]error{}}
+	seen := map[int]bool{root: true}
+	frontier := []int{root}
+	for hop := 0; len(frontier) > 0; hop++ {
+		fetched := fetchAll(client, frontier, workers, hop < depth)
+		var next []int
+		for _, id := range frontier {
+			result := fetched[id]
+			if result.err != nil {
+				logrus.WithError(result.err).Warnf("could not get bug %d", id)
+				g.Missing[id] = result.err
+				continue
+			}
+			g.Nodes[id] = result.bug
+			if hop == depth {
+				continue
+			}
+			for _, neighbour := range append(neighbours(result.bug), result.copies...) {
+				if !seen[neighbour] {
+					seen[neighbour] = true
+					next = append(next, neighbour)
+				}
+			}
+		}
+		sort.Ints(next)
+		frontier = next
+	}
+	g.Edges = edges(g.Nodes)
+	return g
+}
+
+type fetchResult struct {
+	bug *bugzilla.Bug
+	// copies are the duplicates and clones of the bug
+	copies []int
+	err    error
+}
+
+// fetchAll gets the bugs with ids and, if copies is set, searches for their
+// duplicates and clones.
+func fetchAll(client bugzilla.Client, ids []int, workers int, copies bool) map[int]fetchResult {
+	var (
+		results = map[int]fetchResult{}
+		mu      sync.Mutex
+	)
+	bugzilla.ForEach(ids, workers, func(id int) error {
+		result := fetchResult{}
+		result.bug, result.err = client.GetBug(id)
+		if result.err == nil && copies {
+			found, err := client.SearchBugs(copiesQuery(id))
+			if err != nil {
+				logrus.WithError(err).Warnf("could not search for duplicates and clones of bug %d", id)
+			}
+			for _, b := range found {
+				result.copies = append(result.copies, b.ID)
+			}
+		}
+		mu.Lock()
+		results[id] = result
+		mu.Unlock()
+		return nil
+	})
+	return results
+}
+
+// copiesQuery searches for the duplicates and clones of the bug with id.
+func copiesQuery(id int) string {
+	return url.Values{
+		"j_top": {"OR"},
+		"f1":    {"dupe_of"},
+		"o1":    {"equals"},
+		"v1":    {strconv.Itoa(id)},
+		"f2":    {"cf_clone_of"},
+		"o2":    {"equals"},
+		"v2":    {strconv.Itoa(id)},
+	}.Encode()
+}
+
+// neighbours lists every bug a bug refers to.
+func neighbours(bug *bugzilla.Bug) []int {
+	ids := append(append([]int{}, bug.Blocks...), bug.DependsOn...)
+	if bug.DupeOf != 0 {
+		ids = append(ids, bug.DupeOf)
+	}
+	if clone := bug.CloneOf(); clone != 0 {
+		ids = append(ids, clone)
+	}
+	return ids
+}
+
+// edges collects the relationships between the fetched bugs. Both sides of a
+// relationship usually record it, so edges are normalized and deduplicated.
+func edges(nodes map[int]*bugzilla.Bug) []Edge {
+	set := map[Edge]bool{}
+	add := func(e Edge) {
+		if nodes[e.From] != nil && nodes[e.To] != nil {
+			set[e] = true
+		}
+	}
+	for id, bug := range nodes {
+		for _, blocked := range bug.Blocks {
+			add(Edge{From: id, To: blocked, Kind: KindBlocks})
+		}
+		for _, blocker := range bug.DependsOn {
+			add(Edge{From: blocker, To: id, Kind: KindBlocks})
+		}
+		if bug.DupeOf != 0 {
+			add(Edge{From: bug.DupeOf, To: id, Kind: KindDuplicate})
+		}
+		if clone := bug.CloneOf(); clone != 0 {
+			add(Edge{From: clone, To: id, Kind: KindClone})
+		}
+	}
+	// a clone usually also blocks or depends on its original, the clone edge says more
+	for e := range set {
+		if e.Kind != KindClone {
+			continue
+		}
+		delete(set, Edge{From: e.From, To: e.To, Kind: KindBlocks})
+		delete(set, Edge{From: e.To, To: e.From, Kind: KindBlocks})
+	}
+	var list []Edge
+	for e := range set {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].From != list[j].From {
+			return list[i].From < list[j].From
+		}
+		if list[i].To != list[j].To {
+			return list[i].To < list[j].To
+		}
+		return list[i].Kind < list[j].Kind
+	})
+	return list
+}
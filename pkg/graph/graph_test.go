@@ -0,0 +1,77 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+)
+
+func TestCrawl(t *testing.T) {
+	client := &bugzilla.Fake{
+		Bugs: map[int]bugzilla.Bug{
+			// 1 is the master bug, cloned to 2 for 4.4 and 3 for 4.3
+			1: {ID: 1, Status: "MODIFIED", TargetRelease: []string{"4.5.0"}, Summary: "catalog pod crashes", Blocks: []int{2}},
+			2: {ID: 2, Status: "POST", TargetRelease: []string{"4.4.z"}, DependsOn: []int{1}, Blocks: []int{3},
+				CustomFields: map[string]json.RawMessage{"cf_clone_of": json.RawMessage(`"1"`)}},
+			3: {ID: 3, Status: "NEW", TargetRelease: []string{"4.3.z"}, DependsOn: []int{2}, Blocks: []int{4},
+				CustomFields: map[string]json.RawMessage{"cf_clone_of": json.RawMessage(`"2"`)}},
+			// 4 blocks 3 back, making a cycle
+			4: {ID: 4, Status: "NEW", DependsOn: []int{3}, Blocks: []int{3}},
+			// 5 is a dependency of 1 we can't see
+			// 6 is a duplicate of 1, which only 6 records
+			6: {ID: 6, Status: "CLOSED", DupeOf: 1},
+		},
+		BugErrors: map[int]bool{5: true},
+		SearchResults: map[string][]int{
+			copiesQuery(1): {2, 6},
+			copiesQuery(2): {3},
+			copiesQuery(3): {},
+			copiesQuery(4): {},
+			copiesQuery(6): {},
+		},
+	}
+	b := client.Bugs[1]
+	b.DependsOn = []int{5}
+	client.Bugs[1] = b
+
+	g := Crawl(client, 1, 1, 4)
+	require.Len(t, g.Nodes, 3)
+	require.Contains(t, g.Missing, 5)
+	require.Equal(t, []Edge{
+		{From: 1, To: 2, Kind: KindClone},
+		{From: 1, To: 6, Kind: KindDuplicate},
+	}, g.Edges)
+
+	g = Crawl(client, 1, 2, 4)
+	require.Len(t, g.Nodes, 4)
+	require.Equal(t, []Edge{
+		{From: 1, To: 2, Kind: KindClone},
+		{From: 1, To: 6, Kind: KindDuplicate},
+		{From: 2, To: 3, Kind: KindClone},
+	}, g.Edges)
+
+	g = Crawl(client, 1, 10, 4)
+	require.Len(t, g.Nodes, 5)
+	require.Equal(t, []Edge{
+		{From: 1, To: 2, Kind: KindClone},
+		{From: 1, To: 6, Kind: KindDuplicate},
+		{From: 2, To: 3, Kind: KindClone},
+		{From: 3, To: 4, Kind: KindBlocks},
+		{From: 4, To: 3, Kind: KindBlocks},
+	}, g.Edges)
+
+	var tree bytes.Buffer
+	require.NoError(t, g.Tree(&tree, false))
+	require.Equal(t, `1 [MODIFIED 4.5.0] catalog pod crashes
+├── clone 2 [POST 4.4.z] 
+│   └── clone 3 [NEW 4.3.z] 
+│       ├── blocks 4 [NEW ---] 
+│       │   └── blocks 3 (see above)
+│       └── depends on 4 (see above)
+└── duplicate 6 [CLOSED ---] 
+`, tree.String())
+}
@@ -0,0 +1,204 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/view"
+)
+
+// statusColors are the fill colors of bugs in each status, light to dark as
+// a bug gets closer to done.
+var statusColors = map[string]string{
+	"NEW":             "#f4cccc",
+	"ASSIGNED":        "#fce5cd",
+	"ON_DEV":          "#fce5cd",
+	"POST":            "#fff2cc",
+	"MODIFIED":        "#d9ead3",
+	"ON_QA":           "#cfe2f3",
+	"VERIFIED":        "#b6d7a8",
+	"RELEASE_PENDING": "#b6d7a8",
+	"CLOSED":          "#d9d9d9",
+}
+
+// ansiStatusColors color the status in the ascii tree.
+var ansiStatusColors = map[string]string{
+	"NEW":             "31",
+	"ASSIGNED":        "33",
+	"ON_DEV":          "33",
+	"POST":            "93",
+	"MODIFIED":        "36",
+	"ON_QA":           "34",
+	"VERIFIED":        "32",
+	"RELEASE_PENDING": "32",
+	"CLOSED":          "90",
+}
+
+// releasePalette outlines bugs by target release, assigned in release order.
+var releasePalette = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd", "#8c564b", "#e377c2", "#17becf"}
+
+// releaseColors assigns each target release in the graph an outline color.
+func (g *Graph) releaseColors() map[string]string {
+	releases := map[string]bool{}
+	for _, bug := range g.Nodes {
+		releases[release(bug)] = true
+	}
+	var sorted []string
+	for r := range releases {
+		sorted = append(sorted, r)
+	}
+	sort.Strings(sorted)
+	colors := map[string]string{}
+	for i, r := range sorted {
+		colors[r] = releasePalette[i%len(releasePalette)]
+	}
+	return colors
+}
+
+func release(bug *bugzilla.Bug) string {
+	if len(bug.TargetRelease) == 0 {
+		return "---"
+	}
+	return strings.Join(bug.TargetRelease, ",")
+}
+
+func (g *Graph) sortedIDs() []int {
+	var ids []int
+	for id := range g.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// Tree writes the graph as an indented tree rooted at the crawled bug. Bugs
+// reachable along several paths are printed in full once and referred to
+// afterwards.
+func (g *Graph) Tree(w io.Writer, color bool) error {
+	adjacent := map[int][]Edge{}
+	for _, e := range g.Edges {
+		adjacent[e.From] = append(adjacent[e.From], e)
+		adjacent[e.To] = append(adjacent[e.To], e)
+	}
+	p := view.NewPrinter(w)
+	label := func(id int) string {
+		bug := g.Nodes[id]
+		status := bug.Status
+		if color {
+			status = fmt.Sprintf("\x1b[%sm%s\x1b[0m", ansiStatusColors[status], status)
+		}
+		return fmt.Sprintf("%d [%s %s] %s", id, status, release(bug), bug.Summary)
+	}
+
+	printed := map[int]bool{}
+	var walk func(id int, via *Edge, prefix string)
+	walk = func(id int, via *Edge, prefix string) {
+		printed[id] = true
+		// don't point back along the edge we arrived by
+		var edges []Edge
+		for _, e := range adjacent[id] {
+			if via == nil || e != *via {
+				edges = append(edges, e)
+			}
+		}
+		for i, e := range edges {
+			branch, indent := "├── ", "│   "
+			if i == len(edges)-1 {
+				branch, indent = "└── ", "    "
+			}
+			other, relation := e.To, e.Kind
+			if e.To == id {
+				other, relation = e.From, inverse(e.Kind)
+			}
+			if printed[other] {
+				p.Printf("%s%s%s %d (see above)\n", prefix, branch, relation, other)
+				continue
+			}
+			p.Printf("%s%s%s %s\n", prefix, branch, relation, label(other))
+			e := e
+			walk(other, &e, prefix+indent)
+		}
+	}
+	if g.Nodes[g.Root] == nil {
+		return fmt.Errorf("could not get bug %d: %v", g.Root, g.Missing[g.Root])
+	}
+	p.Printf("%s\n", label(g.Root))
+	walk(g.Root, nil, "")
+	return p.Err()
+}
+
+// inverse names an edge kind from the point of view of its target.
+func inverse(kind string) string {
+	switch kind {
+	case KindBlocks:
+		return "depends on"
+	case KindDuplicate:
+		return "duplicate of"
+	case KindClone:
+		return "clone of"
+	}
+	return kind
+}
+
+// DOT writes the graph in Graphviz DOT format. Nodes are filled by status
+// and outlined by target release.
+func (g *Graph) DOT(w io.Writer) error {
+	releases := g.releaseColors()
+	p := view.NewPrinter(w)
+	p.Printf("digraph bugs {\n")
+	p.Printf("  node [shape=box, style=\"filled,rounded\", penwidth=2];\n")
+	for _, id := range g.sortedIDs() {
+		bug := g.Nodes[id]
+		extra := ""
+		if id == g.Root {
+			extra = ", peripheries=2"
+		}
+		p.Printf("  b%d [label=%q, fillcolor=%q, color=%q%s];\n", id,
+			fmt.Sprintf("%d\n%s %s\n%s", id, bug.Status, release(bug), truncate(bug.Summary, 40)),
+			fillColor(bug.Status), releases[release(bug)], extra)
+	}
+	for _, e := range g.Edges {
+		p.Printf("  b%d -> b%d [label=%q];\n", e.From, e.To, e.Kind)
+	}
+	p.Printf("}\n")
+	return p.Err()
+}
+
+// Mermaid writes the graph as a Mermaid flowchart. Nodes are filled by
+// status and outlined by target release.
+func (g *Graph) Mermaid(w io.Writer) error {
+	releases := g.releaseColors()
+	p := view.NewPrinter(w)
+	p.Printf("graph TD\n")
+	for _, id := range g.sortedIDs() {
+		bug := g.Nodes[id]
+		summary := strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;").Replace(truncate(bug.Summary, 40))
+		p.Printf("  b%d[\"%d<br/>%s %s<br/>%s\"]\n", id, id, bug.Status, release(bug), summary)
+	}
+	for _, e := range g.Edges {
+		p.Printf("  b%d -->|%s| b%d\n", e.From, e.Kind, e.To)
+	}
+	for _, id := range g.sortedIDs() {
+		bug := g.Nodes[id]
+		p.Printf("  style b%d fill:%s,stroke:%s,stroke-width:2px\n", id, fillColor(bug.Status), releases[release(bug)])
+	}
+	return p.Err()
+}
+
+func fillColor(status string) string {
+	if c, ok := statusColors[status]; ok {
+		return c
+	}
+	return "#ffffff"
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -1,178 +1,327 @@
 package jira
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
-	"runtime"
-
-	"github.com/juju/persistent-cookiejar"
-	"golang.org/x/net/html"
-	"golang.org/x/net/html/atom"
-	"gopkg.in/andygrunwald/go-jira.v1"
+	"strings"
+
+	"github.com/sirupsen/logrus"
 )
 
-// TODO: this is mostly a POC of authenticating to jira and needs a lot of cleanup
+// DefaultEndpoint is the jira instance operator-framework tracks work in.
+const DefaultEndpoint = "https://issues.redhat.com"
 
-// homeDir returns the OS-specific home path as specified in the environment.
-func homeDir() string {
-	if runtime.GOOS == "windows" {
-		return filepath.Join(os.Getenv("HOMEDRIVE"), os.Getenv("HOMEPATH"))
-	}
-	return os.Getenv("HOME")
+// searchPageSize is how many issues are requested per page when searching.
+const searchPageSize = 50
+
+type Client interface {
+	Endpoint() string
+	GetIssue(ctx context.Context, key string) (*Issue, error)
+	SearchIssues(ctx context.Context, jql string) ([]Issue, error)
+	CreateIssue(ctx context.Context, issue *Issue) (*Issue, error)
+	UpdateIssue(ctx context.Context, key string, update IssueUpdate) error
+	AssignIssue(ctx context.Context, key, username string) error
+	TransitionIssue(ctx context.Context, key, status string) error
+	AddComment(ctx context.Context, key, body string) (*Comment, error)
+	GetRemoteLinks(ctx context.Context, key string) ([]RemoteLink, error)
+	AddRemoteLink(ctx context.Context, key string, link RemoteLink) error
+	AddFixVersion(ctx context.Context, key, version string) error
+	RemoveFixVersion(ctx context.Context, key, version string) error
 }
 
-func NewClient(username, password string) (*jira.Client, error) {
-	jar, err := cookiejar.New(&cookiejar.Options{
-		Filename:              filepath.Join(homeDir(), ".olmcop-cookies"),
-		PersistSessionCookies: true,
-	})
+// NewClient returns a jira client for endpoint that reuses the stored session
+// for username, logging in again when it has expired.
+func NewClient(endpoint, username, password string) (Client, error) {
+	session, err := NewSession(endpoint, username, password)
 	if err != nil {
 		return nil, err
 	}
-	client := &http.Client{
-		Jar: jar,
+	if err := session.Ensure(); err != nil {
+		return nil, err
+	}
+	return NewClientWithHTTPClient(session.HTTPClient(nil), endpoint), nil
+}
+
+// NewClientWithHTTPClient returns a jira client that sends requests with
+// httpClient as-is, which must already carry any authentication.
+func NewClientWithHTTPClient(httpClient *http.Client, endpoint string) Client {
+	return &client{
+		logger:   logrus.WithField("client", "jira"),
+		client:   httpClient,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
 	}
+}
+
+type client struct {
+	logger   *logrus.Entry
+	client   *http.Client
+	endpoint string
+}
 
-	jiraclient, err := jira.NewClient(client, "https://issues.redhat.com")
+// the client is a Client impl
+var _ Client = &client{}
+
+func (c *client) newRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, error) {
+	var buf io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		buf = bytes.NewBuffer(raw)
+	}
+	req, err := http.NewRequest(method, c.endpoint+path, buf)
 	if err != nil {
 		return nil, err
 	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req.WithContext(ctx), nil
+}
 
-	_, _, err = jiraclient.Project.Get("OLM")
-	if err == nil {
-		fmt.Println("Already authenticated")
-		return jiraclient, nil
+func (c *client) request(req *http.Request, logger *logrus.Entry) ([]byte, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, &requestError{statusCode: -1, message: err.Error()}
+	}
+	logger.WithField("response", resp.StatusCode).Debug("Got response from Jira.")
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.WithError(err).Warn("could not close response body")
+		}
+	}()
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read response body: %v", err)
 	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, newRequestError(resp.StatusCode, raw)
+	}
+	return raw, nil
+}
 
-	resp, err := client.Get("https://issues.redhat.com/login.jsp?os_destination=%2Fdefault.jsp")
+// do sends the request and decodes the response into out, if given.
+func (c *client) do(req *http.Request, logger *logrus.Entry, out interface{}) error {
+	raw, err := c.request(req, logger)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("could not reach jira (%d)", resp.StatusCode)
+	if out == nil || len(raw) == 0 {
+		return nil
 	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("could not unmarshal response body: %v", err)
+	}
+	return nil
+}
 
-	samlRequest := getSAMLRequest(resp)
-	if samlRequest == "" {
-		return nil, fmt.Errorf("could not get saml request")
+func (c *client) Endpoint() string {
+	return c.endpoint
+}
+
+// GetIssue retrieves an Issue from the server
+// https://docs.atlassian.com/software/jira/docs/api/REST/8.5.1/#api/2/issue-getIssue
+func (c *client) GetIssue(ctx context.Context, key string) (*Issue, error) {
+	logger := c.logger.WithFields(logrus.Fields{"method": "GetIssue", "key": key})
+	req, err := c.newRequest(ctx, http.MethodGet, "/rest/api/2/issue/"+url.PathEscape(key), nil)
+	if err != nil {
+		return nil, err
 	}
-	if err := resp.Body.Close(); err != nil {
+	var issue Issue
+	if err := c.do(req, logger, &issue); err != nil {
 		return nil, err
 	}
+	return &issue, nil
+}
 
-	formData := url.Values{"SAMLRequest": {samlRequest}}
-	resp, err = client.PostForm("https://sso.redhat.com/auth/realms/redhat-external/protocol/saml", formData)
+// SearchIssues returns every issue matching jql, following pagination.
+// https://docs.atlassian.com/software/jira/docs/api/REST/8.5.1/#api/2/search-searchUsingSearchRequest
+func (c *client) SearchIssues(ctx context.Context, jql string) ([]Issue, error) {
+	logger := c.logger.WithFields(logrus.Fields{"method": "SearchIssues", "jql": jql})
+	var issues []Issue
+	for {
+		search := struct {
+			JQL        string `json:"jql"`
+			StartAt    int    `json:"startAt"`
+			MaxResults int    `json:"maxResults"`
+		}{JQL: jql, StartAt: len(issues), MaxResults: searchPageSize}
+		req, err := c.newRequest(ctx, http.MethodPost, "/rest/api/2/search", search)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			StartAt int     `json:"startAt"`
+			Total   int     `json:"total"`
+			Issues  []Issue `json:"issues"`
+		}
+		if err := c.do(req, logger.WithField("startAt", search.StartAt), &page); err != nil {
+			return nil, err
+		}
+		issues = append(issues, page.Issues...)
+		if len(page.Issues) == 0 || len(issues) >= page.Total {
+			return issues, nil
+		}
+	}
+}
+
+// CreateIssue creates a new issue and returns it as stored by the server.
+// https://docs.atlassian.com/software/jira/docs/api/REST/8.5.1/#api/2/issue-createIssue
+func (c *client) CreateIssue(ctx context.Context, issue *Issue) (*Issue, error) {
+	logger := c.logger.WithField("method", "CreateIssue")
+	req, err := c.newRequest(ctx, http.MethodPost, "/rest/api/2/issue", issue)
 	if err != nil {
 		return nil, err
 	}
-	loginURL := getFormURL(resp)
-	if samlRequest == "" {
-		return nil, fmt.Errorf("could not get login url")
+	var created struct {
+		Key string `json:"key"`
 	}
-	if err := resp.Body.Close(); err != nil {
+	if err := c.do(req, logger, &created); err != nil {
 		return nil, err
 	}
+	return c.GetIssue(ctx, created.Key)
+}
 
-	loginData := url.Values{"username": {username}, "password": {password}}
-	resp, err = client.PostForm(loginURL, loginData)
+// UpdateIssue sets and edits fields on an issue.
+// https://docs.atlassian.com/software/jira/docs/api/REST/8.5.1/#api/2/issue-editIssue
+func (c *client) UpdateIssue(ctx context.Context, key string, update IssueUpdate) error {
+	logger := c.logger.WithFields(logrus.Fields{"method": "UpdateIssue", "key": key})
+	req, err := c.newRequest(ctx, http.MethodPut, "/rest/api/2/issue/"+url.PathEscape(key), update)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	return c.do(req, logger, nil)
+}
 
-	// tokenizer misses the input obj for this response for some reason, parse the whole doc
-	doc, err := html.Parse(resp.Body)
-	if err != nil {
-		return nil, err
+// AssignIssue assigns the issue to username, or unassigns it if username is empty.
+// https://docs.atlassian.com/software/jira/docs/api/REST/8.5.1/#api/2/issue-assign
+func (c *client) AssignIssue(ctx context.Context, key, username string) error {
+	logger := c.logger.WithFields(logrus.Fields{"method": "AssignIssue", "key": key})
+	assignee := struct {
+		Name *string `json:"name"`
+	}{}
+	if username != "" {
+		assignee.Name = &username
 	}
-	samlResp := getSAMLResponse(doc)
-	if err := resp.Body.Close(); err != nil {
-		return nil, err
+	req, err := c.newRequest(ctx, http.MethodPut, "/rest/api/2/issue/"+url.PathEscape(key)+"/assignee", assignee)
+	if err != nil {
+		return err
 	}
+	return c.do(req, logger, nil)
+}
 
-	samlRespFormData := url.Values{"SAMLResponse": {samlResp}}
-	resp, err = client.PostForm("https://sso.jboss.org/login?provider=RedHatExternalProvider", samlRespFormData)
+// TransitionIssue moves an issue into the named status, resolving which of
+// the issue's available transitions leads there.
+// https://docs.atlassian.com/software/jira/docs/api/REST/8.5.1/#api/2/issue-doTransition
+func (c *client) TransitionIssue(ctx context.Context, key, status string) error {
+	logger := c.logger.WithFields(logrus.Fields{"method": "TransitionIssue", "key": key, "status": status})
+	path := "/rest/api/2/issue/" + url.PathEscape(key) + "/transitions"
+	req, err := c.newRequest(ctx, http.MethodGet, path, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("could not login with saml response (%d)", resp.StatusCode)
+	var available struct {
+		Transitions []Transition `json:"transitions"`
 	}
-	if err := jar.Save(); err != nil {
-		return nil, err
+	if err := c.do(req, logger, &available); err != nil {
+		return err
 	}
-	return jiraclient, nil
-}
-
-func getSAMLRequest(response *http.Response) string {
-	doc := html.NewTokenizer(response.Body)
-	for tokenType := doc.Next(); tokenType != html.ErrorToken; {
-		token := doc.Token()
-
-		if tokenType == html.StartTagToken {
-			if token.DataAtom != atom.Textarea {
-				tokenType = doc.Next()
-				continue
-			}
-			for _, attr := range token.Attr {
-				if attr.Key == "name" && attr.Val == "SAMLRequest" {
-					if doc.Next() == html.TextToken {
-						return doc.Token().String()
-					}
-				}
-			}
-		}
-		tokenType = doc.Next()
+	transition, err := findTransition(available.Transitions, status)
+	if err != nil {
+		return fmt.Errorf("cannot transition %s: %v", key, err)
+	}
+
+	payload := struct {
+		Transition struct {
+			ID string `json:"id"`
+		} `json:"transition"`
+	}{}
+	payload.Transition.ID = transition.ID
+	req, err = c.newRequest(ctx, http.MethodPost, path, payload)
+	if err != nil {
+		return err
 	}
-	return ""
+	return c.do(req, logger, nil)
 }
 
-func getSAMLResponse(n *html.Node) string {
-	if n.Type == html.ElementNode && n.DataAtom == atom.Input {
-		isSamlInput := false
-		for _, attr := range n.Attr {
-			if attr.Key == "name" && attr.Val == "SAMLResponse" {
-				isSamlInput = true
-			}
-		}
-		if !isSamlInput {
-			return ""
-		}
-		for _, attr := range n.Attr {
-			if attr.Key == "value" {
-				return attr.Val
-			}
+// findTransition picks the transition whose target status is status,
+// falling back to a transition with that name.
+func findTransition(transitions []Transition, status string) (*Transition, error) {
+	for i, t := range transitions {
+		if strings.EqualFold(t.To.Name, status) {
+			return &transitions[i], nil
 		}
 	}
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		if found := getSAMLResponse(c); found != "" {
-			return found
+	for i, t := range transitions {
+		if strings.EqualFold(t.Name, status) {
+			return &transitions[i], nil
 		}
 	}
-
-	return ""
+	var names []string
+	for _, t := range transitions {
+		names = append(names, t.To.Name)
+	}
+	return nil, fmt.Errorf("no transition to %q, available: %s", status, strings.Join(names, ", "))
 }
 
-func getFormURL(response *http.Response) string {
-	doc := html.NewTokenizer(response.Body)
+// AddComment adds a comment to an issue.
+// https://docs.atlassian.com/software/jira/docs/api/REST/8.5.1/#api/2/issue-addComment
+func (c *client) AddComment(ctx context.Context, key, body string) (*Comment, error) {
+	logger := c.logger.WithFields(logrus.Fields{"method": "AddComment", "key": key})
+	req, err := c.newRequest(ctx, http.MethodPost, "/rest/api/2/issue/"+url.PathEscape(key)+"/comment", Comment{Body: body})
+	if err != nil {
+		return nil, err
+	}
+	var comment Comment
+	if err := c.do(req, logger, &comment); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
 
-	for tokenType := doc.Next(); tokenType != html.ErrorToken; {
-		token := doc.Token()
+// GetRemoteLinks lists the remote links (e.g. to bugzilla or github) on an issue.
+// https://docs.atlassian.com/software/jira/docs/api/REST/8.5.1/#api/2/issue-getRemoteIssueLinks
+func (c *client) GetRemoteLinks(ctx context.Context, key string) ([]RemoteLink, error) {
+	logger := c.logger.WithFields(logrus.Fields{"method": "GetRemoteLinks", "key": key})
+	req, err := c.newRequest(ctx, http.MethodGet, "/rest/api/2/issue/"+url.PathEscape(key)+"/remotelink", nil)
+	if err != nil {
+		return nil, err
+	}
+	var links []RemoteLink
+	if err := c.do(req, logger, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
 
-		if tokenType == html.StartTagToken {
-			if token.DataAtom != atom.Form {
-				tokenType = doc.Next()
-				continue
-			}
-			for _, attr := range token.Attr {
-				if attr.Key == "action" {
-					return attr.Val
-				}
-			}
-		}
-		tokenType = doc.Next()
+// AddRemoteLink links an issue to a remote object. Links with a GlobalID
+// replace any existing link with the same GlobalID.
+// https://docs.atlassian.com/software/jira/docs/api/REST/8.5.1/#api/2/issue-createOrUpdateRemoteIssueLink
+func (c *client) AddRemoteLink(ctx context.Context, key string, link RemoteLink) error {
+	logger := c.logger.WithFields(logrus.Fields{"method": "AddRemoteLink", "key": key})
+	req, err := c.newRequest(ctx, http.MethodPost, "/rest/api/2/issue/"+url.PathEscape(key)+"/remotelink", link)
+	if err != nil {
+		return err
 	}
-	return ""
+	return c.do(req, logger, nil)
+}
+
+// AddFixVersion adds version to the fix versions of an issue.
+func (c *client) AddFixVersion(ctx context.Context, key, version string) error {
+	return c.UpdateIssue(ctx, key, IssueUpdate{Update: map[string][]FieldOperation{
+		"fixVersions": {{Add: map[string]string{"name": version}}},
+	}})
+}
+
+// RemoveFixVersion removes version from the fix versions of an issue.
+func (c *client) RemoveFixVersion(ctx context.Context, key, version string) error {
+	return c.UpdateIssue(ctx, key, IssueUpdate{Update: map[string][]FieldOperation{
+		"fixVersions": {{Remove: map[string]string{"name": version}}},
+	}})
 }
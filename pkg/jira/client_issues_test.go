@@ -0,0 +1,89 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchIssuesPaginates(t *testing.T) {
+	var starts []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/rest/api/2/search", r.URL.Path)
+		var search struct {
+			JQL     string `json:"jql"`
+			StartAt int    `json:"startAt"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&search))
+		require.Equal(t, "project = OLM", search.JQL)
+		starts = append(starts, search.StartAt)
+
+		var issues []Issue
+		for i := search.StartAt; i < search.StartAt+searchPageSize && i < 120; i++ {
+			issues = append(issues, Issue{Key: fmt.Sprintf("OLM-%d", i)})
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{
+			"startAt": search.StartAt,
+			"total":   120,
+			"issues":  issues,
+		}))
+	}))
+	defer server.Close()
+
+	issues, err := NewClientWithHTTPClient(server.Client(), server.URL).SearchIssues(context.Background(), "project = OLM")
+	require.NoError(t, err)
+	require.Len(t, issues, 120)
+	require.Equal(t, []int{0, 50, 100}, starts)
+	require.Equal(t, "OLM-119", issues[119].Key)
+}
+
+func TestTransitionIssue(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		expected string
+		err      bool
+	}{
+		{name: "by target status", status: "code review", expected: "21"},
+		{name: "by transition name", status: "Start Progress", expected: "11"},
+		{name: "unknown status", status: "Closed", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var posted string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/rest/api/2/issue/OLM-1/transitions", r.URL.Path)
+				if r.Method == http.MethodPost {
+					var payload struct {
+						Transition struct {
+							ID string `json:"id"`
+						} `json:"transition"`
+					}
+					require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+					posted = payload.Transition.ID
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				fmt.Fprint(w, `{"transitions": [
+					{"id": "11", "name": "Start Progress", "to": {"name": "In Progress"}},
+					{"id": "21", "name": "Review", "to": {"name": "Code Review"}}
+				]}`)
+			}))
+			defer server.Close()
+
+			err := NewClientWithHTTPClient(server.Client(), server.URL).TransitionIssue(context.Background(), "OLM-1", tt.status)
+			if tt.err {
+				require.Error(t, err)
+				require.Empty(t, posted)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, posted)
+		})
+	}
+}
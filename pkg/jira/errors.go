@@ -0,0 +1,42 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type requestError struct {
+	statusCode int
+	message    string
+}
+
+func (e requestError) Error() string {
+	return e.message
+}
+
+// newRequestError builds a requestError, surfacing jira's own error messages
+// from the body when it has any.
+func newRequestError(code int, body []byte) *requestError {
+	var parsed struct {
+		ErrorMessages []string          `json:"errorMessages"`
+		Errors        map[string]string `json:"errors"`
+	}
+	messages := []string{fmt.Sprintf("response code %d", code)}
+	if err := json.Unmarshal(body, &parsed); err == nil {
+		messages = append(messages, parsed.ErrorMessages...)
+		for field, msg := range parsed.Errors {
+			messages = append(messages, field+": "+msg)
+		}
+	}
+	return &requestError{statusCode: code, message: strings.Join(messages, ": ")}
+}
+
+func IsNotFound(err error) bool {
+	reqError, ok := err.(*requestError)
+	if !ok {
+		return false
+	}
+	return reqError.statusCode == http.StatusNotFound
+}
@@ -0,0 +1,151 @@
+package jira
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	ssoSAMLURL     = "https://sso.redhat.com/auth/realms/redhat-external/protocol/saml"
+	ssoProviderURL = "https://sso.jboss.org/login?provider=RedHatExternalProvider"
+)
+
+// samlLogin walks the Red Hat SSO SAML flow, leaving the session cookies in
+// the client's jar.
+func samlLogin(client *http.Client, endpoint, username, password string) error {
+	resp, err := client.Get(endpoint + "/login.jsp?os_destination=%2Fdefault.jsp")
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("could not reach jira (%d)", resp.StatusCode)
+	}
+
+	samlRequest := getSAMLRequest(resp)
+	if samlRequest == "" {
+		return fmt.Errorf("could not get saml request")
+	}
+	if err := resp.Body.Close(); err != nil {
+		return err
+	}
+
+	formData := url.Values{"SAMLRequest": {samlRequest}}
+	resp, err = client.PostForm(ssoSAMLURL, formData)
+	if err != nil {
+		return err
+	}
+	loginURL := getFormURL(resp)
+	if loginURL == "" {
+		return fmt.Errorf("could not get login url")
+	}
+	if err := resp.Body.Close(); err != nil {
+		return err
+	}
+
+	loginData := url.Values{"username": {username}, "password": {password}}
+	resp, err = client.PostForm(loginURL, loginData)
+	if err != nil {
+		return err
+	}
+
+	// tokenizer misses the input obj for this response for some reason, parse the whole doc
+	doc, err := html.Parse(resp.Body)
+	if err != nil {
+		return err
+	}
+	samlResp := getSAMLResponse(doc)
+	if err := resp.Body.Close(); err != nil {
+		return err
+	}
+	if samlResp == "" {
+		return fmt.Errorf("could not get saml response, check jira credentials")
+	}
+
+	samlRespFormData := url.Values{"SAMLResponse": {samlResp}}
+	resp, err = client.PostForm(ssoProviderURL, samlRespFormData)
+	if err != nil {
+		return err
+	}
+	if err := resp.Body.Close(); err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("could not login with saml response (%d)", resp.StatusCode)
+	}
+	return nil
+}
+
+func getSAMLRequest(response *http.Response) string {
+	doc := html.NewTokenizer(response.Body)
+	for tokenType := doc.Next(); tokenType != html.ErrorToken; {
+		token := doc.Token()
+
+		if tokenType == html.StartTagToken {
+			if token.DataAtom != atom.Textarea {
+				tokenType = doc.Next()
+				continue
+			}
+			for _, attr := range token.Attr {
+				if attr.Key == "name" && attr.Val == "SAMLRequest" {
+					if doc.Next() == html.TextToken {
+						return doc.Token().String()
+					}
+				}
+			}
+		}
+		tokenType = doc.Next()
+	}
+	return ""
+}
+
+func getSAMLResponse(n *html.Node) string {
+	if n.Type == html.ElementNode && n.DataAtom == atom.Input {
+		isSamlInput := false
+		for _, attr := range n.Attr {
+			if attr.Key == "name" && attr.Val == "SAMLResponse" {
+				isSamlInput = true
+			}
+		}
+		if !isSamlInput {
+			return ""
+		}
+		for _, attr := range n.Attr {
+			if attr.Key == "value" {
+				return attr.Val
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := getSAMLResponse(c); found != "" {
+			return found
+		}
+	}
+
+	return ""
+}
+
+func getFormURL(response *http.Response) string {
+	doc := html.NewTokenizer(response.Body)
+
+	for tokenType := doc.Next(); tokenType != html.ErrorToken; {
+		token := doc.Token()
+
+		if tokenType == html.StartTagToken {
+			if token.DataAtom != atom.Form {
+				tokenType = doc.Next()
+				continue
+			}
+			for _, attr := range token.Attr {
+				if attr.Key == "action" {
+					return attr.Val
+				}
+			}
+		}
+		tokenType = doc.Next()
+	}
+	return ""
+}
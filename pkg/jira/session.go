@@ -0,0 +1,240 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/persistent-cookiejar"
+	"github.com/sirupsen/logrus"
+
+	"github.com/ecordell/cop/pkg/config"
+)
+
+// sessionMaxAge is how long we trust a stored session before logging in again.
+// Red Hat SSO expires idle sessions well before cookies are dropped from the jar,
+// so we track the age ourselves instead of relying on cookie expiry.
+const sessionMaxAge = 8 * time.Hour
+
+// sessionMeta is persisted next to the cookie jar.
+type sessionMeta struct {
+	AuthenticatedAt time.Time `json:"authenticated_at"`
+}
+
+// Session holds the persisted cookies for one user against one jira endpoint.
+type Session struct {
+	logger   *logrus.Entry
+	endpoint string
+	username string
+	password string
+
+	jar      *cookiejar.Jar
+	jarPath  string
+	metaPath string
+
+	// login uses the jar directly, without the reauth transport, so that a
+	// failing login can't recurse
+	login *http.Client
+	// authenticate logs login in, it is samlLogin but for tests
+	authenticate func(client *http.Client, endpoint, username, password string) error
+
+	mu sync.Mutex
+	// generation counts logins, so that requests rejected by the same
+	// session only log in again once
+	generation int
+}
+
+// NewSession loads (or creates) the session for username on endpoint.
+// Sessions are stored in the cop data dir, one file per endpoint and user.
+func NewSession(endpoint, username, password string) (*Session, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jira endpoint %q: %v", endpoint, err)
+	}
+	if username == "" {
+		return nil, fmt.Errorf("must provide jira username or login with `cop login jira`")
+	}
+	base, err := config.Path("jira", url.PathEscape(u.Host), url.PathEscape(username))
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{
+		logger:   logrus.WithFields(logrus.Fields{"client": "jira", "endpoint": u.Host, "user": username}),
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		username: username,
+		password: password,
+		jarPath:  base + ".cookies",
+		metaPath: base + ".json",
+
+		authenticate: samlLogin,
+	}
+	s.jar, err = cookiejar.New(&cookiejar.Options{
+		Filename:              s.jarPath,
+		PersistSessionCookies: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.login = &http.Client{Jar: s.jar}
+	return s, nil
+}
+
+// HTTPClient returns a client that sends the session cookies and logs in
+// again transparently if jira answers 401.
+func (s *Session) HTTPClient(base http.RoundTripper) *http.Client {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	s.login.Transport = base
+	return &http.Client{
+		Jar:       s.jar,
+		Transport: &reauthTransport{base: base, session: s},
+	}
+}
+
+// Expired reports whether the stored session is missing or too old to trust.
+func (s *Session) Expired() bool {
+	u, err := url.Parse(s.endpoint)
+	if err != nil || len(s.jar.Cookies(u)) == 0 {
+		return true
+	}
+	raw, err := ioutil.ReadFile(s.metaPath)
+	if err != nil {
+		return true
+	}
+	var meta sessionMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return true
+	}
+	return time.Since(meta.AuthenticatedAt) > sessionMaxAge
+}
+
+// Valid asks jira whether the stored cookies still identify a user.
+func (s *Session) Valid() bool {
+	resp, err := s.login.Get(s.endpoint + "/rest/auth/1/session")
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// Ensure makes sure the session is authenticated, logging in if the stored
+// session has expired or been rejected.
+func (s *Session) Ensure() error {
+	if !s.Expired() && s.Valid() {
+		s.logger.Debug("reusing stored jira session")
+		return nil
+	}
+	return s.Refresh()
+}
+
+// Refresh discards any stored cookies and logs in again.
+func (s *Session) Refresh() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refresh()
+}
+
+// cookies returns the session cookies for u and the generation of the
+// session they belong to
+func (s *Session) cookies(u *url.URL) ([]*http.Cookie, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jar.Cookies(u), s.generation
+}
+
+// refreshSince logs in again unless that already happened since generation,
+// so that requests rejected together only log in once.
+func (s *Session) refreshSince(generation int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.generation != generation {
+		s.logger.Debug("jira session was already renewed")
+		return nil
+	}
+	return s.refresh()
+}
+
+func (s *Session) refresh() error {
+	if s.password == "" {
+		return fmt.Errorf("jira session for %s expired and no password is available, run `cop login jira`", s.username)
+	}
+	s.logger.Debug("logging in to jira")
+	s.jar.RemoveAll()
+	if err := s.authenticate(s.login, s.endpoint, s.username, s.password); err != nil {
+		return err
+	}
+	s.generation++
+	return s.save()
+}
+
+func (s *Session) save() error {
+	if err := s.jar.Save(); err != nil {
+		return err
+	}
+	// the jar only sets permissions on files it creates
+	if err := os.Chmod(s.jarPath, 0600); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(sessionMeta{AuthenticatedAt: time.Now()})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.metaPath, raw, 0600)
+}
+
+// reauthTransport retries a request once after logging in again if jira
+// rejects it with a 401.
+type reauthTransport struct {
+	base    http.RoundTripper
+	session *Session
+}
+
+func (t *reauthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cookies, generation := t.session.cookies(req.URL)
+	resp, err := t.base.RoundTrip(withCookies(req, cookies))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	// a consumed body can't be sent again
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+	if err := resp.Body.Close(); err != nil {
+		t.session.logger.WithError(err).Warn("could not close response body")
+	}
+
+	t.session.logger.Debug("jira session rejected, logging in again")
+	if err := t.session.refreshSince(generation); err != nil {
+		return nil, fmt.Errorf("jira session expired and re-authentication failed: %v", err)
+	}
+
+	cookies, _ = t.session.cookies(req.URL)
+	retry := withCookies(req, cookies)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	return t.base.RoundTrip(retry)
+}
+
+// withCookies copies req to send with cookies, in place of those the
+// http.Client filled in, which may be from an older session.
+func withCookies(req *http.Request, cookies []*http.Cookie) *http.Request {
+	out := req.Clone(req.Context())
+	out.Header.Del("Cookie")
+	for _, c := range cookies {
+		out.AddCookie(c)
+	}
+	return out
+}
@@ -0,0 +1,235 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeJira accepts requests carrying the session cookie of its latest login
+type fakeJira struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	session  string
+	logins   int
+	comments []string
+}
+
+func newFakeJira() *fakeJira {
+	f := &fakeJira{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if r.URL.Path == "/login" {
+			f.logins++
+			f.session = fmt.Sprintf("session-%d", f.logins)
+			http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: f.session, Path: "/"})
+			return
+		}
+		if c, err := r.Cookie("JSESSIONID"); err != nil || f.session == "" || c.Value != f.session {
+			http.Error(w, "session expired", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/rest/auth/1/session":
+		case "/rest/api/2/issue/OLM-1/comment":
+			var c Comment
+			if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			f.comments = append(f.comments, c.Body)
+			json.NewEncoder(w).Encode(c)
+		default:
+			fmt.Fprint(w, "{}")
+		}
+	}))
+	return f
+}
+
+// expire makes jira forget the current session
+func (f *fakeJira) expire() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.session = ""
+}
+
+func (f *fakeJira) loginCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.logins
+}
+
+// testSession returns a session against f in a temporary data dir, logging
+// in by visiting f's /login instead of through SSO.
+func testSession(t *testing.T, f *fakeJira) (*Session, func()) {
+	dir, err := ioutil.TempDir("", "cop-jira")
+	require.NoError(t, err)
+	dataDir := os.Getenv("COP_DATA_DIR")
+	os.Setenv("COP_DATA_DIR", dir)
+	s, err := NewSession(f.URL, "me", "hunter2")
+	require.NoError(t, err)
+	s.authenticate = func(client *http.Client, endpoint, username, password string) error {
+		resp, err := client.Get(endpoint + "/login")
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+	return s, func() {
+		os.Setenv("COP_DATA_DIR", dataDir)
+		os.RemoveAll(dir)
+	}
+}
+
+func writeMeta(t *testing.T, s *Session, at time.Time) {
+	raw, err := json.Marshal(sessionMeta{AuthenticatedAt: at})
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(s.metaPath, raw, 0600))
+}
+
+func TestSessionExpired(t *testing.T) {
+	f := newFakeJira()
+	defer f.Close()
+	s, cleanup := testSession(t, f)
+	defer cleanup()
+
+	// nothing stored yet
+	require.True(t, s.Expired())
+
+	u, err := url.Parse(f.URL)
+	require.NoError(t, err)
+	s.jar.SetCookies(u, []*http.Cookie{{Name: "JSESSIONID", Value: "stored", Path: "/"}})
+	require.True(t, s.Expired(), "cookies without a record of when they were made")
+
+	writeMeta(t, s, time.Now().Add(-time.Hour))
+	require.False(t, s.Expired())
+	writeMeta(t, s, time.Now().Add(-sessionMaxAge-time.Minute))
+	require.True(t, s.Expired())
+	require.NoError(t, ioutil.WriteFile(s.metaPath, []byte("not json"), 0600))
+	require.True(t, s.Expired())
+}
+
+func TestSessionEnsure(t *testing.T) {
+	f := newFakeJira()
+	defer f.Close()
+	s, cleanup := testSession(t, f)
+	defer cleanup()
+
+	require.NoError(t, s.Ensure())
+	require.Equal(t, 1, f.loginCount())
+	require.False(t, s.Expired())
+
+	// a fresh session jira still accepts is reused
+	require.NoError(t, s.Ensure())
+	require.Equal(t, 1, f.loginCount())
+
+	// an old one is replaced without asking jira
+	writeMeta(t, s, time.Now().Add(-sessionMaxAge-time.Minute))
+	require.NoError(t, s.Ensure())
+	require.Equal(t, 2, f.loginCount())
+
+	// as is one jira rejects
+	f.expire()
+	require.NoError(t, s.Ensure())
+	require.Equal(t, 3, f.loginCount())
+}
+
+func TestReauthRetriesWithBody(t *testing.T) {
+	f := newFakeJira()
+	defer f.Close()
+	s, cleanup := testSession(t, f)
+	defer cleanup()
+	require.NoError(t, s.Ensure())
+	client := NewClientWithHTTPClient(s.HTTPClient(nil), f.URL)
+
+	f.expire()
+	comment, err := client.AddComment(context.Background(), "OLM-1", "Looking into it.")
+	require.NoError(t, err)
+	require.Equal(t, "Looking into it.", comment.Body)
+	require.Equal(t, 2, f.loginCount())
+	// the body was sent again after logging in
+	require.Equal(t, []string{"Looking into it."}, f.comments)
+}
+
+func TestReauthWithoutReplayableBody(t *testing.T) {
+	f := newFakeJira()
+	defer f.Close()
+	s, cleanup := testSession(t, f)
+	defer cleanup()
+	require.NoError(t, s.Ensure())
+
+	f.expire()
+	req, err := http.NewRequest(http.MethodPost, f.URL+"/rest/api/2/issue/OLM-1/comment", ioutil.NopCloser(strings.NewReader(`{"body": "once"}`)))
+	require.NoError(t, err)
+	require.Nil(t, req.GetBody)
+	resp, err := s.HTTPClient(nil).Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	// the 401 is passed on rather than retried with an empty body
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.Equal(t, 1, f.loginCount())
+	require.Empty(t, f.comments)
+}
+
+func TestReauthFails(t *testing.T) {
+	f := newFakeJira()
+	defer f.Close()
+	s, cleanup := testSession(t, f)
+	defer cleanup()
+	require.NoError(t, s.Ensure())
+	client := NewClientWithHTTPClient(s.HTTPClient(nil), f.URL)
+
+	f.expire()
+	s.authenticate = func(*http.Client, string, string, string) error {
+		return fmt.Errorf("could not get saml response, check jira credentials")
+	}
+	_, err := client.GetIssue(context.Background(), "OLM-1")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "jira session expired and re-authentication failed: could not get saml response")
+
+	// without a password there's no logging in again at all
+	s.password = ""
+	_, err = client.GetIssue(context.Background(), "OLM-1")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "run `cop login jira`")
+}
+
+func TestReauthOnceForParallelRequests(t *testing.T) {
+	f := newFakeJira()
+	defer f.Close()
+	s, cleanup := testSession(t, f)
+	defer cleanup()
+	require.NoError(t, s.Ensure())
+	client := NewClientWithHTTPClient(s.HTTPClient(nil), f.URL)
+
+	f.expire()
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.GetIssue(context.Background(), "OLM-1")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, f.loginCount())
+}
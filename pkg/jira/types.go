@@ -0,0 +1,60 @@
+package jira
+
+import (
+	"gopkg.in/andygrunwald/go-jira.v1"
+)
+
+// Issue is a jira issue. See API documentation at:
+// https://docs.atlassian.com/software/jira/docs/api/REST/8.5.1/#api/2/issue-getIssue
+type Issue = jira.Issue
+
+// IssueFields holds the standard and custom fields of an Issue.
+type IssueFields = jira.IssueFields
+
+// Comment is a comment on an Issue.
+type Comment = jira.Comment
+
+// Transition moves an Issue from its current status to another.
+type Transition = jira.Transition
+
+// User is a jira user.
+type User = jira.User
+
+// IssueUpdate contains fields to set or edit on an Issue. See API documentation at:
+// https://docs.atlassian.com/software/jira/docs/api/REST/8.5.1/#api/2/issue-editIssue
+type IssueUpdate struct {
+	// Fields are set to the given values, replacing what was there.
+	Fields map[string]interface{} `json:"fields,omitempty"`
+	// Update holds operations that edit a field relative to its current value.
+	Update map[string][]FieldOperation `json:"update,omitempty"`
+}
+
+// FieldOperation is a single edit to a field of an Issue.
+type FieldOperation struct {
+	Set    interface{} `json:"set,omitempty"`
+	Add    interface{} `json:"add,omitempty"`
+	Remove interface{} `json:"remove,omitempty"`
+}
+
+// RemoteLink links an Issue to an object in another system. See API documentation at:
+// https://developer.atlassian.com/server/jira/platform/jira-rest-api-for-remote-issue-links/
+type RemoteLink struct {
+	// ID is the jira ID of the link.
+	ID int `json:"id,omitempty"`
+	// GlobalID uniquely identifies the remote object, e.g. the bugzilla URL.
+	GlobalID string `json:"globalId,omitempty"`
+	// Relationship describes how the issue relates to the remote object.
+	Relationship string `json:"relationship,omitempty"`
+	// Object is the remote object being linked to.
+	Object RemoteLinkObject `json:"object"`
+}
+
+// RemoteLinkObject describes the remote end of a RemoteLink.
+type RemoteLinkObject struct {
+	// URL is where the remote object can be viewed.
+	URL string `json:"url"`
+	// Title is displayed as the link text.
+	Title string `json:"title"`
+	// Summary is displayed next to the link.
+	Summary string `json:"summary,omitempty"`
+}
@@ -0,0 +1,187 @@
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+)
+
+// Wrap returns a client that records every change made through c in j, as
+// made by command. The bug is read before each change for the old values.
+func Wrap(c bugzilla.Client, j *Journal, command string) bugzilla.Client {
+	return &client{Client: c, journal: j, command: command, now: time.Now}
+}
+
+type client struct {
+	bugzilla.Client
+	journal *Journal
+	command string
+	now     func() time.Time
+}
+
+func (c *client) record(id int, changes []Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	e := &Entry{Time: c.now().UTC(), Command: c.command, Bug: id, Changes: changes}
+	if err := c.journal.Append(e); err != nil {
+		return fmt.Errorf("changed bug %d but could not record it in the journal: %v", id, err)
+	}
+	return nil
+}
+
+func (c *client) UpdateBug(id int, update bugzilla.BugUpdate) error {
+	before, err := c.Client.GetBug(id)
+	if err != nil {
+		return fmt.Errorf("could not read bug %d before changing it: %v", id, err)
+	}
+	if err := c.Client.UpdateBug(id, update); err != nil {
+		return err
+	}
+	return c.record(id, diff(before, update))
+}
+
+func (c *client) UpdateInternalWhiteboard(id int, value string) (*bugzilla.Bug, error) {
+	before, err := c.Client.GetBug(id)
+	if err != nil {
+		return nil, fmt.Errorf("could not read bug %d before changing it: %v", id, err)
+	}
+	bug, err := c.Client.UpdateInternalWhiteboard(id, value)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	if before.InternalWhiteboard != value {
+		changes = append(changes, Change{Field: FieldInternalWhiteboard, Old: before.InternalWhiteboard, New: value})
+	}
+	return bug, c.record(id, changes)
+}
+
+func (c *client) AddPullRequestAsExternalBug(id int, org, repo string, num int) (bool, error) {
+	changed, err := c.Client.AddPullRequestAsExternalBug(id, org, repo, num)
+	if err != nil || !changed {
+		return changed, err
+	}
+	return changed, c.record(id, []Change{{Field: FieldExternalBug, New: fmt.Sprintf("%s/%s#%d", org, repo, num)}})
+}
+
+// value is the current value of field on bug, as recorded in changes
+func value(bug *bugzilla.Bug, field string) string {
+	switch field {
+	case FieldStatus:
+		return bug.Status
+	case FieldResolution:
+		return bug.Resolution
+	case FieldDupeOf:
+		if bug.DupeOf == 0 {
+			return ""
+		}
+		return strconv.Itoa(bug.DupeOf)
+	case FieldAssignedTo:
+		return bug.AssignedTo
+	case FieldPriority:
+		return bug.Priority
+	case FieldSeverity:
+		return bug.Severity
+	case FieldWhiteboard:
+		return bug.Whiteboard
+	case FieldInternalWhiteboard:
+		return bug.InternalWhiteboard
+	case FieldSubComponents:
+		return subComponents(bug.SubComponents)
+	}
+	return ""
+}
+
+// subComponents formats sub components as json, or nothing if there are none
+func subComponents(s map[string][]string) string {
+	set := map[string][]string{}
+	for component, subs := range s {
+		if len(subs) > 0 {
+			set[component] = subs
+		}
+	}
+	if len(set) == 0 {
+		return ""
+	}
+	// maps marshal with sorted keys
+	data, _ := json.Marshal(set)
+	return string(data)
+}
+
+// flags formats flags like bugzilla shows them, e.g. needinfo?(user)
+func flags(fs []bugzilla.FlagChange) string {
+	var out []string
+	for _, f := range fs {
+		s := f.Name + f.Status
+		if f.Requestee != "" {
+			s += "(" + f.Requestee + ")"
+		}
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return strings.Join(out, ", ")
+}
+
+// diff returns the changes update makes to bug
+func diff(bug *bugzilla.Bug, update bugzilla.BugUpdate) []Change {
+	var changes []Change
+	add := func(field, new string) {
+		if old := value(bug, field); old != new {
+			changes = append(changes, Change{Field: field, Old: old, New: new})
+		}
+	}
+	if update.Status != "" {
+		add(FieldStatus, update.Status)
+	}
+	if update.Resolution != "" {
+		add(FieldResolution, update.Resolution)
+	}
+	if update.DupeOf != 0 {
+		add(FieldDupeOf, strconv.Itoa(update.DupeOf))
+	}
+	if update.AssignedTo != "" {
+		add(FieldAssignedTo, update.AssignedTo)
+	}
+	if update.Priority != "" {
+		add(FieldPriority, update.Priority)
+	}
+	if update.Severity != "" {
+		add(FieldSeverity, update.Severity)
+	}
+	if update.Whiteboard != nil {
+		add(FieldWhiteboard, *update.Whiteboard)
+	}
+	if update.SubComponents != nil {
+		// components left out of the update keep their sub components
+		after := map[string][]string{}
+		for component, subs := range bug.SubComponents {
+			after[component] = subs
+		}
+		for component, subs := range update.SubComponents {
+			after[component] = subs
+		}
+		add(FieldSubComponents, subComponents(after))
+	}
+	if len(update.Flags) > 0 {
+		var old []bugzilla.FlagChange
+		for _, f := range bug.Flags {
+			for _, u := range update.Flags {
+				if f.Name == u.Name {
+					old = append(old, bugzilla.FlagChange{Name: f.Name, Status: f.Status, Requestee: f.Requestee})
+					break
+				}
+			}
+		}
+		changes = append(changes, Change{Field: FieldFlags, Old: flags(old), New: flags(update.Flags)})
+	}
+	if update.Comment != nil {
+		changes = append(changes, Change{Field: FieldComment, New: update.Comment.Body})
+	}
+	return changes
+}
@@ -0,0 +1,245 @@
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ecordell/cop/pkg/jira"
+)
+
+// WrapJira returns a jira client that records every change made through c
+// in j, as made by command. The issue is read before each change that can be
+// undone, for the old values.
+func WrapJira(c jira.Client, j *Journal, command string) jira.Client {
+	return &jiraClient{Client: c, journal: j, command: command, now: time.Now}
+}
+
+type jiraClient struct {
+	jira.Client
+	journal *Journal
+	command string
+	now     func() time.Time
+}
+
+func (c *jiraClient) record(key string, changes []Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	e := &Entry{Time: c.now().UTC(), Command: c.command, Issue: key, Changes: changes}
+	if err := c.journal.Append(e); err != nil {
+		return fmt.Errorf("changed %s but could not record it in the journal: %v", key, err)
+	}
+	return nil
+}
+
+// before reads an issue before changing it
+func (c *jiraClient) before(ctx context.Context, key string) (*jira.Issue, error) {
+	issue, err := c.Client.GetIssue(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s before changing it: %v", key, err)
+	}
+	return issue, nil
+}
+
+func (c *jiraClient) CreateIssue(ctx context.Context, issue *jira.Issue) (*jira.Issue, error) {
+	created, err := c.Client.CreateIssue(ctx, issue)
+	if err != nil {
+		return nil, err
+	}
+	var summary string
+	if issue.Fields != nil {
+		summary = issue.Fields.Summary
+	}
+	return created, c.record(created.Key, []Change{{Field: FieldCreated, New: summary}})
+}
+
+func (c *jiraClient) UpdateIssue(ctx context.Context, key string, update jira.IssueUpdate) error {
+	if err := c.Client.UpdateIssue(ctx, key, update); err != nil {
+		return err
+	}
+	data, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+	return c.record(key, []Change{{Field: FieldFields, New: string(data)}})
+}
+
+func (c *jiraClient) AssignIssue(ctx context.Context, key, username string) error {
+	issue, err := c.before(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := c.Client.AssignIssue(ctx, key, username); err != nil {
+		return err
+	}
+	var changes []Change
+	if old := issueValue(issue, FieldAssignedTo); old != username {
+		changes = append(changes, Change{Field: FieldAssignedTo, Old: old, New: username})
+	}
+	return c.record(key, changes)
+}
+
+func (c *jiraClient) TransitionIssue(ctx context.Context, key, status string) error {
+	issue, err := c.before(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := c.Client.TransitionIssue(ctx, key, status); err != nil {
+		return err
+	}
+	var changes []Change
+	if old := issueValue(issue, FieldStatus); !strings.EqualFold(old, status) {
+		changes = append(changes, Change{Field: FieldStatus, Old: old, New: status})
+	}
+	return c.record(key, changes)
+}
+
+func (c *jiraClient) AddComment(ctx context.Context, key, body string) (*jira.Comment, error) {
+	comment, err := c.Client.AddComment(ctx, key, body)
+	if err != nil {
+		return nil, err
+	}
+	return comment, c.record(key, []Change{{Field: FieldComment, New: body}})
+}
+
+func (c *jiraClient) AddRemoteLink(ctx context.Context, key string, link jira.RemoteLink) error {
+	if err := c.Client.AddRemoteLink(ctx, key, link); err != nil {
+		return err
+	}
+	return c.record(key, []Change{{Field: FieldRemoteLink, New: link.Object.URL}})
+}
+
+func (c *jiraClient) AddFixVersion(ctx context.Context, key, version string) error {
+	return c.changeFixVersions(ctx, key, version, c.Client.AddFixVersion)
+}
+
+func (c *jiraClient) RemoveFixVersion(ctx context.Context, key, version string) error {
+	return c.changeFixVersions(ctx, key, version, c.Client.RemoveFixVersion)
+}
+
+// changeFixVersions records the fix versions of an issue before and after change
+func (c *jiraClient) changeFixVersions(ctx context.Context, key, version string, change func(ctx context.Context, key, version string) error) error {
+	issue, err := c.before(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := change(ctx, key, version); err != nil {
+		return err
+	}
+	after, err := c.before(ctx, key)
+	if err != nil {
+		return fmt.Errorf("changed %s but could not read it back: %v", key, err)
+	}
+	var changes []Change
+	if old, now := issueValue(issue, FieldFixVersions), issueValue(after, FieldFixVersions); old != now {
+		changes = append(changes, Change{Field: FieldFixVersions, Old: old, New: now})
+	}
+	return c.record(key, changes)
+}
+
+// issueValue is the current value of field on issue, as recorded in changes
+func issueValue(issue *jira.Issue, field string) string {
+	f := issue.Fields
+	if f == nil {
+		return ""
+	}
+	switch field {
+	case FieldStatus:
+		if f.Status != nil {
+			return f.Status.Name
+		}
+	case FieldAssignedTo:
+		if f.Assignee != nil {
+			return f.Assignee.Name
+		}
+	case FieldFixVersions:
+		var versions []string
+		for _, v := range f.FixVersions {
+			versions = append(versions, v.Name)
+		}
+		return versionList(versions)
+	}
+	return ""
+}
+
+// versionList formats fix versions in order, e.g. "4.4.z, 4.5.0"
+func versionList(versions []string) string {
+	sort.Strings(versions)
+	return strings.Join(versions, ", ")
+}
+
+// splitVersions is the inverse of versionList
+func splitVersions(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ", ")
+}
+
+// undoIssue reverts entry on its issue, returning the changes made and those
+// that can't be reverted.
+func undoIssue(ctx context.Context, c jira.Client, entry *Entry) (undone, skipped []Change, err error) {
+	if jc, ok := c.(*jiraClient); ok {
+		c = jc.Client
+	}
+	issue, err := c.GetIssue(ctx, entry.Issue)
+	if err != nil {
+		return nil, nil, err
+	}
+	var reverts []func() error
+	for _, ch := range entry.Changes {
+		ch := ch
+		switch ch.Field {
+		case FieldStatus, FieldAssignedTo, FieldFixVersions:
+		default:
+			skipped = append(skipped, ch)
+			continue
+		}
+		if current := issueValue(issue, ch.Field); !strings.EqualFold(current, ch.New) {
+			return nil, nil, fmt.Errorf("%s has changed since entry %d: %s is %s, not %s", entry.Issue, entry.ID, ch.Field, quote(current), quote(ch.New))
+		}
+		undone = append(undone, Change{Field: ch.Field, Old: ch.New, New: ch.Old})
+		switch ch.Field {
+		case FieldStatus:
+			reverts = append(reverts, func() error { return c.TransitionIssue(ctx, entry.Issue, ch.Old) })
+		case FieldAssignedTo:
+			reverts = append(reverts, func() error { return c.AssignIssue(ctx, entry.Issue, ch.Old) })
+		case FieldFixVersions:
+			old, now := map[string]bool{}, map[string]bool{}
+			for _, v := range splitVersions(ch.Old) {
+				old[v] = true
+			}
+			for _, v := range splitVersions(ch.New) {
+				now[v] = true
+			}
+			for v := range now {
+				if !old[v] {
+					v := v
+					reverts = append(reverts, func() error { return c.RemoveFixVersion(ctx, entry.Issue, v) })
+				}
+			}
+			for v := range old {
+				if !now[v] {
+					v := v
+					reverts = append(reverts, func() error { return c.AddFixVersion(ctx, entry.Issue, v) })
+				}
+			}
+		}
+	}
+	if len(undone) == 0 {
+		return nil, nil, fmt.Errorf("nothing in entry %d can be undone", entry.ID)
+	}
+	for _, revert := range reverts {
+		if err := revert(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if _, err := c.AddComment(ctx, entry.Issue, fmt.Sprintf("Undoing a change made by %s.", entry.Command)); err != nil {
+		return nil, nil, err
+	}
+	return undone, skipped, nil
+}
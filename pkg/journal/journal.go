@@ -0,0 +1,247 @@
+// Package journal keeps an append-only record of every change cop makes to
+// bugs and jira issues, so that changes can be reviewed and undone.
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ecordell/cop/pkg/config"
+)
+
+// fileName is the name of the journal in cop's data directory.
+const fileName = "journal.log"
+
+// Fields of a bug or jira issue that changes are recorded for.
+const (
+	FieldStatus             = "status"
+	FieldResolution         = "resolution"
+	FieldDupeOf             = "dupe_of"
+	FieldAssignedTo         = "assigned_to"
+	FieldPriority           = "priority"
+	FieldSeverity           = "severity"
+	FieldWhiteboard         = "whiteboard"
+	FieldInternalWhiteboard = "internal_whiteboard"
+	FieldSubComponents      = "sub_components"
+	FieldFlags              = "flags"
+	FieldComment            = "comment"
+	FieldExternalBug        = "external_bug"
+
+	// Fields only jira issues have. Fields records an edit made with
+	// UpdateIssue, as json.
+	FieldFixVersions = "fix_versions"
+	FieldRemoteLink  = "remote_link"
+	FieldCreated     = "created"
+	FieldFields      = "fields"
+)
+
+// Change is a field of a bug or issue that changed.
+type Change struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Entry records one change cop made to a bug or a jira issue.
+type Entry struct {
+	// ID numbers entries in the order they were made, from 1.
+	ID   int       `json:"id"`
+	Time time.Time `json:"time"`
+	// Command is the command line that made the change.
+	Command string `json:"command"`
+	// Bug is the ID of the bug changed, or Issue the key of the jira issue.
+	Bug     int      `json:"bug,omitempty"`
+	Issue   string   `json:"issue,omitempty"`
+	Changes []Change `json:"changes"`
+	// Undoes is the ID of the entry this one reverted, if any.
+	Undoes int `json:"undoes,omitempty"`
+}
+
+// Journal is the journal file, one json entry per line.
+type Journal struct {
+	path string
+	mu   sync.Mutex
+}
+
+const (
+	// lockWait is how long Append waits for another cop to finish appending.
+	lockWait = 10 * time.Second
+	// staleLock is how old a lock file is when the cop that made it must have
+	// died without removing it.
+	staleLock = time.Minute
+)
+
+// Open returns the journal at path, which is created on the first Append.
+func Open(path string) *Journal {
+	return &Journal{path: path}
+}
+
+// Default returns the journal in cop's data directory.
+func Default() (*Journal, error) {
+	path, err := config.Path(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return Open(path), nil
+}
+
+// Entries reads every entry, oldest first.
+func (j *Journal) Entries() ([]Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.read()
+}
+
+func (j *Journal) read() ([]Entry, error) {
+	f, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	// comments can make for long lines
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("could not parse %s line %d: %v", j.path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Entry returns the entry with id.
+func (j *Journal) Entry(id int) (*Entry, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].ID == id {
+			return &entries[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no journal entry %d, see `cop history`", id)
+}
+
+// Append numbers e and adds it to the journal. Appends are serialized across
+// processes with a lock file, so that concurrent cops don't reuse IDs.
+func (j *Journal) Append(e *Entry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	unlock, err := j.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	entries, err := j.read()
+	if err != nil {
+		return err
+	}
+	e.ID = 1
+	if len(entries) > 0 {
+		e.ID = entries[len(entries)-1].ID + 1
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// lock takes the journal's lock file, waiting for another process to release
+// it, and returns a function that releases it.
+func (j *Journal) lock() (func(), error) {
+	path := j.path + ".lock"
+	deadline := time.Now().Add(lockWait)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLock {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("journal is locked by %s, remove it if no other cop is running", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// secretFlags are flags whose values are left out of recorded commands.
+var secretFlags = []string{"apikey", "token", "pass", "secret"}
+
+// CommandLine formats args, as in os.Args, to record as the command of an
+// entry, leaving out the values of flags for credentials.
+func CommandLine(args []string) string {
+	out := make([]string, 0, len(args))
+	redactNext := false
+	for i, arg := range args {
+		switch {
+		case i == 0:
+			arg = "cop"
+		case redactNext:
+			arg = "REDACTED"
+			redactNext = false
+		case len(arg) > 2 && (arg[:2] == "-k" || arg[:2] == "-p"):
+			// a short flag with its value attached
+			arg = arg[:2] + "REDACTED"
+		case strings.HasPrefix(arg, "-"):
+			name := strings.TrimLeft(arg, "-")
+			eq := strings.Index(name, "=")
+			if eq >= 0 {
+				name = name[:eq]
+			}
+			if !isSecret(name) {
+				break
+			}
+			if eq >= 0 {
+				arg = arg[:strings.Index(arg, "=")+1] + "REDACTED"
+			} else {
+				redactNext = true
+			}
+		}
+		out = append(out, arg)
+	}
+	return strings.Join(out, " ")
+}
+
+func isSecret(flag string) bool {
+	if flag == "k" || flag == "p" {
+		// the short forms of --bz-apikey and --jira-pass
+		return true
+	}
+	for _, s := range secretFlags {
+		if strings.Contains(flag, s) {
+			return true
+		}
+	}
+	return false
+}
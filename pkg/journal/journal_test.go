@@ -0,0 +1,295 @@
+package journal
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	gojira "gopkg.in/andygrunwald/go-jira.v1"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/jira"
+	"github.com/ecordell/cop/pkg/transport"
+)
+
+func testJournal(t *testing.T) (*Journal, func()) {
+	dir, err := ioutil.TempDir("", "cop-journal")
+	require.NoError(t, err)
+	return Open(filepath.Join(dir, "journal.log")), func() { os.RemoveAll(dir) }
+}
+
+func TestJournalRecordsAndUndoes(t *testing.T) {
+	j, cleanup := testJournal(t)
+	defer cleanup()
+	fake := &bugzilla.Fake{
+		Bugs: map[int]bugzilla.Bug{
+			1: {ID: 1, Status: "NEW", AssignedTo: "nobody@redhat.com", Priority: "unspecified", InternalWhiteboard: "backport-to: 4.4"},
+		},
+		BugErrors: map[int]bool{},
+	}
+	client := Wrap(fake, j, "cop bz triage")
+
+	whiteboard := "catalog"
+	require.NoError(t, client.UpdateBug(1, bugzilla.BugUpdate{
+		AssignedTo:    "catalog@redhat.com",
+		Priority:      "high",
+		Whiteboard:    &whiteboard,
+		SubComponents: map[string][]string{"OLM": {"OperatorHub"}},
+		Comment:       &bugzilla.BugComment{Body: "Triaged."},
+	}))
+	_, err := client.UpdateInternalWhiteboard(1, "backport-to: 4.3")
+	require.NoError(t, err)
+	// nothing changes, so nothing is recorded
+	_, err = client.UpdateInternalWhiteboard(1, "backport-to: 4.3")
+	require.NoError(t, err)
+
+	entries, err := j.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, 1, entries[0].ID)
+	require.Equal(t, "cop bz triage", entries[0].Command)
+	require.Equal(t, []Change{
+		{Field: FieldAssignedTo, Old: "nobody@redhat.com", New: "catalog@redhat.com"},
+		{Field: FieldPriority, Old: "unspecified", New: "high"},
+		{Field: FieldWhiteboard, Old: "", New: "catalog"},
+		{Field: FieldSubComponents, Old: "", New: `{"OLM":["OperatorHub"]}`},
+		{Field: FieldComment, Old: "", New: "Triaged."},
+	}, entries[0].Changes)
+	require.Equal(t, []Change{{Field: FieldInternalWhiteboard, Old: "backport-to: 4.4", New: "backport-to: 4.3"}}, entries[1].Changes)
+
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	undone, err := Undo(client, j, 1, "cop undo 1", now)
+	require.NoError(t, err)
+	require.Equal(t, 3, undone.Entry.ID)
+	require.Equal(t, 1, undone.Entry.Undoes)
+	require.Equal(t, []Change{{Field: FieldComment, Old: "", New: "Triaged."}}, undone.Skipped)
+	bug := fake.Bugs[1]
+	require.Equal(t, "nobody@redhat.com", bug.AssignedTo)
+	require.Equal(t, "unspecified", bug.Priority)
+	require.Equal(t, "", bug.Whiteboard)
+	require.Equal(t, "", subComponents(bug.SubComponents))
+	require.Equal(t, "backport-to: 4.3", bug.InternalWhiteboard)
+
+	// the undo is recorded once, and can't be repeated
+	entries, err = j.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	_, err = Undo(client, j, 1, "cop undo 1", now)
+	require.EqualError(t, err, "entry 1 was already undone by entry 3")
+
+	// someone else changed the internal whiteboard since
+	_, err = fake.UpdateInternalWhiteboard(1, "backport-to: 4.2")
+	require.NoError(t, err)
+	_, err = Undo(client, j, 2, "cop undo 2", now)
+	require.EqualError(t, err, `bug 1 has changed since entry 2: internal_whiteboard is "backport-to: 4.2", not "backport-to: 4.3"`)
+	require.Equal(t, "backport-to: 4.2", fake.Bugs[1].InternalWhiteboard)
+
+	_, err = Undo(client, j, 9, "cop undo 9", now)
+	require.Error(t, err)
+}
+
+func TestUndoStatus(t *testing.T) {
+	j, cleanup := testJournal(t)
+	defer cleanup()
+	fake := &bugzilla.Fake{
+		Bugs:      map[int]bugzilla.Bug{1: {ID: 1, Status: "NEW"}},
+		BugErrors: map[int]bool{},
+	}
+	client := Wrap(fake, j, "cop bz dupes 1 --mark-dupe-of 2")
+	require.NoError(t, client.UpdateBug(1, bugzilla.BugUpdate{Status: "CLOSED", Resolution: "DUPLICATE", DupeOf: 2}))
+	entry, err := j.Entry(1)
+	require.NoError(t, err)
+	require.Equal(t, []Change{
+		{Field: FieldStatus, Old: "NEW", New: "CLOSED"},
+		{Field: FieldResolution, Old: "", New: "DUPLICATE"},
+		{Field: FieldDupeOf, Old: "", New: "2"},
+	}, entry.Changes)
+
+	_, err = Undo(client, j, 1, "cop undo 1", time.Now())
+	require.NoError(t, err)
+	update := fake.Updates[len(fake.Updates)-1].Update
+	require.Equal(t, "NEW", update.Status)
+	require.Equal(t, "", update.Resolution)
+	require.Equal(t, 0, update.DupeOf)
+}
+
+func TestUndoRefusesDryRun(t *testing.T) {
+	j, cleanup := testJournal(t)
+	defer cleanup()
+	fake := &bugzilla.Fake{
+		Bugs:      map[int]bugzilla.Bug{1: {ID: 1, Status: "NEW"}},
+		BugErrors: map[int]bool{},
+	}
+	client := Wrap(fake, j, "cop bz reconcile --apply")
+	require.NoError(t, client.UpdateBug(1, bugzilla.BugUpdate{Status: "ASSIGNED"}))
+
+	dryRun = func() bool { return true }
+	defer func() { dryRun = transport.DryRun }()
+	_, err := Undo(client, j, 1, "cop undo 1 --dry-run", time.Now())
+	require.EqualError(t, err, "can't undo entry 1 in a dry run")
+	_, err = UndoIssue(context.Background(), nil, j, 1, "cop undo 1 --dry-run", time.Now())
+	require.Error(t, err)
+	require.Len(t, fake.Updates, 1)
+
+	// the entry can still be undone for real
+	entries, err := j.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	dryRun = transport.DryRun
+	_, err = Undo(client, j, 1, "cop undo 1", time.Now())
+	require.NoError(t, err)
+}
+
+func TestConcurrentAppends(t *testing.T) {
+	j, cleanup := testJournal(t)
+	defer cleanup()
+	// separate journals on the same file stand in for separate processes
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(bug int) {
+			defer wg.Done()
+			errs <- Open(j.path).Append(&Entry{Bug: bug, Changes: []Change{{Field: FieldStatus, Old: "NEW", New: "ASSIGNED"}}})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	entries, err := j.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 20)
+	for i, e := range entries {
+		require.Equal(t, i+1, e.ID)
+	}
+	_, err = os.Stat(j.path + ".lock")
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestCommandLine(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"/usr/bin/cop", "bz", "triage", "--dry-run"}, want: "cop bz triage --dry-run"},
+		{args: []string{"cop", "bz", "dupes", "-k", "s3cret", "1"}, want: "cop bz dupes -k REDACTED 1"},
+		{args: []string{"cop", "bz", "dupes", "-ks3cret", "1"}, want: "cop bz dupes -kREDACTED 1"},
+		{args: []string{"cop", "serve", "--bz-apikey=s3cret", "--webhook-secret", "hush"}, want: "cop serve --bz-apikey=REDACTED --webhook-secret REDACTED"},
+		{args: []string{"cop", "bz", "sync", "--jira-pass", "hunter2", "--set", "Release=4.4"}, want: "cop bz sync --jira-pass REDACTED --set Release=4.4"},
+	} {
+		require.Equal(t, tt.want, CommandLine(tt.args))
+	}
+}
+
+// fakeJira keeps issues in memory, for the methods the journal uses
+type fakeJira struct {
+	jira.Client
+	issues   map[string]*jira.Issue
+	comments []string
+}
+
+func (f *fakeJira) GetIssue(ctx context.Context, key string) (*jira.Issue, error) {
+	issue, ok := f.issues[key]
+	if !ok {
+		return nil, fmt.Errorf("no issue %s", key)
+	}
+	copied := *issue
+	fields := *issue.Fields
+	copied.Fields = &fields
+	return &copied, nil
+}
+
+func (f *fakeJira) AssignIssue(ctx context.Context, key, username string) error {
+	f.issues[key].Fields.Assignee = &jira.User{Name: username}
+	return nil
+}
+
+func (f *fakeJira) TransitionIssue(ctx context.Context, key, status string) error {
+	f.issues[key].Fields.Status = &gojira.Status{Name: status}
+	return nil
+}
+
+func (f *fakeJira) AddComment(ctx context.Context, key, body string) (*jira.Comment, error) {
+	f.comments = append(f.comments, body)
+	return &jira.Comment{Body: body}, nil
+}
+
+func (f *fakeJira) AddFixVersion(ctx context.Context, key, version string) error {
+	fields := f.issues[key].Fields
+	fields.FixVersions = append(fields.FixVersions, &gojira.FixVersion{Name: version})
+	return nil
+}
+
+func (f *fakeJira) RemoveFixVersion(ctx context.Context, key, version string) error {
+	fields := f.issues[key].Fields
+	var kept []*gojira.FixVersion
+	for _, v := range fields.FixVersions {
+		if v.Name != version {
+			kept = append(kept, v)
+		}
+	}
+	fields.FixVersions = kept
+	return nil
+}
+
+func TestJiraChanges(t *testing.T) {
+	j, cleanup := testJournal(t)
+	defer cleanup()
+	fake := &fakeJira{issues: map[string]*jira.Issue{
+		"OLM-1": {Key: "OLM-1", Fields: &jira.IssueFields{
+			Status:      &gojira.Status{Name: "To Do"},
+			FixVersions: []*gojira.FixVersion{{Name: "4.4"}},
+		}},
+	}}
+	client := WrapJira(fake, j, "cop jira transition OLM-1 In Progress")
+	ctx := context.Background()
+
+	require.NoError(t, client.TransitionIssue(ctx, "OLM-1", "In Progress"))
+	require.NoError(t, client.AssignIssue(ctx, "OLM-1", "me"))
+	require.NoError(t, client.AddFixVersion(ctx, "OLM-1", "4.5"))
+	_, err := client.AddComment(ctx, "OLM-1", "Started.")
+	require.NoError(t, err)
+
+	entries, err := j.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	require.Equal(t, "OLM-1", entries[0].Issue)
+	require.Equal(t, 0, entries[0].Bug)
+	require.Equal(t, []Change{{Field: FieldStatus, Old: "To Do", New: "In Progress"}}, entries[0].Changes)
+	require.Equal(t, []Change{{Field: FieldAssignedTo, Old: "", New: "me"}}, entries[1].Changes)
+	require.Equal(t, []Change{{Field: FieldFixVersions, Old: "4.4", New: "4.4, 4.5"}}, entries[2].Changes)
+	require.Equal(t, []Change{{Field: FieldComment, Old: "", New: "Started."}}, entries[3].Changes)
+
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	_, err = Undo(&bugzilla.Fake{}, j, 1, "cop undo 1", now)
+	require.EqualError(t, err, "entry 1 changed jira issue OLM-1, not a bug")
+	for _, id := range []int{1, 2, 3} {
+		undone, err := UndoIssue(ctx, client, j, id, "cop undo", now)
+		require.NoError(t, err)
+		require.Equal(t, id, undone.Entry.Undoes)
+		require.Equal(t, "OLM-1", undone.Entry.Issue)
+	}
+	issue := fake.issues["OLM-1"].Fields
+	require.Equal(t, "To Do", issue.Status.Name)
+	require.Equal(t, "", issue.Assignee.Name)
+	require.Len(t, issue.FixVersions, 1)
+	require.Equal(t, "4.4", issue.FixVersions[0].Name)
+
+	// comments stay, and so the entry has nothing to undo
+	_, err = UndoIssue(ctx, client, j, 4, "cop undo 4", now)
+	require.EqualError(t, err, "nothing in entry 4 can be undone")
+
+	// someone else moved the issue on since
+	require.NoError(t, client.TransitionIssue(ctx, "OLM-1", "In Progress"))
+	fake.issues["OLM-1"].Fields.Status.Name = "Done"
+	_, err = UndoIssue(ctx, client, j, 8, "cop undo 8", now)
+	require.EqualError(t, err, `OLM-1 has changed since entry 8: status is "Done", not "In Progress"`)
+}
@@ -0,0 +1,220 @@
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/jira"
+	"github.com/ecordell/cop/pkg/transport"
+)
+
+// undoable are the fields whose old values can be put back. Comments can't be
+// deleted, and flags and links to pull requests aren't reverted.
+var undoable = map[string]bool{
+	FieldStatus:             true,
+	FieldResolution:         true,
+	FieldDupeOf:             true,
+	FieldAssignedTo:         true,
+	FieldPriority:           true,
+	FieldSeverity:           true,
+	FieldWhiteboard:         true,
+	FieldInternalWhiteboard: true,
+	FieldSubComponents:      true,
+}
+
+// Undone is the outcome of Undo.
+type Undone struct {
+	// Entry records the undo in the journal.
+	Entry *Entry
+	// Skipped are the changes that can't be undone.
+	Skipped []Change
+}
+
+// dryRun reports whether changes are being skipped, in which case nothing
+// would be reverted but the entry would be recorded as undone.
+var dryRun = transport.DryRun
+
+// Undo reverts the entry with id by setting the old values of its fields
+// again, and records that in the journal as made by command. It refuses if
+// any of those fields has changed since, or if the entry was already undone.
+func Undo(c bugzilla.Client, j *Journal, id int, command string, now time.Time) (*Undone, error) {
+	if jc, ok := c.(*client); ok {
+		// the undo is recorded below, along with what it undoes
+		c = jc.Client
+	}
+	entry, err := j.undoable(id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Bug == 0 {
+		return nil, fmt.Errorf("entry %d changed jira issue %s, not a bug", id, entry.Issue)
+	}
+
+	bug, err := c.GetBug(entry.Bug)
+	if err != nil {
+		return nil, err
+	}
+	undone := &Undone{Entry: &Entry{Time: now.UTC(), Command: command, Bug: entry.Bug, Undoes: id}}
+	var (
+		update     bugzilla.BugUpdate
+		updated    bool
+		whiteboard *string
+	)
+	for _, ch := range entry.Changes {
+		if !undoable[ch.Field] {
+			undone.Skipped = append(undone.Skipped, ch)
+			continue
+		}
+		if current := value(bug, ch.Field); current != ch.New {
+			return nil, fmt.Errorf("bug %d has changed since entry %d: %s is %s, not %s", entry.Bug, id, ch.Field, quote(current), quote(ch.New))
+		}
+		undone.Entry.Changes = append(undone.Entry.Changes, Change{Field: ch.Field, Old: ch.New, New: ch.Old})
+		if ch.Field == FieldInternalWhiteboard {
+			old := ch.Old
+			whiteboard = &old
+			continue
+		}
+		if err := revert(&update, ch); err != nil {
+			return nil, fmt.Errorf("can't undo entry %d: %v", id, err)
+		}
+		updated = true
+	}
+	if len(undone.Entry.Changes) == 0 {
+		return nil, fmt.Errorf("nothing in entry %d can be undone", id)
+	}
+
+	if updated {
+		update.Comment = &bugzilla.BugComment{Body: fmt.Sprintf("Undoing a change made by %s.", entry.Command)}
+		if err := c.UpdateBug(entry.Bug, update); err != nil {
+			return nil, err
+		}
+	}
+	if whiteboard != nil {
+		if _, err := c.UpdateInternalWhiteboard(entry.Bug, *whiteboard); err != nil {
+			return nil, err
+		}
+	}
+	if err := j.Append(undone.Entry); err != nil {
+		return nil, fmt.Errorf("undid entry %d but could not record it in the journal: %v", id, err)
+	}
+	return undone, nil
+}
+
+// UndoIssue is Undo for an entry that changed a jira issue. Statuses,
+// assignees and fix versions are put back; jira may not allow moving the
+// issue back to its old status.
+func UndoIssue(ctx context.Context, c jira.Client, j *Journal, id int, command string, now time.Time) (*Undone, error) {
+	entry, err := j.undoable(id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Issue == "" {
+		return nil, fmt.Errorf("entry %d changed bug %d, not a jira issue", id, entry.Bug)
+	}
+	changes, skipped, err := undoIssue(ctx, c, entry)
+	if err != nil {
+		return nil, err
+	}
+	undone := &Undone{
+		Entry:   &Entry{Time: now.UTC(), Command: command, Issue: entry.Issue, Changes: changes, Undoes: id},
+		Skipped: skipped,
+	}
+	if err := j.Append(undone.Entry); err != nil {
+		return nil, fmt.Errorf("undid entry %d but could not record it in the journal: %v", id, err)
+	}
+	return undone, nil
+}
+
+// undoable returns the entry with id, unless it was already undone or this
+// is a dry run
+func (j *Journal) undoable(id int) (*Entry, error) {
+	if dryRun() {
+		return nil, fmt.Errorf("can't undo entry %d in a dry run", id)
+	}
+	entries, err := j.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var entry *Entry
+	for i := range entries {
+		switch {
+		case entries[i].ID == id:
+			entry = &entries[i]
+		case entries[i].Undoes == id:
+			return nil, fmt.Errorf("entry %d was already undone by entry %d", id, entries[i].ID)
+		}
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("no journal entry %d, see `cop history`", id)
+	}
+	return entry, nil
+}
+
+// revert sets the old value of a change on update
+func revert(update *bugzilla.BugUpdate, ch Change) error {
+	switch ch.Field {
+	case FieldStatus:
+		update.Status = ch.Old
+	case FieldResolution:
+		// an open bug has no resolution, reopening it clears the resolution
+		update.Resolution = ch.Old
+	case FieldDupeOf:
+		// likewise, reopening a duplicate clears what it duplicates
+		if ch.Old != "" {
+			dupeOf, err := strconv.Atoi(ch.Old)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q", ch.Field, ch.Old)
+			}
+			update.DupeOf = dupeOf
+		}
+	case FieldAssignedTo, FieldPriority, FieldSeverity:
+		if ch.Old == "" {
+			return fmt.Errorf("%s can't be set back to nothing", ch.Field)
+		}
+		switch ch.Field {
+		case FieldAssignedTo:
+			update.AssignedTo = ch.Old
+		case FieldPriority:
+			update.Priority = ch.Old
+		case FieldSeverity:
+			update.Severity = ch.Old
+		}
+	case FieldWhiteboard:
+		old := ch.Old
+		update.Whiteboard = &old
+	case FieldSubComponents:
+		update.SubComponents = map[string][]string{}
+		if ch.New != "" {
+			// clear the components that were set, in case they weren't before
+			var set map[string][]string
+			if err := json.Unmarshal([]byte(ch.New), &set); err != nil {
+				return fmt.Errorf("invalid %s %q", ch.Field, ch.New)
+			}
+			for component := range set {
+				update.SubComponents[component] = []string{}
+			}
+		}
+		if ch.Old != "" {
+			var old map[string][]string
+			if err := json.Unmarshal([]byte(ch.Old), &old); err != nil {
+				return fmt.Errorf("invalid %s %q", ch.Field, ch.Old)
+			}
+			for component, subs := range old {
+				update.SubComponents[component] = subs
+			}
+		}
+	}
+	return nil
+}
+
+func quote(s string) string {
+	if s == "" {
+		return "empty"
+	}
+	return strconv.Quote(strings.TrimSpace(s))
+}
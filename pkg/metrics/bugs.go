@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+)
+
+var (
+	bugsDesc = prometheus.NewDesc("cop_bugs", "Bugs matching a query.",
+		[]string{"query", "status", "severity", "target_release", "component"}, nil)
+	refreshedDesc = prometheus.NewDesc("cop_bugs_last_refresh_timestamp_seconds", "When the bugs of a query were last counted.",
+		[]string{"query"}, nil)
+)
+
+// bugLabels are the values of the labels of cop_bugs after the query
+type bugLabels struct {
+	status, severity, targetRelease, component string
+}
+
+// Bugs counts the bugs of bugzilla searches by status, severity, target
+// release and component, refreshing the counts on an interval. It is a
+// prometheus.Collector, so that a scrape sees the counts of one refresh.
+type Bugs struct {
+	client bugzilla.Client
+	// queries are the searches to count, by name
+	queries map[string]string
+	now     func() time.Time
+	logger  *logrus.Entry
+
+	mu sync.Mutex
+	// counts are the last counts of each query, kept when a refresh fails
+	counts map[string]map[bugLabels]float64
+	// refreshed are when each query was last counted
+	refreshed map[string]time.Time
+}
+
+// NewBugs returns the bug counts of queries, which are bugzilla search
+// queries by name, registered with r.
+func NewBugs(r prometheus.Registerer, client bugzilla.Client, queries map[string]string) *Bugs {
+	b := &Bugs{
+		client:    client,
+		queries:   queries,
+		now:       time.Now,
+		logger:    logrus.WithField("component", "metrics"),
+		counts:    map[string]map[bugLabels]float64{},
+		refreshed: map[string]time.Time{},
+	}
+	r.MustRegister(b)
+	return b
+}
+
+// Describe implements prometheus.Collector.
+func (b *Bugs) Describe(ch chan<- *prometheus.Desc) {
+	ch <- bugsDesc
+	ch <- refreshedDesc
+}
+
+// Collect implements prometheus.Collector.
+func (b *Bugs) Collect(ch chan<- prometheus.Metric) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for query, counts := range b.counts {
+		for l, n := range counts {
+			ch <- prometheus.MustNewConstMetric(bugsDesc, prometheus.GaugeValue, n, query, l.status, l.severity, l.targetRelease, l.component)
+		}
+	}
+	for query, t := range b.refreshed {
+		ch <- prometheus.MustNewConstMetric(refreshedDesc, prometheus.GaugeValue, float64(t.Unix()), query)
+	}
+}
+
+// Refresh counts the bugs of every query again. A query that fails keeps
+// its previous counts, and the first error is returned.
+func (b *Bugs) Refresh() error {
+	var firstErr error
+	for name, query := range b.queries {
+		logger := b.logger.WithField("query", name)
+		bugs, err := b.client.SearchBugs(query)
+		if err != nil {
+			logger.WithError(err).Warn("could not count bugs")
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		counts := map[bugLabels]float64{}
+		for _, bug := range bugs {
+			counts[bugLabels{
+				status:        bug.Status,
+				severity:      bug.Severity,
+				targetRelease: strings.Join(bug.TargetRelease, ","),
+				component:     strings.Join(bug.Component, ","),
+			}]++
+		}
+		b.mu.Lock()
+		b.counts[name] = counts
+		b.refreshed[name] = b.now()
+		b.mu.Unlock()
+		logger.WithField("bugs", len(bugs)).Debug("counted bugs")
+	}
+	return firstErr
+}
+
+// Run refreshes the counts now and then every interval until ctx is done.
+func (b *Bugs) Run(ctx context.Context, interval time.Duration) {
+	b.Refresh()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.Refresh()
+		}
+	}
+}
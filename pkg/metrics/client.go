@@ -0,0 +1,225 @@
+// Package metrics exports Prometheus metrics about bugs and the requests cop
+// makes for them.
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/jira"
+)
+
+// ClientMetrics track the requests cop makes to bugzilla and jira.
+type ClientMetrics struct {
+	// Duration is the latency of calls by client and method.
+	Duration *prometheus.HistogramVec
+	// Errors counts failed calls by client and method.
+	Errors *prometheus.CounterVec
+}
+
+// NewClientMetrics returns client metrics registered with r.
+func NewClientMetrics(r prometheus.Registerer) *ClientMetrics {
+	m := &ClientMetrics{
+		Duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "cop_client_request_duration_seconds",
+			Help:    "Latency of calls to bugzilla and jira.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"client", "method"}),
+		Errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cop_client_request_errors_total",
+			Help: "Calls to bugzilla and jira that failed.",
+		}, []string{"client", "method"}),
+	}
+	r.MustRegister(m.Duration, m.Errors)
+	return m
+}
+
+func (m *ClientMetrics) observe(client, method string, start time.Time, err error) {
+	m.Duration.WithLabelValues(client, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.Errors.WithLabelValues(client, method).Inc()
+	}
+}
+
+// InstrumentBugzilla returns a client that records every call to c in m.
+func InstrumentBugzilla(c bugzilla.Client, m *ClientMetrics) bugzilla.Client {
+	return &bugzillaClient{client: c, metrics: m}
+}
+
+type bugzillaClient struct {
+	client  bugzilla.Client
+	metrics *ClientMetrics
+}
+
+var _ bugzilla.Client = &bugzillaClient{}
+
+func (c *bugzillaClient) observe(method string, start time.Time, err error) {
+	c.metrics.observe("bugzilla", method, start, err)
+}
+
+func (c *bugzillaClient) Endpoint() string {
+	return c.client.Endpoint()
+}
+
+func (c *bugzillaClient) GetBug(id int) (*bugzilla.Bug, error) {
+	start := time.Now()
+	bug, err := c.client.GetBug(id)
+	c.observe("GetBug", start, err)
+	return bug, err
+}
+
+func (c *bugzillaClient) GetExternalBugPRsOnBug(id int) ([]bugzilla.GithubExternalBug, error) {
+	start := time.Now()
+	prs, err := c.client.GetExternalBugPRsOnBug(id)
+	c.observe("GetExternalBugPRsOnBug", start, err)
+	return prs, err
+}
+
+func (c *bugzillaClient) GetJiraIssueForBug(id int) ([]bugzilla.JiraExternalBug, error) {
+	start := time.Now()
+	issues, err := c.client.GetJiraIssueForBug(id)
+	c.observe("GetJiraIssueForBug", start, err)
+	return issues, err
+}
+
+func (c *bugzillaClient) SearchBugs(query string) ([]*bugzilla.Bug, error) {
+	start := time.Now()
+	bugs, err := c.client.SearchBugs(query)
+	c.observe("SearchBugs", start, err)
+	return bugs, err
+}
+
+func (c *bugzillaClient) UpdateInternalWhiteboard(id int, value string) (*bugzilla.Bug, error) {
+	start := time.Now()
+	bug, err := c.client.UpdateInternalWhiteboard(id, value)
+	c.observe("UpdateInternalWhiteboard", start, err)
+	return bug, err
+}
+
+func (c *bugzillaClient) GetCommentsOnBug(id int) ([]bugzilla.Comment, error) {
+	start := time.Now()
+	comments, err := c.client.GetCommentsOnBug(id)
+	c.observe("GetCommentsOnBug", start, err)
+	return comments, err
+}
+
+func (c *bugzillaClient) GetBugHistory(id int) ([]bugzilla.History, error) {
+	start := time.Now()
+	history, err := c.client.GetBugHistory(id)
+	c.observe("GetBugHistory", start, err)
+	return history, err
+}
+
+func (c *bugzillaClient) UpdateBug(id int, update bugzilla.BugUpdate) error {
+	start := time.Now()
+	err := c.client.UpdateBug(id, update)
+	c.observe("UpdateBug", start, err)
+	return err
+}
+
+func (c *bugzillaClient) AddPullRequestAsExternalBug(id int, org, repo string, num int) (bool, error) {
+	start := time.Now()
+	changed, err := c.client.AddPullRequestAsExternalBug(id, org, repo, num)
+	c.observe("AddPullRequestAsExternalBug", start, err)
+	return changed, err
+}
+
+// InstrumentJira returns a client that records every call to c in m.
+func InstrumentJira(c jira.Client, m *ClientMetrics) jira.Client {
+	return &jiraClient{client: c, metrics: m}
+}
+
+type jiraClient struct {
+	client  jira.Client
+	metrics *ClientMetrics
+}
+
+var _ jira.Client = &jiraClient{}
+
+func (c *jiraClient) observe(method string, start time.Time, err error) {
+	c.metrics.observe("jira", method, start, err)
+}
+
+func (c *jiraClient) Endpoint() string {
+	return c.client.Endpoint()
+}
+
+func (c *jiraClient) GetIssue(ctx context.Context, key string) (*jira.Issue, error) {
+	start := time.Now()
+	issue, err := c.client.GetIssue(ctx, key)
+	c.observe("GetIssue", start, err)
+	return issue, err
+}
+
+func (c *jiraClient) SearchIssues(ctx context.Context, jql string) ([]jira.Issue, error) {
+	start := time.Now()
+	issues, err := c.client.SearchIssues(ctx, jql)
+	c.observe("SearchIssues", start, err)
+	return issues, err
+}
+
+func (c *jiraClient) CreateIssue(ctx context.Context, issue *jira.Issue) (*jira.Issue, error) {
+	start := time.Now()
+	created, err := c.client.CreateIssue(ctx, issue)
+	c.observe("CreateIssue", start, err)
+	return created, err
+}
+
+func (c *jiraClient) UpdateIssue(ctx context.Context, key string, update jira.IssueUpdate) error {
+	start := time.Now()
+	err := c.client.UpdateIssue(ctx, key, update)
+	c.observe("UpdateIssue", start, err)
+	return err
+}
+
+func (c *jiraClient) AssignIssue(ctx context.Context, key, username string) error {
+	start := time.Now()
+	err := c.client.AssignIssue(ctx, key, username)
+	c.observe("AssignIssue", start, err)
+	return err
+}
+
+func (c *jiraClient) TransitionIssue(ctx context.Context, key, status string) error {
+	start := time.Now()
+	err := c.client.TransitionIssue(ctx, key, status)
+	c.observe("TransitionIssue", start, err)
+	return err
+}
+
+func (c *jiraClient) AddComment(ctx context.Context, key, body string) (*jira.Comment, error) {
+	start := time.Now()
+	comment, err := c.client.AddComment(ctx, key, body)
+	c.observe("AddComment", start, err)
+	return comment, err
+}
+
+func (c *jiraClient) GetRemoteLinks(ctx context.Context, key string) ([]jira.RemoteLink, error) {
+	start := time.Now()
+	links, err := c.client.GetRemoteLinks(ctx, key)
+	c.observe("GetRemoteLinks", start, err)
+	return links, err
+}
+
+func (c *jiraClient) AddRemoteLink(ctx context.Context, key string, link jira.RemoteLink) error {
+	start := time.Now()
+	err := c.client.AddRemoteLink(ctx, key, link)
+	c.observe("AddRemoteLink", start, err)
+	return err
+}
+
+func (c *jiraClient) AddFixVersion(ctx context.Context, key, version string) error {
+	start := time.Now()
+	err := c.client.AddFixVersion(ctx, key, version)
+	c.observe("AddFixVersion", start, err)
+	return err
+}
+
+func (c *jiraClient) RemoveFixVersion(ctx context.Context, key, version string) error {
+	start := time.Now()
+	err := c.client.RemoveFixVersion(ctx, key, version)
+	c.observe("RemoveFixVersion", start, err)
+	return err
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/jira"
+)
+
+// requests is how many calls to method of client were observed
+func requests(t *testing.T, r prometheus.Gatherer, client, method string) uint64 {
+	families, err := r.Gather()
+	require.NoError(t, err)
+	for _, f := range families {
+		if f.GetName() != "cop_client_request_duration_seconds" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["client"] == client && labels["method"] == method {
+				return m.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+	return 0
+}
+
+func TestBugs(t *testing.T) {
+	client := &bugzilla.Fake{
+		Bugs: map[int]bugzilla.Bug{
+			1: {ID: 1, Status: "NEW", Severity: "high", TargetRelease: []string{"4.5.0"}, Component: []string{"OLM"}},
+			2: {ID: 2, Status: "NEW", Severity: "high", TargetRelease: []string{"4.5.0"}, Component: []string{"OLM"}},
+			3: {ID: 3, Status: "POST", Severity: "low", TargetRelease: []string{"---"}, Component: []string{"OLM"}},
+		},
+		BugErrors:     map[int]bool{},
+		SearchResults: map[string][]int{"open": {1, 2, 3}, "mine": {3}},
+	}
+	r := prometheus.NewRegistry()
+	m := NewClientMetrics(r)
+	b := NewBugs(r, InstrumentBugzilla(client, m), map[string]string{"open": "open", "mine": "mine"})
+	b.now = func() time.Time { return time.Unix(1600000000, 0) }
+	require.NoError(t, b.Refresh())
+
+	require.NoError(t, testutil.GatherAndCompare(r, strings.NewReader(`
+# HELP cop_bugs Bugs matching a query.
+# TYPE cop_bugs gauge
+cop_bugs{component="OLM",query="mine",severity="low",status="POST",target_release="---"} 1
+cop_bugs{component="OLM",query="open",severity="high",status="NEW",target_release="4.5.0"} 2
+cop_bugs{component="OLM",query="open",severity="low",status="POST",target_release="---"} 1
+# HELP cop_bugs_last_refresh_timestamp_seconds When the bugs of a query were last counted.
+# TYPE cop_bugs_last_refresh_timestamp_seconds gauge
+cop_bugs_last_refresh_timestamp_seconds{query="mine"} 1.6e+09
+cop_bugs_last_refresh_timestamp_seconds{query="open"} 1.6e+09
+`), "cop_bugs", "cop_bugs_last_refresh_timestamp_seconds"))
+	require.Equal(t, uint64(2), requests(t, r, "bugzilla", "SearchBugs"))
+	require.NoError(t, testutil.GatherAndCompare(r, strings.NewReader(""), "cop_client_request_errors_total"))
+
+	// a bug moving on changes the counts, and the old series goes away
+	client.Bugs[3] = bugzilla.Bug{ID: 3, Status: "MODIFIED", Severity: "low", TargetRelease: []string{"---"}, Component: []string{"OLM"}}
+	require.NoError(t, b.Refresh())
+	require.NoError(t, testutil.GatherAndCompare(r, strings.NewReader(`
+# HELP cop_bugs Bugs matching a query.
+# TYPE cop_bugs gauge
+cop_bugs{component="OLM",query="mine",severity="low",status="MODIFIED",target_release="---"} 1
+cop_bugs{component="OLM",query="open",severity="high",status="NEW",target_release="4.5.0"} 2
+cop_bugs{component="OLM",query="open",severity="low",status="MODIFIED",target_release="---"} 1
+`), "cop_bugs"))
+}
+
+func TestInstrumentBugzillaCountsErrors(t *testing.T) {
+	r := prometheus.NewRegistry()
+	m := NewClientMetrics(r)
+	client := InstrumentBugzilla(&bugzilla.Fake{BugErrors: map[int]bool{1: true}}, m)
+	_, err := client.GetBug(1)
+	require.Error(t, err)
+	require.Equal(t, float64(1), testutil.ToFloat64(m.Errors.WithLabelValues("bugzilla", "GetBug")))
+}
+
+// failingJira fails every call
+type failingJira struct {
+	jira.Client
+}
+
+func (failingJira) GetIssue(ctx context.Context, key string) (*jira.Issue, error) {
+	return nil, errors.New("jira is down")
+}
+
+func TestInstrumentJira(t *testing.T) {
+	r := prometheus.NewRegistry()
+	m := NewClientMetrics(r)
+	client := InstrumentJira(failingJira{}, m)
+	_, err := client.GetIssue(context.Background(), "OLM-1")
+	require.Error(t, err)
+	require.Equal(t, uint64(1), requests(t, r, "jira", "GetIssue"))
+	require.Equal(t, float64(1), testutil.ToFloat64(m.Errors.WithLabelValues("jira", "GetIssue")))
+}
@@ -0,0 +1,214 @@
+// Package notify delivers bug change events to places other than a terminal.
+//
+// Notifications are configured per saved query in the cop config:
+//
+//	queries:
+//	  mine:
+//	    params: ...
+//	    notify:
+//	      digest: 15m
+//	      max_per_hour: 4
+//	      sinks:
+//	      - slack:
+//	          webhook: https://hooks.slack.com/services/...
+//	      - webhook:
+//	          url: https://example.com/hook
+//	          body: '{"text": {{json .Title}}}'
+//	      - email:
+//	          server: smtp.example.com:587
+//	          from: cop@example.com
+//	          to: [team@example.com]
+//	      - desktop: {}
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/config"
+	"github.com/ecordell/cop/pkg/watch"
+)
+
+// Notification is a batch of events sent to each sink.
+type Notification struct {
+	Title  string        `json:"title"`
+	Events []watch.Event `json:"events"`
+	// Endpoint is the bugzilla the events came from, for linking to bugs.
+	Endpoint string `json:"-"`
+}
+
+// BugURL links to a bug in the notification.
+func (n Notification) BugURL(id int) string {
+	return bugzilla.BugURL(n.Endpoint, id)
+}
+
+// Text renders the notification as plain text, one event per line.
+func (n Notification) Text() string {
+	lines := []string{n.Title, ""}
+	for _, e := range n.Events {
+		lines = append(lines, fmt.Sprintf("- %s\n  %s", e, n.BugURL(e.Bug)))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Sink delivers notifications somewhere.
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, n Notification) error
+}
+
+// NewSink builds the sink described by cfg.
+func NewSink(cfg config.SinkConfig) (Sink, error) {
+	var sinks []Sink
+	if cfg.Slack != nil {
+		sinks = append(sinks, NewSlackSink(*cfg.Slack))
+	}
+	if cfg.Webhook != nil {
+		s, err := NewWebhookSink(*cfg.Webhook)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	if cfg.Email != nil {
+		sinks = append(sinks, NewEmailSink(*cfg.Email))
+	}
+	if cfg.Desktop != nil {
+		sinks = append(sinks, NewDesktopSink(*cfg.Desktop))
+	}
+	if len(sinks) != 1 {
+		return nil, fmt.Errorf("each notification sink must set exactly one of slack, webhook, email or desktop")
+	}
+	return sinks[0], nil
+}
+
+// Notifier batches events and sends them to its sinks, respecting the
+// configured digest period and rate limit.
+type Notifier struct {
+	title    string
+	endpoint string
+	sinks    []Sink
+	digest   time.Duration
+	limit    int
+
+	mu      sync.Mutex
+	pending []watch.Event
+	// unsent are the events each sink failed to send, by sink index, to be
+	// retried with its next notification
+	unsent map[int][]watch.Event
+	// sent are the times of notifications in the last hour
+	sent     []time.Time
+	lastSent time.Time
+	now      func() time.Time
+}
+
+// New returns a notifier for events from endpoint, titling notifications
+// with title.
+func New(cfg config.NotifyConfig, title, endpoint string) (*Notifier, error) {
+	n := &Notifier{
+		title:    title,
+		endpoint: endpoint,
+		digest:   cfg.Digest,
+		limit:    cfg.MaxPerHour,
+		now:      time.Now,
+	}
+	for _, c := range cfg.Sinks {
+		s, err := NewSink(c)
+		if err != nil {
+			return nil, err
+		}
+		n.sinks = append(n.sinks, s)
+	}
+	if len(n.sinks) == 0 {
+		return nil, fmt.Errorf("no notification sinks configured")
+	}
+	return n, nil
+}
+
+// Add queues events for the next notification.
+func (n *Notifier) Add(events ...watch.Event) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.pending = append(n.pending, events...)
+}
+
+// Flush sends the queued events if the digest period has passed and the
+// rate limit allows it, or regardless of both if force is set. Every sink is
+// tried; the first failure is returned. The events a sink fails to send are
+// kept for it and sent again with its next notification, and a flush that
+// no sink could send doesn't count towards the digest period or rate limit.
+func (n *Notifier) Flush(ctx context.Context, force bool) error {
+	n.mu.Lock()
+	now := n.now()
+	if (len(n.pending) == 0 && len(n.unsent) == 0) || (!force && !n.due(now)) {
+		n.mu.Unlock()
+		return nil
+	}
+	batches := make([][]watch.Event, len(n.sinks))
+	for i := range n.sinks {
+		batches[i] = append(append([]watch.Event{}, n.unsent[i]...), n.pending...)
+	}
+	n.pending = nil
+	n.unsent = nil
+	n.mu.Unlock()
+
+	unsent := map[int][]watch.Event{}
+	sent := false
+	var firstErr error
+	for i, s := range n.sinks {
+		events := batches[i]
+		if len(events) == 0 {
+			continue
+		}
+		if err := s.Send(ctx, n.notification(events)); err != nil {
+			unsent[i] = events
+			if firstErr == nil {
+				firstErr = fmt.Errorf("could not notify %s: %v", s.Name(), err)
+			}
+			continue
+		}
+		sent = true
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(unsent) > 0 {
+		n.unsent = unsent
+	}
+	if sent {
+		n.lastSent = now
+		n.sent = append(n.sent, now)
+	}
+	return firstErr
+}
+
+// notification titles a batch of events
+func (n *Notifier) notification(events []watch.Event) Notification {
+	title := n.title
+	if len(events) > 1 {
+		title = fmt.Sprintf("%s: %d changes", n.title, len(events))
+	}
+	return Notification{Title: title, Events: events, Endpoint: n.endpoint}
+}
+
+// due reports whether a notification may be sent now
+func (n *Notifier) due(now time.Time) bool {
+	if n.digest > 0 && now.Sub(n.lastSent) < n.digest {
+		return false
+	}
+	if n.limit <= 0 {
+		return true
+	}
+	recent := n.sent[:0]
+	for _, t := range n.sent {
+		if now.Sub(t) < time.Hour {
+			recent = append(recent, t)
+		}
+	}
+	n.sent = recent
+	return len(n.sent) < n.limit
+}
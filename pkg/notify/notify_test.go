@@ -0,0 +1,266 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ecordell/cop/pkg/config"
+	"github.com/ecordell/cop/pkg/watch"
+)
+
+var testEvents = []watch.Event{
+	{Time: "2020-03-01T12:00:00Z", Bug: 1, Summary: "catalog <crashes>", Kind: watch.KindChanged, Field: "status", From: "NEW", To: "ASSIGNED"},
+	{Time: "2020-03-01T12:05:00Z", Bug: 2, Summary: "proxy ignored", Kind: watch.KindComment, Who: "qe@redhat.com"},
+}
+
+// recordSink keeps what it was sent
+type recordSink struct {
+	sent []Notification
+}
+
+func (s *recordSink) Name() string {
+	return "record"
+}
+
+func (s *recordSink) Send(ctx context.Context, n Notification) error {
+	s.sent = append(s.sent, n)
+	return nil
+}
+
+func TestNotifierDigestAndRateLimit(t *testing.T) {
+	sink := &recordSink{}
+	now := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	n := &Notifier{title: "@mine", sinks: []Sink{sink}, digest: 10 * time.Minute, limit: 2, now: func() time.Time { return now }}
+	ctx := context.Background()
+
+	// the first events go out straight away
+	n.Add(testEvents[0])
+	require.NoError(t, n.Flush(ctx, false))
+	require.Len(t, sink.sent, 1)
+	require.Equal(t, "@mine", sink.sent[0].Title)
+
+	// later ones wait for the digest period
+	n.Add(testEvents[1])
+	now = now.Add(5 * time.Minute)
+	require.NoError(t, n.Flush(ctx, false))
+	require.Len(t, sink.sent, 1)
+	n.Add(testEvents[0])
+	now = now.Add(5 * time.Minute)
+	require.NoError(t, n.Flush(ctx, false))
+	require.Len(t, sink.sent, 2)
+	require.Equal(t, "@mine: 2 changes", sink.sent[1].Title)
+	require.Len(t, sink.sent[1].Events, 2)
+
+	// two an hour is the limit, so the next batch is held back
+	n.Add(testEvents[1])
+	now = now.Add(20 * time.Minute)
+	require.NoError(t, n.Flush(ctx, false))
+	require.Len(t, sink.sent, 2)
+	now = now.Add(31 * time.Minute)
+	require.NoError(t, n.Flush(ctx, false))
+	require.Len(t, sink.sent, 3)
+
+	// forcing sends whatever is left regardless
+	n.Add(testEvents[0])
+	require.NoError(t, n.Flush(ctx, true))
+	require.Len(t, sink.sent, 4)
+	require.NoError(t, n.Flush(ctx, true))
+	require.Len(t, sink.sent, 4)
+}
+
+// failingSink fails while fail is set
+type failingSink struct {
+	recordSink
+	fail bool
+}
+
+func (s *failingSink) Send(ctx context.Context, n Notification) error {
+	if s.fail {
+		return fmt.Errorf("unreachable")
+	}
+	return s.recordSink.Send(ctx, n)
+}
+
+func TestNotifierRetriesFailedSends(t *testing.T) {
+	ok := &recordSink{}
+	failing := &failingSink{fail: true}
+	now := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	n := &Notifier{title: "@mine", sinks: []Sink{ok, failing}, limit: 1, now: func() time.Time { return now }}
+	ctx := context.Background()
+
+	// the sink that works gets the events, the other keeps them
+	n.Add(testEvents[0])
+	require.EqualError(t, n.Flush(ctx, false), "could not notify record: unreachable")
+	require.Len(t, ok.sent, 1)
+	require.Empty(t, failing.sent)
+
+	// once it recovers it gets them with the next events, and the other
+	// sink only gets what is new
+	failing.fail = false
+	n.Add(testEvents[1])
+	now = now.Add(time.Hour)
+	require.NoError(t, n.Flush(ctx, false))
+	require.Len(t, ok.sent, 2)
+	require.Equal(t, testEvents[1:], ok.sent[1].Events)
+	require.Len(t, failing.sent, 1)
+	require.Equal(t, testEvents, failing.sent[0].Events)
+
+	// a flush no sink could send doesn't use up the rate limit
+	lone := &failingSink{fail: true}
+	n = &Notifier{title: "@mine", sinks: []Sink{lone}, limit: 1, now: func() time.Time { return now }}
+	n.Add(testEvents[0])
+	require.Error(t, n.Flush(ctx, false))
+	lone.fail = false
+	require.NoError(t, n.Flush(ctx, false))
+	require.Len(t, lone.sent, 1)
+	require.Equal(t, testEvents[:1], lone.sent[0].Events)
+}
+
+func TestSlackAndWebhookSinks(t *testing.T) {
+	var bodies []string
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		auth = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+	n := Notification{Title: "@mine: 2 changes", Events: testEvents, Endpoint: "https://bugzilla.example.com"}
+	ctx := context.Background()
+
+	require.NoError(t, NewSlackSink(config.SlackConfig{Webhook: server.URL}).Send(ctx, n))
+	var slack map[string]string
+	require.NoError(t, json.Unmarshal([]byte(bodies[0]), &slack))
+	require.Equal(t, "*@mine: 2 changes*\n"+
+		"• <https://bugzilla.example.com/show_bug.cgi?id=1|Bug 1: status NEW→ASSIGNED (catalog &lt;crashes&gt;)>\n"+
+		"• <https://bugzilla.example.com/show_bug.cgi?id=2|Bug 2: new comment by qe@redhat.com (proxy ignored)>", slack["text"])
+
+	webhook, err := NewWebhookSink(config.WebhookConfig{
+		URL:     server.URL,
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+		Body:    `{"summary": {{json .Title}}, "bugs": [{{range $i, $e := .Events}}{{if $i}}, {{end}}{{$e.Bug}}{{end}}]}`,
+	})
+	require.NoError(t, err)
+	require.NoError(t, webhook.Send(ctx, n))
+	require.JSONEq(t, `{"summary": "@mine: 2 changes", "bugs": [1, 2]}`, bodies[1])
+	require.Equal(t, "Bearer secret", auth)
+
+	plain, err := NewWebhookSink(config.WebhookConfig{URL: server.URL})
+	require.NoError(t, err)
+	require.NoError(t, plain.Send(ctx, n))
+	var decoded Notification
+	require.NoError(t, json.Unmarshal([]byte(bodies[2]), &decoded))
+	require.Equal(t, testEvents, decoded.Events)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such hook", http.StatusNotFound)
+	}))
+	defer failing.Close()
+	require.EqualError(t, NewSlackSink(config.SlackConfig{Webhook: failing.URL}).Send(ctx, n), "response code 404: no such hook")
+}
+
+// smtpStandIn accepts one message over a minimal SMTP conversation and
+// returns what it was sent.
+func smtpStandIn(t *testing.T) (string, <-chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	received := make(chan string, 1)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) {
+			conn.Write([]byte(s + "\r\n"))
+		}
+		reply("220 localhost stand-in")
+		var data []string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.Fields(line + " x")[0])
+			switch cmd {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "DATA":
+				reply("354 go ahead")
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data = append(data, l)
+				}
+				received <- strings.Join(data, "")
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return l.Addr().String(), received
+}
+
+func TestEmailSink(t *testing.T) {
+	addr, received := smtpStandIn(t)
+	sink := NewEmailSink(config.EmailConfig{Server: addr, From: "cop@example.com", To: []string{"team@example.com"}})
+	n := Notification{Title: "@mine: 2 changes", Events: testEvents, Endpoint: "https://bugzilla.example.com"}
+	require.NoError(t, sink.Send(context.Background(), n))
+
+	msg := <-received
+	require.Contains(t, msg, "Subject: @mine: 2 changes\r\n")
+	require.Contains(t, msg, "To: team@example.com\r\n")
+	require.Contains(t, msg, "- Bug 2: new comment by qe@redhat.com (proxy ignored)\r\n  https://bugzilla.example.com/show_bug.cgi?id=2\r\n")
+}
+
+func TestEmailSinkGivesUp(t *testing.T) {
+	// a server that accepts connections but never answers
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			defer conn.Close()
+			ioutil.ReadAll(conn)
+		}
+	}()
+	sink := NewEmailSink(config.EmailConfig{Server: l.Addr().String(), From: "cop@example.com", To: []string{"team@example.com"}})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	require.Error(t, sink.Send(ctx, Notification{Title: "@mine", Events: testEvents}))
+	require.True(t, time.Since(start) < 5*time.Second)
+}
+
+func TestNewSink(t *testing.T) {
+	_, err := NewSink(config.SinkConfig{})
+	require.Error(t, err)
+	_, err = NewSink(config.SinkConfig{Slack: &config.SlackConfig{}, Desktop: &config.DesktopConfig{}})
+	require.Error(t, err)
+	s, err := NewSink(config.SinkConfig{Desktop: &config.DesktopConfig{}})
+	require.NoError(t, err)
+	require.Equal(t, "desktop", s.Name())
+}
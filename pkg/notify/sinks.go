@@ -0,0 +1,252 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os/exec"
+	"runtime"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ecordell/cop/pkg/config"
+)
+
+// SlackSink posts notifications to a Slack incoming webhook.
+type SlackSink struct {
+	webhook string
+	client  *http.Client
+}
+
+func NewSlackSink(cfg config.SlackConfig) *SlackSink {
+	return &SlackSink{webhook: cfg.Webhook, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (s *SlackSink) Name() string {
+	return "slack"
+}
+
+func (s *SlackSink) Send(ctx context.Context, n Notification) error {
+	lines := []string{fmt.Sprintf("*%s*", n.Title)}
+	for _, e := range n.Events {
+		lines = append(lines, fmt.Sprintf("• <%s|%s>", n.BugURL(e.Bug), slackEscape(e.String())))
+	}
+	body, err := json.Marshal(map[string]string{"text": strings.Join(lines, "\n")})
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.client, s.webhook, nil, body)
+}
+
+// slackEscape escapes the characters slack treats as markup
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// WebhookSink posts notifications to a url.
+type WebhookSink struct {
+	url     string
+	headers map[string]string
+	body    *template.Template
+	client  *http.Client
+}
+
+func NewWebhookSink(cfg config.WebhookConfig) (*WebhookSink, error) {
+	s := &WebhookSink{url: cfg.URL, headers: cfg.Headers, client: &http.Client{Timeout: 30 * time.Second}}
+	if cfg.Body != "" {
+		t, err := template.New("body").Funcs(template.FuncMap{"json": toJSON}).Parse(cfg.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook body template: %v", err)
+		}
+		s.body = t
+	}
+	return s, nil
+}
+
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+func (s *WebhookSink) Name() string {
+	return "webhook"
+}
+
+func (s *WebhookSink) Send(ctx context.Context, n Notification) error {
+	var body []byte
+	if s.body == nil {
+		data, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+		body = data
+	} else {
+		var buf bytes.Buffer
+		if err := s.body.Execute(&buf, n); err != nil {
+			return fmt.Errorf("could not render webhook body: %v", err)
+		}
+		body = buf.Bytes()
+	}
+	return post(ctx, s.client, s.url, s.headers, body)
+}
+
+func post(ctx context.Context, client *http.Client, url string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("response code %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// EmailSink sends notifications as plain text email.
+type EmailSink struct {
+	cfg config.EmailConfig
+}
+
+func NewEmailSink(cfg config.EmailConfig) *EmailSink {
+	return &EmailSink{cfg: cfg}
+}
+
+func (s *EmailSink) Name() string {
+	return "email"
+}
+
+func (s *EmailSink) Send(ctx context.Context, n Notification) error {
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		host := strings.Split(s.cfg.Server, ":")[0]
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, host)
+	}
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", n.Title)
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.Replace(n.Text(), "\n", "\r\n", -1))
+	return sendMail(ctx, s.cfg.Server, auth, s.cfg.From, s.cfg.To, msg.Bytes())
+}
+
+// emailTimeout bounds a whole SMTP conversation, like the http sinks' client timeout
+const emailTimeout = 30 * time.Second
+
+// sendMail is smtp.SendMail, giving up when ctx is done or after emailTimeout.
+func sendMail(ctx context.Context, server string, auth smtp.Auth, from string, to []string, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, emailTimeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	// an interrupt unblocks the conversation too
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	host, _, err := net.SplitHostPort(server)
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// DesktopSink shows notifications with the desktop's notification service.
+type DesktopSink struct {
+	run func(name string, args ...string) error
+}
+
+func NewDesktopSink(config.DesktopConfig) *DesktopSink {
+	return &DesktopSink{run: func(name string, args ...string) error {
+		return exec.Command(name, args...).Run()
+	}}
+}
+
+func (s *DesktopSink) Name() string {
+	return "desktop"
+}
+
+func (s *DesktopSink) Send(ctx context.Context, n Notification) error {
+	var lines []string
+	for _, e := range n.Events {
+		lines = append(lines, e.String())
+	}
+	body := strings.Join(lines, "\n")
+	switch runtime.GOOS {
+	case "linux":
+		return s.run("notify-send", "--app-name=cop", n.Title, body)
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(body), appleScriptString(n.Title))
+		return s.run("osascript", "-e", script)
+	default:
+		return fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+	}
+}
+
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
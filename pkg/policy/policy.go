@@ -0,0 +1,373 @@
+// Package policy checks bugs against declarative SLA rules, like "urgent
+// bugs must be ASSIGNED within a day", and works out how to remediate them.
+//
+// A policy is a yaml file of rules. Every open bug a rule matches violates it:
+//
+//	rules:
+//	- name: urgent-unassigned
+//	  description: urgent bugs must be ASSIGNED within 1 day
+//	  match:
+//	    severity: [urgent]
+//	    status: [NEW]
+//	    older_than: 1d
+//	  remediate:
+//	    comment: "This urgent bug has been NEW for {{.Age}}, please triage it."
+//	    needinfo_assignee: true
+//	- name: urgent-without-pr
+//	  description: urgent bugs must have a PR within 7 days
+//	  match:
+//	    severity: [urgent]
+//	    status_before: POST
+//	    older_than: 7d
+//
+// All the conditions of a match must hold. Keywords and flags may be negated
+// with a leading !, and flags are a name with an optional status, e.g.
+// blocker+ or !needinfo?. Both need quoting in yaml:
+//
+//	flags: ["blocker?", "!needinfo"]
+package policy
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/view"
+	"github.com/ecordell/cop/pkg/workflow"
+)
+
+// Policy is a set of rules bugs must follow.
+type Policy struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+// Rule is a condition that no bug should meet.
+type Rule struct {
+	Name        string       `yaml:"name"`
+	Description string       `yaml:"description,omitempty"`
+	Match       Match        `yaml:"match"`
+	Remediate   *Remediation `yaml:"remediate,omitempty"`
+
+	olderThan    time.Duration
+	unchangedFor time.Duration
+	comment      *template.Template
+}
+
+// Match selects the bugs violating a rule.
+type Match struct {
+	Severity []string `yaml:"severity,omitempty"`
+	Priority []string `yaml:"priority,omitempty"`
+	Status   []string `yaml:"status,omitempty"`
+	// StatusBefore matches bugs earlier in the workflow than a status.
+	StatusBefore string   `yaml:"status_before,omitempty"`
+	Keywords     []string `yaml:"keywords,omitempty"`
+	Flags        []string `yaml:"flags,omitempty"`
+	// OlderThan matches bugs filed longer ago than a duration like 7d.
+	OlderThan string `yaml:"older_than,omitempty"`
+	// UnchangedFor matches bugs last changed longer ago than a duration.
+	UnchangedFor string `yaml:"unchanged_for,omitempty"`
+}
+
+// Remediation is what is done about a violation when remediation is applied.
+type Remediation struct {
+	// Comment is a text/template rendered with the Violation.
+	Comment string `yaml:"comment,omitempty"`
+	// NeedinfoAssignee requests needinfo from the bug's assignee.
+	NeedinfoAssignee bool `yaml:"needinfo_assignee,omitempty"`
+}
+
+// Load reads a policy file.
+func Load(path string) (*Policy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %v", path, err)
+	}
+	return p, nil
+}
+
+// Parse parses and validates a policy.
+func Parse(data []byte) (*Policy, error) {
+	p := &Policy{}
+	if err := yaml.UnmarshalStrict(data, p); err != nil {
+		return nil, err
+	}
+	if len(p.Rules) == 0 {
+		return nil, fmt.Errorf("no rules")
+	}
+	names := map[string]bool{}
+	for i, r := range p.Rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("rule %d has no name", i+1)
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("rule %s is defined twice", r.Name)
+		}
+		names[r.Name] = true
+		if err := r.compile(); err != nil {
+			return nil, fmt.Errorf("rule %s: %v", r.Name, err)
+		}
+	}
+	return p, nil
+}
+
+func (r *Rule) compile() error {
+	var err error
+	if r.Match.OlderThan != "" {
+		if r.olderThan, err = view.ParseAge(r.Match.OlderThan); err != nil {
+			return fmt.Errorf("invalid older_than: %v", err)
+		}
+	}
+	if r.Match.UnchangedFor != "" {
+		if r.unchangedFor, err = view.ParseAge(r.Match.UnchangedFor); err != nil {
+			return fmt.Errorf("invalid unchanged_for: %v", err)
+		}
+	}
+	if s := r.Match.StatusBefore; s != "" && !workflow.Before(workflow.StatusNew, s) {
+		return fmt.Errorf("invalid status_before %q", s)
+	}
+	if r.Remediate != nil && r.Remediate.Comment != "" {
+		if r.comment, err = template.New(r.Name).Parse(r.Remediate.Comment); err != nil {
+			return fmt.Errorf("invalid comment: %v", err)
+		}
+	}
+	return nil
+}
+
+// Violation is a bug breaking a rule.
+type Violation struct {
+	Rule        string `json:"rule"`
+	Description string `json:"description,omitempty"`
+	ID          int    `json:"id"`
+	Summary     string `json:"summary"`
+	Status      string `json:"status"`
+	Severity    string `json:"severity"`
+	Priority    string `json:"priority"`
+	AssignedTo  string `json:"assigned_to"`
+	// Age is how many days ago the bug was filed, e.g. 3d.
+	Age string `json:"age"`
+	// Remediation describes what applying remediation does, if anything.
+	Remediation string `json:"remediation,omitempty"`
+	// RemediationError is why applying remediation failed, if it did.
+	RemediationError string `json:"remediation_error,omitempty"`
+
+	bug  *bugzilla.Bug
+	rule *Rule
+}
+
+// Check evaluates every rule against every bug. Bugs past the workflow's open
+// states are never in violation.
+func (p *Policy) Check(bugs []*bugzilla.Bug, now time.Time) ([]*Violation, error) {
+	var violations []*Violation
+	for _, b := range bugs {
+		if !workflow.Before(b.Status, workflow.StatusVerified) {
+			continue
+		}
+		created, err := time.Parse(time.RFC3339, b.CreationTime)
+		if err != nil {
+			return nil, fmt.Errorf("bug %d has an invalid creation time: %v", b.ID, err)
+		}
+		changed, err := time.Parse(time.RFC3339, b.LastChangeTime)
+		if err != nil {
+			changed = created
+		}
+		for _, r := range p.Rules {
+			if !r.matches(b, now.Sub(created), now.Sub(changed)) {
+				continue
+			}
+			v := &Violation{
+				Rule:        r.Name,
+				Description: r.Description,
+				ID:          b.ID,
+				Summary:     b.Summary,
+				Status:      b.Status,
+				Severity:    b.Severity,
+				Priority:    b.Priority,
+				AssignedTo:  b.AssignedTo,
+				Age:         fmt.Sprintf("%dd", int(now.Sub(created).Hours()/24)),
+				bug:         b,
+				rule:        r,
+			}
+			if r.Remediate != nil {
+				var actions []string
+				if r.comment != nil {
+					actions = append(actions, "comment")
+				}
+				if r.Remediate.NeedinfoAssignee && b.AssignedTo != "" {
+					actions = append(actions, "needinfo "+b.AssignedTo)
+				}
+				v.Remediation = strings.Join(actions, ", ")
+			}
+			violations = append(violations, v)
+		}
+	}
+	return violations, nil
+}
+
+func (r *Rule) matches(b *bugzilla.Bug, age, idle time.Duration) bool {
+	m := r.Match
+	switch {
+	case len(m.Severity) > 0 && !contains(m.Severity, b.Severity):
+		return false
+	case len(m.Priority) > 0 && !contains(m.Priority, b.Priority):
+		return false
+	case len(m.Status) > 0 && !contains(m.Status, b.Status):
+		return false
+	case m.StatusBefore != "" && !workflow.Before(b.Status, m.StatusBefore):
+		return false
+	case r.olderThan > 0 && age <= r.olderThan:
+		return false
+	case r.unchangedFor > 0 && idle <= r.unchangedFor:
+		return false
+	}
+	for _, k := range m.Keywords {
+		name, negated := negation(k)
+		if contains(b.Keywords, name) == negated {
+			return false
+		}
+	}
+	for _, f := range m.Flags {
+		spec, negated := negation(f)
+		if hasFlag(b, spec) == negated {
+			return false
+		}
+	}
+	return true
+}
+
+func negation(s string) (string, bool) {
+	if strings.HasPrefix(s, "!") {
+		return s[1:], true
+	}
+	return s, false
+}
+
+// hasFlag reports whether a bug has a flag like blocker+, or blocker with any status
+func hasFlag(b *bugzilla.Bug, spec string) bool {
+	name, status := spec, ""
+	if n := len(spec); n > 1 && strings.ContainsAny(spec[n-1:], "+-?") {
+		name, status = spec[:n-1], spec[n-1:]
+	}
+	for _, f := range b.Flags {
+		if f.Name == name && (status == "" || f.Status == status) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Remediate carries out the remediation of violations, returning the bugs
+// that were changed. Anything already done is left out so that remediation
+// can be applied every night without piling up comments: a needinfo already
+// pending from the assignee isn't requested again, and a rule only ever
+// comments once on a bug. A violation that fails to be remediated doesn't
+// stop the others; its RemediationError is set and an error counting the
+// failures is returned.
+func Remediate(client bugzilla.Client, violations []*Violation) ([]int, error) {
+	var changed []int
+	failed := 0
+	for _, v := range violations {
+		ok, err := v.remediate(client)
+		if err != nil {
+			v.RemediationError = err.Error()
+			failed++
+			continue
+		}
+		if ok {
+			changed = append(changed, v.ID)
+		}
+	}
+	if failed > 0 {
+		return changed, fmt.Errorf("could not remediate %d of %d violations", failed, len(violations))
+	}
+	return changed, nil
+}
+
+// remediate carries out the remediation of v, reporting whether the bug changed.
+func (v *Violation) remediate(client bugzilla.Client) (bool, error) {
+	var comments []bugzilla.Comment
+	if v.rule.comment != nil {
+		var err error
+		if comments, err = client.GetCommentsOnBug(v.ID); err != nil {
+			return false, fmt.Errorf("could not get comments of bug %d: %v", v.ID, err)
+		}
+	}
+	update, err := v.update(comments)
+	if err != nil || update == nil {
+		return false, err
+	}
+	if err := client.UpdateBug(v.ID, *update); err != nil {
+		return false, fmt.Errorf("could not remediate bug %d for rule %s: %v", v.ID, v.Rule, err)
+	}
+	// later rules violated by the same bug see the needinfo as pending
+	for _, f := range update.Flags {
+		v.bug.Flags = append(v.bug.Flags, bugzilla.Flag{Name: f.Name, Status: f.Status, Requestee: f.Requestee})
+	}
+	return true, nil
+}
+
+// update is the change remediating a violation, or nil if there is nothing to do.
+func (v *Violation) update(comments []bugzilla.Comment) (*bugzilla.BugUpdate, error) {
+	r := v.rule.Remediate
+	if r == nil {
+		return nil, nil
+	}
+	update := &bugzilla.BugUpdate{}
+	if r.NeedinfoAssignee && v.AssignedTo != "" && !needinfoPending(v.bug, v.AssignedTo) {
+		update.Flags = []bugzilla.FlagChange{{Name: "needinfo", Status: "?", Requestee: v.AssignedTo}}
+	}
+	if v.rule.comment != nil {
+		var buf bytes.Buffer
+		if err := v.rule.comment.Execute(&buf, v); err != nil {
+			return nil, fmt.Errorf("could not render comment of rule %s: %v", v.Rule, err)
+		}
+		if !commented(comments, v.marker()) {
+			update.Comment = &bugzilla.BugComment{Body: strings.TrimSpace(buf.String()) + "\n\n" + v.marker()}
+		}
+	}
+	if len(update.Flags) == 0 && update.Comment == nil {
+		return nil, nil
+	}
+	return update, nil
+}
+
+func needinfoPending(b *bugzilla.Bug, requestee string) bool {
+	for _, f := range b.Flags {
+		if f.Name == "needinfo" && f.Status == "?" && f.Requestee == requestee {
+			return true
+		}
+	}
+	return false
+}
+
+// marker ends remediation comments, to find them again
+func (v *Violation) marker() string {
+	return fmt.Sprintf("(cop policy rule %s)", v.Rule)
+}
+
+func commented(comments []bugzilla.Comment, marker string) bool {
+	for _, c := range comments {
+		if strings.Contains(c.Text, marker) {
+			return true
+		}
+	}
+	return false
+}
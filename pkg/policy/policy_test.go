@@ -0,0 +1,137 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+)
+
+const testPolicy = `
+rules:
+- name: urgent-unassigned
+  description: urgent bugs must be ASSIGNED within 1 day
+  match:
+    severity: [urgent]
+    status: [NEW]
+    older_than: 1d
+  remediate:
+    comment: "This urgent bug has been NEW for {{.Age}}, please triage it."
+    needinfo_assignee: true
+- name: urgent-without-pr
+  match:
+    severity: [urgent]
+    status_before: POST
+    older_than: 7d
+  remediate:
+    needinfo_assignee: true
+- name: untriaged-blockers
+  match:
+    flags: ["blocker?", "!needinfo"]
+    keywords: ["!Triaged"]
+`
+
+var now = time.Date(2020, 3, 10, 0, 0, 0, 0, time.UTC)
+
+func filed(days int) string {
+	return now.Add(-time.Duration(days) * 24 * time.Hour).Format(time.RFC3339)
+}
+
+func testBugs() []*bugzilla.Bug {
+	return []*bugzilla.Bug{
+		{ID: 1, Severity: "urgent", Status: "NEW", AssignedTo: "dev@redhat.com", CreationTime: filed(8)},
+		{ID: 2, Severity: "urgent", Status: "NEW", CreationTime: filed(0)},
+		{ID: 3, Severity: "urgent", Status: "POST", CreationTime: filed(30)},
+		{ID: 4, Severity: "low", Status: "ASSIGNED", CreationTime: filed(3), Flags: []bugzilla.Flag{{Name: "blocker", Status: "?"}}},
+		{ID: 5, Severity: "low", Status: "ASSIGNED", CreationTime: filed(3), Keywords: []string{"Triaged"}, Flags: []bugzilla.Flag{{Name: "blocker", Status: "?"}}},
+		{ID: 6, Severity: "urgent", Status: "CLOSED", CreationTime: filed(30)},
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, bad := range []string{
+		"",
+		"rules: [{match: {}}]",
+		"rules: [{name: a}, {name: a}]",
+		"rules: [{name: a, match: {older_than: soon}}]",
+		"rules: [{name: a, match: {status_before: DONE}}]",
+		"rules: [{name: a, remediate: {comment: '{{.Nope'}}]",
+		"rules: [{name: a, match: {sevrity: [urgent]}}]",
+	} {
+		_, err := Parse([]byte(bad))
+		require.Error(t, err, bad)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	require.NoError(t, err)
+	violations, err := p.Check(testBugs(), now)
+	require.NoError(t, err)
+
+	var found []string
+	for _, v := range violations {
+		found = append(found, v.Rule+" "+v.Age+" "+v.Remediation)
+	}
+	require.Equal(t, []string{
+		"urgent-unassigned 8d comment, needinfo dev@redhat.com",
+		"urgent-without-pr 8d needinfo dev@redhat.com",
+		"untriaged-blockers 3d ",
+	}, found)
+	require.Equal(t, 4, violations[2].ID)
+}
+
+func TestRemediate(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	require.NoError(t, err)
+	bugs := testBugs()
+	client := &bugzilla.Fake{Bugs: map[int]bugzilla.Bug{}}
+	for _, b := range bugs {
+		client.Bugs[b.ID] = *b
+	}
+	violations, err := p.Check(bugs, now)
+	require.NoError(t, err)
+
+	changed, err := Remediate(client, violations)
+	require.NoError(t, err)
+	// the second rule's needinfo was already requested by the first
+	require.Equal(t, []int{1}, changed)
+	require.Len(t, client.Updates, 1)
+	update := client.Updates[0].Update
+	require.Equal(t, []bugzilla.FlagChange{{Name: "needinfo", Status: "?", Requestee: "dev@redhat.com"}}, update.Flags)
+	require.Equal(t, "This urgent bug has been NEW for 8d, please triage it.\n\n(cop policy rule urgent-unassigned)", update.Comment.Body)
+
+	// running again the next day changes nothing
+	bug := client.Bugs[1]
+	violations, err = p.Check([]*bugzilla.Bug{&bug}, now.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, violations, 2)
+	changed, err = Remediate(client, violations)
+	require.NoError(t, err)
+	require.Empty(t, changed)
+}
+
+func TestRemediateContinuesPastFailures(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	require.NoError(t, err)
+	bugs := []*bugzilla.Bug{
+		{ID: 1, Severity: "urgent", Status: "NEW", AssignedTo: "dev@redhat.com", CreationTime: filed(8)},
+		{ID: 7, Severity: "urgent", Status: "NEW", AssignedTo: "qe@redhat.com", CreationTime: filed(8)},
+	}
+	client := &bugzilla.Fake{Bugs: map[int]bugzilla.Bug{}, BugErrors: map[int]bool{1: true}}
+	for _, b := range bugs {
+		client.Bugs[b.ID] = *b
+	}
+	violations, err := p.Check(bugs, now)
+	require.NoError(t, err)
+	require.Len(t, violations, 4)
+
+	changed, err := Remediate(client, violations)
+	require.EqualError(t, err, "could not remediate 2 of 4 violations")
+	require.Equal(t, []int{7}, changed)
+	require.Equal(t, "could not remediate bug 1 for rule urgent-unassigned: injected error updating bug", violations[0].RemediationError)
+	require.Equal(t, "could not remediate bug 1 for rule urgent-without-pr: injected error updating bug", violations[1].RemediationError)
+	require.Empty(t, violations[2].RemediationError)
+}
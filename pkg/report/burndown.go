@@ -0,0 +1,70 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ecordell/cop/pkg/config"
+)
+
+// Snapshot is the state of a release at one run of the report.
+type Snapshot struct {
+	Time     time.Time `json:"time"`
+	Total    int       `json:"total"`
+	Open     int       `json:"open"`
+	Blockers int       `json:"blockers"`
+}
+
+// History is the snapshots of previous runs of a release report, kept in the
+// cop data dir to chart the burndown.
+type History struct {
+	path      string
+	Snapshots []Snapshot `json:"snapshots"`
+}
+
+// LoadHistory loads the history of a release, which is empty the first time.
+func LoadHistory(release string) (*History, error) {
+	name := strings.Replace(release, string(os.PathSeparator), "_", -1)
+	path, err := config.Path("reports", "release-"+name+".json")
+	if err != nil {
+		return nil, err
+	}
+	h := &History{path: path}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", path, err)
+	}
+	return h, nil
+}
+
+// Record adds a snapshot, replacing any earlier one from the same day so that
+// the burndown has one point per day.
+func (h *History) Record(s Snapshot) {
+	day := s.Time.Format("2006-01-02")
+	kept := h.Snapshots[:0]
+	for _, old := range h.Snapshots {
+		if old.Time.Format("2006-01-02") != day {
+			kept = append(kept, old)
+		}
+	}
+	h.Snapshots = append(kept, s)
+}
+
+// Save writes the history back to the data dir.
+func (h *History) Save() error {
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(h.path, data, 0600)
+}
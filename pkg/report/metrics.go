@@ -0,0 +1,253 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/workflow"
+)
+
+// cycleTargets are the statuses cycle time is measured to.
+var cycleTargets = []string{workflow.StatusModified, workflow.StatusVerified}
+
+// Distribution summarizes a set of durations.
+type Distribution struct {
+	Count int           `json:"count"`
+	Sum   time.Duration `json:"sum"`
+	Mean  time.Duration `json:"mean"`
+	P50   time.Duration `json:"p50"`
+	P90   time.Duration `json:"p90"`
+	Max   time.Duration `json:"max"`
+}
+
+// NewDistribution summarizes durations, using the nearest rank for percentiles.
+func NewDistribution(durations []time.Duration) Distribution {
+	d := Distribution{Count: len(durations)}
+	if d.Count == 0 {
+		return d
+	}
+	sorted := append([]time.Duration{}, durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	for _, v := range sorted {
+		d.Sum += v
+	}
+	d.Mean = d.Sum / time.Duration(d.Count)
+	d.P50 = percentile(sorted, 0.5)
+	d.P90 = percentile(sorted, 0.9)
+	d.Max = sorted[len(sorted)-1]
+	return d
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// StatusDistribution is the time bugs spent in one status.
+type StatusDistribution struct {
+	Status string `json:"status"`
+	Distribution
+}
+
+// AssigneeLoad is the open bugs assigned to someone.
+type AssigneeLoad struct {
+	Assignee string `json:"assignee"`
+	Open     int    `json:"open"`
+	// ByStatus counts the open bugs in each status.
+	ByStatus []Bucket `json:"by_status"`
+}
+
+// Metrics describes how bugs moved through the workflow over a period.
+type Metrics struct {
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+	// Bugs is the number of bugs the metrics were computed from.
+	Bugs int `json:"bugs"`
+	// TimeInStatus is how long bugs stayed in each status before leaving it,
+	// or until now if they haven't yet, for stays that overlap the period.
+	TimeInStatus []StatusDistribution `json:"time_in_status"`
+	// CycleTime is how long bugs took from being filed to first reaching each
+	// status, for bugs that reached it during the period.
+	CycleTime []StatusDistribution `json:"cycle_time"`
+	// Fixed is the number of bugs that reached MODIFIED during the period.
+	Fixed int `json:"fixed"`
+	// Reopened is how many of the fixed bugs later moved back before MODIFIED.
+	Reopened   int            `json:"reopened"`
+	ReopenRate float64        `json:"reopen_rate"`
+	Load       []AssigneeLoad `json:"load"`
+}
+
+// MetricsInput is what metrics are computed from.
+type MetricsInput struct {
+	Bugs    []*bugzilla.Bug
+	History map[int][]bugzilla.History
+	Since   time.Time
+	Now     time.Time
+}
+
+// stay is a period a bug spent in one status.
+type stay struct {
+	status     string
+	start, end time.Time
+	// done is false for the status the bug is still in.
+	done bool
+}
+
+// stays reconstructs the statuses a bug went through from its history. The
+// bug is assumed to have been filed in the status its first change moved it
+// out of.
+func stays(b *bugzilla.Bug, history []bugzilla.History, now time.Time) ([]stay, error) {
+	created, err := time.Parse(time.RFC3339, b.CreationTime)
+	if err != nil {
+		return nil, fmt.Errorf("bug %d has an invalid creation time: %v", b.ID, err)
+	}
+	var out []stay
+	current := stay{status: b.Status, start: created}
+	first := true
+	for _, h := range history {
+		for _, c := range h.Changes {
+			if c.FieldName != "status" {
+				continue
+			}
+			if first {
+				current.status = c.Removed
+				first = false
+			}
+			current.end, current.done = h.When, true
+			out = append(out, current)
+			current = stay{status: c.Added, start: h.When}
+		}
+	}
+	current.end = now
+	return append(out, current), nil
+}
+
+// NewMetrics computes metrics for the bugs from their histories.
+func NewMetrics(in MetricsInput) (*Metrics, error) {
+	m := &Metrics{Since: in.Since, Until: in.Now, Bugs: len(in.Bugs)}
+	inStatus := map[string][]time.Duration{}
+	cycle := map[string][]time.Duration{}
+	load := map[string]map[string]int{}
+	for _, b := range in.Bugs {
+		bugStays, err := stays(b, in.History[b.ID], in.Now)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range bugStays {
+			if s.end.Before(in.Since) {
+				continue
+			}
+			inStatus[s.status] = append(inStatus[s.status], s.end.Sub(s.start))
+		}
+
+		filed := bugStays[0].start
+		for _, target := range cycleTargets {
+			if reached, ok := firstReached(bugStays, target); ok && !reached.Before(in.Since) {
+				cycle[target] = append(cycle[target], reached.Sub(filed))
+			}
+		}
+
+		if fixed, ok := firstReached(bugStays, workflow.StatusModified); ok && !fixed.Before(in.Since) {
+			m.Fixed++
+			if reopened(bugStays) {
+				m.Reopened++
+			}
+		}
+
+		if IsOpen(b) {
+			if load[b.AssignedTo] == nil {
+				load[b.AssignedTo] = map[string]int{}
+			}
+			load[b.AssignedTo][b.Status]++
+		}
+	}
+	if m.Fixed > 0 {
+		m.ReopenRate = float64(m.Reopened) / float64(m.Fixed)
+	}
+	m.TimeInStatus = distributions(inStatus)
+	m.CycleTime = distributions(cycle)
+
+	for assignee, statuses := range load {
+		l := AssigneeLoad{Assignee: assignee, ByStatus: buckets(statuses, workflow.Before)}
+		for _, n := range statuses {
+			l.Open += n
+		}
+		m.Load = append(m.Load, l)
+	}
+	sort.Slice(m.Load, func(i, j int) bool {
+		if m.Load[i].Open != m.Load[j].Open {
+			return m.Load[i].Open > m.Load[j].Open
+		}
+		return m.Load[i].Assignee < m.Load[j].Assignee
+	})
+	return m, nil
+}
+
+// firstReached finds when a bug first reached the target status or a later
+// one. Closing a bug doesn't count, since it may not have been fixed.
+func firstReached(bugStays []stay, target string) (time.Time, bool) {
+	for _, s := range bugStays {
+		if s.status != workflow.StatusClosed && !workflow.Before(s.status, target) {
+			return s.start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// reopened reports whether a bug moved back before MODIFIED after reaching it.
+func reopened(bugStays []stay) bool {
+	fixed := false
+	for _, s := range bugStays {
+		if s.status != workflow.StatusClosed && !workflow.Before(s.status, workflow.StatusModified) {
+			fixed = true
+		} else if fixed && workflow.Before(s.status, workflow.StatusModified) {
+			return true
+		}
+	}
+	return false
+}
+
+func distributions(durations map[string][]time.Duration) []StatusDistribution {
+	var out []StatusDistribution
+	for status, d := range durations {
+		out = append(out, StatusDistribution{Status: status, Distribution: NewDistribution(d)})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i].Status, out[j].Status
+		if workflow.Before(a, b) || workflow.Before(b, a) {
+			return workflow.Before(a, b)
+		}
+		return a < b
+	})
+	return out
+}
+
+// FetchHistory gets the history of each bug, with up to workers requests at once.
+func FetchHistory(client bugzilla.Client, bugs []*bugzilla.Bug, workers int) (map[int][]bugzilla.History, error) {
+	var (
+		ids     []int
+		history = map[int][]bugzilla.History{}
+		mu      sync.Mutex
+	)
+	for _, b := range bugs {
+		ids = append(ids, b.ID)
+	}
+	err := bugzilla.ForEach(ids, workers, func(id int) error {
+		h, err := client.GetBugHistory(id)
+		if err != nil {
+			return fmt.Errorf("could not get history of bug %d: %v", id, err)
+		}
+		mu.Lock()
+		history[id] = h
+		mu.Unlock()
+		return nil
+	})
+	return history, err
+}
@@ -0,0 +1,119 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ecordell/cop/pkg/view"
+)
+
+// CSV writes the metrics as rows of metric, label, statistic and value, with
+// durations in hours.
+func (m *Metrics) CSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	write := func(metric, label, stat, value string) {
+		out.Write([]string{metric, label, stat, value})
+	}
+	hours := func(d time.Duration) string {
+		return strconv.FormatFloat(d.Hours(), 'f', 1, 64)
+	}
+	distribution := func(metric string, ds []StatusDistribution) {
+		for _, d := range ds {
+			write(metric, d.Status, "count", strconv.Itoa(d.Count))
+			write(metric, d.Status, "mean", hours(d.Mean))
+			write(metric, d.Status, "p50", hours(d.P50))
+			write(metric, d.Status, "p90", hours(d.P90))
+			write(metric, d.Status, "max", hours(d.Max))
+		}
+	}
+
+	write("metric", "label", "statistic", "value")
+	distribution("time_in_status_hours", m.TimeInStatus)
+	distribution("cycle_time_hours", m.CycleTime)
+	write("fixed_bugs", "", "count", strconv.Itoa(m.Fixed))
+	write("reopened_bugs", "", "count", strconv.Itoa(m.Reopened))
+	write("reopen_rate", "", "ratio", strconv.FormatFloat(m.ReopenRate, 'f', 3, 64))
+	for _, l := range m.Load {
+		write("open_bugs", l.Assignee, "count", strconv.Itoa(l.Open))
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// Prometheus writes the metrics in the Prometheus text exposition format,
+// with durations in seconds.
+func (m *Metrics) Prometheus(w io.Writer) error {
+	p := view.NewPrinter(w)
+	header := func(name, kind, help string) {
+		p.Printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+	seconds := func(d time.Duration) string {
+		return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+	}
+	summary := func(name, label string, ds []StatusDistribution) {
+		for _, d := range ds {
+			l := fmt.Sprintf("%s=%s", label, promQuote(d.Status))
+			p.Printf("%s{%s,quantile=\"0.5\"} %s\n", name, l, seconds(d.P50))
+			p.Printf("%s{%s,quantile=\"0.9\"} %s\n", name, l, seconds(d.P90))
+			p.Printf("%s{%s,quantile=\"1\"} %s\n", name, l, seconds(d.Max))
+			p.Printf("%s_sum{%s} %s\n", name, l, seconds(d.Sum))
+			p.Printf("%s_count{%s} %d\n", name, l, d.Count)
+		}
+	}
+
+	header("cop_bug_time_in_status_seconds", "summary", "Time bugs spent in a status.")
+	summary("cop_bug_time_in_status_seconds", "status", m.TimeInStatus)
+	header("cop_bug_cycle_time_seconds", "summary", "Time from a bug being filed to first reaching a status.")
+	summary("cop_bug_cycle_time_seconds", "status", m.CycleTime)
+	header("cop_bugs_fixed", "gauge", "Bugs that reached MODIFIED during the period.")
+	p.Printf("cop_bugs_fixed %d\n", m.Fixed)
+	header("cop_bugs_reopened", "gauge", "Fixed bugs that later moved back before MODIFIED.")
+	p.Printf("cop_bugs_reopened %d\n", m.Reopened)
+	header("cop_bug_reopen_ratio", "gauge", "Fraction of fixed bugs that were reopened.")
+	p.Printf("cop_bug_reopen_ratio %s\n", strconv.FormatFloat(m.ReopenRate, 'f', -1, 64))
+	header("cop_bugs_open", "gauge", "Open bugs by assignee and status.")
+	for _, l := range m.Load {
+		for _, b := range l.ByStatus {
+			p.Printf("cop_bugs_open{assignee=%s,status=%s} %d\n", promQuote(l.Assignee), promQuote(b.Name), b.Count)
+		}
+	}
+	return p.Err()
+}
+
+// promQuote quotes a label value for the Prometheus text format.
+func promQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s) + `"`
+}
+
+// Markdown renders the metrics as Markdown tables.
+func (m *Metrics) Markdown(w io.Writer) error {
+	p := view.NewPrinter(w)
+	p.Printf("# Bug metrics\n\n%d bugs changed between %s and %s.\n", m.Bugs, m.Since.Format("2006-01-02"), m.Until.Format("2006-01-02"))
+	distribution := func(title string, ds []StatusDistribution) {
+		p.Printf("\n## %s\n\n| Status | Bugs | Mean | p50 | p90 | Max |\n|---|---:|---:|---:|---:|---:|\n", title)
+		for _, d := range ds {
+			p.Printf("| %s | %d | %s | %s | %s | %s |\n", d.Status, d.Count, days(d.Mean), days(d.P50), days(d.P90), days(d.Max))
+		}
+	}
+	distribution("Time in status", m.TimeInStatus)
+	distribution("Cycle time from filing", m.CycleTime)
+	p.Printf("\n## Reopened\n\n%d of %d bugs fixed during the period were reopened (%.0f%%).\n", m.Reopened, m.Fixed, m.ReopenRate*100)
+	p.Printf("\n## Open bugs by assignee\n\n| Assignee | Open | By status |\n|---|---:|---|\n")
+	for _, l := range m.Load {
+		var statuses []string
+		for _, b := range l.ByStatus {
+			statuses = append(statuses, fmt.Sprintf("%s %d", b.Name, b.Count))
+		}
+		p.Printf("| %s | %d | %s |\n", markdownEscape(l.Assignee), l.Open, strings.Join(statuses, ", "))
+	}
+	return p.Err()
+}
+
+// days formats a duration in days, which suits how long bugs take.
+func days(d time.Duration) string {
+	return fmt.Sprintf("%.1fd", d.Hours()/24)
+}
@@ -0,0 +1,105 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+)
+
+func day(d int) time.Time {
+	return time.Date(2020, 3, d, 0, 0, 0, 0, time.UTC)
+}
+
+func statusChange(d int, from, to string) bugzilla.History {
+	return bugzilla.History{When: day(d), Who: "dev@redhat.com", Changes: []bugzilla.Change{
+		{FieldName: "cc", Added: "qe@redhat.com"},
+		{FieldName: "status", Removed: from, Added: to},
+	}}
+}
+
+func testMetrics(t *testing.T) *Metrics {
+	created := func(d int) string {
+		return day(d).Format(time.RFC3339)
+	}
+	m, err := NewMetrics(MetricsInput{
+		Bugs: []*bugzilla.Bug{
+			{ID: 1, Status: "VERIFIED", AssignedTo: "alice@redhat.com", CreationTime: created(1)},
+			{ID: 2, Status: "ASSIGNED", AssignedTo: "alice@redhat.com", CreationTime: created(2)},
+			{ID: 3, Status: "NEW", AssignedTo: "bob@redhat.com", CreationTime: created(1)},
+		},
+		History: map[int][]bugzilla.History{
+			1: {
+				statusChange(3, "NEW", "ASSIGNED"),
+				statusChange(5, "ASSIGNED", "POST"),
+				statusChange(6, "POST", "MODIFIED"),
+				statusChange(8, "MODIFIED", "ON_QA"),
+				statusChange(9, "ON_QA", "VERIFIED"),
+			},
+			2: {
+				statusChange(4, "NEW", "MODIFIED"),
+				statusChange(7, "MODIFIED", "ASSIGNED"),
+			},
+		},
+		Since: day(2),
+		Now:   day(10),
+	})
+	require.NoError(t, err)
+	return m
+}
+
+func TestNewMetrics(t *testing.T) {
+	m := testMetrics(t)
+	d := 24 * time.Hour
+
+	require.Equal(t, "NEW", m.TimeInStatus[0].Status)
+	require.Equal(t, Distribution{Count: 3, Sum: 13 * d, Mean: 13 * d / 3, P50: 2 * d, P90: 9 * d, Max: 9 * d}, m.TimeInStatus[0].Distribution)
+	var statuses []string
+	for _, s := range m.TimeInStatus {
+		statuses = append(statuses, s.Status)
+	}
+	require.Equal(t, []string{"NEW", "ASSIGNED", "POST", "MODIFIED", "ON_QA", "VERIFIED"}, statuses)
+
+	require.Len(t, m.CycleTime, 2)
+	require.Equal(t, StatusDistribution{Status: "MODIFIED", Distribution: Distribution{Count: 2, Sum: 7 * d, Mean: 7 * d / 2, P50: 2 * d, P90: 5 * d, Max: 5 * d}}, m.CycleTime[0])
+	require.Equal(t, 8*d, m.CycleTime[1].Max)
+
+	require.Equal(t, 2, m.Fixed)
+	require.Equal(t, 1, m.Reopened)
+	require.Equal(t, 0.5, m.ReopenRate)
+	require.Equal(t, []AssigneeLoad{
+		{Assignee: "alice@redhat.com", Open: 1, ByStatus: []Bucket{{"ASSIGNED", 1}}},
+		{Assignee: "bob@redhat.com", Open: 1, ByStatus: []Bucket{{"NEW", 1}}},
+	}, m.Load)
+}
+
+func TestMetricsExport(t *testing.T) {
+	m := testMetrics(t)
+
+	var csv bytes.Buffer
+	require.NoError(t, m.CSV(&csv))
+	require.Contains(t, csv.String(), "metric,label,statistic,value\ntime_in_status_hours,NEW,count,3\ntime_in_status_hours,NEW,mean,104.0\n")
+	require.Contains(t, csv.String(), "reopen_rate,,ratio,0.500\nopen_bugs,alice@redhat.com,count,1\n")
+
+	var prom bytes.Buffer
+	require.NoError(t, m.Prometheus(&prom))
+	require.Contains(t, prom.String(), "# TYPE cop_bug_cycle_time_seconds summary\n"+
+		"cop_bug_cycle_time_seconds{status=\"MODIFIED\",quantile=\"0.5\"} 172800\n")
+	require.Contains(t, prom.String(), "cop_bug_cycle_time_seconds_count{status=\"MODIFIED\"} 2\n")
+	require.Contains(t, prom.String(), "cop_bugs_open{assignee=\"bob@redhat.com\",status=\"NEW\"} 1\n")
+}
+
+func TestFetchHistory(t *testing.T) {
+	client := &bugzilla.Fake{BugHistory: map[int][]bugzilla.History{1: {statusChange(3, "NEW", "ASSIGNED")}}}
+	history, err := FetchHistory(client, []*bugzilla.Bug{{ID: 1}, {ID: 2}}, 2)
+	require.NoError(t, err)
+	require.Len(t, history[1], 1)
+	require.Empty(t, history[2])
+
+	client.BugErrors = map[int]bool{2: true}
+	_, err = FetchHistory(client, []*bugzilla.Bug{{ID: 1}, {ID: 2}}, 2)
+	require.EqualError(t, err, "could not get history of bug 2: injected error getting history")
+}
@@ -0,0 +1,160 @@
+package report
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/view"
+	"github.com/ecordell/cop/pkg/workflow"
+)
+
+// Bucket counts the bugs sharing a value.
+type Bucket struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Table is a list of bugs with the columns of the CLI's bug views.
+type Table struct {
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+	// IDs are the bugs in each row, for linking.
+	IDs []int `json:"ids"`
+}
+
+// Release describes how ready a release is to ship.
+type Release struct {
+	Release     string    `json:"release"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Endpoint    string    `json:"endpoint"`
+	Total       int       `json:"total"`
+	Open        int       `json:"open"`
+	ByStatus    []Bucket  `json:"by_status"`
+	BySeverity  []Bucket  `json:"by_severity"`
+	// ByPRState is only filled in for open bugs when PRs were looked up.
+	ByPRState []Bucket `json:"by_pr_state,omitempty"`
+	// Blockers are open bugs proposed or accepted as release blockers.
+	Blockers Table      `json:"blockers"`
+	OpenBugs Table      `json:"open_bugs"`
+	Burndown []Snapshot `json:"burndown"`
+}
+
+// ReleaseInput is what a release report is built from.
+type ReleaseInput struct {
+	Release  string
+	Endpoint string
+	Bugs     []*bugzilla.Bug
+	// PRStates are the workflow.PRState of each bug, if they were looked up.
+	PRStates map[int]string
+	// RowView picks the columns shown for each bug.
+	RowView func(bugzilla.Bug) view.CLIMarshaller
+	Now     time.Time
+}
+
+// IsOpen reports whether a bug still needs work before it can ship.
+func IsOpen(b *bugzilla.Bug) bool {
+	return workflow.Before(b.Status, workflow.StatusVerified)
+}
+
+// IsBlocker reports whether a bug is proposed or accepted as a release blocker.
+func IsBlocker(b *bugzilla.Bug) bool {
+	for _, f := range b.Flags {
+		if f.Name == "blocker" && (f.Status == "+" || f.Status == "?") {
+			return true
+		}
+	}
+	return false
+}
+
+// NewRelease summarizes the bugs targeting a release.
+func NewRelease(in ReleaseInput) (*Release, error) {
+	r := &Release{Release: in.Release, GeneratedAt: in.Now, Endpoint: in.Endpoint, Total: len(in.Bugs)}
+	status, severity, prState := map[string]int{}, map[string]int{}, map[string]int{}
+	var open, blockers []*bugzilla.Bug
+	for _, b := range in.Bugs {
+		status[b.Status]++
+		if !IsOpen(b) {
+			continue
+		}
+		open = append(open, b)
+		severity[b.Severity]++
+		if in.PRStates != nil {
+			prState[in.PRStates[b.ID]]++
+		}
+		if IsBlocker(b) {
+			blockers = append(blockers, b)
+		}
+	}
+	r.Open = len(open)
+	r.ByStatus = buckets(status, workflow.Before)
+	r.BySeverity = buckets(severity, bugzilla.MoreUrgent)
+	if in.PRStates != nil {
+		r.ByPRState = buckets(prState, nil)
+	}
+
+	// the most urgent and least far along come first
+	sort.SliceStable(open, func(i, j int) bool {
+		a, b := open[i], open[j]
+		if a.Severity != b.Severity && (bugzilla.MoreUrgent(a.Severity, b.Severity) || bugzilla.MoreUrgent(b.Severity, a.Severity)) {
+			return bugzilla.MoreUrgent(a.Severity, b.Severity)
+		}
+		return workflow.Before(a.Status, b.Status)
+	})
+	sort.SliceStable(blockers, func(i, j int) bool {
+		return workflow.Before(blockers[i].Status, blockers[j].Status)
+	})
+	var err error
+	if r.OpenBugs, err = table(open, in); err != nil {
+		return nil, err
+	}
+	if r.Blockers, err = table(blockers, in); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// buckets orders counts with less, falling back to the most common first
+func buckets(counts map[string]int, less func(a, b string) bool) []Bucket {
+	var out []Bucket
+	for name, n := range counts {
+		if name == "" {
+			name = "(none)"
+		}
+		out = append(out, Bucket{Name: name, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if less != nil && (less(a.Name, b.Name) || less(b.Name, a.Name)) {
+			return less(a.Name, b.Name)
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Name < b.Name
+	})
+	return out
+}
+
+func table(bugs []*bugzilla.Bug, in ReleaseInput) (Table, error) {
+	t := Table{Rows: [][]string{}, IDs: []int{}}
+	for _, b := range bugs {
+		row := in.RowView(*b)
+		values, err := row.MarshallCLI()
+		if err != nil {
+			return t, err
+		}
+		if t.Columns == nil {
+			t.Columns = view.Fields(row)
+			if in.PRStates != nil {
+				t.Columns = append(t.Columns, "PRs")
+			}
+		}
+		if in.PRStates != nil {
+			values = append(values, in.PRStates[b.ID])
+		}
+		t.Rows = append(t.Rows, values)
+		t.IDs = append(t.IDs, b.ID)
+	}
+	return t, nil
+}
@@ -0,0 +1,101 @@
+package report
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/view"
+)
+
+type testRow struct {
+	ID      int    `cli:"ID"`
+	Status  string `cli:"Status"`
+	Summary string `cli:"Summary"`
+}
+
+func (r testRow) MarshallCLI() ([]string, error) {
+	return view.MarshallCLI(r)
+}
+
+func testInput() ReleaseInput {
+	return ReleaseInput{
+		Release:  "4.5.0",
+		Endpoint: "https://bugzilla.example.com",
+		Bugs: []*bugzilla.Bug{
+			{ID: 1, Status: "NEW", Severity: "low", Summary: "catalog | crashes"},
+			{ID: 2, Status: "POST", Severity: "urgent", Summary: "proxy ignored", Flags: []bugzilla.Flag{{Name: "blocker", Status: "+"}}},
+			{ID: 3, Status: "VERIFIED", Severity: "high", Summary: "fixed", Flags: []bugzilla.Flag{{Name: "blocker", Status: "+"}}},
+			{ID: 4, Status: "ASSIGNED", Severity: "urgent", Summary: "upgrade hangs", Flags: []bugzilla.Flag{{Name: "blocker", Status: "-"}}},
+		},
+		PRStates: map[int]string{1: "no PR", 2: "PR open", 4: "no PR"},
+		RowView: func(b bugzilla.Bug) view.CLIMarshaller {
+			return testRow{ID: b.ID, Status: b.Status, Summary: b.Summary}
+		},
+		Now: time.Date(2020, 3, 2, 9, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestNewRelease(t *testing.T) {
+	r, err := NewRelease(testInput())
+	require.NoError(t, err)
+	require.Equal(t, 4, r.Total)
+	require.Equal(t, 3, r.Open)
+	require.Equal(t, []Bucket{{"NEW", 1}, {"ASSIGNED", 1}, {"POST", 1}, {"VERIFIED", 1}}, r.ByStatus)
+	require.Equal(t, []Bucket{{"urgent", 2}, {"low", 1}}, r.BySeverity)
+	require.Equal(t, []Bucket{{"no PR", 2}, {"PR open", 1}}, r.ByPRState)
+
+	require.Equal(t, []string{"ID", "Status", "Summary", "PRs"}, r.OpenBugs.Columns)
+	require.Equal(t, []int{4, 2, 1}, r.OpenBugs.IDs)
+	require.Equal(t, []string{"2", "POST", "proxy ignored", "PR open"}, r.Blockers.Rows[0])
+	require.Equal(t, []int{2}, r.Blockers.IDs)
+}
+
+func TestRender(t *testing.T) {
+	r, err := NewRelease(testInput())
+	require.NoError(t, err)
+	r.Burndown = []Snapshot{
+		{Time: time.Date(2020, 3, 1, 9, 0, 0, 0, time.UTC), Total: 4, Open: 4, Blockers: 2},
+		{Time: time.Date(2020, 3, 2, 9, 0, 0, 0, time.UTC), Total: 4, Open: 3, Blockers: 1},
+	}
+
+	var md bytes.Buffer
+	require.NoError(t, r.Markdown(&md))
+	require.Contains(t, md.String(), "**3** of 4 bugs are still open")
+	require.Contains(t, md.String(), "| [2](https://bugzilla.example.com/show_bug.cgi?id=2) | POST | proxy ignored | PR open |\n")
+	require.Contains(t, md.String(), `| [1](https://bugzilla.example.com/show_bug.cgi?id=1) | NEW | catalog \| crashes | no PR |`)
+	require.Contains(t, md.String(), "| 2020-03-02 | 3 | -1 | 4 | 1 | `██████████████████████` |\n")
+
+	var html bytes.Buffer
+	require.NoError(t, r.HTML(&html))
+	require.Contains(t, html.String(), `<td><a href="https://bugzilla.example.com/show_bug.cgi?id=2">2</a></td><td>POST</td>`)
+	require.Contains(t, html.String(), `<div class="bar" style="width: 75%"></div>`)
+}
+
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cop-report")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer os.Setenv("COP_DATA_DIR", os.Getenv("COP_DATA_DIR"))
+	os.Setenv("COP_DATA_DIR", dir)
+
+	h, err := LoadHistory("4.5.0")
+	require.NoError(t, err)
+	require.Empty(t, h.Snapshots)
+	day := time.Date(2020, 3, 1, 9, 0, 0, 0, time.UTC)
+	h.Record(Snapshot{Time: day, Open: 5})
+	h.Record(Snapshot{Time: day.Add(24 * time.Hour), Open: 4})
+	// a second run on the same day replaces the first
+	h.Record(Snapshot{Time: day.Add(26 * time.Hour), Open: 3})
+	require.NoError(t, h.Save())
+
+	h, err = LoadHistory("4.5.0")
+	require.NoError(t, err)
+	require.Len(t, h.Snapshots, 2)
+	require.Equal(t, 3, h.Snapshots[1].Open)
+}
@@ -0,0 +1,187 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/view"
+)
+
+// burndownWidth is the length of the longest bar in the text burndown chart
+const burndownWidth = 30
+
+// Markdown renders the report as Markdown.
+func (r *Release) Markdown(w io.Writer) error {
+	p := view.NewPrinter(w)
+	p.Printf("# Release readiness: %s\n\n", r.Release)
+	p.Printf("Generated %s. **%d** of %d bugs are still open.\n", r.GeneratedAt.Format("2006-01-02 15:04 MST"), r.Open, r.Total)
+
+	p.Printf("\n## Blockers\n\n")
+	if len(r.Blockers.Rows) == 0 {
+		p.Printf("No open blockers.\n")
+	} else {
+		r.markdownTable(p, r.Blockers)
+	}
+
+	p.Printf("\n## Summary\n")
+	for _, section := range []struct {
+		title   string
+		buckets []Bucket
+	}{
+		{"By status", r.ByStatus},
+		{"Open by severity", r.BySeverity},
+		{"Open by linked PRs", r.ByPRState},
+	} {
+		if len(section.buckets) == 0 {
+			continue
+		}
+		p.Printf("\n| %s | Bugs |\n|---|---:|\n", section.title)
+		for _, b := range section.buckets {
+			p.Printf("| %s | %d |\n", markdownEscape(b.Name), b.Count)
+		}
+	}
+
+	if len(r.Burndown) > 0 {
+		p.Printf("\n## Burndown\n\n| Date | Open | Change | Total | Blockers | |\n|---|---:|---:|---:|---:|---|\n")
+		for _, point := range r.burndown() {
+			p.Printf("| %s | %d | %s | %d | %d | `%s` |\n", point.Date, point.Open, point.Change, point.Total, point.Blockers, strings.Repeat("█", point.Bar))
+		}
+	}
+
+	p.Printf("\n## Open bugs\n\n")
+	if len(r.OpenBugs.Rows) == 0 {
+		p.Printf("Nothing left open.\n")
+	} else {
+		r.markdownTable(p, r.OpenBugs)
+	}
+	return p.Err()
+}
+
+func (r *Release) markdownTable(p *view.Printer, t Table) {
+	p.Printf("| %s |\n", strings.Join(t.Columns, " | "))
+	p.Printf("|%s\n", strings.Repeat("---|", len(t.Columns)))
+	for i, row := range t.Rows {
+		cells := make([]string, len(row))
+		for j, c := range row {
+			cells[j] = markdownEscape(c)
+		}
+		// the first column is the bug id
+		cells[0] = fmt.Sprintf("[%s](%s)", cells[0], bugzilla.BugURL(r.Endpoint, t.IDs[i]))
+		p.Printf("| %s |\n", strings.Join(cells, " | "))
+	}
+}
+
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+// burndownPoint is a row of the burndown chart
+type burndownPoint struct {
+	Date     string
+	Open     int
+	Change   string
+	Total    int
+	Blockers int
+	// Bar is the length of the point's bar, scaled to the most open bugs
+	Bar int
+	// Percent is the bar length as a percentage, for html
+	Percent int
+}
+
+func (r *Release) burndown() []burndownPoint {
+	max := 0
+	for _, s := range r.Burndown {
+		if s.Open > max {
+			max = s.Open
+		}
+	}
+	var points []burndownPoint
+	for i, s := range r.Burndown {
+		point := burndownPoint{Date: s.Time.Format("2006-01-02"), Open: s.Open, Total: s.Total, Blockers: s.Blockers, Change: "-"}
+		if i > 0 {
+			point.Change = fmt.Sprintf("%+d", s.Open-r.Burndown[i-1].Open)
+		}
+		if max > 0 {
+			point.Bar = s.Open * burndownWidth / max
+			point.Percent = s.Open * 100 / max
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+var htmlTemplate = template.Must(template.New("release").Funcs(template.FuncMap{
+	"bugURL": bugzilla.BugURL,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Release readiness: {{.Release.Release}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.6em; text-align: left; }
+td.n { text-align: right; }
+.bar { background: #c00; height: 0.8em; }
+</style>
+</head>
+<body>
+<h1>Release readiness: {{.Release.Release}}</h1>
+<p>Generated {{.Release.GeneratedAt.Format "2006-01-02 15:04 MST"}}. <strong>{{.Release.Open}}</strong> of {{.Release.Total}} bugs are still open.</p>
+<h2>Blockers</h2>
+{{if .Release.Blockers.Rows}}{{template "table" .Blockers}}{{else}}<p>No open blockers.</p>{{end}}
+<h2>Summary</h2>
+{{range .Sections}}{{if .Buckets}}<table>
+<tr><th>{{.Title}}</th><th>Bugs</th></tr>
+{{range .Buckets}}<tr><td>{{.Name}}</td><td class="n">{{.Count}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}
+{{if .Burndown}}<h2>Burndown</h2>
+<table>
+<tr><th>Date</th><th>Open</th><th>Change</th><th>Total</th><th>Blockers</th><th style="width: 20em"></th></tr>
+{{range .Burndown}}<tr><td>{{.Date}}</td><td class="n">{{.Open}}</td><td class="n">{{.Change}}</td><td class="n">{{.Total}}</td><td class="n">{{.Blockers}}</td><td><div class="bar" style="width: {{.Percent}}%"></div></td></tr>
+{{end}}</table>
+{{end}}
+<h2>Open bugs</h2>
+{{if .Release.OpenBugs.Rows}}{{template "table" .OpenBugs}}{{else}}<p>Nothing left open.</p>{{end}}
+</body>
+</html>
+{{define "table"}}<table>
+<tr>{{range .Table.Columns}}<th>{{.}}</th>{{end}}</tr>
+{{range $i, $row := .Table.Rows}}<tr>{{range $j, $cell := $row}}{{if eq $j 0}}<td><a href="{{bugURL $.Endpoint (index $.Table.IDs $i)}}">{{$cell}}</a></td>{{else}}<td>{{$cell}}</td>{{end}}{{end}}</tr>
+{{end}}</table>
+{{end}}`))
+
+// htmlTable pairs a table with the endpoint its bugs link to
+type htmlTable struct {
+	Endpoint string
+	Table    Table
+}
+
+// HTML renders the report as a standalone HTML page.
+func (r *Release) HTML(w io.Writer) error {
+	type section struct {
+		Title   string
+		Buckets []Bucket
+	}
+	return htmlTemplate.Execute(w, struct {
+		Release  *Release
+		Blockers htmlTable
+		OpenBugs htmlTable
+		Sections []section
+		Burndown []burndownPoint
+	}{
+		Release:  r,
+		Blockers: htmlTable{Endpoint: r.Endpoint, Table: r.Blockers},
+		OpenBugs: htmlTable{Endpoint: r.Endpoint, Table: r.OpenBugs},
+		Sections: []section{
+			{"By status", r.ByStatus},
+			{"Open by severity", r.BySeverity},
+			{"Open by linked PRs", r.ByPRState},
+		},
+		Burndown: r.burndown(),
+	})
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxCacheEntries bounds the cache, since keys include arbitrary query values.
+const maxCacheEntries = 1000
+
+// cache keeps the results of bugzilla lookups in memory and refreshes them in
+// the background, so that clients get answers without waiting on bugzilla.
+type cache struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	// idle is how long an entry is kept without being asked for.
+	idle time.Duration
+	// max is how many entries are kept, forgetting the least recently used.
+	max    int
+	now    func() time.Time
+	logger *logrus.Entry
+}
+
+type entry struct {
+	load      func() (interface{}, error)
+	value     interface{}
+	fetchedAt time.Time
+	usedAt    time.Time
+	// loading is closed once the first load is done
+	loading chan struct{}
+	err     error
+}
+
+// loaded reports whether the first load of e is done
+func (e *entry) loaded() bool {
+	select {
+	case <-e.loading:
+		return true
+	default:
+		return false
+	}
+}
+
+func newCache(idle time.Duration) *cache {
+	return &cache{
+		entries: map[string]*entry{},
+		idle:    idle,
+		max:     maxCacheEntries,
+		now:     time.Now,
+		logger:  logrus.WithField("component", "cache"),
+	}
+}
+
+// get returns the value cached for key, loading it with load the first time.
+// Failed loads aren't cached.
+func (c *cache) get(key string, load func() (interface{}, error)) (interface{}, time.Time, error) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		c.evict()
+		e = &entry{load: load, loading: make(chan struct{})}
+		c.entries[key] = e
+		c.mu.Unlock()
+		value, err := load()
+		c.mu.Lock()
+		e.value, e.err, e.fetchedAt = value, err, c.now()
+		if err != nil {
+			delete(c.entries, key)
+		}
+		close(e.loading)
+	} else {
+		c.mu.Unlock()
+		<-e.loading
+		c.mu.Lock()
+	}
+	e.usedAt = c.now()
+	value, fetchedAt, err := e.value, e.fetchedAt, e.err
+	c.mu.Unlock()
+	return value, fetchedAt, err
+}
+
+// forget drops the entry for key, so that it is loaded again when next asked for.
+func (c *cache) forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// evict forgets the entries that have been idle for too long and, if the
+// cache is still full, the least recently used ones. Entries still being
+// loaded for the first time are kept. c.mu must be held.
+func (c *cache) evict() {
+	var keys []string
+	for key, e := range c.entries {
+		if !e.loaded() {
+			continue
+		}
+		if c.now().Sub(e.usedAt) > c.idle {
+			delete(c.entries, key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(c.entries) < c.max {
+		return
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.entries[keys[i]].usedAt.Before(c.entries[keys[j]].usedAt)
+	})
+	for _, key := range keys {
+		if len(c.entries) < c.max {
+			return
+		}
+		delete(c.entries, key)
+	}
+}
+
+// refresh reloads every entry used recently and forgets the rest. An entry
+// that fails to reload keeps its previous value.
+func (c *cache) refresh() {
+	c.mu.Lock()
+	c.evict()
+	keys := map[string]*entry{}
+	for key, e := range c.entries {
+		if e.loaded() {
+			keys[key] = e
+		}
+	}
+	c.mu.Unlock()
+
+	for key, e := range keys {
+		value, err := e.load()
+		if err != nil {
+			c.logger.WithError(err).WithField("key", key).Warn("could not refresh")
+			continue
+		}
+		c.mu.Lock()
+		e.value, e.fetchedAt = value, c.now()
+		c.mu.Unlock()
+	}
+}
+
+// run refreshes the cache every interval until ctx is done.
+func (c *cache) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.refresh()
+		}
+	}
+}
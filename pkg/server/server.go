@@ -0,0 +1,430 @@
+// Package server exposes cop over a JSON REST API, so that bots and
+// dashboards can share its bugzilla queries and reports:
+//
+//	GET /healthz                              always ok, without a token
+//	GET /api/queries                          the saved queries
+//	GET /api/queries/NAME/bugs?Release=4.4    the bugs of a saved query, with templated values
+//	GET /api/bugs/ID                          a bug and its linked pull requests
+//	GET /api/backports?release=4.5.0          the backport each bug of a release wants
+//	GET /api/reports/release/4.5.0?prs=true   a release readiness report
+//	GET /metrics                              Prometheus metrics, if enabled
+//	POST /webhooks/github                     GitHub webhook deliveries, if enabled
+//
+// Bugzilla lookups are cached and refreshed in the background.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/config"
+	"github.com/ecordell/cop/pkg/github"
+	"github.com/ecordell/cop/pkg/report"
+	"github.com/ecordell/cop/pkg/view"
+	"github.com/ecordell/cop/pkg/workflow"
+)
+
+// shutdownTimeout is how long requests in flight get to finish on shutdown.
+const shutdownTimeout = 10 * time.Second
+
+// Options configure the server.
+type Options struct {
+	Client bugzilla.Client
+	// GitHub looks up linked pull requests. Without it, they aren't.
+	GitHub github.Client
+	// Refresh is how often cached lookups are refreshed.
+	Refresh time.Duration
+	// Token, if set, must be given as a bearer token on every API request.
+	Token string
+	// Product and Components scope the release reports.
+	Product    string
+	Components []string
+	// RowView picks the columns of the bugs in reports.
+	RowView func(bugzilla.Bug) view.CLIMarshaller
+	// Metrics, if set, serves /metrics for Prometheus to scrape.
+	Metrics http.Handler
+	// Webhook, if set, serves /webhooks/github. Deliveries are signed rather
+	// than sent with the token.
+	Webhook http.Handler
+}
+
+// cacheIdle is how long cached responses are kept without being asked for:
+// ten refreshes, or an hour if they aren't refreshed.
+func cacheIdle(refresh time.Duration) time.Duration {
+	if refresh <= 0 {
+		return time.Hour
+	}
+	return 10 * refresh
+}
+
+// Server serves the API.
+type Server struct {
+	opts   Options
+	cache  *cache
+	mux    *http.ServeMux
+	logger *logrus.Entry
+}
+
+// New returns a server for opts.
+func New(opts Options) *Server {
+	s := &Server{
+		opts:   opts,
+		cache:  newCache(cacheIdle(opts.Refresh)),
+		mux:    http.NewServeMux(),
+		logger: logrus.WithField("component", "server"),
+	}
+	s.mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	s.mux.Handle("/api/queries", s.api(s.queries))
+	s.mux.Handle("/api/queries/", s.api(s.queryBugs))
+	s.mux.Handle("/api/bugs/", s.api(s.bug))
+	s.mux.Handle("/api/backports", s.api(s.backports))
+	s.mux.Handle("/api/reports/release/", s.api(s.releaseReport))
+	if opts.Metrics != nil {
+		s.mux.Handle("/metrics", s.metrics(opts.Metrics))
+	}
+	if opts.Webhook != nil {
+		s.mux.Handle("/webhooks/github", opts.Webhook)
+	}
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Run serves on addr until ctx is done, then waits for requests in flight.
+func (s *Server) Run(ctx context.Context, addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, l)
+}
+
+// Serve serves on l until ctx is done, then waits for requests in flight.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	srv := &http.Server{Handler: s}
+	if s.opts.Refresh > 0 {
+		go s.cache.run(ctx, s.opts.Refresh)
+	}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Serve(l)
+	}()
+	s.logger.WithField("addr", l.Addr().String()).Info("serving")
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	s.logger.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// httpError is an error with the status code to respond with
+type httpError struct {
+	code int
+	err  error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &httpError{code: http.StatusBadRequest, err: fmt.Errorf(format, args...)}
+}
+
+func notFound(format string, args ...interface{}) error {
+	return &httpError{code: http.StatusNotFound, err: fmt.Errorf(format, args...)}
+}
+
+// response is the body of every API response
+type response struct {
+	// FetchedAt is when the data was fetched from bugzilla.
+	FetchedAt *time.Time  `json:"fetched_at,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// handler serves an API call, returning the data to respond with and when it
+// was fetched.
+type handler func(r *http.Request) (interface{}, time.Time, error)
+
+// api checks the bearer token and encodes what h returns as json.
+func (s *Server) api(h handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		logger := s.logger.WithFields(logrus.Fields{"method": r.Method, "path": r.URL.Path})
+		code, resp := s.call(h, r)
+		w.Header().Set("Content-Type", "application/json")
+		if code == http.StatusUnauthorized {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="cop"`)
+		}
+		w.WriteHeader(code)
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			logger.WithError(err).Debug("could not write response")
+		}
+		logger.WithFields(logrus.Fields{"code": code, "duration": time.Since(start)}).Debug("served")
+	})
+}
+
+func (s *Server) call(h handler, r *http.Request) (int, response) {
+	if !s.authorized(r) {
+		return http.StatusUnauthorized, response{Error: "missing or invalid bearer token"}
+	}
+	if r.Method != http.MethodGet {
+		return http.StatusMethodNotAllowed, response{Error: "only GET is supported"}
+	}
+	data, fetchedAt, err := h(r)
+	if err != nil {
+		code := http.StatusBadGateway
+		if he, ok := err.(*httpError); ok {
+			code = he.code
+		}
+		return code, response{Error: err.Error()}
+	}
+	resp := response{Data: data}
+	if !fetchedAt.IsZero() {
+		resp.FetchedAt = &fetchedAt
+	}
+	return http.StatusOK, resp
+}
+
+// metrics checks the bearer token before serving h, like api does.
+func (s *Server) metrics(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="cop"`)
+			http.Error(w, "missing or invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if s.opts.Token == "" {
+		return true
+	}
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, prefix)), []byte(s.opts.Token)) == 1
+}
+
+// search runs a bugzilla search through the cache
+func (s *Server) search(query string) ([]*bugzilla.Bug, time.Time, error) {
+	v, fetchedAt, err := s.cache.get("search:"+query, func() (interface{}, error) {
+		return s.opts.Client.SearchBugs(query)
+	})
+	if err != nil {
+		return nil, fetchedAt, err
+	}
+	return v.([]*bugzilla.Bug), fetchedAt, nil
+}
+
+// savedQuery is a saved query as listed by the API
+type savedQuery struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Params      url.Values `json:"params"`
+}
+
+func (s *Server) queries(r *http.Request) (interface{}, time.Time, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	queries := []savedQuery{}
+	for _, name := range cfg.QueryNames() {
+		q := cfg.Queries[name]
+		queries = append(queries, savedQuery{Name: name, Description: q.Description, Params: q.Params})
+	}
+	return queries, time.Time{}, nil
+}
+
+// queryBugs serves /api/queries/NAME/bugs, with query parameters filling in
+// templated values
+func (s *Server) queryBugs(r *http.Request) (interface{}, time.Time, error) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/queries/")
+	if !strings.HasSuffix(rest, "/bugs") {
+		return nil, time.Time{}, notFound("no such endpoint %s", r.URL.Path)
+	}
+	name := strings.TrimSuffix(rest, "/bugs")
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	q, err := cfg.Query(name)
+	if err != nil {
+		return nil, time.Time{}, notFound("%v", err)
+	}
+	vars := map[string]string{}
+	for k, v := range r.URL.Query() {
+		vars[k] = v[0]
+	}
+	query, err := bugzilla.ExpandQuery(q.Params, vars)
+	if err != nil {
+		return nil, time.Time{}, badRequest("%v", err)
+	}
+	bugs, fetchedAt, err := s.search(query)
+	return bugs, fetchedAt, err
+}
+
+// bugDetail is a bug as shown by the API
+type bugDetail struct {
+	Bug *bugzilla.Bug       `json:"bug"`
+	PRs []workflow.LinkedPR `json:"prs,omitempty"`
+	// PRState summarizes the linked pull requests, see workflow.PRState.
+	PRState string `json:"pr_state,omitempty"`
+	// PRErrors are why pull requests couldn't be looked up, making the state unknown.
+	PRErrors []string `json:"pr_errors,omitempty"`
+}
+
+func (s *Server) bug(r *http.Request) (interface{}, time.Time, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/bugs/"))
+	if err != nil {
+		return nil, time.Time{}, badRequest("invalid bug id: %v", err)
+	}
+	return s.cache.get(fmt.Sprintf("bug:%d", id), func() (interface{}, error) {
+		bug, err := s.opts.Client.GetBug(id)
+		if err != nil {
+			if bugzilla.IsNotFound(err) {
+				return nil, notFound("bug %d not found", id)
+			}
+			return nil, err
+		}
+		detail := &bugDetail{Bug: bug}
+		if s.opts.GitHub != nil {
+			detail.PRs, err = workflow.LinkedPRs(s.opts.Client, s.opts.GitHub, id)
+			if err != nil {
+				s.logger.WithError(err).WithField("bug", id).Warn("could not look up linked PRs")
+				detail.PRState = workflow.PRStateUnknown
+				detail.PRErrors = []string{err.Error()}
+				return detail, nil
+			}
+			for _, pr := range detail.PRs {
+				if pr.Err != nil {
+					detail.PRErrors = append(detail.PRErrors, fmt.Sprintf("%s/%s#%d: %v", pr.Org, pr.Repo, pr.Num, pr.Err))
+				}
+			}
+			detail.PRState = workflow.PRState(detail.PRs)
+		}
+		return detail, nil
+	})
+}
+
+// BugChanged forgets the cached copy of a bug, for when it is known to have
+// changed, e.g. by a webhook delivery.
+func (s *Server) BugChanged(id int) {
+	s.cache.forget(fmt.Sprintf("bug:%d", id))
+}
+
+// backport is the backport a bug wants, from its internal whiteboard
+type backport struct {
+	ID            int      `json:"id"`
+	Summary       string   `json:"summary"`
+	Status        string   `json:"status"`
+	AssignedTo    string   `json:"assigned_to"`
+	TargetRelease []string `json:"target_release"`
+	// BackportTo is empty if no backport has been decided on.
+	BackportTo string `json:"backport_to"`
+}
+
+func (s *Server) backports(r *http.Request) (interface{}, time.Time, error) {
+	release := r.URL.Query().Get("release")
+	if release == "" {
+		return nil, time.Time{}, badRequest("the release parameter is required")
+	}
+	bugs, fetchedAt, err := s.search(s.releaseQuery(release, true))
+	if err != nil {
+		return nil, fetchedAt, err
+	}
+	backports := []backport{}
+	for _, b := range bugs {
+		backports = append(backports, backport{
+			ID:            b.ID,
+			Summary:       b.Summary,
+			Status:        b.Status,
+			AssignedTo:    b.AssignedTo,
+			TargetRelease: b.TargetRelease,
+			BackportTo:    workflow.Backport(*b),
+		})
+	}
+	return backports, fetchedAt, nil
+}
+
+// releaseQuery searches the bugs targeting a release, only open ones if open
+func (s *Server) releaseQuery(release string, open bool) string {
+	params := url.Values{
+		"classification": {"Red Hat"},
+		"product":        {s.opts.Product},
+		"component":      s.opts.Components,
+		"target_release": {release},
+	}
+	if open {
+		params["bug_status"] = []string{
+			workflow.StatusNew, workflow.StatusAssigned, workflow.StatusOnDev,
+			workflow.StatusPost, workflow.StatusModified, workflow.StatusOnQA,
+		}
+	}
+	return params.Encode()
+}
+
+func (s *Server) releaseReport(r *http.Request) (interface{}, time.Time, error) {
+	release := strings.TrimPrefix(r.URL.Path, "/api/reports/release/")
+	if release == "" || strings.Contains(release, "/") {
+		return nil, time.Time{}, notFound("no such endpoint %s", r.URL.Path)
+	}
+	prs := r.URL.Query().Get("prs") == "true" && s.opts.GitHub != nil
+	key := fmt.Sprintf("release:%s:%t", release, prs)
+	return s.cache.get(key, func() (interface{}, error) {
+		bugs, err := s.opts.Client.SearchBugs(s.releaseQuery(release, false))
+		if err != nil {
+			return nil, err
+		}
+		in := report.ReleaseInput{
+			Release:  release,
+			Endpoint: s.opts.Client.Endpoint(),
+			Bugs:     bugs,
+			RowView:  s.opts.RowView,
+			Now:      time.Now(),
+		}
+		if prs {
+			in.PRStates = map[int]string{}
+			for _, b := range bugs {
+				if !report.IsOpen(b) {
+					continue
+				}
+				linked, err := workflow.LinkedPRs(s.opts.Client, s.opts.GitHub, b.ID)
+				if err != nil {
+					s.logger.WithError(err).WithField("bug", b.ID).Warn("could not look up linked PRs")
+					in.PRStates[b.ID] = workflow.PRStateUnknown
+					continue
+				}
+				in.PRStates[b.ID] = workflow.PRState(linked)
+			}
+		}
+		return report.NewRelease(in)
+	})
+}
@@ -0,0 +1,235 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/config"
+	"github.com/ecordell/cop/pkg/github"
+	"github.com/ecordell/cop/pkg/view"
+	"github.com/ecordell/cop/pkg/workflow"
+)
+
+type testRow struct {
+	ID int `cli:"ID"`
+}
+
+func (r testRow) MarshallCLI() ([]string, error) {
+	return view.MarshallCLI(r)
+}
+
+func testServer(t *testing.T) (*Server, *bugzilla.Fake) {
+	client := &bugzilla.Fake{
+		EndpointString: "https://bugzilla.example.com",
+		Bugs: map[int]bugzilla.Bug{
+			1: {ID: 1, Summary: "catalog crashes", Status: "NEW", TargetRelease: []string{"4.5.0"}, InternalWhiteboard: "backport-to: 4.4"},
+			2: {ID: 2, Summary: "proxy ignored", Status: "VERIFIED", TargetRelease: []string{"4.5.0"}},
+		},
+		SearchResults: map[string][]int{"assigned_to=me%40example.com&target_release=4.4.z": {2}},
+	}
+	s := New(Options{
+		Client:     client,
+		Token:      "secret",
+		Product:    "OpenShift Container Platform",
+		Components: []string{"OLM"},
+		RowView: func(b bugzilla.Bug) view.CLIMarshaller {
+			return testRow{ID: b.ID}
+		},
+	})
+	return s, client
+}
+
+func get(t *testing.T, s http.Handler, path, token string, data interface{}) int {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	var resp struct {
+		Data  json.RawMessage `json:"data"`
+		Error string          `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp), rec.Body.String())
+	if data != nil && rec.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(resp.Data, data))
+	}
+	return rec.Code
+}
+
+func TestAPI(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cop-server")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer os.Setenv("COP_DATA_DIR", os.Getenv("COP_DATA_DIR"))
+	os.Setenv("COP_DATA_DIR", dir)
+	cfg := &config.Config{}
+	cfg.SetQuery("mine", config.Query{Params: url.Values{"assigned_to": {"me@example.com"}, "target_release": {"{{.Release}}"}}})
+	require.NoError(t, cfg.Save())
+
+	s, client := testServer(t)
+	require.Equal(t, http.StatusUnauthorized, get(t, s, "/api/queries", "", nil))
+	require.Equal(t, http.StatusUnauthorized, get(t, s, "/api/queries", "wrong", nil))
+
+	var queries []savedQuery
+	require.Equal(t, http.StatusOK, get(t, s, "/api/queries", "secret", &queries))
+	require.Equal(t, "mine", queries[0].Name)
+
+	var bugs []bugzilla.Bug
+	require.Equal(t, http.StatusOK, get(t, s, "/api/queries/mine/bugs?Release=4.4.z", "secret", &bugs))
+	require.Len(t, bugs, 1)
+	require.Equal(t, 2, bugs[0].ID)
+	require.Equal(t, http.StatusNotFound, get(t, s, "/api/queries/theirs/bugs", "secret", nil))
+
+	var bug bugDetail
+	require.Equal(t, http.StatusOK, get(t, s, "/api/bugs/1", "secret", &bug))
+	require.Equal(t, "catalog crashes", bug.Bug.Summary)
+	require.Equal(t, http.StatusNotFound, get(t, s, "/api/bugs/3", "secret", nil))
+	require.Equal(t, http.StatusBadRequest, get(t, s, "/api/bugs/x", "secret", nil))
+
+	var backports []backport
+	require.Equal(t, http.StatusOK, get(t, s, "/api/backports?release=4.5.0", "secret", &backports))
+	require.Len(t, backports, 2)
+	for _, bp := range backports {
+		if bp.ID == 1 {
+			require.Equal(t, "4.4", bp.BackportTo)
+		} else {
+			require.Equal(t, "", bp.BackportTo)
+		}
+	}
+	require.Equal(t, http.StatusBadRequest, get(t, s, "/api/backports", "secret", nil))
+
+	var release struct {
+		Total int `json:"total"`
+		Open  int `json:"open"`
+	}
+	require.Equal(t, http.StatusOK, get(t, s, "/api/reports/release/4.5.0", "secret", &release))
+	require.Equal(t, 2, release.Total)
+	require.Equal(t, 1, release.Open)
+
+	// answers come from the cache until it is refreshed
+	bug1 := client.Bugs[1]
+	bug1.Summary = "catalog crashes on startup"
+	client.Bugs[1] = bug1
+	require.Equal(t, http.StatusOK, get(t, s, "/api/bugs/1", "secret", &bug))
+	require.Equal(t, "catalog crashes", bug.Bug.Summary)
+	s.cache.refresh()
+	require.Equal(t, http.StatusOK, get(t, s, "/api/bugs/1", "secret", &bug))
+	require.Equal(t, "catalog crashes on startup", bug.Bug.Summary)
+
+	// or until the bug is known to have changed
+	bug1.Status = "POST"
+	client.Bugs[1] = bug1
+	s.BugChanged(1)
+	require.Equal(t, http.StatusOK, get(t, s, "/api/bugs/1", "secret", &bug))
+	require.Equal(t, "POST", bug.Bug.Status)
+}
+
+func TestMetrics(t *testing.T) {
+	s := New(Options{
+		Client: &bugzilla.Fake{},
+		Token:  "secret",
+		Metrics: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("up 1\n"))
+		}),
+	})
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "up 1\n", rec.Body.String())
+}
+
+func TestCacheForgetsIdleEntries(t *testing.T) {
+	c := newCache(time.Minute)
+	now := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	loads := 0
+	load := func() (interface{}, error) {
+		loads++
+		return loads, nil
+	}
+	v, _, err := c.get("a", load)
+	require.NoError(t, err)
+	require.Equal(t, 1, v)
+
+	now = now.Add(30 * time.Second)
+	c.refresh()
+	v, fetchedAt, _ := c.get("a", load)
+	require.Equal(t, 2, v)
+	require.Equal(t, now, fetchedAt)
+
+	now = now.Add(2 * time.Minute)
+	c.refresh()
+	require.Empty(t, c.entries)
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newCache(time.Hour)
+	c.max = 2
+	now := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	load := func() (interface{}, error) { return 1, nil }
+	for _, key := range []string{"a", "b", "a", "c"} {
+		now = now.Add(time.Second)
+		_, _, err := c.get(key, load)
+		require.NoError(t, err)
+	}
+	require.Len(t, c.entries, 2)
+	require.Contains(t, c.entries, "a")
+	require.Contains(t, c.entries, "c")
+
+	// without a refresh, idle entries are forgotten when new ones come in
+	now = now.Add(2 * time.Hour)
+	_, _, err := c.get("d", load)
+	require.NoError(t, err)
+	require.Len(t, c.entries, 1)
+}
+
+func TestBugWithUnknownPRs(t *testing.T) {
+	s, client := testServer(t)
+	s.opts.GitHub = &github.Fake{Err: errors.New("API rate limit exceeded")}
+	client.ExternalBugs = map[int][]bugzilla.ExternalBug{
+		1: {{Type: bugzilla.ExternalBugType{URL: "https://github.com/"}, BugzillaBugID: 1, ExternalBugID: "operator-framework/operator-lifecycle-manager/pull/1234"}},
+	}
+	var bug bugDetail
+	require.Equal(t, http.StatusOK, get(t, s, "/api/bugs/1", "secret", &bug))
+	require.Equal(t, workflow.PRStateUnknown, bug.PRState)
+	require.Equal(t, []string{"operator-framework/operator-lifecycle-manager#1234: API rate limit exceeded"}, bug.PRErrors)
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	s, _ := testServer(t)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- s.Serve(ctx, l)
+	}()
+
+	resp, err := http.Get("http://" + l.Addr().String() + "/healthz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	cancel()
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,118 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// Fixture is a recorded request and its response, with secrets scrubbed.
+type Fixture struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request as recorded in a Fixture.
+type RecordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// RecordedResponse is a response as recorded in a Fixture.
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// unsafeChars are left out of fixture file names
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9.]+`)
+
+// recorder saves every request and response to a directory
+type recorder struct {
+	base http.RoundTripper
+	dir  string
+	mu   sync.Mutex
+	// n is the number of the last fixture saved
+	n int
+}
+
+// NewRecorder returns a transport that sends requests with base and saves
+// each with its response as a Fixture in dir, numbered in the order they were
+// made after any fixtures already there.
+func NewRecorder(base http.RoundTripper, dir string) (http.RoundTripper, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	files, err := fixtureFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &recorder{base: base, dir: dir, n: len(files)}, nil
+}
+
+func (t *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	header := scrubHeader(resp.Header)
+	// the body may change length when scrubbed
+	header.Del("Content-Length")
+	f := Fixture{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    scrubURL(req.URL, false),
+			Body:   scrubBody(req.Header.Get("Content-Type"), body),
+		},
+		Response: RecordedResponse{
+			Status: resp.StatusCode,
+			Header: header,
+			Body:   scrubBody(resp.Header.Get("Content-Type"), respBody),
+		},
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.n++
+	name := fmt.Sprintf("%04d-%s-%s", t.n, req.Method, unsafeChars.ReplaceAllString(req.URL.Host+req.URL.Path, "_"))
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	if err := ioutil.WriteFile(filepath.Join(t.dir, name+".json"), data, 0600); err != nil {
+		return nil, fmt.Errorf("could not record %s %s: %v", req.Method, req.URL.Path, err)
+	}
+	return resp, nil
+}
+
+// fixtureFiles lists the fixtures in dir in the order they were recorded
+func fixtureFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,74 @@
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// replayer answers requests with recorded responses
+type replayer struct {
+	mu        sync.Mutex
+	responses map[string][]RecordedResponse
+}
+
+// NewReplayer returns a transport that answers requests with the responses
+// recorded in dir, without sending them. Requests match recordings by method,
+// url and body, ignoring credentials. A request made several times gets the
+// responses recorded for it in order, and then the last one again. A request
+// that wasn't recorded fails.
+func NewReplayer(dir string) (http.RoundTripper, error) {
+	files, err := fixtureFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no recorded requests in %s", dir)
+	}
+	t := &replayer{responses: map[string][]RecordedResponse{}}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var f Fixture
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("could not parse %s: %v", file, err)
+		}
+		u, err := url.Parse(f.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid url in %s: %v", file, err)
+		}
+		key := requestKey(f.Request.Method, u, f.Request.Body)
+		t.responses[key] = append(t.responses[key], f.Response)
+	}
+	return t, nil
+}
+
+// requestKey identifies a request regardless of credentials. body must
+// already be scrubbed.
+func requestKey(method string, u *url.URL, body string) string {
+	return method + " " + scrubURL(u, true) + "\n" + body
+}
+
+func (t *replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	key := requestKey(req.Method, req.URL, scrubBody(req.Header.Get("Content-Type"), body))
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	recorded := t.responses[key]
+	if len(recorded) == 0 {
+		return nil, fmt.Errorf("no recorded response for %s %s", req.Method, scrubURL(req.URL, false))
+	}
+	r := recorded[0]
+	if len(recorded) > 1 {
+		t.responses[key] = recorded[1:]
+	}
+	return response(req, r.Status, r.Header, []byte(r.Body)), nil
+}
@@ -0,0 +1,162 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// redacted replaces secrets in fixtures and logs.
+const redacted = "REDACTED"
+
+// secretNames are the parts of names of query parameters, form fields, json
+// keys and headers that hold credentials.
+var secretNames = []string{"api_key", "apikey", "api-key", "token", "password", "secret", "authorization", "cookie", "saml"}
+
+func isSecret(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range secretNames {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// scrubURL returns u with the values of secret query parameters replaced. If
+// drop is set they are left out altogether, so that requests made with
+// different credentials compare equal.
+func scrubURL(u *url.URL, drop bool) string {
+	scrubbed := *u
+	scrubbed.User = nil
+	query := u.Query()
+	for name := range query {
+		if !isSecret(name) {
+			continue
+		}
+		if drop {
+			query.Del(name)
+		} else {
+			query[name] = []string{redacted}
+		}
+	}
+	// Encode sorts the parameters
+	scrubbed.RawQuery = query.Encode()
+	return scrubbed.String()
+}
+
+// scrubBody replaces secrets in json, form and html bodies.
+func scrubBody(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if strings.HasPrefix(contentType, "text/html") {
+		return scrubHTML(body)
+	}
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return redacted
+		}
+		for name := range form {
+			if isSecret(name) {
+				form[name] = []string{redacted}
+			}
+		}
+		return form.Encode()
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return string(body)
+	}
+	scrubbed, err := json.Marshal(scrubJSON(v))
+	if err != nil {
+		return string(body)
+	}
+	return string(scrubbed)
+}
+
+// scrubHTML replaces the values of secret form fields, like the SAMLResponse
+// the SSO login hands to jira, and leaves the rest of the page as it was.
+func scrubHTML(body []byte) string {
+	var out bytes.Buffer
+	z := html.NewTokenizer(bytes.NewReader(body))
+	// the text of a secret textarea is replaced
+	secretText := false
+	for {
+		tokenType := z.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			return out.String()
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+			if !isSecret(attr(token, "name")) {
+				break
+			}
+			switch token.DataAtom {
+			case atom.Input:
+				for i := range token.Attr {
+					if token.Attr[i].Key == "value" {
+						token.Attr[i].Val = redacted
+					}
+				}
+				out.WriteString(token.String())
+				continue
+			case atom.Textarea:
+				secretText = tokenType == html.StartTagToken
+			}
+		case html.TextToken:
+			if secretText {
+				secretText = false
+				out.WriteString(redacted)
+				continue
+			}
+		case html.EndTagToken:
+			secretText = false
+		}
+		out.Write(z.Raw())
+	}
+}
+
+func attr(token html.Token, key string) string {
+	for _, a := range token.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func scrubJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, value := range v {
+			if isSecret(k) {
+				v[k] = redacted
+			} else {
+				v[k] = scrubJSON(value)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = scrubJSON(v[i])
+		}
+	}
+	return v
+}
+
+// scrubHeader returns h without secret headers.
+func scrubHeader(h http.Header) http.Header {
+	scrubbed := http.Header{}
+	for name, values := range h {
+		if !isSecret(name) {
+			scrubbed[name] = values
+		}
+	}
+	return scrubbed
+}
@@ -0,0 +1,178 @@
+// Package transport swaps the HTTP transport cop's clients send requests
+// with, to skip writes in a dry run, or to record requests and responses as
+// fixtures and replay them later without a network.
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Options choose what happens to the requests cop sends.
+type Options struct {
+	// DryRun sends requests that only read, and logs and skips the rest.
+	DryRun bool
+	// Record is a directory to save every request and response to.
+	Record string
+	// Replay is a directory of recorded responses to answer requests with,
+	// instead of sending them.
+	Replay string
+}
+
+// Wrap returns base wrapped to follow o.
+func (o Options) Wrap(base http.RoundTripper) (http.RoundTripper, error) {
+	if o.Record != "" && o.Replay != "" {
+		return nil, fmt.Errorf("can't record and replay at once")
+	}
+	rt := base
+	if o.Replay != "" {
+		replayer, err := NewReplayer(o.Replay)
+		if err != nil {
+			return nil, err
+		}
+		rt = replayer
+	}
+	if o.DryRun {
+		rt = NewDryRun(rt)
+	}
+	if o.Record != "" {
+		recorder, err := NewRecorder(rt, o.Record)
+		if err != nil {
+			return nil, err
+		}
+		rt = recorder
+	}
+	return rt, nil
+}
+
+// installed are the options of Install
+var installed Options
+
+// Install makes every client that uses http.DefaultTransport, which is all
+// of cop's, follow o.
+func Install(o Options) error {
+	rt, err := o.Wrap(http.DefaultTransport)
+	if err != nil {
+		return err
+	}
+	http.DefaultTransport = rt
+	installed = o
+	return nil
+}
+
+// DryRun reports whether writes are being skipped.
+func DryRun() bool {
+	return installed.DryRun
+}
+
+// Replaying reports whether responses are being replayed.
+func Replaying() bool {
+	return installed.Replay != ""
+}
+
+// readOnly reports whether req doesn't change anything, so that it is still
+// sent in a dry run.
+func readOnly(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	path := req.URL.Path
+	switch {
+	case strings.HasSuffix(path, "/rest/api/2/search"):
+		// jira searches are POSTed for long queries
+		return true
+	case strings.Contains(path, "/rest/auth/"):
+		// logging in to jira through its session api
+		return true
+	case ssoHosts[req.URL.Hostname()] &&
+		strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded"):
+		// the sign-in forms of jira's SAML login
+		return true
+	}
+	return false
+}
+
+// ssoHosts serve the SAML login forms jira sessions are started with
+var ssoHosts = map[string]bool{
+	"sso.redhat.com": true,
+	"sso.jboss.org":  true,
+}
+
+// dryRun sends read only requests and skips the rest
+type dryRun struct {
+	base   http.RoundTripper
+	logger *logrus.Entry
+}
+
+// NewDryRun returns a transport that sends requests that only read with base,
+// and logs and skips the rest, answering them with an empty success.
+func NewDryRun(base http.RoundTripper) http.RoundTripper {
+	return &dryRun{base: base, logger: logrus.WithField("component", "dry-run")}
+}
+
+func (t *dryRun) RoundTrip(req *http.Request) (*http.Response, error) {
+	if readOnly(req) {
+		return t.base.RoundTrip(req)
+	}
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	t.logger.WithFields(logrus.Fields{
+		"method": req.Method,
+		"url":    scrubURL(req.URL, false),
+		"body":   scrubBody(req.Header.Get("Content-Type"), body),
+	}).Info("dry run, not sending")
+	return response(req, http.StatusOK, nil, skippedBody(body)), nil
+}
+
+// skippedBody answers a skipped request. JSON-RPC clients check that the
+// answer has the id of their request, so it is echoed back.
+func skippedBody(request []byte) []byte {
+	var rpc struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	if json.Unmarshal(request, &rpc) == nil && rpc.Method != "" && len(rpc.ID) > 0 {
+		return []byte(fmt.Sprintf(`{"id":%s,"result":null}`, rpc.ID))
+	}
+	return []byte("{}")
+}
+
+// readBody reads the body of req and puts it back for sending
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func response(req *http.Request, code int, header http.Header, body []byte) *http.Response {
+	if header == nil {
+		header = http.Header{"Content-Type": {"application/json"}}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		StatusCode:    code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,215 @@
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+)
+
+// fakeBugzilla answers like bugzilla, counting the writes it gets
+func fakeBugzilla(writes *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/bug/1":
+			fmt.Fprint(w, `{"bugs":[{"id":1,"status":"NEW","summary":"catalog crashes","update_token":"t0k3n"}]}`)
+		case r.Method == http.MethodPut && r.URL.Path == "/rest/bug/1":
+			*writes++
+			fmt.Fprint(w, `{"bugs":[{"id":1}]}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/jsonrpc.cgi":
+			*writes++
+			fmt.Fprint(w, `{"id":"identifier","result":{"bugs":[{"id":1,"changes":{"ext_bz_bug_map.ext_bz_bug_id":{"added":"org/repo/pull/2"}}}]}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func install(t *testing.T, o Options) func() {
+	base, before := http.DefaultTransport, installed
+	require.NoError(t, Install(o))
+	return func() {
+		http.DefaultTransport, installed = base, before
+	}
+}
+
+func client(endpoint, key string) bugzilla.Client {
+	return bugzilla.NewClient(func() []byte { return []byte(key) }, endpoint)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cop-transport")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writes := 0
+	server := fakeBugzilla(&writes)
+
+	restore := install(t, Options{Record: dir})
+	c := client(server.URL, "s3cret")
+	bug, err := c.GetBug(1)
+	require.NoError(t, err)
+	require.Equal(t, "catalog crashes", bug.Summary)
+	require.NoError(t, c.UpdateBug(1, bugzilla.BugUpdate{Status: "POST"}))
+	changed, err := c.AddPullRequestAsExternalBug(1, "org", "repo", 2)
+	require.NoError(t, err)
+	require.True(t, changed)
+	restore()
+	server.Close()
+	require.Equal(t, 2, writes)
+
+	files, err := fixtureFiles(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	require.Equal(t, "0001-GET-127.0.0.1_", filepath.Base(files[0])[:len("0001-GET-127.0.0.1_")])
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		require.NoError(t, err)
+		require.NotContains(t, string(data), "s3cret", file)
+		require.NotContains(t, string(data), "session=abc", file)
+	}
+	var f Fixture
+	data, err := ioutil.ReadFile(files[0])
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &f))
+	require.True(t, strings.HasSuffix(f.Request.URL, "/rest/bug/1?api_key=REDACTED"), f.Request.URL)
+	require.Contains(t, f.Response.Body, `"update_token":"REDACTED"`)
+
+	// the server is gone, and the key is different, but the answers are the same
+	defer install(t, Options{Replay: dir})()
+	c = client(server.URL, "another")
+	bug, err = c.GetBug(1)
+	require.NoError(t, err)
+	require.Equal(t, "catalog crashes", bug.Summary)
+	require.NoError(t, c.UpdateBug(1, bugzilla.BugUpdate{Status: "POST"}))
+	changed, err = c.AddPullRequestAsExternalBug(1, "org", "repo", 2)
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.True(t, Replaying())
+
+	_, err = c.GetBug(2)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no recorded response for GET")
+}
+
+// fakeSSO answers like the SSO login jira sessions are started with
+func fakeSSO() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		switch r.URL.Path {
+		case "/login.jsp":
+			fmt.Fprint(w, `<html><body><form action="/saml"><textarea name="SAMLRequest">s4mlr3quest</textarea></form></body></html>`)
+		case "/saml":
+			fmt.Fprint(w, `<html><body><form method="post" action="/provider">`+
+				`<input type="hidden" name="SAMLResponse" value="s4mlr3sponse"/>`+
+				`<input type="hidden" name="RelayState" value="/default.jsp"/>`+
+				`</form></body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestRecordAndReplaySAMLLogin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cop-transport")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	server := fakeSSO()
+
+	login := func() (string, string) {
+		resp, err := http.Get(server.URL + "/login.jsp")
+		require.NoError(t, err)
+		page, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+		resp, err = http.PostForm(server.URL+"/saml", url.Values{"SAMLRequest": {"s4mlr3quest"}, "password": {"hunter2"}})
+		require.NoError(t, err)
+		form, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return string(page), string(form)
+	}
+
+	restore := install(t, Options{Record: dir})
+	page, form := login()
+	restore()
+	server.Close()
+	require.Contains(t, page, "s4mlr3quest")
+	require.Contains(t, form, "s4mlr3sponse")
+
+	files, err := fixtureFiles(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		require.NoError(t, err)
+		for _, secret := range []string{"s4mlr3quest", "s4mlr3sponse", "hunter2"} {
+			require.NotContains(t, string(data), secret, file)
+		}
+	}
+
+	// the pages replay with the secrets redacted and everything else intact
+	defer install(t, Options{Replay: dir})()
+	page, form = login()
+	require.Equal(t, `<html><body><form action="/saml"><textarea name="SAMLRequest">REDACTED</textarea></form></body></html>`, page)
+	require.Equal(t, `<html><body><form method="post" action="/provider">`+
+		`<input type="hidden" name="SAMLResponse" value="REDACTED"/>`+
+		`<input type="hidden" name="RelayState" value="/default.jsp"/>`+
+		`</form></body></html>`, form)
+}
+
+func TestDryRun(t *testing.T) {
+	writes := 0
+	server := fakeBugzilla(&writes)
+	defer server.Close()
+	defer install(t, Options{DryRun: true})()
+	require.True(t, DryRun())
+
+	c := client(server.URL, "s3cret")
+	bug, err := c.GetBug(1)
+	require.NoError(t, err)
+	require.Equal(t, "NEW", bug.Status)
+	require.NoError(t, c.UpdateBug(1, bugzilla.BugUpdate{Status: "POST"}))
+	// the JSON-RPC answer has the id the client checks for
+	changed, err := c.AddPullRequestAsExternalBug(1, "org", "repo", 2)
+	require.NoError(t, err)
+	require.False(t, changed)
+	require.Equal(t, 0, writes)
+}
+
+func TestScrubBody(t *testing.T) {
+	require.Equal(t, `{"params":[{"api_key":"REDACTED","bug_ids":[1]}]}`, scrubBody("application/json", []byte(`{"params":[{"api_key":"s3cret","bug_ids":[1]}]}`)))
+	require.Equal(t, "password=REDACTED&username=me", scrubBody("application/x-www-form-urlencoded", []byte("username=me&password=hunter2")))
+	require.Equal(t, "not json", scrubBody("text/plain", []byte("not json")))
+}
+
+func TestReadOnly(t *testing.T) {
+	request := func(method, url, contentType string) *http.Request {
+		req, err := http.NewRequest(method, url, nil)
+		require.NoError(t, err)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		return req
+	}
+	form := "application/x-www-form-urlencoded"
+	require.True(t, readOnly(request(http.MethodGet, "https://bugzilla.redhat.com/rest/bug/1", "")))
+	require.True(t, readOnly(request(http.MethodPost, "https://issues.redhat.com/rest/api/2/search", "application/json")))
+	require.True(t, readOnly(request(http.MethodPost, "https://issues.redhat.com/rest/auth/1/session", "application/json")))
+	require.True(t, readOnly(request(http.MethodPost, "https://sso.redhat.com/auth/realms/redhat-external/protocol/saml", form)))
+	require.True(t, readOnly(request(http.MethodPost, "https://sso.jboss.org/login?provider=RedHatExternalProvider", form)))
+	require.False(t, readOnly(request(http.MethodPost, "https://bugzilla.redhat.com/process_bug.cgi", form)))
+	require.False(t, readOnly(request(http.MethodPut, "https://bugzilla.redhat.com/rest/bug/1", "application/json")))
+	require.False(t, readOnly(request(http.MethodPost, "https://issues.redhat.com/rest/api/2/issue/X-1/comment", "application/json")))
+}
@@ -0,0 +1,78 @@
+package triage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Entry records a rule applied to a bug.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Bug     int       `json:"bug"`
+	Rule    string    `json:"rule"`
+	Changes []string  `json:"changes"`
+}
+
+// Audit is an append-only log of the rules applied to bugs, one json entry per
+// line. It is also how reruns know not to apply a rule again.
+type Audit struct {
+	path    string
+	applied map[int]map[string]bool
+}
+
+// OpenAudit reads the audit log at path, which is created on the first Record.
+func OpenAudit(path string) (*Audit, error) {
+	a := &Audit{path: path, applied: map[int]map[string]bool{}}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("could not parse %s line %d: %v", path, line, err)
+		}
+		a.add(e)
+	}
+	return a, scanner.Err()
+}
+
+func (a *Audit) add(e Entry) {
+	if a.applied[e.Bug] == nil {
+		a.applied[e.Bug] = map[string]bool{}
+	}
+	a.applied[e.Bug][e.Rule] = true
+}
+
+// Applied returns the names of the rules already applied to a bug.
+func (a *Audit) Applied(bug int) map[string]bool {
+	return a.applied[bug]
+}
+
+// Record appends entries to the log.
+func (a *Audit) Record(entries ...Entry) error {
+	f, err := os.OpenFile(a.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			return err
+		}
+		a.add(e)
+	}
+	return f.Close()
+}
@@ -0,0 +1,150 @@
+// Package triage routes bugs with rules matching on what a bug is about and
+// setting who should look at it.
+//
+// Rules are kept in yaml:
+//
+//	rules:
+//	- name: catalog
+//	  match:
+//	    summary: (?i)catalog|index image|grpc
+//	    component: [OLM]
+//	  set:
+//	    assignee: catalog-owner@redhat.com
+//	    sub_component: OperatorHub
+//	    whiteboard: [catalog]
+//	- name: webhooks
+//	  match:
+//	    summary: (?i)webhook
+//	    description: (?i)(validating|mutating|conversion) webhook
+//	  set:
+//	    assignee: webhook-owner@redhat.com
+//	    priority: high
+//
+// Summary and description are regular expressions, the other matches are
+// lists of values of which the bug must have one (all, for keywords). Every
+// matching rule is applied in order, and a field set by an earlier rule is
+// not overridden by a later one.
+package triage
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+)
+
+// Rules is a list of triage rules.
+type Rules struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+// Rule sets fields of the bugs it matches.
+type Rule struct {
+	Name  string `yaml:"name"`
+	Match Match  `yaml:"match"`
+	Set   Set    `yaml:"set"`
+
+	summary     *regexp.Regexp
+	description *regexp.Regexp
+}
+
+// Match selects the bugs a rule applies to.
+type Match struct {
+	Summary     string   `yaml:"summary,omitempty"`
+	Description string   `yaml:"description,omitempty"`
+	Component   []string `yaml:"component,omitempty"`
+	Reporter    []string `yaml:"reporter,omitempty"`
+	Keywords    []string `yaml:"keywords,omitempty"`
+}
+
+// Set is what a rule changes on the bugs it matches.
+type Set struct {
+	Assignee     string `yaml:"assignee,omitempty"`
+	SubComponent string `yaml:"sub_component,omitempty"`
+	Priority     string `yaml:"priority,omitempty"`
+	// Whiteboard tokens are added to the status whiteboard.
+	Whiteboard []string `yaml:"whiteboard,omitempty"`
+}
+
+// Load reads a rules file.
+func Load(path string) (*Rules, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rules %s: %v", path, err)
+	}
+	return r, nil
+}
+
+// Parse parses and validates triage rules.
+func Parse(data []byte) (*Rules, error) {
+	rules := &Rules{}
+	if err := yaml.UnmarshalStrict(data, rules); err != nil {
+		return nil, err
+	}
+	if len(rules.Rules) == 0 {
+		return nil, fmt.Errorf("no rules")
+	}
+	names := map[string]bool{}
+	for i, r := range rules.Rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("rule %d has no name", i+1)
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("rule %s is defined twice", r.Name)
+		}
+		names[r.Name] = true
+		if err := r.compile(); err != nil {
+			return nil, fmt.Errorf("rule %s: %v", r.Name, err)
+		}
+	}
+	return rules, nil
+}
+
+func (r *Rule) compile() error {
+	var err error
+	if r.Match.Summary != "" {
+		if r.summary, err = regexp.Compile(r.Match.Summary); err != nil {
+			return fmt.Errorf("invalid summary: %v", err)
+		}
+	}
+	if r.Match.Description != "" {
+		if r.description, err = regexp.Compile(r.Match.Description); err != nil {
+			return fmt.Errorf("invalid description: %v", err)
+		}
+	}
+	if p := r.Set.Priority; p != "" && !contains(bugzilla.Priorities, p) {
+		return fmt.Errorf("invalid priority %q", p)
+	}
+	s := r.Set
+	if s.Assignee == "" && s.SubComponent == "" && s.Priority == "" && len(s.Whiteboard) == 0 {
+		return fmt.Errorf("sets nothing")
+	}
+	return nil
+}
+
+// NeedsDescription reports whether any rule matches on the description,
+// which costs a request per bug to fetch.
+func (rules *Rules) NeedsDescription() bool {
+	for _, r := range rules.Rules {
+		if r.description != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
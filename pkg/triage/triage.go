@@ -0,0 +1,132 @@
+package triage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+)
+
+// Change is what one rule changes on a bug.
+type Change struct {
+	Rule    string   `json:"rule"`
+	Changes []string `json:"changes"`
+}
+
+// Decision is the triage of one bug: the rules that change it and the update
+// making those changes.
+type Decision struct {
+	Bug     *bugzilla.Bug
+	Applied []Change
+	Update  bugzilla.BugUpdate
+}
+
+// Triage decides what the rules change on a bug. Rules in done have already
+// been applied to the bug and are not applied again, though the fields they
+// set are still not overridden by later rules, so that a bug rerouted by hand
+// stays where it was put. It returns nil if nothing changes.
+func (rules *Rules) Triage(b *bugzilla.Bug, description string, done map[string]bool) *Decision {
+	d := &Decision{Bug: b}
+	var assignee, subComponent, priority bool
+	whiteboard := strings.Fields(b.Whiteboard)
+	for _, r := range rules.Rules {
+		if !r.matches(b, description) {
+			continue
+		}
+		var changes []string
+		s := r.Set
+		if s.Assignee != "" && !assignee {
+			assignee = true
+			if !done[r.Name] && s.Assignee != b.AssignedTo {
+				d.Update.AssignedTo = s.Assignee
+				changes = append(changes, change("assignee", b.AssignedTo, s.Assignee))
+			}
+		}
+		if s.SubComponent != "" && !subComponent && len(b.Component) > 0 {
+			subComponent = true
+			component := b.Component[0]
+			current := b.SubComponents[component]
+			if !done[r.Name] && (len(current) != 1 || current[0] != s.SubComponent) {
+				d.Update.SubComponents = map[string][]string{component: {s.SubComponent}}
+				changes = append(changes, change("sub component", strings.Join(current, ","), s.SubComponent))
+			}
+		}
+		if s.Priority != "" && !priority {
+			priority = true
+			if !done[r.Name] && s.Priority != b.Priority {
+				d.Update.Priority = s.Priority
+				changes = append(changes, change("priority", b.Priority, s.Priority))
+			}
+		}
+		if !done[r.Name] {
+			for _, token := range s.Whiteboard {
+				if !contains(whiteboard, token) {
+					whiteboard = append(whiteboard, token)
+					changes = append(changes, "whiteboard +"+token)
+				}
+			}
+		}
+		if len(changes) > 0 {
+			d.Applied = append(d.Applied, Change{Rule: r.Name, Changes: changes})
+		}
+	}
+	if len(d.Applied) == 0 {
+		return nil
+	}
+	if wb := strings.Join(whiteboard, " "); wb != strings.Join(strings.Fields(b.Whiteboard), " ") {
+		d.Update.Whiteboard = &wb
+	}
+	return d
+}
+
+// change describes a field changing
+func change(field, from, to string) string {
+	if from == "" {
+		from = "(none)"
+	}
+	return fmt.Sprintf("%s %s → %s", field, from, to)
+}
+
+func (r *Rule) matches(b *bugzilla.Bug, description string) bool {
+	m := r.Match
+	if r.summary != nil && !r.summary.MatchString(b.Summary) {
+		return false
+	}
+	if r.description != nil && !r.description.MatchString(description) {
+		return false
+	}
+	if len(m.Component) > 0 && !anyOf(m.Component, b.Component) {
+		return false
+	}
+	if len(m.Reporter) > 0 && !contains(m.Reporter, b.Creator) {
+		return false
+	}
+	for _, k := range m.Keywords {
+		if !contains(b.Keywords, k) {
+			return false
+		}
+	}
+	return true
+}
+
+func anyOf(want, have []string) bool {
+	for _, h := range have {
+		if contains(want, h) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply makes a decision's update and records the rules applied in the audit log.
+func Apply(client bugzilla.Client, d *Decision, audit *Audit, now time.Time) error {
+	if err := client.UpdateBug(d.Bug.ID, d.Update); err != nil {
+		return fmt.Errorf("could not update bug %d: %v", d.Bug.ID, err)
+	}
+	var entries []Entry
+	for _, c := range d.Applied {
+		entries = append(entries, Entry{Time: now, Bug: d.Bug.ID, Rule: c.Rule, Changes: c.Changes})
+	}
+	return audit.Record(entries...)
+}
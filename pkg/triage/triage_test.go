@@ -0,0 +1,108 @@
+package triage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+)
+
+const testRules = `
+rules:
+- name: catalog
+  match:
+    summary: (?i)catalog|index image
+    component: [OLM]
+  set:
+    assignee: catalog@redhat.com
+    sub_component: OperatorHub
+    whiteboard: [catalog]
+- name: webhooks
+  match:
+    description: (?i)conversion webhook
+  set:
+    assignee: webhooks@redhat.com
+    priority: high
+    whiteboard: [webhook]
+- name: qe-reported
+  match:
+    reporter: [qe@redhat.com]
+    keywords: [Regression]
+  set:
+    priority: urgent
+`
+
+func TestParse(t *testing.T) {
+	for _, bad := range []string{
+		"",
+		"rules: [{set: {priority: high}}]",
+		"rules: [{name: a}]",
+		"rules: [{name: a, set: {priority: soon}}]",
+		"rules: [{name: a, match: {summary: '('}, set: {priority: high}}]",
+		"rules: [{name: a, set: {priority: high}}, {name: a, set: {priority: low}}]",
+	} {
+		_, err := Parse([]byte(bad))
+		require.Error(t, err, bad)
+	}
+}
+
+func TestTriage(t *testing.T) {
+	rules, err := Parse([]byte(testRules))
+	require.NoError(t, err)
+	require.True(t, rules.NeedsDescription())
+
+	bug := &bugzilla.Bug{ID: 1, Summary: "Catalog pod crashes on conversion webhook", Component: []string{"OLM"}, Whiteboard: "triaged"}
+	d := rules.Triage(bug, "the conversion webhook times out", nil)
+	require.Equal(t, []Change{
+		{Rule: "catalog", Changes: []string{"assignee (none) → catalog@redhat.com", "sub component (none) → OperatorHub", "whiteboard +catalog"}},
+		// the assignee was already set by the catalog rule
+		{Rule: "webhooks", Changes: []string{"priority (none) → high", "whiteboard +webhook"}},
+	}, d.Applied)
+	whiteboard := "triaged catalog webhook"
+	require.Equal(t, bugzilla.BugUpdate{
+		AssignedTo:    "catalog@redhat.com",
+		SubComponents: map[string][]string{"OLM": {"OperatorHub"}},
+		Priority:      "high",
+		Whiteboard:    &whiteboard,
+	}, d.Update)
+
+	// rules already applied are skipped, but still claim their fields
+	d = rules.Triage(bug, "the conversion webhook times out", map[string]bool{"catalog": true})
+	require.Equal(t, []Change{{Rule: "webhooks", Changes: []string{"priority (none) → high", "whiteboard +webhook"}}}, d.Applied)
+
+	// nothing to do for a bug that already looks the part
+	bug = &bugzilla.Bug{ID: 2, Summary: "upgrade", Creator: "qe@redhat.com", Keywords: []string{"Regression"}, Priority: "urgent"}
+	require.Nil(t, rules.Triage(bug, "", nil))
+}
+
+func TestApplyAndAudit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cop-triage")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "triage.log")
+
+	rules, err := Parse([]byte(testRules))
+	require.NoError(t, err)
+	client := &bugzilla.Fake{Bugs: map[int]bugzilla.Bug{1: {ID: 1, Summary: "index image not pulled", Component: []string{"OLM"}}}}
+	audit, err := OpenAudit(path)
+	require.NoError(t, err)
+
+	bug := client.Bugs[1]
+	d := rules.Triage(&bug, "", audit.Applied(1))
+	require.NoError(t, Apply(client, d, audit, time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)))
+	require.Equal(t, "catalog@redhat.com", client.Bugs[1].AssignedTo)
+	require.Equal(t, "catalog", client.Bugs[1].Whiteboard)
+
+	// someone reroutes the bug by hand, and a rerun leaves it be
+	bug = client.Bugs[1]
+	bug.AssignedTo = "someone@redhat.com"
+	audit, err = OpenAudit(path)
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"catalog": true}, audit.Applied(1))
+	require.Nil(t, rules.Triage(&bug, "", audit.Applied(1)))
+}
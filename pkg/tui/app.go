@@ -0,0 +1,241 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chzyer/readline"
+	"github.com/sirupsen/logrus"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/view"
+)
+
+// backportPrefix is how the desired backport is recorded in the internal
+// whiteboard, matching `cop bz backport`.
+const backportPrefix = "backport-to: "
+
+// Options configure the interface.
+type Options struct {
+	Client bugzilla.Client
+	// Query is the search listing the bugs to triage.
+	Query string
+	// Refresh is how often the bugs are searched for again.
+	Refresh time.Duration
+	// Backports are the versions offered when setting a backport.
+	Backports []string
+	// RowView picks the columns shown for each bug.
+	RowView func(bugzilla.Bug) view.CLIMarshaller
+	// OpenBrowser opens a url, defaulting to view.OpenBrowser.
+	OpenBrowser func(url string) error
+}
+
+// Screen is what the app draws on.
+type Screen interface {
+	Size() (int, int)
+	Draw(lines []string) error
+}
+
+// App runs the interface: it feeds key presses to the model and carries out
+// the model's actions against bugzilla in the background.
+type App struct {
+	opts  Options
+	model *Model
+	// results carry the outcome of background work back to the main loop,
+	// which is the only place the model is changed.
+	results    chan func(*Model)
+	refreshing bool
+	// stale is set when the bugs changed during a refresh, so it needs redoing
+	stale  bool
+	logger *logrus.Entry
+}
+
+func NewApp(opts Options) *App {
+	if opts.OpenBrowser == nil {
+		opts.OpenBrowser = view.OpenBrowser
+	}
+	return &App{
+		opts:    opts,
+		model:   NewModel(opts.RowView, opts.Backports),
+		results: make(chan func(*Model), 16),
+		logger:  logrus.WithField("component", "tui"),
+	}
+}
+
+// Run takes over the terminal until the user quits or ctx is done.
+func Run(ctx context.Context, opts Options) error {
+	term, err := OpenTerminal()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := term.Close(); err != nil {
+			logrus.WithError(err).Warn("could not restore terminal")
+		}
+	}()
+	resized := make(chan struct{}, 1)
+	readline.DefaultOnWidthChanged(func() {
+		select {
+		case resized <- struct{}{}:
+		default:
+		}
+	})
+	return NewApp(opts).Loop(ctx, term.Keys(), resized, term)
+}
+
+// Loop handles events until the user quits or ctx is done.
+func (a *App) Loop(ctx context.Context, keys <-chan Key, resized <-chan struct{}, screen Screen) error {
+	var tick <-chan time.Time
+	if a.opts.Refresh > 0 {
+		ticker := time.NewTicker(a.opts.Refresh)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	a.model.Resize(screen.Size())
+	a.model.SetStatus("Loading bugs…")
+	a.refresh(true)
+	for {
+		if err := screen.Draw(a.model.View()); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case k, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			action := a.model.HandleKey(k)
+			if action.Kind == ActionQuit {
+				return nil
+			}
+			a.do(action)
+		case result := <-a.results:
+			result(a.model)
+		case <-resized:
+			a.model.Resize(screen.Size())
+		case <-tick:
+			a.refresh(false)
+		}
+	}
+}
+
+// background runs work off the main loop and applies its result to the model
+func (a *App) background(work func() func(*Model)) {
+	go func() {
+		a.results <- work()
+	}()
+}
+
+// refresh searches for the bugs again, saying so in the footer if announce is set
+func (a *App) refresh(announce bool) {
+	if a.refreshing {
+		a.stale = true
+		return
+	}
+	a.refreshing = true
+	a.background(func() func(*Model) {
+		bugs, err := a.opts.Client.SearchBugs(a.opts.Query)
+		return func(m *Model) {
+			a.refreshing = false
+			if a.stale {
+				a.stale = false
+				a.refresh(false)
+			}
+			if err != nil {
+				m.SetStatus("Could not load bugs: %v", err)
+				return
+			}
+			m.SetBugs(bugs)
+			if announce {
+				m.SetStatus("Loaded %d bugs at %s.", len(bugs), time.Now().Format("15:04"))
+			}
+		}
+	})
+}
+
+func (a *App) do(action Action) {
+	client := a.opts.Client
+	switch action.Kind {
+	case ActionRefresh:
+		a.model.SetStatus("Refreshing…")
+		a.refresh(true)
+	case ActionOpen:
+		for _, id := range action.IDs {
+			if err := a.opts.OpenBrowser(bugzilla.BugURL(client.Endpoint(), id)); err != nil {
+				a.model.SetStatus("Could not open bug %d: %v", id, err)
+				return
+			}
+		}
+	case ActionComments:
+		id := action.IDs[0]
+		a.background(func() func(*Model) {
+			comments, err := client.GetCommentsOnBug(id)
+			return func(m *Model) {
+				m.SetComments(id, comments, err)
+			}
+		})
+	case ActionAssign:
+		a.update(action, func(bugs string) string {
+			return fmt.Sprintf("Assigned %s to %s.", bugs, action.Value)
+		}, func(id int) error {
+			return client.UpdateBug(id, bugzilla.BugUpdate{AssignedTo: action.Value})
+		})
+	case ActionPriority:
+		a.update(action, func(bugs string) string {
+			return fmt.Sprintf("Set priority of %s to %s.", bugs, action.Value)
+		}, func(id int) error {
+			return client.UpdateBug(id, bugzilla.BugUpdate{Priority: action.Value})
+		})
+	case ActionNeedinfo:
+		a.update(action, func(bugs string) string {
+			return fmt.Sprintf("Requested info on %s from %s.", bugs, action.Value)
+		}, func(id int) error {
+			return client.UpdateBug(id, bugzilla.BugUpdate{Flags: []bugzilla.FlagChange{
+				{Name: "needinfo", Status: "?", Requestee: action.Value},
+			}})
+		})
+	case ActionBackport:
+		a.update(action, func(bugs string) string {
+			return fmt.Sprintf("Set backport of %s to %s.", bugs, action.Value)
+		}, func(id int) error {
+			_, err := client.UpdateInternalWhiteboard(id, backportPrefix+action.Value)
+			return err
+		})
+	}
+}
+
+// update applies change to each bug in the background, then refreshes the
+// list to show the result. done describes the change given the bugs changed.
+// A bug that can't be changed doesn't stop the others, and each failure is
+// reported.
+func (a *App) update(action Action, done func(bugs string) string, change func(id int) error) {
+	a.model.SetStatus("Updating %s…", describe(action.IDs))
+	a.background(func() func(*Model) {
+		var (
+			changed []int
+			failed  []string
+		)
+		for _, id := range action.IDs {
+			if err := change(id); err != nil {
+				a.logger.WithError(err).WithField("id", id).Debug("update failed")
+				failed = append(failed, fmt.Sprintf("bug %d: %v", id, err))
+				continue
+			}
+			changed = append(changed, id)
+		}
+		return func(m *Model) {
+			switch {
+			case len(failed) == 0:
+				m.SetStatus("%s", done(describe(changed)))
+			case len(changed) == 0:
+				m.SetStatus("Could not update %s", strings.Join(failed, "; "))
+			default:
+				m.SetStatus("%s Could not update %s", done(describe(changed)), strings.Join(failed, "; "))
+			}
+			a.refresh(false)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package tui
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+)
+
+type fakeScreen struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (s *fakeScreen) Size() (int, int) {
+	return 120, 20
+}
+
+func (s *fakeScreen) Draw(lines []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lines = lines
+	return nil
+}
+
+func (s *fakeScreen) footer() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.lines) == 0 {
+		return ""
+	}
+	return s.lines[len(s.lines)-1]
+}
+
+func TestAppLoop(t *testing.T) {
+	client := &bugzilla.Fake{
+		EndpointString: "https://bugzilla.example.com",
+		Bugs: map[int]bugzilla.Bug{
+			1: {ID: 1, Status: "NEW", Summary: "catalog operator crashes on start", Priority: "unspecified"},
+		},
+		BugComments: map[int][]bugzilla.Comment{1: {{Text: "Steps to reproduce"}}},
+	}
+	var opened []string
+	app := NewApp(Options{
+		Client:    client,
+		RowView:   testRowView,
+		Backports: []string{"4.4"},
+		OpenBrowser: func(url string) error {
+			opened = append(opened, url)
+			return nil
+		},
+	})
+
+	keys := make(chan Key)
+	screen := &fakeScreen{}
+	done := make(chan error)
+	go func() {
+		done <- app.Loop(context.Background(), keys, nil, screen)
+	}()
+	send := func(s string) {
+		for _, k := range ParseKeys([]byte(s)) {
+			keys <- k
+		}
+	}
+	waitFor := func(text string) {
+		require.Eventually(t, func() bool {
+			return strings.Contains(screen.footer(), text)
+		}, 5*time.Second, 5*time.Millisecond, "footer never showed %q", text)
+	}
+
+	waitFor("Loaded 1 bugs")
+	send("p2")
+	waitFor("Set priority of bug 1 to high.")
+	send("ndev@example.com\r")
+	waitFor("Requested info on bug 1 from dev@example.com.")
+	send("b4.4\r")
+	waitFor("Set backport of bug 1 to 4.4.")
+	send("o")
+	send("q")
+	require.NoError(t, <-done)
+
+	bug, err := client.GetBug(1)
+	require.NoError(t, err)
+	require.Equal(t, "high", bug.Priority)
+	require.Equal(t, "backport-to: 4.4", bug.InternalWhiteboard)
+	require.Equal(t, []bugzilla.Flag{{Name: "needinfo", Status: "?", Requestee: "dev@example.com"}}, bug.Flags)
+	require.Equal(t, []string{"https://bugzilla.example.com/show_bug.cgi?id=1"}, opened)
+}
+
+func TestAppBulkUpdateContinuesPastFailures(t *testing.T) {
+	client := &bugzilla.Fake{
+		EndpointString: "https://bugzilla.example.com",
+		Bugs: map[int]bugzilla.Bug{
+			1: {ID: 1, Status: "NEW", Summary: "catalog operator crashes on start", Priority: "unspecified"},
+			2: {ID: 2, Status: "NEW", Summary: "proxy settings ignored", Priority: "unspecified"},
+			3: {ID: 3, Status: "NEW", Summary: "subscription stuck upgrading", Priority: "unspecified"},
+		},
+		BugErrors: map[int]bool{2: true},
+	}
+	app := NewApp(Options{Client: client, RowView: testRowView})
+
+	keys := make(chan Key)
+	screen := &fakeScreen{}
+	done := make(chan error)
+	go func() {
+		done <- app.Loop(context.Background(), keys, nil, screen)
+	}()
+	waitFor := func(text string) {
+		require.Eventually(t, func() bool {
+			return strings.Contains(screen.footer(), text)
+		}, 5*time.Second, 5*time.Millisecond, "footer never showed %q", text)
+	}
+
+	waitFor("Loaded 3 bugs")
+	for _, k := range ParseKeys([]byte("   p2")) {
+		keys <- k
+	}
+	waitFor("Set priority of 2 bugs to high. Could not update bug 2: injected error updating bug")
+	keys <- Key{Rune: 'q'}
+	require.NoError(t, <-done)
+
+	for _, id := range []int{1, 3} {
+		require.Equal(t, "high", client.Bugs[id].Priority)
+	}
+}
@@ -0,0 +1,35 @@
+package tui
+
+import (
+	"unicode"
+)
+
+// fuzzyMatch reports whether the runes of pattern appear in text in order,
+// ignoring case, and scores the match. Runes matched in a row or at the start
+// of a word score higher.
+func fuzzyMatch(pattern, text string) (int, bool) {
+	p := []rune(pattern)
+	if len(p) == 0 {
+		return 0, true
+	}
+	score, pi := 0, 0
+	prevMatched := false
+	prev := ' '
+	for _, r := range text {
+		if pi < len(p) && unicode.ToLower(r) == unicode.ToLower(p[pi]) {
+			score++
+			if prevMatched {
+				score += 2
+			}
+			if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+				score += 3
+			}
+			pi++
+			prevMatched = true
+		} else {
+			prevMatched = false
+		}
+		prev = r
+	}
+	return score, pi == len(p)
+}
@@ -0,0 +1,127 @@
+package tui
+
+import (
+	"io"
+)
+
+// Special is a key that doesn't type a character.
+type Special int
+
+const (
+	KeyNone Special = iota
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyPageUp
+	KeyPageDown
+	KeyHome
+	KeyEnd
+	KeyEnter
+	KeyTab
+	KeyBackspace
+	KeyEscape
+	KeyCtrlC
+)
+
+// Key is a single key press, either a typed rune or a special key.
+type Key struct {
+	Rune    rune
+	Special Special
+}
+
+func runeKey(r rune) Key {
+	return Key{Rune: r}
+}
+
+func specialKey(s Special) Key {
+	return Key{Special: s}
+}
+
+// csiKeys are the final bytes of the escape sequences for special keys
+var csiKeys = map[byte]Special{
+	'A': KeyUp,
+	'B': KeyDown,
+	'C': KeyRight,
+	'D': KeyLeft,
+	'H': KeyHome,
+	'F': KeyEnd,
+}
+
+// numbered escape sequences, like ESC [ 5 ~
+var tildeKeys = map[string]Special{
+	"1": KeyHome,
+	"4": KeyEnd,
+	"5": KeyPageUp,
+	"6": KeyPageDown,
+	"7": KeyHome,
+	"8": KeyEnd,
+}
+
+// ParseKeys splits raw terminal input into key presses. An escape that
+// doesn't start a known sequence is reported as KeyEscape.
+func ParseKeys(input []byte) []Key {
+	var keys []Key
+	runes := []rune(string(input))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch r {
+		case '\r', '\n':
+			keys = append(keys, specialKey(KeyEnter))
+		case '\t':
+			keys = append(keys, specialKey(KeyTab))
+		case 0x7f, 0x08:
+			keys = append(keys, specialKey(KeyBackspace))
+		case 0x03:
+			keys = append(keys, specialKey(KeyCtrlC))
+		case 0x1b:
+			key, n := parseEscape(runes[i+1:])
+			keys = append(keys, key)
+			i += n
+		default:
+			if r >= ' ' {
+				keys = append(keys, runeKey(r))
+			}
+		}
+	}
+	return keys
+}
+
+// parseEscape parses the runes following an escape, returning the key and
+// how many runes it consumed.
+func parseEscape(rest []rune) (Key, int) {
+	if len(rest) < 2 || (rest[0] != '[' && rest[0] != 'O') {
+		return specialKey(KeyEscape), 0
+	}
+	if s, ok := csiKeys[byte(rest[1])]; ok {
+		return specialKey(s), 2
+	}
+	for j := 1; j < len(rest); j++ {
+		if rest[j] == '~' {
+			if s, ok := tildeKeys[string(rest[1:j])]; ok {
+				return specialKey(s), j + 1
+			}
+			return Key{}, j + 1
+		}
+		if rest[j] < '0' || rest[j] > '9' {
+			break
+		}
+	}
+	return specialKey(KeyEscape), 0
+}
+
+// ReadKeys reads key presses from r until it fails, sending them to keys.
+func ReadKeys(r io.Reader, keys chan<- Key) error {
+	buf := make([]byte, 256)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			return err
+		}
+		for _, k := range ParseKeys(buf[:n]) {
+			if k != (Key{}) {
+				keys <- k
+			}
+		}
+	}
+}
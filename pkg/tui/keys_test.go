@@ -0,0 +1,32 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeys(t *testing.T) {
+	require.Equal(t, []Key{
+		runeKey('j'),
+		specialKey(KeyUp),
+		specialKey(KeyPageDown),
+		runeKey('é'),
+		specialKey(KeyEnter),
+		specialKey(KeyBackspace),
+		specialKey(KeyEscape),
+	}, ParseKeys([]byte("j\x1b[A\x1b[6~é\r\x7f\x1b")))
+	require.Equal(t, []Key{specialKey(KeyEscape), runeKey('q')}, ParseKeys([]byte("\x1bq")))
+	require.Equal(t, []Key{specialKey(KeyHome), specialKey(KeyCtrlC)}, ParseKeys([]byte("\x1bOH\x03")))
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	_, ok := fuzzyMatch("olmcrash", "1812345  NEW  OLM pod crashes")
+	require.True(t, ok)
+	_, ok = fuzzyMatch("crashy", "1812345  NEW  OLM pod crashes")
+	require.False(t, ok)
+
+	word, _ := fuzzyMatch("pod", "OLM pod crashes")
+	scattered, _ := fuzzyMatch("pod", "operator deployment")
+	require.True(t, word > scattered)
+}
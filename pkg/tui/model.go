@@ -0,0 +1,489 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/view"
+)
+
+// ActionKind is something the model asks the app to do in the background.
+type ActionKind int
+
+const (
+	ActionNone ActionKind = iota
+	ActionQuit
+	ActionRefresh
+	ActionOpen
+	ActionComments
+	ActionAssign
+	ActionPriority
+	ActionBackport
+	ActionNeedinfo
+)
+
+// Action is a request from the model to the app, applying to the bugs in IDs.
+type Action struct {
+	Kind  ActionKind
+	IDs   []int
+	Value string
+}
+
+type mode int
+
+const (
+	modeList mode = iota
+	modeDetail
+	modeFilter
+	modePrompt
+)
+
+// prompt asks for a value in the footer before running an action
+type prompt struct {
+	label   string
+	input   []rune
+	choices []string
+	// back is the mode to return to once the prompt is done
+	back   mode
+	submit func(value string) Action
+}
+
+// Model is the state of the interface. It handles key presses and draws
+// itself, leaving anything slow to the app through Actions.
+type Model struct {
+	rowView   func(bugzilla.Bug) view.CLIMarshaller
+	backports []string
+
+	bugs []*bugzilla.Bug
+	// header and rows are the rendered table for bugs
+	header  string
+	rows    []string
+	visible []int
+	cursor  int
+	offset  int
+
+	selected map[int]bool
+	filter   []rune
+	mode     mode
+	prompt   *prompt
+
+	comments     map[int][]bugzilla.Comment
+	commentErrs  map[int]error
+	changed      map[int]string
+	detailScroll int
+
+	status string
+	width  int
+	height int
+}
+
+// NewModel returns an empty model. rowView picks the columns shown for each
+// bug, and backports are the versions offered when setting a backport.
+func NewModel(rowView func(bugzilla.Bug) view.CLIMarshaller, backports []string) *Model {
+	return &Model{
+		rowView:     rowView,
+		backports:   backports,
+		selected:    map[int]bool{},
+		comments:    map[int][]bugzilla.Comment{},
+		commentErrs: map[int]error{},
+		changed:     map[int]string{},
+		width:       80,
+		height:      24,
+	}
+}
+
+// Resize sets the size of the screen.
+func (m *Model) Resize(width, height int) {
+	m.width, m.height = width, height
+	m.scroll()
+}
+
+// SetStatus shows a message in the footer.
+func (m *Model) SetStatus(format string, args ...interface{}) {
+	m.status = fmt.Sprintf(format, args...)
+}
+
+// SetBugs replaces the bugs shown, keeping the cursor and selection on the
+// same bugs where they still exist.
+func (m *Model) SetBugs(bugs []*bugzilla.Bug) {
+	current := m.Current()
+	m.bugs = bugs
+	present := map[int]bool{}
+	views := make([]view.CLIMarshaller, 0, len(bugs))
+	for _, b := range bugs {
+		present[b.ID] = true
+		views = append(views, m.rowView(*b))
+		// comments are reloaded for bugs that changed since they were fetched
+		if last, ok := m.changed[b.ID]; ok && last != b.LastChangeTime {
+			delete(m.comments, b.ID)
+			delete(m.commentErrs, b.ID)
+		}
+		m.changed[b.ID] = b.LastChangeTime
+	}
+	for id := range m.selected {
+		if !present[id] {
+			delete(m.selected, id)
+		}
+	}
+
+	m.header, m.rows = "", nil
+	if len(views) > 0 {
+		lines, err := view.Table(views)
+		if err != nil {
+			m.SetStatus("Could not render bugs: %v", err)
+		} else {
+			m.header, m.rows = lines[0], lines[1:]
+		}
+	}
+	// the old visible indexes don't apply to the new bugs
+	m.visible = nil
+	m.refilter()
+	if current != nil {
+		m.moveTo(current.ID)
+	}
+}
+
+// SetComments records the comments on a bug, or why they couldn't be loaded.
+func (m *Model) SetComments(id int, comments []bugzilla.Comment, err error) {
+	if err != nil {
+		m.commentErrs[id] = err
+		return
+	}
+	delete(m.commentErrs, id)
+	m.comments[id] = comments
+}
+
+// Current returns the bug under the cursor.
+func (m *Model) Current() *bugzilla.Bug {
+	if m.cursor < 0 || m.cursor >= len(m.visible) {
+		return nil
+	}
+	return m.bugs[m.visible[m.cursor]]
+}
+
+// Selected returns the ids of the selected bugs in order.
+func (m *Model) Selected() []int {
+	ids := make([]int, 0, len(m.selected))
+	for id := range m.selected {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// targets are the bugs an action applies to: the selection, or else the
+// bug under the cursor.
+func (m *Model) targets() []int {
+	if ids := m.Selected(); len(ids) > 0 {
+		return ids
+	}
+	if b := m.Current(); b != nil {
+		return []int{b.ID}
+	}
+	return nil
+}
+
+// refilter recomputes the visible bugs, keeping the cursor on the current
+// bug if it is still visible
+func (m *Model) refilter() {
+	current := m.Current()
+	m.visible = m.visible[:0]
+	scores := map[int]int{}
+	for i, row := range m.rows {
+		if score, ok := fuzzyMatch(string(m.filter), row); ok {
+			scores[i] = score
+			m.visible = append(m.visible, i)
+		}
+	}
+	if len(m.filter) > 0 {
+		sort.SliceStable(m.visible, func(a, b int) bool {
+			return scores[m.visible[a]] > scores[m.visible[b]]
+		})
+	}
+	m.cursor = 0
+	if current != nil {
+		m.moveTo(current.ID)
+	}
+	m.scroll()
+}
+
+// typeFilter changes the filter, moving the cursor to the best match
+func (m *Model) typeFilter(filter []rune) {
+	m.filter = filter
+	m.refilter()
+	m.cursor = 0
+	m.scroll()
+}
+
+func (m *Model) moveTo(id int) {
+	for i, idx := range m.visible {
+		if m.bugs[idx].ID == id {
+			m.cursor = i
+			break
+		}
+	}
+	m.scroll()
+}
+
+func (m *Model) move(delta int) {
+	m.cursor += delta
+	m.detailScroll = 0
+	m.scroll()
+}
+
+// listHeight is how many bug rows fit in the list pane
+func (m *Model) listHeight() int {
+	// title, table header and footer
+	if h := m.height - 3; h > 0 {
+		return h
+	}
+	return 1
+}
+
+// scroll keeps the cursor in range and on screen
+func (m *Model) scroll() {
+	if m.cursor >= len(m.visible) {
+		m.cursor = len(m.visible) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+m.listHeight() {
+		m.offset = m.cursor - m.listHeight() + 1
+	}
+}
+
+// HandleKey updates the model for a key press and returns anything the app
+// should do in response.
+func (m *Model) HandleKey(k Key) Action {
+	if k.Special == KeyCtrlC {
+		return Action{Kind: ActionQuit}
+	}
+	switch m.mode {
+	case modeFilter:
+		return m.handleFilterKey(k)
+	case modePrompt:
+		return m.handlePromptKey(k)
+	case modeDetail:
+		return m.handleDetailKey(k)
+	default:
+		return m.handleListKey(k)
+	}
+}
+
+func (m *Model) handleListKey(k Key) Action {
+	switch {
+	case k.Rune == 'j' || k.Special == KeyDown:
+		m.move(1)
+	case k.Rune == 'k' || k.Special == KeyUp:
+		m.move(-1)
+	case k.Special == KeyPageDown:
+		m.move(m.listHeight())
+	case k.Special == KeyPageUp:
+		m.move(-m.listHeight())
+	case k.Rune == 'g' || k.Special == KeyHome:
+		m.move(-len(m.visible))
+	case k.Rune == 'G' || k.Special == KeyEnd:
+		m.move(len(m.visible))
+	case k.Rune == ' ':
+		if b := m.Current(); b != nil {
+			if m.selected[b.ID] {
+				delete(m.selected, b.ID)
+			} else {
+				m.selected[b.ID] = true
+			}
+			m.move(1)
+		}
+	case k.Rune == 'V':
+		for _, idx := range m.visible {
+			m.selected[m.bugs[idx].ID] = true
+		}
+	case k.Rune == 'X':
+		m.selected = map[int]bool{}
+	case k.Rune == '/':
+		m.mode = modeFilter
+	case k.Special == KeyEscape:
+		if len(m.filter) > 0 {
+			m.filter = nil
+			m.refilter()
+		}
+	case k.Special == KeyEnter || k.Special == KeyTab || k.Special == KeyRight || k.Rune == 'l':
+		return m.openDetail()
+	case k.Rune == 'q':
+		return Action{Kind: ActionQuit}
+	default:
+		return m.handleActionKey(k)
+	}
+	return Action{}
+}
+
+func (m *Model) openDetail() Action {
+	b := m.Current()
+	if b == nil {
+		return Action{}
+	}
+	m.mode = modeDetail
+	m.detailScroll = 0
+	if _, ok := m.comments[b.ID]; ok {
+		return Action{}
+	}
+	return Action{Kind: ActionComments, IDs: []int{b.ID}}
+}
+
+func (m *Model) handleDetailKey(k Key) Action {
+	switch {
+	case k.Rune == 'j' || k.Special == KeyDown:
+		m.detailScroll++
+	case k.Rune == 'k' || k.Special == KeyUp:
+		if m.detailScroll > 0 {
+			m.detailScroll--
+		}
+	case k.Special == KeyPageDown || k.Rune == ' ':
+		m.detailScroll += m.listHeight()
+	case k.Special == KeyPageUp:
+		m.detailScroll -= m.listHeight()
+		if m.detailScroll < 0 {
+			m.detailScroll = 0
+		}
+	case k.Rune == 'J':
+		m.move(1)
+		return m.openDetail()
+	case k.Rune == 'K':
+		m.move(-1)
+		return m.openDetail()
+	case k.Special == KeyEscape || k.Special == KeyTab || k.Special == KeyLeft || k.Rune == 'h':
+		m.mode = modeList
+	case k.Rune == 'q':
+		return Action{Kind: ActionQuit}
+	default:
+		return m.handleActionKey(k)
+	}
+	return Action{}
+}
+
+// handleActionKey handles the shortcuts that work in both the list and the
+// detail pane.
+func (m *Model) handleActionKey(k Key) Action {
+	ids := m.targets()
+	if len(ids) == 0 {
+		if k.Rune == 'r' {
+			return Action{Kind: ActionRefresh}
+		}
+		return Action{}
+	}
+	act := func(kind ActionKind) func(string) Action {
+		return func(v string) Action {
+			return Action{Kind: kind, IDs: ids, Value: v}
+		}
+	}
+	switch k.Rune {
+	case 'r':
+		return Action{Kind: ActionRefresh}
+	case 'o':
+		return Action{Kind: ActionOpen, IDs: ids}
+	case 'a':
+		m.ask(fmt.Sprintf("Assign %s to", describe(ids)), nil, act(ActionAssign))
+	case 'p':
+		m.ask(fmt.Sprintf("Priority for %s", describe(ids)), bugzilla.Priorities, act(ActionPriority))
+	case 'b':
+		m.ask(fmt.Sprintf("Backport %s to", describe(ids)), m.backports, act(ActionBackport))
+	case 'n':
+		m.ask(fmt.Sprintf("Needinfo on %s from", describe(ids)), nil, act(ActionNeedinfo))
+	}
+	return Action{}
+}
+
+func describe(ids []int) string {
+	if len(ids) == 1 {
+		return fmt.Sprintf("bug %d", ids[0])
+	}
+	return fmt.Sprintf("%d bugs", len(ids))
+}
+
+func (m *Model) ask(label string, choices []string, submit func(string) Action) {
+	m.prompt = &prompt{label: label, choices: choices, back: m.mode, submit: submit}
+	m.mode = modePrompt
+}
+
+func (m *Model) handleFilterKey(k Key) Action {
+	switch {
+	case k.Special == KeyEnter:
+		m.mode = modeList
+	case k.Special == KeyEscape:
+		m.filter = nil
+		m.mode = modeList
+		m.refilter()
+	case k.Special == KeyBackspace:
+		if len(m.filter) > 0 {
+			m.typeFilter(m.filter[:len(m.filter)-1])
+		}
+	case k.Special == KeyDown:
+		m.move(1)
+	case k.Special == KeyUp:
+		m.move(-1)
+	case k.Rune != 0:
+		m.typeFilter(append(m.filter, k.Rune))
+	}
+	return Action{}
+}
+
+func (m *Model) handlePromptKey(k Key) Action {
+	p := m.prompt
+	switch {
+	case k.Special == KeyEscape:
+		m.mode, m.prompt = p.back, nil
+	case k.Special == KeyBackspace:
+		if len(p.input) > 0 {
+			p.input = p.input[:len(p.input)-1]
+		}
+	case k.Special == KeyEnter:
+		value := strings.TrimSpace(string(p.input))
+		if value == "" {
+			return Action{}
+		}
+		if len(p.choices) > 0 && !contains(p.choices, value) {
+			m.SetStatus("%q is not one of %s", value, strings.Join(p.choices, ", "))
+			return Action{}
+		}
+		m.mode, m.prompt = p.back, nil
+		return p.submit(value)
+	case p.numbered() && len(p.input) == 0 && k.Rune >= '1' && k.Rune <= '9':
+		i, _ := strconv.Atoi(string(k.Rune))
+		if i <= len(p.choices) {
+			m.mode, m.prompt = p.back, nil
+			return p.submit(p.choices[i-1])
+		}
+	case k.Rune != 0:
+		p.input = append(p.input, k.Rune)
+	}
+	return Action{}
+}
+
+// numbered reports whether choices can be picked by number, which they can't
+// be if they are numbers themselves, like versions.
+func (p *prompt) numbered() bool {
+	if len(p.choices) == 0 {
+		return false
+	}
+	for _, c := range p.choices {
+		if c != "" && c[0] >= '0' && c[0] <= '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
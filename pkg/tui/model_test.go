@@ -0,0 +1,144 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/view"
+)
+
+type testRow struct {
+	ID      int    `cli:"ID"`
+	Status  string `cli:"Status"`
+	Summary string `cli:"Summary,30"`
+}
+
+func (r testRow) MarshallCLI() ([]string, error) {
+	return view.MarshallCLI(r)
+}
+
+func testRowView(b bugzilla.Bug) view.CLIMarshaller {
+	return testRow{ID: b.ID, Status: b.Status, Summary: b.Summary}
+}
+
+func testBugs() []*bugzilla.Bug {
+	return []*bugzilla.Bug{
+		{ID: 1, Status: "NEW", Summary: "catalog operator crashes on start"},
+		{ID: 2, Status: "ASSIGNED", Summary: "install plan stuck pending"},
+		{ID: 3, Status: "POST", Summary: "packageserver ignores proxy"},
+	}
+}
+
+func typeKeys(m *Model, s string) Action {
+	var last Action
+	for _, k := range ParseKeys([]byte(s)) {
+		last = m.HandleKey(k)
+	}
+	return last
+}
+
+func TestModelSelectionAndActions(t *testing.T) {
+	m := NewModel(testRowView, []string{"4.3", "4.4"})
+	m.SetBugs(testBugs())
+
+	// with nothing selected, actions apply to the bug under the cursor
+	require.Equal(t, Action{Kind: ActionOpen, IDs: []int{1}}, typeKeys(m, "o"))
+
+	typeKeys(m, " j ")
+	require.Equal(t, []int{1, 3}, m.Selected())
+	require.Equal(t, Action{Kind: ActionPriority, IDs: []int{1, 3}, Value: "high"}, typeKeys(m, "p2"))
+	require.Equal(t, Action{Kind: ActionAssign, IDs: []int{1, 3}, Value: "me@redhat.com"}, typeKeys(m, "ame@redhat.com\r"))
+	require.Equal(t, Action{Kind: ActionBackport, IDs: []int{1, 3}, Value: "4.4"}, typeKeys(m, "b4.4\r"))
+
+	// choices are enforced, and escape cancels
+	require.Equal(t, Action{}, typeKeys(m, "b4.9\r"))
+	require.Equal(t, modePrompt, m.mode)
+	typeKeys(m, "\x1b")
+	require.Equal(t, modeList, m.mode)
+
+	typeKeys(m, "X")
+	require.Empty(t, m.Selected())
+	require.Equal(t, Action{Kind: ActionQuit}, typeKeys(m, "q"))
+}
+
+func TestModelFilter(t *testing.T) {
+	m := NewModel(testRowView, nil)
+	m.SetBugs(testBugs())
+
+	typeKeys(m, "/proxy\r")
+	require.Len(t, m.visible, 1)
+	require.Equal(t, 3, m.Current().ID)
+
+	// refreshing keeps the cursor on the same bug
+	bugs := append([]*bugzilla.Bug{{ID: 4, Status: "NEW", Summary: "proxy env not passed"}}, testBugs()...)
+	m.SetBugs(bugs)
+	require.Len(t, m.visible, 2)
+	require.Equal(t, 3, m.Current().ID)
+
+	typeKeys(m, "\x1b")
+	require.Len(t, m.visible, 4)
+	require.Equal(t, 3, m.Current().ID)
+}
+
+func TestModelDetail(t *testing.T) {
+	m := NewModel(testRowView, nil)
+	m.SetBugs(testBugs())
+	require.Equal(t, Action{Kind: ActionComments, IDs: []int{1}}, typeKeys(m, "\r"))
+	m.SetComments(1, []bugzilla.Comment{{Count: 0, Creator: "qe@redhat.com", Text: "Steps to reproduce"}}, nil)
+
+	// comments are cached until the bug changes
+	typeKeys(m, "\t")
+	require.Equal(t, Action{}, typeKeys(m, "\r"))
+	bugs := testBugs()
+	bugs[0].LastChangeTime = "2020-03-01T00:00:00Z"
+	m.SetBugs(bugs)
+	typeKeys(m, "\t")
+	require.Equal(t, Action{Kind: ActionComments, IDs: []int{1}}, typeKeys(m, "\r"))
+}
+
+func TestModelView(t *testing.T) {
+	m := NewModel(testRowView, nil)
+	m.Resize(100, 8)
+	var bugs []*bugzilla.Bug
+	for i := 1; i <= 20; i++ {
+		bugs = append(bugs, &bugzilla.Bug{ID: i, Status: "NEW", Summary: fmt.Sprintf("bug number %d, ünïcödé", i)})
+	}
+	m.SetBugs(bugs)
+	typeKeys(m, "G")
+
+	lines := m.View()
+	require.Len(t, lines, 8)
+	for _, l := range lines {
+		require.Equal(t, 100, utf8.RuneCountInString(stripANSI(l)), l)
+	}
+	// the cursor is scrolled into view
+	require.Contains(t, lines[6], "bug number 20")
+	require.Contains(t, lines[1], "Bug 20: bug number 20")
+}
+
+func stripANSI(s string) string {
+	for _, code := range []string{reverse, bold, reset} {
+		s = strings.Replace(s, code, "", -1)
+	}
+	return s
+}
+
+func TestWrap(t *testing.T) {
+	require.Equal(t, []string{"the quick", "brown fox", "", "abcdefghij", "klm"}, wrap("the quick brown fox\n\nabcdefghijklm", 10))
+}
+
+func TestModelShrinkingRefresh(t *testing.T) {
+	m := NewModel(testRowView, nil)
+	m.SetBugs(testBugs())
+	typeKeys(m, "G")
+	m.SetBugs(testBugs()[:1])
+	require.Equal(t, 1, m.Current().ID)
+	m.SetBugs(nil)
+	require.Nil(t, m.Current())
+	require.Len(t, m.View(), 24)
+}
@@ -0,0 +1,201 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ecordell/cop/pkg/view"
+)
+
+const help = "j/k move  space select  / filter  enter details  a assign  p priority  b backport  n needinfo  o open  r refresh  q quit"
+
+// View draws the model as lines exactly filling the screen.
+func (m *Model) View() []string {
+	lines := []string{m.title()}
+
+	listWidth := m.width * 55 / 100
+	if m.mode == modeDetail {
+		listWidth = m.width * 35 / 100
+	}
+	detailWidth := m.width - listWidth - 1
+	list := m.listPane(listWidth)
+	detail := m.detailPane(detailWidth)
+	for i := 0; i < m.height-2; i++ {
+		lines = append(lines, cell(list, i, listWidth)+"│"+cell(detail, i, detailWidth))
+	}
+
+	return append(lines, m.footer())
+}
+
+func (m *Model) title() string {
+	title := fmt.Sprintf(" cop · %d bugs", len(m.bugs))
+	if len(m.filter) > 0 {
+		title += fmt.Sprintf(" · %d matching %q", len(m.visible), string(m.filter))
+	}
+	if n := len(m.selected); n > 0 {
+		title += fmt.Sprintf(" · %d selected", n)
+	}
+	return reverse + pad(title, m.width) + reset
+}
+
+func (m *Model) footer() string {
+	switch m.mode {
+	case modeFilter:
+		return pad("/"+string(m.filter)+"█", m.width)
+	case modePrompt:
+		p := m.prompt
+		label := p.label
+		if len(p.choices) > 0 {
+			choices := p.choices
+			if p.numbered() {
+				choices = nil
+				for i, c := range p.choices {
+					choices = append(choices, fmt.Sprintf("%d %s", i+1, c))
+				}
+			}
+			label += " [" + strings.Join(choices, ", ") + "]"
+		}
+		return pad(label+": "+string(p.input)+"█", m.width)
+	}
+	if m.status != "" {
+		return pad(m.status, m.width)
+	}
+	return pad(help, m.width)
+}
+
+// listPane renders the table of visible bugs, the first line being its header
+func (m *Model) listPane(width int) []string {
+	if len(m.rows) == 0 {
+		return []string{"", "  No bugs."}
+	}
+	lines := []string{bold + pad("  "+m.header, width) + reset}
+	end := m.offset + m.listHeight()
+	if end > len(m.visible) {
+		end = len(m.visible)
+	}
+	for i := m.offset; i < end; i++ {
+		idx := m.visible[i]
+		marker := "  "
+		if m.selected[m.bugs[idx].ID] {
+			marker = "● "
+		}
+		line := pad(marker+m.rows[idx], width)
+		if i == m.cursor {
+			line = reverse + line + reset
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// detailPane renders the bug under the cursor and its comments
+func (m *Model) detailPane(width int) []string {
+	b := m.Current()
+	if b == nil || width < 4 {
+		return nil
+	}
+	var lines []string
+	add := func(text string) {
+		lines = append(lines, wrap(text, width-1)...)
+	}
+	add(fmt.Sprintf("Bug %d: %s", b.ID, b.Summary))
+	add("")
+	row := m.rowView(*b)
+	if values, err := row.MarshallCLI(); err == nil {
+		for i, f := range view.Fields(row) {
+			if f == "ID" || f == "Summary" {
+				continue
+			}
+			add(fmt.Sprintf("%s: %s", f, values[i]))
+		}
+	}
+	add(fmt.Sprintf("Component: %s", strings.Join(b.Component, ", ")))
+	add(fmt.Sprintf("Target: %s", strings.Join(b.TargetRelease, ", ")))
+	for _, f := range b.Flags {
+		if f.Requestee != "" {
+			add(fmt.Sprintf("Flag: %s%s %s", f.Name, f.Status, f.Requestee))
+		}
+	}
+	add("")
+
+	comments, loaded := m.comments[b.ID]
+	switch {
+	case m.commentErrs[b.ID] != nil:
+		add(fmt.Sprintf("Could not load comments: %v", m.commentErrs[b.ID]))
+	case !loaded && m.mode == modeDetail:
+		add("Loading comments…")
+	case !loaded:
+		add("Press enter to read the comments.")
+	}
+	for _, c := range comments {
+		lines = append(lines, bold+pad(fmt.Sprintf("#%d %s, %s", c.Count, c.Creator, view.Ago(c.CreationTime)), width)+reset)
+		add(c.Text)
+		add("")
+	}
+
+	if m.mode != modeDetail {
+		return lines
+	}
+	if max := len(lines) - 1; m.detailScroll > max {
+		m.detailScroll = max
+	}
+	return lines[m.detailScroll:]
+}
+
+// cell returns line i of a pane padded to width, or blanks past its end
+func cell(pane []string, i, width int) string {
+	if i < len(pane) {
+		if strings.Contains(pane[i], "\x1b") {
+			// already padded before styling
+			return pane[i]
+		}
+		return pad(pane[i], width)
+	}
+	return strings.Repeat(" ", width)
+}
+
+// pad truncates or pads s with spaces to exactly width runes.
+func pad(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	s = view.Truncate(s, width)
+	if n := utf8.RuneCountInString(s); n < width {
+		s += strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// wrap splits text into lines of at most width runes, breaking on spaces
+// where possible.
+func wrap(text string, width int) []string {
+	if width < 1 {
+		width = 1
+	}
+	var lines []string
+	for _, para := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		line := []rune{}
+		for _, word := range strings.Fields(para) {
+			w := []rune(word)
+			if len(line) > 0 && len(line)+1+len(w) > width {
+				lines = append(lines, string(line))
+				line = line[:0]
+			}
+			for len(w) > width {
+				if len(line) > 0 {
+					lines = append(lines, string(line))
+					line = line[:0]
+				}
+				lines = append(lines, string(w[:width]))
+				w = w[width:]
+			}
+			if len(line) > 0 {
+				line = append(line, ' ')
+			}
+			line = append(line, w...)
+		}
+		lines = append(lines, string(line))
+	}
+	return lines
+}
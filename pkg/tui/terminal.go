@@ -0,0 +1,87 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/chzyer/readline"
+)
+
+// ANSI escape sequences used to draw the screen
+const (
+	altScreenOn  = "\x1b[?1049h"
+	altScreenOff = "\x1b[?1049l"
+	cursorHide   = "\x1b[?25l"
+	cursorShow   = "\x1b[?25h"
+	cursorHome   = "\x1b[H"
+	clearLine    = "\x1b[K"
+	reverse      = "\x1b[7m"
+	bold         = "\x1b[1m"
+	reset        = "\x1b[0m"
+)
+
+// Terminal is the full screen terminal the interface draws on.
+type Terminal struct {
+	in    *os.File
+	out   io.Writer
+	state *readline.State
+}
+
+// OpenTerminal switches stdin to raw mode and stdout to the alternate
+// screen. Close restores both.
+func OpenTerminal() (*Terminal, error) {
+	fd := int(os.Stdin.Fd())
+	if !readline.IsTerminal(fd) {
+		return nil, fmt.Errorf("cop tui needs to run in a terminal")
+	}
+	state, err := readline.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	t := &Terminal{in: os.Stdin, out: os.Stdout, state: state}
+	fmt.Fprint(t.out, altScreenOn+cursorHide)
+	return t, nil
+}
+
+// Close restores the terminal to how it was before OpenTerminal.
+func (t *Terminal) Close() error {
+	fmt.Fprint(t.out, cursorShow+altScreenOff)
+	return readline.Restore(int(t.in.Fd()), t.state)
+}
+
+// Size returns the width and height of the terminal.
+func (t *Terminal) Size() (int, int) {
+	w, h, err := readline.GetSize(int(os.Stdout.Fd()))
+	if err != nil || w <= 0 || h <= 0 {
+		return 80, 24
+	}
+	return w, h
+}
+
+// Keys reads key presses in the background.
+func (t *Terminal) Keys() <-chan Key {
+	keys := make(chan Key)
+	go func() {
+		// reading stops with an error once the terminal is closed
+		_ = ReadKeys(t.in, keys)
+	}()
+	return keys
+}
+
+// Draw replaces the screen with lines, which must already fit the screen.
+func (t *Terminal) Draw(lines []string) error {
+	var b strings.Builder
+	b.WriteString(cursorHome)
+	for i, l := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(l)
+		b.WriteString(clearLine)
+	}
+	b.WriteString("\x1b[J")
+	_, err := io.WriteString(t.out, b.String())
+	return err
+}
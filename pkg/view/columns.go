@@ -0,0 +1,204 @@
+package view
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Document is a value decoded into generic json, so that fields can be picked
+// out of it by name at runtime.
+type Document map[string]interface{}
+
+// NewDocument converts v to a Document through its json encoding.
+func NewDocument(v interface{}) (Document, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc Document
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%T is not a json object: %v", v, err)
+	}
+	return doc, nil
+}
+
+// Lookup resolves a dotted path of json field names, like
+// "assigned_to_detail.real_name".
+func (d Document) Lookup(path string) (interface{}, bool) {
+	var cur interface{} = map[string]interface{}(d)
+	for _, part := range strings.Split(path, ".") {
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = obj[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// String renders the value at path for display. Lists are joined with
+// commas, and missing values are empty.
+func (d Document) String(path string) string {
+	v, _ := d.Lookup(path)
+	return format(v)
+}
+
+func format(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, e := range v {
+			parts = append(parts, format(e))
+		}
+		return strings.Join(parts, ",")
+	case map[string]interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(data)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// Column picks a field out of a Document by path, truncated to Width runes
+// if Width is set. The column is headed by Name, or by the path if unnamed.
+type Column struct {
+	Name  string
+	Path  string
+	Width int
+}
+
+// ParseColumns parses column specs of the form "path" or "path:width".
+func ParseColumns(specs []string) ([]Column, error) {
+	columns := make([]Column, 0, len(specs))
+	for _, spec := range specs {
+		c := Column{Path: spec}
+		if i := strings.LastIndex(spec, ":"); i >= 0 {
+			width, err := strconv.Atoi(spec[i+1:])
+			if err != nil || width < 1 {
+				return nil, fmt.Errorf("invalid width in column %q", spec)
+			}
+			c = Column{Path: spec[:i], Width: width}
+		}
+		if c.Path == "" {
+			return nil, fmt.Errorf("empty column name in %q", spec)
+		}
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
+// Row shows a chosen set of columns from a Document.
+type Row struct {
+	Document
+	Columns []Column
+}
+
+func (r Row) MarshallCLI() ([]string, error) {
+	values := make([]string, 0, len(r.Columns))
+	for _, c := range r.Columns {
+		values = append(values, Truncate(r.String(c.Path), c.Width))
+	}
+	return values, nil
+}
+
+func (r Row) CLIFields() []string {
+	fields := make([]string, 0, len(r.Columns))
+	for _, c := range r.Columns {
+		if c.Name != "" {
+			fields = append(fields, c.Name)
+			continue
+		}
+		fields = append(fields, c.Path)
+	}
+	return fields
+}
+
+var _ CLIMarshaller = Row{}
+var _ CLIFielder = Row{}
+
+// SortKey orders documents by the value at Path. Values compare as numbers
+// when both are numeric, and as strings otherwise, unless Less is set.
+type SortKey struct {
+	Path string
+	Desc bool
+	Less func(a, b string) bool
+}
+
+// ParseSortKeys parses sort keys like "status" or "-last_change_time", where
+// a leading dash sorts descending.
+func ParseSortKeys(specs []string) []SortKey {
+	keys := make([]SortKey, 0, len(specs))
+	for _, spec := range specs {
+		keys = append(keys, SortKey{Path: strings.TrimPrefix(spec, "-"), Desc: strings.HasPrefix(spec, "-")})
+	}
+	return keys
+}
+
+// Sort stably sorts docs by each key in turn.
+func Sort(docs []Document, keys []SortKey) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, k := range keys {
+			a, b := docs[i].String(k.Path), docs[j].String(k.Path)
+			if a == b {
+				continue
+			}
+			less, more := compare(k, a, b), compare(k, b, a)
+			if less == more {
+				continue
+			}
+			return less != k.Desc
+		}
+		return false
+	})
+}
+
+func compare(k SortKey, a, b string) bool {
+	if k.Less != nil {
+		return k.Less(a, b)
+	}
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		return fa < fb
+	}
+	return a < b
+}
+
+// Group is a set of documents sharing a value.
+type Group struct {
+	Value     string
+	Documents []Document
+}
+
+// GroupBy splits docs by their value at path, keeping the order in which
+// each value first appears.
+func GroupBy(docs []Document, path string) []Group {
+	var groups []Group
+	index := map[string]int{}
+	for _, d := range docs {
+		v := d.String(path)
+		i, ok := index[v]
+		if !ok {
+			i = len(groups)
+			index[v] = i
+			groups = append(groups, Group{Value: v})
+		}
+		groups[i].Documents = append(groups[i].Documents, d)
+	}
+	return groups
+}
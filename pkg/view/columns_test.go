@@ -0,0 +1,69 @@
+package view
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncate(t *testing.T) {
+	require.Equal(t, "héllo", Truncate("héllo", 5))
+	require.Equal(t, "hé…", Truncate("héllo", 3))
+	require.Equal(t, "日本…", Truncate("日本語のバグ", 3))
+	require.Equal(t, "日本語のバグ", Truncate("日本語のバグ", 0))
+}
+
+func TestMarshallCLITruncatesRunes(t *testing.T) {
+	v := struct {
+		Summary string `cli:"Summary,4"`
+	}{Summary: "crash in ünïcödé"}
+	values, err := MarshallCLI(v)
+	require.NoError(t, err)
+	require.Equal(t, []string{"cra…"}, values)
+}
+
+func TestDocumentColumns(t *testing.T) {
+	type detail struct {
+		RealName string `json:"real_name"`
+	}
+	type bug struct {
+		ID        int      `json:"id"`
+		Component []string `json:"component"`
+		Detail    detail   `json:"assigned_to_detail"`
+	}
+	doc, err := NewDocument(bug{ID: 1812345, Component: []string{"OLM", "Logging"}, Detail: detail{RealName: "Zoë"}})
+	require.NoError(t, err)
+
+	columns, err := ParseColumns([]string{"id", "component", "assigned_to_detail.real_name:2", "missing"})
+	require.NoError(t, err)
+	row := Row{Document: doc, Columns: columns}
+	values, err := row.MarshallCLI()
+	require.NoError(t, err)
+	require.Equal(t, []string{"1812345", "OLM,Logging", "Z…", ""}, values)
+	require.Equal(t, []string{"id", "component", "assigned_to_detail.real_name", "missing"}, Fields(row))
+
+	_, err = ParseColumns([]string{"summary:wide"})
+	require.Error(t, err)
+}
+
+func TestSortAndGroup(t *testing.T) {
+	docs := []Document{
+		{"id": 3, "component": "OLM", "priority": "high"},
+		{"id": 10, "component": "Logging", "priority": "high"},
+		{"id": 2, "component": "OLM", "priority": "low"},
+		{"id": 1, "component": "OLM", "priority": "high"},
+	}
+	Sort(docs, ParseSortKeys([]string{"priority", "-id"}))
+	ids := []interface{}{}
+	for _, d := range docs {
+		ids = append(ids, d["id"])
+	}
+	require.Equal(t, []interface{}{10, 3, 1, 2}, ids)
+
+	groups := GroupBy(docs, "component")
+	require.Len(t, groups, 2)
+	require.Equal(t, "Logging", groups[0].Value)
+	require.Len(t, groups[0].Documents, 1)
+	require.Equal(t, "OLM", groups[1].Value)
+	require.Len(t, groups[1].Documents, 3)
+}
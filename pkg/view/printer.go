@@ -0,0 +1,30 @@
+package view
+
+import (
+	"fmt"
+	"io"
+)
+
+// Printer remembers the first write error so rendering code can stay linear.
+type Printer struct {
+	w   io.Writer
+	err error
+}
+
+// NewPrinter returns a Printer writing to w.
+func NewPrinter(w io.Writer) *Printer {
+	return &Printer{w: w}
+}
+
+// Printf writes to the underlying writer unless an earlier write failed.
+func (p *Printer) Printf(format string, args ...interface{}) {
+	if p.err != nil {
+		return
+	}
+	_, p.err = fmt.Fprintf(p.w, format, args...)
+}
+
+// Err returns the first write error, if any.
+func (p *Printer) Err() error {
+	return p.err
+}
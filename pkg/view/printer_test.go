@@ -0,0 +1,39 @@
+package view
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// failingWriter fails every write after the first n
+type failingWriter struct {
+	n   int
+	buf bytes.Buffer
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.n == 0 {
+		return 0, errors.New("disk full")
+	}
+	w.n--
+	return w.buf.Write(p)
+}
+
+func TestPrinter(t *testing.T) {
+	w := &failingWriter{n: 1}
+	p := NewPrinter(w)
+	p.Printf("# %s\n", "title")
+	p.Printf("lost\n")
+	p.Printf("also lost\n")
+	require.EqualError(t, p.Err(), "disk full")
+	require.Equal(t, "# title\n", w.buf.String())
+
+	var out bytes.Buffer
+	p = NewPrinter(&out)
+	p.Printf("%d bugs\n", 2)
+	require.NoError(t, p.Err())
+	require.Equal(t, "2 bugs\n", out.String())
+}
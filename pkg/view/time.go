@@ -0,0 +1,51 @@
+package view
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Ago describes how long ago t was in rough, human terms, e.g. "2 days ago".
+func Ago(t time.Time) string {
+	return ago(time.Since(t))
+}
+
+func ago(d time.Duration) string {
+	unit := func(n int, name string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", name)
+		}
+		return fmt.Sprintf("%d %ss ago", n, name)
+	}
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return unit(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return unit(int(d/time.Hour), "hour")
+	case d < 30*24*time.Hour:
+		return unit(int(d/(24*time.Hour)), "day")
+	case d < 365*24*time.Hour:
+		return unit(int(d/(30*24*time.Hour)), "month")
+	default:
+		return unit(int(d/(365*24*time.Hour)), "year")
+	}
+}
+
+// ParseAge parses a duration that may also be given in days or weeks, like
+// "90d" or "2w", as well as anything time.ParseDuration accepts.
+func ParseAge(s string) (time.Duration, error) {
+	units := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if len(s) > 1 {
+		if unit, ok := units[s[len(s)-1]]; ok {
+			n, err := strconv.Atoi(s[:len(s)-1])
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q: %v", s, err)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
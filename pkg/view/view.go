@@ -0,0 +1,275 @@
+package view
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/manifoldco/promptui"
+	"github.com/sirupsen/logrus"
+)
+
+const cliTag = "cli"
+
+// Output formats understood by Print
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+)
+
+type CLIMarshaller interface {
+	MarshallCLI() ([]string, error)
+}
+
+// MarshallCLI renders the `cli` tagged fields of a struct in field order.
+// A tag of `cli:"Name,50"` truncates the value to 50 characters.
+func MarshallCLI(v interface{}) ([]string, error) {
+	values := []string{}
+	var err error
+	val := reflect.Indirect(reflect.ValueOf(v))
+	for i := 0; i < val.Type().NumField(); i++ {
+		tag := val.Type().Field(i).Tag.Get(cliTag)
+
+		var maxLen int
+		parts := strings.Split(tag, ",")
+		if len(parts) > 2 {
+			return nil, fmt.Errorf("too many parts to field tag %s", tag)
+		}
+		if len(parts) == 2 {
+			maxLen, err = strconv.Atoi(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("couldn't get length from struct tag %s: %v", tag, err)
+			}
+		}
+
+		// Skip if tag is not defined or ignored
+		if tag == "" || tag == "-" {
+			continue
+		}
+
+		if maxLen == 0 {
+			values = append(values, fmt.Sprintf("%v", val.Field(i).Interface()))
+			continue
+		}
+
+		// if maxlen, assume string
+		values = append(values, Truncate(val.Field(i).String(), maxLen))
+	}
+	return values, err
+}
+
+// Truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func Truncate(s string, n int) string {
+	r := []rune(s)
+	if n <= 0 || len(r) <= n {
+		return s
+	}
+	if n == 1 {
+		return "…"
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// CLIFielder is implemented by views whose columns aren't known until
+// runtime, instead of being declared with `cli` tags.
+type CLIFielder interface {
+	CLIFields() []string
+}
+
+func Fields(b CLIMarshaller) []string {
+	if f, ok := b.(CLIFielder); ok {
+		return f.CLIFields()
+	}
+	fields := []string{}
+
+	val := reflect.Indirect(reflect.ValueOf(b))
+	for i := 0; i < val.Type().NumField(); i++ {
+		tag := val.Type().Field(i).Tag.Get(cliTag)
+
+		// Skip if tag is not defined or ignored
+		if tag == "" || tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		fields = append(fields, parts[0])
+	}
+	return fields
+}
+
+func TabHeader(s []string) string {
+	return "  " + strings.Join(s, "\t  ") + "\n"
+}
+
+func TabLine(s []string) string {
+	return strings.Join(s, "\t") + "\n"
+}
+
+// Table renders options as aligned rows, the first line being the header.
+func Table(options []CLIMarshaller) ([]string, error) {
+	return render(options, TabLine)
+}
+
+func render(options []CLIMarshaller, header func([]string) string) ([]string, error) {
+	var buffer bytes.Buffer
+	w := tabwriter.NewWriter(&buffer, 0, 0, 2, ' ', 0)
+	if len(options) == 0 {
+		return nil, fmt.Errorf("can't render a table with no rows")
+	}
+	if _, err := fmt.Fprint(w, header(Fields(options[0]))); err != nil {
+		return nil, err
+	}
+	for _, o := range options {
+		values, err := o.MarshallCLI()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := fmt.Fprint(w, TabLine(values)); err != nil {
+			logrus.Error(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n"), nil
+}
+
+// Print writes options to w in the given format. JSON output is a list of
+// objects keyed by column name.
+func Print(w io.Writer, format string, options []CLIMarshaller) error {
+	switch format {
+	case FormatTable, "":
+		if len(options) == 0 {
+			return nil
+		}
+		lines, err := Table(options)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, strings.Join(lines, "\n"))
+		return err
+	case FormatJSON:
+		rows, err := Objects(options)
+		if err != nil {
+			return err
+		}
+		return PrintJSON(w, rows)
+	default:
+		return fmt.Errorf("unknown output format %q, must be %s or %s", format, FormatTable, FormatJSON)
+	}
+}
+
+// Objects renders options as objects keyed by column name.
+func Objects(options []CLIMarshaller) ([]map[string]string, error) {
+	rows := []map[string]string{}
+	for _, o := range options {
+		values, err := o.MarshallCLI()
+		if err != nil {
+			return nil, err
+		}
+		row := map[string]string{}
+		for i, f := range Fields(o) {
+			row[f] = values[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// PrintJSON writes v to w as indented JSON.
+func PrintJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// PrintJSONLine writes v to w as a single line of JSON.
+func PrintJSONLine(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+type MultiSelectView struct {
+	options []CLIMarshaller
+}
+
+func NewMultiSelectView(options []CLIMarshaller) *MultiSelectView {
+	return &MultiSelectView{
+		options: options,
+	}
+}
+
+// Select prompts for one of the options and returns its index.
+func (v *MultiSelectView) Select() (int, error) {
+	if len(v.options) == 0 {
+		return -1, fmt.Errorf("can't prompt with no options")
+	}
+	// the header is indented to line up with the select cursor
+	lines, err := render(v.options, TabHeader)
+	if err != nil {
+		return -1, err
+	}
+	prompt := promptui.Select{
+		Label:        lines[0],
+		Size:         10,
+		Items:        lines[1:],
+		HideSelected: true,
+	}
+
+	row, _, err := prompt.Run()
+	if err != nil {
+		return -1, err
+	}
+	return row, nil
+}
+
+func (v *MultiSelectView) Prompt() error {
+	row, err := v.Select()
+	if err != nil {
+		return err
+	}
+	fmt.Println(row)
+	return nil
+}
+
+// Action is an entry in an action menu.
+type Action struct {
+	Label string
+	Do    func() error
+}
+
+// PromptActions asks which action to take and runs it.
+func PromptActions(label string, actions []Action) error {
+	items := []string{}
+	for _, a := range actions {
+		items = append(items, a.Label)
+	}
+	prompt := promptui.Select{
+		Label: label,
+		Items: items,
+	}
+	i, _, err := prompt.Run()
+	if err != nil {
+		return err
+	}
+	return actions[i].Do()
+}
+
+// have not tested outside of macos
+func OpenBrowser(url string) error {
+	switch runtime.GOOS {
+	case "linux":
+		return exec.Command("xdg-open", url).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	case "darwin":
+		return exec.Command("open", url).Start()
+	default:
+		return fmt.Errorf("unsupported platform")
+	}
+}
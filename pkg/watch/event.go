@@ -0,0 +1,148 @@
+package watch
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+)
+
+// Kinds of event
+const (
+	// KindNew is a bug that started matching the query.
+	KindNew = "new"
+	// KindChanged is a change to a field of a bug.
+	KindChanged = "changed"
+	// KindComment is a new comment on a bug.
+	KindComment = "comment"
+	// KindFlag is a flag being set, changed or cleared on a bug.
+	KindFlag = "flag"
+)
+
+// Event is a change to a bug seen between two polls.
+type Event struct {
+	// Time is when the bug changed, as reported by bugzilla.
+	Time    string `json:"time"`
+	Bug     int    `json:"bug"`
+	Summary string `json:"summary"`
+	Kind    string `json:"kind"`
+	// Field is the changed field or flag.
+	Field string `json:"field,omitempty"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+	// Who is the author of a comment, or the setter of a flag.
+	Who string `json:"who,omitempty"`
+	// Text is the text of a comment.
+	Text string `json:"text,omitempty"`
+	// ForYou is set when a flag is requested of the watching user.
+	ForYou bool `json:"for_you,omitempty"`
+}
+
+func (e Event) String() string {
+	var what string
+	switch e.Kind {
+	case KindNew:
+		what = fmt.Sprintf("new %s bug", e.To)
+	case KindComment:
+		what = fmt.Sprintf("new comment by %s", e.Who)
+	case KindFlag:
+		who := e.Who
+		if e.ForYou {
+			who = "you"
+		}
+		switch {
+		case e.To == "":
+			what = fmt.Sprintf("flag %s%s cleared", e.Field, e.From)
+		case strings.HasSuffix(e.To, "?") && who != "":
+			what = fmt.Sprintf("flag %s set on %s", e.To, who)
+		default:
+			what = fmt.Sprintf("flag %s set", e.To)
+		}
+	default:
+		what = fmt.Sprintf("%s %s→%s", e.Field, orNone(e.From), orNone(e.To))
+	}
+	return fmt.Sprintf("Bug %d: %s (%s)", e.Bug, what, e.Summary)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// fields are the bug fields changes are reported for
+var fields = []struct {
+	name string
+	get  func(b *bugzilla.Bug) string
+}{
+	{"status", func(b *bugzilla.Bug) string { return b.Status }},
+	{"resolution", func(b *bugzilla.Bug) string { return b.Resolution }},
+	{"assigned_to", func(b *bugzilla.Bug) string { return b.AssignedTo }},
+	{"priority", func(b *bugzilla.Bug) string { return b.Priority }},
+	{"severity", func(b *bugzilla.Bug) string { return b.Severity }},
+	{"target_release", func(b *bugzilla.Bug) string { return strings.Join(b.TargetRelease, ",") }},
+	{"component", func(b *bugzilla.Bug) string { return strings.Join(b.Component, ",") }},
+	{"keywords", func(b *bugzilla.Bug) string { return strings.Join(b.Keywords, ",") }},
+	{"summary", func(b *bugzilla.Bug) string { return b.Summary }},
+	{"cf_internal_whiteboard", func(b *bugzilla.Bug) string { return b.InternalWhiteboard }},
+}
+
+// Diff returns the field and flag changes between two snapshots of a bug.
+// me is the login of the watching user, for flags requested of them.
+func Diff(old, new *bugzilla.Bug, me string) []Event {
+	event := func(kind string) Event {
+		return Event{Time: new.LastChangeTime, Bug: new.ID, Summary: new.Summary, Kind: kind}
+	}
+	var events []Event
+	for _, f := range fields {
+		from, to := f.get(old), f.get(new)
+		if from == to {
+			continue
+		}
+		e := event(KindChanged)
+		e.Field, e.From, e.To = f.name, from, to
+		events = append(events, e)
+	}
+
+	oldFlags, newFlags := flagStates(old.Flags), flagStates(new.Flags)
+	for _, f := range new.Flags {
+		key := flagKey(f)
+		if oldFlags[key] == newFlags[key] {
+			continue
+		}
+		e := event(KindFlag)
+		e.Field, e.From, e.To, e.Who = f.Name, oldFlags[key], f.Name+f.Status, f.Setter
+		if oldFlags[key] != "" {
+			e.From = f.Name + oldFlags[key]
+		}
+		if f.Requestee != "" {
+			e.Who = f.Requestee
+			e.ForYou = me != "" && f.Requestee == me
+		}
+		events = append(events, e)
+	}
+	for _, f := range old.Flags {
+		if _, ok := newFlags[flagKey(f)]; ok {
+			continue
+		}
+		e := event(KindFlag)
+		e.Field, e.From = f.Name, f.Status
+		events = append(events, e)
+	}
+	return events
+}
+
+// flags are identified by name and requestee, since a bug can have several
+// needinfos at once
+func flagKey(f bugzilla.Flag) string {
+	return f.Name + "\x00" + f.Requestee
+}
+
+func flagStates(flags []bugzilla.Flag) map[string]string {
+	states := map[string]string{}
+	for _, f := range flags {
+		states[flagKey(f)] = f.Status
+	}
+	return states
+}
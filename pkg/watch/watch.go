@@ -0,0 +1,191 @@
+package watch
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+)
+
+// idsPerSearch limits how many known bugs are checked in one search, to keep
+// urls a reasonable length
+const idsPerSearch = 200
+
+// Watcher polls a bugzilla search and reports how the bugs change.
+type Watcher struct {
+	client bugzilla.Client
+	query  string
+	// me is the login of the watching user
+	me     string
+	logger *logrus.Entry
+
+	// bugs is the last snapshot of every bug seen
+	bugs map[int]*bugzilla.Bug
+	// since is the latest change seen, from which the next poll searches
+	since  string
+	primed bool
+}
+
+// NewWatcher watches the bugs matching query. me is the login of the watching
+// user, so flags requested of them can be pointed out.
+func NewWatcher(client bugzilla.Client, query, me string) *Watcher {
+	return &Watcher{
+		client: client,
+		query:  query,
+		me:     me,
+		logger: logrus.WithField("component", "watch"),
+		bugs:   map[int]*bugzilla.Bug{},
+	}
+}
+
+// Poll searches for bugs changed since the last poll and returns what
+// changed. The first poll only takes the initial snapshot.
+func (w *Watcher) Poll() ([]Event, error) {
+	if !w.primed {
+		bugs, err := w.client.SearchBugs(w.query)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range bugs {
+			w.record(b)
+		}
+		if w.since == "" {
+			w.since = time.Now().UTC().Format(time.RFC3339)
+		}
+		w.primed = true
+		return nil, nil
+	}
+
+	changed, err := w.changed()
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	for _, b := range changed {
+		old, known := w.bugs[b.ID]
+		switch {
+		case !known:
+			events = append(events, Event{Time: b.LastChangeTime, Bug: b.ID, Summary: b.Summary, Kind: KindNew, To: b.Status})
+		case old.LastChangeTime == b.LastChangeTime:
+			// searches include the bugs changed at exactly the last change seen
+			continue
+		default:
+			events = append(events, Diff(old, b, w.me)...)
+			comments, err := w.newComments(old, b)
+			if err != nil {
+				// the field changes are still worth reporting
+				w.logger.WithError(err).WithField("id", b.ID).Warn("could not get comments")
+			}
+			events = append(events, comments...)
+		}
+		w.record(b)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time < events[j].Time
+	})
+	return events, nil
+}
+
+// Run polls every interval until ctx is done, passing the events from each
+// poll to handle, even when there are none.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration, handle func([]Event) error) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		events, err := w.Poll()
+		if err != nil {
+			// a failed poll is retried at the next interval
+			w.logger.WithError(err).Warn("could not poll bugzilla")
+		}
+		if err := handle(events); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Watcher) record(b *bugzilla.Bug) {
+	w.bugs[b.ID] = b
+	if b.LastChangeTime > w.since {
+		w.since = b.LastChangeTime
+	}
+}
+
+// changed finds the bugs changed since the last poll: those now matching the
+// query, and any known bugs, which may have changed so they no longer match.
+func (w *Watcher) changed() ([]*bugzilla.Bug, error) {
+	delta := "&last_change_time=" + url.QueryEscape(w.since)
+	bugs, err := w.client.SearchBugs(w.query + delta)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[int]bool{}
+	for _, b := range bugs {
+		seen[b.ID] = true
+	}
+
+	var ids []string
+	for id := range w.bugs {
+		if !seen[id] {
+			ids = append(ids, strconv.Itoa(id))
+		}
+	}
+	sort.Strings(ids)
+	for len(ids) > 0 {
+		n := idsPerSearch
+		if n > len(ids) {
+			n = len(ids)
+		}
+		known, err := w.client.SearchBugs("id=" + strings.Join(ids[:n], ",") + delta)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range known {
+			if !seen[b.ID] {
+				seen[b.ID] = true
+				bugs = append(bugs, b)
+			}
+		}
+		ids = ids[n:]
+	}
+	return bugs, nil
+}
+
+// newComments returns the comments added to a bug since the old snapshot
+func (w *Watcher) newComments(old, b *bugzilla.Bug) ([]Event, error) {
+	since, err := time.Parse(time.RFC3339, old.LastChangeTime)
+	if err != nil {
+		// without knowing when we last looked, every comment would look new
+		w.logger.WithError(err).WithField("id", old.ID).Debug("could not parse last change time")
+		return nil, nil
+	}
+	comments, err := w.client.GetCommentsOnBug(b.ID)
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	for _, c := range comments {
+		if !c.CreationTime.After(since) {
+			continue
+		}
+		events = append(events, Event{
+			Time:    c.CreationTime.UTC().Format(time.RFC3339),
+			Bug:     b.ID,
+			Summary: b.Summary,
+			Kind:    KindComment,
+			Who:     c.Creator,
+			Text:    c.Text,
+		})
+	}
+	return events, nil
+}
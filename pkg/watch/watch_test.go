@@ -0,0 +1,56 @@
+package watch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+)
+
+func TestWatcherPoll(t *testing.T) {
+	client := &bugzilla.Fake{
+		Bugs: map[int]bugzilla.Bug{
+			1: {ID: 1, Summary: "catalog crashes", Status: "NEW", LastChangeTime: "2020-03-01T10:00:00Z",
+				Flags: []bugzilla.Flag{{Name: "needinfo", Status: "?", Requestee: "dev@redhat.com"}}},
+			2: {ID: 2, Summary: "proxy ignored", Status: "POST", LastChangeTime: "2020-03-01T11:00:00Z"},
+		},
+	}
+	w := NewWatcher(client, "component=OLM", "me@redhat.com")
+
+	events, err := w.Poll()
+	require.NoError(t, err)
+	require.Empty(t, events)
+	require.Equal(t, "2020-03-01T11:00:00Z", w.since)
+
+	// nothing changed
+	events, err = w.Poll()
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	client.Bugs[1] = bugzilla.Bug{ID: 1, Summary: "catalog crashes", Status: "ASSIGNED", AssignedTo: "me@redhat.com", LastChangeTime: "2020-03-01T12:00:00Z",
+		Flags: []bugzilla.Flag{{Name: "needinfo", Status: "?", Requestee: "me@redhat.com", Setter: "qe@redhat.com"}}}
+	client.Bugs[3] = bugzilla.Bug{ID: 3, Summary: "new bug", Status: "NEW", LastChangeTime: "2020-03-01T12:30:00Z"}
+	client.BugComments = map[int][]bugzilla.Comment{1: {
+		{Count: 0, Creator: "qe@redhat.com", Text: "description", CreationTime: time.Date(2020, 3, 1, 9, 0, 0, 0, time.UTC)},
+		{Count: 1, Creator: "qe@redhat.com", Text: "can you look?", CreationTime: time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)},
+	}}
+
+	events, err = w.Poll()
+	require.NoError(t, err)
+	var lines []string
+	for _, e := range events {
+		lines = append(lines, e.String())
+	}
+	require.Equal(t, []string{
+		"Bug 1: status NEW→ASSIGNED (catalog crashes)",
+		"Bug 1: assigned_to (none)→me@redhat.com (catalog crashes)",
+		"Bug 1: flag needinfo? set on you (catalog crashes)",
+		"Bug 1: flag needinfo? cleared (catalog crashes)",
+		"Bug 1: new comment by qe@redhat.com (catalog crashes)",
+		"Bug 3: new NEW bug (new bug)",
+	}, lines)
+	require.True(t, events[2].ForYou)
+	require.Equal(t, "2020-03-01T12:30:00Z", w.since)
+}
@@ -0,0 +1,335 @@
+// Package webhook handles GitHub webhook deliveries, so that bugs move along
+// the workflow as soon as their pull requests do instead of on the next poll.
+//
+// A pull_request event whose title references "Bug NNNNNNN" links the pull
+// request to the bug, then moves the bug to POST when the pull request is
+// opened or to MODIFIED once its pull requests have merged, as
+// workflow.Reconcile decides.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/github"
+	"github.com/ecordell/cop/pkg/workflow"
+)
+
+// Headers GitHub sends with every delivery.
+const (
+	EventHeader     = "X-GitHub-Event"
+	DeliveryHeader  = "X-GitHub-Delivery"
+	SignatureHeader = "X-Hub-Signature-256"
+	// SHA1SignatureHeader is only checked when there is no SHA-256 signature.
+	SHA1SignatureHeader = "X-Hub-Signature"
+)
+
+// maxPayload is the largest payload GitHub sends.
+const maxPayload = 25 << 20
+
+// ValidateSignature checks that body was signed with secret, given the
+// X-Hub-Signature-256 and X-Hub-Signature headers of a delivery.
+func ValidateSignature(secret, body []byte, sha256Header, sha1Header string) error {
+	if len(secret) == 0 {
+		return fmt.Errorf("no webhook secret is configured")
+	}
+	var (
+		prefix string
+		sig    string
+		newMAC func() hash.Hash
+	)
+	switch {
+	case sha256Header != "":
+		prefix, sig, newMAC = "sha256=", sha256Header, sha256.New
+	case sha1Header != "":
+		prefix, sig, newMAC = "sha1=", sha1Header, sha1.New
+	default:
+		return fmt.Errorf("delivery is not signed")
+	}
+	if !strings.HasPrefix(sig, prefix) {
+		return fmt.Errorf("invalid signature %q", sig)
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(sig, prefix))
+	if err != nil {
+		return fmt.Errorf("invalid signature %q: %v", sig, err)
+	}
+	mac := hmac.New(newMAC, secret)
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return fmt.Errorf("signature does not match")
+	}
+	return nil
+}
+
+// Delivery is a webhook delivery, as recorded for replaying.
+type Delivery struct {
+	// Event is the type of event, e.g. pull_request.
+	Event string `json:"event"`
+	// ID is GitHub's unique ID of the delivery.
+	ID      string          `json:"delivery,omitempty"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// ReadDelivery reads a delivery recorded by a Handler. Payloads saved by
+// other means, e.g. copied from GitHub's list of recent deliveries, are read
+// as deliveries of event.
+func ReadDelivery(path, event string) (Delivery, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Delivery{}, err
+	}
+	var d Delivery
+	if err := json.Unmarshal(data, &d); err != nil {
+		return Delivery{}, fmt.Errorf("could not parse %s: %v", path, err)
+	}
+	if d.Event != "" && len(d.Payload) > 0 {
+		return d, nil
+	}
+	if event == "" {
+		return Delivery{}, fmt.Errorf("%s is not a recorded delivery, give the event of the payload", path)
+	}
+	return Delivery{Event: event, Payload: data}, nil
+}
+
+// PullRequestEvent is the payload of a pull_request event. See
+// https://developer.github.com/webhooks/event-payloads/#pull_request
+type PullRequestEvent struct {
+	// Action is what happened, e.g. opened or closed.
+	Action      string             `json:"action"`
+	Number      int                `json:"number"`
+	PullRequest github.PullRequest `json:"pull_request"`
+	Repository  Repository         `json:"repository"`
+}
+
+// Repository is the repository of an event.
+type Repository struct {
+	Name  string      `json:"name"`
+	Owner github.User `json:"owner"`
+}
+
+// BugResult is what a delivery did to a bug.
+type BugResult struct {
+	ID int `json:"id"`
+	// Linked is true if the pull request was newly linked to the bug.
+	Linked bool `json:"linked,omitempty"`
+	// From and To are the statuses the bug moved between, if it moved.
+	From     string   `json:"from,omitempty"`
+	To       string   `json:"to,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// Result is what a delivery did.
+type Result struct {
+	Event  string `json:"event"`
+	Action string `json:"action,omitempty"`
+	// PR is the pull request of the event, as org/repo#num.
+	PR string `json:"pr,omitempty"`
+	// Ignored says why nothing was done, if nothing was.
+	Ignored string      `json:"ignored,omitempty"`
+	Bugs    []BugResult `json:"bugs,omitempty"`
+}
+
+// Handler acts on webhook deliveries.
+type Handler struct {
+	Client bugzilla.Client
+	// GitHub looks up the other pull requests linked to a bug. Without it, a
+	// bug with other pull requests isn't moved.
+	GitHub github.Client
+	// Secret is the secret deliveries are signed with.
+	Secret []byte
+	// Branches map target releases to the branches fixes merge into.
+	Branches workflow.Branches
+	// DryRun reads bugs but changes nothing.
+	DryRun bool
+	// Record, if set, is a directory every valid delivery is saved to, for
+	// replaying with ReadDelivery.
+	Record string
+	// Changed, if set, is called with every bug a delivery linked a pull
+	// request to or moved, e.g. to forget cached copies of it.
+	Changed func(id int)
+}
+
+// ServeHTTP checks the signature of a delivery and handles it.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := logrus.WithFields(logrus.Fields{"component": "webhook", "delivery": r.Header.Get(DeliveryHeader)})
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxPayload))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not read payload: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := ValidateSignature(h.Secret, body, r.Header.Get(SignatureHeader), r.Header.Get(SHA1SignatureHeader)); err != nil {
+		logger.WithError(err).Warn("rejected delivery")
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	d := Delivery{Event: r.Header.Get(EventHeader), ID: r.Header.Get(DeliveryHeader), Payload: body}
+	if h.Record != "" {
+		if err := h.record(d); err != nil {
+			logger.WithError(err).Warn("could not record delivery")
+		}
+	}
+
+	result, err := h.Handle(d)
+	code := http.StatusOK
+	if err != nil {
+		logger.WithError(err).Warn("could not handle delivery")
+		code = http.StatusBadGateway
+		if result == nil {
+			http.Error(w, err.Error(), code)
+			return
+		}
+	}
+	logger.WithFields(logrus.Fields{"event": d.Event, "pr": result.PR}).Debug("handled delivery")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(result)
+}
+
+// record saves d to the Record directory
+func (h *Handler) record(d Delivery) error {
+	if err := os.MkdirAll(h.Record, 0700); err != nil {
+		return err
+	}
+	id := d.ID
+	if id == "" {
+		id = time.Now().UTC().Format("20060102T150405.000000000")
+	}
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(h.Record, fmt.Sprintf("%s-%s.json", d.Event, filepath.Base(id))), data, 0600)
+}
+
+// Handle acts on a delivery. Errors with single bugs are reported in the
+// result, and the first is returned.
+func (h *Handler) Handle(d Delivery) (*Result, error) {
+	result := &Result{Event: d.Event}
+	switch d.Event {
+	case "ping":
+		result.Ignored = "ping"
+		return result, nil
+	case "pull_request":
+	default:
+		result.Ignored = fmt.Sprintf("%s events aren't handled", d.Event)
+		return result, nil
+	}
+
+	var event PullRequestEvent
+	if err := json.Unmarshal(d.Payload, &event); err != nil {
+		return nil, fmt.Errorf("could not parse pull_request payload: %v", err)
+	}
+	org, repo, pr := event.Repository.Owner.Login, event.Repository.Name, event.PullRequest
+	if pr.Number == 0 {
+		pr.Number = event.Number
+	}
+	result.Action = event.Action
+	result.PR = fmt.Sprintf("%s/%s#%d", org, repo, pr.Number)
+	switch {
+	case event.Action == "opened" || event.Action == "reopened":
+	case event.Action == "closed" && pr.Merged:
+	case event.Action == "closed":
+		result.Ignored = "closed without merging"
+		return result, nil
+	default:
+		result.Ignored = fmt.Sprintf("%s pull requests aren't handled", event.Action)
+		return result, nil
+	}
+	ids := bugzilla.References(pr.Title)
+	if len(ids) == 0 {
+		result.Ignored = "the title references no bug"
+		return result, nil
+	}
+
+	var firstErr error
+	for _, id := range ids {
+		bug, err := h.handleBug(id, org, repo, &pr)
+		if h.Changed != nil && !h.DryRun && (bug.Linked || bug.To != "") {
+			h.Changed(id)
+		}
+		if err != nil {
+			bug.Error = err.Error()
+			if firstErr == nil {
+				firstErr = fmt.Errorf("bug %d: %v", id, err)
+			}
+		}
+		result.Bugs = append(result.Bugs, bug)
+	}
+	return result, firstErr
+}
+
+// handleBug links pr to the bug with id and moves the bug along
+func (h *Handler) handleBug(id int, org, repo string, pr *github.PullRequest) (BugResult, error) {
+	result := BugResult{ID: id}
+	exts, err := h.Client.GetExternalBugPRsOnBug(id)
+	if err != nil {
+		return result, err
+	}
+	linked := false
+	for _, ext := range exts {
+		if ext.Org == org && ext.Repo == repo && ext.Num == pr.Number {
+			linked = true
+		}
+	}
+	if !linked {
+		if !h.DryRun {
+			if _, err := h.Client.AddPullRequestAsExternalBug(id, org, repo, pr.Number); err != nil {
+				return result, fmt.Errorf("could not link %s/%s#%d: %v", org, repo, pr.Number, err)
+			}
+		}
+		result.Linked = true
+		exts = append(exts, bugzilla.GithubExternalBug{Org: org, Repo: repo, Num: pr.Number})
+	}
+
+	bug, err := h.Client.GetBug(id)
+	if err != nil {
+		return result, err
+	}
+	var prs []workflow.LinkedPR
+	for _, ext := range exts {
+		linkedPR := workflow.LinkedPR{GithubExternalBug: ext}
+		switch {
+		case ext.Org == org && ext.Repo == repo && ext.Num == pr.Number:
+			linkedPR.PullRequest = pr
+		case h.GitHub != nil:
+			// Reconcile warns about a PR that can't be fetched
+			if linkedPR.PullRequest, err = h.GitHub.GetPullRequest(ext.Org, ext.Repo, ext.Num); err != nil {
+				linkedPR.PullRequest, linkedPR.Err = nil, err
+			}
+		}
+		prs = append(prs, linkedPR)
+	}
+
+	reconciled := workflow.Reconcile(bug, prs, h.Branches)
+	result.Warnings = reconciled.Warnings
+	t := reconciled.Transition
+	if t == nil {
+		return result, nil
+	}
+	if !h.DryRun {
+		if err := workflow.Apply(h.Client, t); err != nil {
+			return result, err
+		}
+	}
+	result.From, result.To = t.From, t.To
+	return result, nil
+}
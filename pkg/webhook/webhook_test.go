@@ -0,0 +1,192 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/github"
+	"github.com/ecordell/cop/pkg/workflow"
+)
+
+func sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestValidateSignature(t *testing.T) {
+	secret, body := []byte("s3cret"), []byte(`{"action":"opened"}`)
+	require.NoError(t, ValidateSignature(secret, body, sign(secret, body), ""))
+	require.Error(t, ValidateSignature(secret, body, sign([]byte("other"), body), ""))
+	require.Error(t, ValidateSignature(secret, []byte(`{"action":"closed"}`), sign(secret, body), ""))
+	require.Error(t, ValidateSignature(secret, body, "", ""))
+	require.Error(t, ValidateSignature(nil, body, sign(nil, body), ""))
+	require.Error(t, ValidateSignature(secret, body, "sha256=zz", ""))
+	// a valid sha1 signature doesn't make up for a bad sha256 one
+	require.Error(t, ValidateSignature(secret, body, "sha256=00", "sha1=00"))
+}
+
+func payload(action, title string, merged bool) []byte {
+	data, _ := json.Marshal(map[string]interface{}{
+		"action": action,
+		"number": 1234,
+		"pull_request": map[string]interface{}{
+			"number": 1234,
+			"title":  title,
+			"state":  map[bool]string{true: "closed", false: "open"}[action == "closed"],
+			"merged": merged,
+			"base":   map[string]string{"ref": "master"},
+		},
+		"repository": map[string]interface{}{
+			"name":  "operator-lifecycle-manager",
+			"owner": map[string]string{"login": "operator-framework"},
+		},
+	})
+	return data
+}
+
+func TestHandle(t *testing.T) {
+	client := &bugzilla.Fake{
+		Bugs: map[int]bugzilla.Bug{
+			1812345: {ID: 1812345, Status: "ASSIGNED", TargetRelease: []string{"4.5.0"}},
+			1823456: {ID: 1823456, Status: "NEW", TargetRelease: []string{"4.5.0"}},
+		},
+		BugErrors: map[int]bool{},
+	}
+	var changed []int
+	h := &Handler{Client: client, Branches: workflow.Branches{MasterRelease: "4.5"}, Changed: func(id int) {
+		changed = append(changed, id)
+	}}
+
+	result, err := h.Handle(Delivery{Event: "pull_request", Payload: payload("opened", "Bug 1812345: catalog crashes", false)})
+	require.NoError(t, err)
+	require.Equal(t, "operator-framework/operator-lifecycle-manager#1234", result.PR)
+	require.Equal(t, []BugResult{{ID: 1812345, Linked: true, From: "ASSIGNED", To: "POST"}}, result.Bugs)
+	require.Len(t, client.ExternalBugs[1812345], 1)
+	require.Equal(t, "POST", client.Updates[0].Update.Status)
+	bug := client.Bugs[1812345]
+	bug.Status = "POST"
+	client.Bugs[1812345] = bug
+
+	result, err = h.Handle(Delivery{Event: "pull_request", Payload: payload("closed", "Bug 1812345: catalog crashes", true)})
+	require.NoError(t, err)
+	require.Equal(t, []BugResult{{ID: 1812345, From: "POST", To: "MODIFIED"}}, result.Bugs)
+	require.Len(t, client.ExternalBugs[1812345], 1)
+
+	for _, d := range []Delivery{
+		{Event: "push", Payload: []byte(`{}`)},
+		{Event: "pull_request", Payload: payload("closed", "Bug 1812345: catalog crashes", false)},
+		{Event: "pull_request", Payload: payload("labeled", "Bug 1812345: catalog crashes", false)},
+		{Event: "pull_request", Payload: payload("opened", "Bump dependencies", false)},
+	} {
+		result, err := h.Handle(d)
+		require.NoError(t, err)
+		require.NotEmpty(t, result.Ignored)
+	}
+	require.Len(t, client.Updates, 2)
+	require.Equal(t, []int{1812345, 1812345}, changed)
+
+	// in a dry run nothing changes
+	h.DryRun = true
+	result, err = h.Handle(Delivery{Event: "pull_request", Payload: payload("opened", "Bug 1823456: proxy ignored", false)})
+	require.NoError(t, err)
+	require.Equal(t, []BugResult{{ID: 1823456, Linked: true, From: "NEW", To: "POST"}}, result.Bugs)
+	require.Empty(t, client.ExternalBugs[1823456])
+	require.Len(t, client.Updates, 2)
+	require.Len(t, changed, 2)
+}
+
+func TestHandleOtherPRsUnknown(t *testing.T) {
+	client := &bugzilla.Fake{
+		Bugs:      map[int]bugzilla.Bug{1812345: {ID: 1812345, Status: "POST", TargetRelease: []string{"4.5.0"}}},
+		BugErrors: map[int]bool{},
+	}
+	_, err := client.AddPullRequestAsExternalBug(1812345, "operator-framework", "api", 7)
+	require.NoError(t, err)
+	h := &Handler{Client: client, Branches: workflow.Branches{MasterRelease: "4.5"}}
+
+	// without github, the other linked PR may still be open
+	result, err := h.Handle(Delivery{Event: "pull_request", Payload: payload("closed", "Bug 1812345: catalog crashes", true)})
+	require.NoError(t, err)
+	require.Equal(t, "", result.Bugs[0].To)
+	require.NotEmpty(t, result.Bugs[0].Warnings)
+	require.Empty(t, client.Updates)
+}
+
+func TestServeHTTPRecordsAndReplays(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cop-webhook")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	client := &bugzilla.Fake{
+		Bugs:      map[int]bugzilla.Bug{1812345: {ID: 1812345, Status: "NEW", TargetRelease: []string{"4.5.0"}}},
+		BugErrors: map[int]bool{},
+	}
+	secret := []byte("s3cret")
+	h := &Handler{Client: client, Secret: secret, Branches: workflow.Branches{MasterRelease: "4.5"}, DryRun: true, Record: dir}
+
+	body := payload("opened", "Bug 1812345: catalog crashes", false)
+	post := func(sig string) int {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/github", bytes.NewReader(body))
+		req.Header.Set(EventHeader, "pull_request")
+		req.Header.Set(DeliveryHeader, "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+		req.Header.Set(SignatureHeader, sig)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	require.Equal(t, http.StatusForbidden, post(sign([]byte("wrong"), body)))
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, files)
+
+	require.Equal(t, http.StatusOK, post(sign(secret, body)))
+	files, err = ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	d, err := ReadDelivery(filepath.Join(dir, files[0].Name()), "")
+	require.NoError(t, err)
+	require.Equal(t, "pull_request", d.Event)
+	result, err := h.Handle(d)
+	require.NoError(t, err)
+	require.Equal(t, "POST", result.Bugs[0].To)
+
+	// a bare payload needs its event
+	raw := filepath.Join(dir, "payload.json")
+	require.NoError(t, ioutil.WriteFile(raw, body, 0600))
+	_, err = ReadDelivery(raw, "")
+	require.Error(t, err)
+	d, err = ReadDelivery(raw, "pull_request")
+	require.NoError(t, err)
+	require.JSONEq(t, string(body), string(d.Payload))
+}
+
+func TestHandleOtherPRsFail(t *testing.T) {
+	client := &bugzilla.Fake{
+		Bugs:      map[int]bugzilla.Bug{1812345: {ID: 1812345, Status: "POST", TargetRelease: []string{"4.5.0"}}},
+		BugErrors: map[int]bool{},
+	}
+	_, err := client.AddPullRequestAsExternalBug(1812345, "operator-framework", "api", 7)
+	require.NoError(t, err)
+	h := &Handler{Client: client, GitHub: &github.Fake{Err: errors.New("API rate limit exceeded")}, Branches: workflow.Branches{MasterRelease: "4.5"}}
+
+	result, err := h.Handle(Delivery{Event: "pull_request", Payload: payload("closed", "Bug 1812345: catalog crashes", true)})
+	require.NoError(t, err)
+	require.Equal(t, "", result.Bugs[0].To)
+	require.Contains(t, result.Bugs[0].Warnings, "could not look up operator-framework/api#7: API rate limit exceeded")
+	require.Empty(t, client.Updates)
+}
@@ -0,0 +1,269 @@
+package workflow
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/github"
+)
+
+// Bug statuses in the order a bug moves through them.
+const (
+	StatusNew            = "NEW"
+	StatusAssigned       = "ASSIGNED"
+	StatusOnDev          = "ON_DEV"
+	StatusPost           = "POST"
+	StatusModified       = "MODIFIED"
+	StatusOnQA           = "ON_QA"
+	StatusVerified       = "VERIFIED"
+	StatusReleasePending = "RELEASE_PENDING"
+	StatusClosed         = "CLOSED"
+)
+
+var statusOrder = map[string]int{
+	StatusNew:            0,
+	StatusAssigned:       1,
+	StatusOnDev:          2,
+	StatusPost:           3,
+	StatusModified:       4,
+	StatusOnQA:           5,
+	StatusVerified:       6,
+	StatusReleasePending: 7,
+	StatusClosed:         8,
+}
+
+// Before reports whether status a comes earlier in the workflow than b.
+func Before(a, b string) bool {
+	ia, ok := statusOrder[a]
+	if !ok {
+		return false
+	}
+	ib, ok := statusOrder[b]
+	if !ok {
+		return false
+	}
+	return ia < ib
+}
+
+// LinkedPR is a pull request linked to a bug through its external trackers.
+type LinkedPR struct {
+	bugzilla.GithubExternalBug
+	*github.PullRequest
+	// Err is why the pull request couldn't be looked up, if it couldn't.
+	Err error `json:"-"`
+}
+
+// Backport returns the release a bug should be backported to, as recorded in
+// its internal whiteboard by `cop bz backport`, e.g. "backport-to: 4.4". It is
+// empty if no backport has been decided on.
+func Backport(bug bugzilla.Bug) string {
+	whiteboard := strings.Split(bug.InternalWhiteboard, ":")
+	if len(whiteboard) < 2 {
+		return ""
+	}
+	return strings.Trim(whiteboard[1], " ")
+}
+
+// Branches maps a bug's target release to the branch its fix should merge into.
+type Branches struct {
+	// MasterRelease is the release currently developed on the default branch, e.g. 4.5.
+	MasterRelease string
+	// MasterBranch is the name of the default branch.
+	MasterBranch string
+}
+
+// Expected returns the branch fixes for targetRelease should merge into.
+// 4.5.0 and 4.5.z both map to release-4.5, unless 4.5 is in development on master.
+func (b Branches) Expected(targetRelease string) string {
+	parts := strings.Split(targetRelease, ".")
+	if len(parts) < 2 {
+		return ""
+	}
+	minor := parts[0] + "." + parts[1]
+	if minor == b.MasterRelease {
+		if b.MasterBranch == "" {
+			return "master"
+		}
+		return b.MasterBranch
+	}
+	return "release-" + minor
+}
+
+// Transition is a status change the workflow calls for.
+type Transition struct {
+	Bug  *bugzilla.Bug
+	From string
+	// Via are the statuses the bug passes through on the way to To, e.g.
+	// ASSIGNED for a NEW bug, which has to be assigned before it moves to POST.
+	Via    []string
+	To     string
+	Reason string
+}
+
+// Steps are the statuses set in turn to make the transition, ending with To.
+func (t *Transition) Steps() []string {
+	return append(append([]string{}, t.Via...), t.To)
+}
+
+// Apply makes the transition one status at a time, commenting why on the last.
+func Apply(client bugzilla.Client, t *Transition) error {
+	for _, status := range t.Via {
+		if err := client.UpdateBug(t.Bug.ID, bugzilla.BugUpdate{Status: status}); err != nil {
+			return fmt.Errorf("could not move to %s: %v", status, err)
+		}
+	}
+	err := client.UpdateBug(t.Bug.ID, bugzilla.BugUpdate{
+		Status: t.To,
+		Comment: &bugzilla.BugComment{
+			Body: fmt.Sprintf("Moving to %s: %s.", t.To, t.Reason),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("could not move to %s: %v", t.To, err)
+	}
+	return nil
+}
+
+// Result is the outcome of reconciling a bug against its pull requests.
+type Result struct {
+	// Transition is the change to make, or nil if the bug is up to date.
+	Transition *Transition
+	// Warnings describe links that don't fit the workflow and need a human.
+	Warnings []string
+}
+
+// Reconcile decides which status a bug should be in given its linked pull
+// requests. A bug moves to POST when a PR targeting the right branch is
+// linked, and to MODIFIED once all such PRs have merged, by way of ASSIGNED
+// if it is NEW. Bugs never move backwards, and don't move at all while the
+// state of a linked PR is unknown.
+func Reconcile(bug *bugzilla.Bug, prs []LinkedPR, branches Branches) Result {
+	var result Result
+	expected := map[string]bool{}
+	for _, r := range bug.TargetRelease {
+		if branch := branches.Expected(r); branch != "" {
+			expected[branch] = true
+		}
+	}
+
+	var open, merged []string
+	unknown := false
+	for _, pr := range prs {
+		name := fmt.Sprintf("%s/%s#%d", pr.Org, pr.Repo, pr.Num)
+		if pr.PullRequest == nil {
+			if pr.Err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("could not look up %s: %v", name, pr.Err))
+			} else {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("could not look up %s", name))
+			}
+			unknown = true
+			continue
+		}
+		if pr.State == "closed" && !pr.Merged {
+			continue
+		}
+		if len(expected) > 0 && !expected[pr.Base.Ref] {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s merges into %s but the bug targets %s (expected %s)",
+				name, pr.Base.Ref, strings.Join(bug.TargetRelease, ", "), strings.Join(sortedKeys(expected), ", ")))
+			continue
+		}
+		if pr.Merged {
+			merged = append(merged, name)
+		} else {
+			open = append(open, name)
+		}
+	}
+
+	var to, reason string
+	switch {
+	case len(merged) > 0 && len(open) == 0:
+		to = StatusModified
+		reason = fmt.Sprintf("%s merged", strings.Join(merged, ", "))
+	case len(open) > 0:
+		to = StatusPost
+		reason = fmt.Sprintf("%s linked", strings.Join(append(open, merged...), ", "))
+	default:
+		return result
+	}
+	if !Before(bug.Status, to) {
+		return result
+	}
+	if unknown {
+		// the pull requests we know nothing of may still be open
+		result.Warnings = append(result.Warnings, fmt.Sprintf("not moving to %s without knowing the state of every linked PR", to))
+		return result
+	}
+	result.Transition = &Transition{Bug: bug, From: bug.Status, To: to, Reason: reason}
+	if Before(bug.Status, StatusAssigned) {
+		result.Transition.Via = []string{StatusAssigned}
+	}
+	return result
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// States of a bug's linked pull requests, as summarized by PRState.
+const (
+	PRStateNone    = "no PR"
+	PRStateOpen    = "PR open"
+	PRStateMerged  = "PR merged"
+	PRStateClosed  = "PR closed"
+	PRStateUnknown = "unknown"
+)
+
+// PRState summarizes the linked pull requests of a bug: open if any are
+// still open, merged if all the rest merged, and closed if none merged.
+func PRState(prs []LinkedPR) string {
+	if len(prs) == 0 {
+		return PRStateNone
+	}
+	var open, merged, unknown bool
+	for _, pr := range prs {
+		switch {
+		case pr.PullRequest == nil:
+			unknown = true
+		case pr.Merged:
+			merged = true
+		case pr.State != "closed":
+			open = true
+		}
+	}
+	switch {
+	case open:
+		return PRStateOpen
+	case unknown:
+		return PRStateUnknown
+	case merged:
+		return PRStateMerged
+	default:
+		return PRStateClosed
+	}
+}
+
+// LinkedPRs looks up the pull requests linked to a bug. PRs that can't be
+// fetched are returned without details and with the error, so Reconcile can
+// warn about them.
+func LinkedPRs(bz bugzilla.Client, gh github.Client, id int) ([]LinkedPR, error) {
+	exts, err := bz.GetExternalBugPRsOnBug(id)
+	if err != nil {
+		return nil, err
+	}
+	prs := make([]LinkedPR, 0, len(exts))
+	for _, ext := range exts {
+		linked := LinkedPR{GithubExternalBug: ext}
+		if linked.PullRequest, err = gh.GetPullRequest(ext.Org, ext.Repo, ext.Num); err != nil {
+			linked.PullRequest, linked.Err = nil, err
+		}
+		prs = append(prs, linked)
+	}
+	return prs, nil
+}
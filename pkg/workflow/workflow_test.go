@@ -0,0 +1,118 @@
+package workflow
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ecordell/cop/pkg/bugzilla"
+	"github.com/ecordell/cop/pkg/github"
+)
+
+func pr(num int, base, state string, merged bool) LinkedPR {
+	return LinkedPR{
+		GithubExternalBug: bugzilla.GithubExternalBug{Org: "operator-framework", Repo: "operator-lifecycle-manager", Num: num},
+		PullRequest:       &github.PullRequest{Number: num, Base: github.Ref{Ref: base}, State: state, Merged: merged},
+	}
+}
+
+func TestBranchesExpected(t *testing.T) {
+	b := Branches{MasterRelease: "4.5"}
+	require.Equal(t, "master", b.Expected("4.5.0"))
+	require.Equal(t, "release-4.4", b.Expected("4.4.z"))
+	require.Equal(t, "release-4.3", b.Expected("4.3.0"))
+	require.Equal(t, "", b.Expected("---"))
+}
+
+func TestReconcile(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		target   string
+		prs      []LinkedPR
+		to       string
+		via      []string
+		warnings int
+	}{
+		{name: "no prs", status: StatusNew, target: "4.5.0"},
+		{name: "open pr moves to post", status: StatusAssigned, target: "4.5.0", prs: []LinkedPR{pr(1, "master", "open", false)}, to: StatusPost},
+		{name: "new bugs are assigned on the way", status: StatusNew, target: "4.5.0", prs: []LinkedPR{pr(1, "master", "open", false)}, to: StatusPost, via: []string{StatusAssigned}},
+		{name: "merged pr moves to modified", status: StatusPost, target: "4.4.z", prs: []LinkedPR{pr(1, "release-4.4", "closed", true)}, to: StatusModified},
+		{name: "assigned with merged pr skips post", status: StatusAssigned, target: "4.5.0", prs: []LinkedPR{pr(1, "master", "closed", true)}, to: StatusModified},
+		{name: "partially merged stays in post", status: StatusPost, target: "4.5.0", prs: []LinkedPR{pr(1, "master", "closed", true), pr(2, "master", "open", false)}},
+		{name: "never moves backwards", status: StatusOnQA, target: "4.5.0", prs: []LinkedPR{pr(1, "master", "open", false)}},
+		{name: "closed unmerged prs are ignored", status: StatusAssigned, target: "4.5.0", prs: []LinkedPR{pr(1, "master", "closed", false)}},
+		{name: "wrong branch is a warning", status: StatusPost, target: "4.4.z", prs: []LinkedPR{pr(1, "master", "closed", true)}, warnings: 1},
+		{name: "missing pr details is a warning", status: StatusNew, target: "4.5.0", prs: []LinkedPR{{GithubExternalBug: bugzilla.GithubExternalBug{Num: 1}}}, warnings: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bug := &bugzilla.Bug{ID: 1, Status: tt.status, TargetRelease: []string{tt.target}}
+			result := Reconcile(bug, tt.prs, Branches{MasterRelease: "4.5"})
+			require.Len(t, result.Warnings, tt.warnings)
+			if tt.to == "" {
+				require.Nil(t, result.Transition)
+				return
+			}
+			require.NotNil(t, result.Transition)
+			require.Equal(t, tt.status, result.Transition.From)
+			require.Equal(t, tt.to, result.Transition.To)
+			require.Equal(t, tt.via, result.Transition.Via)
+		})
+	}
+}
+
+func TestPRState(t *testing.T) {
+	require.Equal(t, PRStateNone, PRState(nil))
+	require.Equal(t, PRStateOpen, PRState([]LinkedPR{pr(1, "master", "closed", true), pr(2, "master", "open", false)}))
+	require.Equal(t, PRStateMerged, PRState([]LinkedPR{pr(1, "master", "closed", true), pr(2, "master", "closed", false)}))
+	require.Equal(t, PRStateClosed, PRState([]LinkedPR{pr(1, "master", "closed", false)}))
+	require.Equal(t, PRStateUnknown, PRState([]LinkedPR{{GithubExternalBug: bugzilla.GithubExternalBug{Num: 1}}}))
+}
+
+func TestReconcileReportsLookupErrors(t *testing.T) {
+	bug := &bugzilla.Bug{ID: 1, Status: StatusNew, TargetRelease: []string{"4.5.0"}}
+	prs := []LinkedPR{{
+		GithubExternalBug: bugzilla.GithubExternalBug{Org: "operator-framework", Repo: "operator-lifecycle-manager", Num: 1},
+		Err:               errors.New("API rate limit exceeded"),
+	}}
+	result := Reconcile(bug, prs, Branches{MasterRelease: "4.5"})
+	require.Equal(t, []string{"could not look up operator-framework/operator-lifecycle-manager#1: API rate limit exceeded"}, result.Warnings)
+}
+
+func TestReconcileWaitsForUnknownPRs(t *testing.T) {
+	bug := &bugzilla.Bug{ID: 1, Status: StatusPost, TargetRelease: []string{"4.5.0"}}
+	prs := []LinkedPR{
+		pr(1, "master", "closed", true),
+		{GithubExternalBug: bugzilla.GithubExternalBug{Org: "operator-framework", Repo: "api", Num: 2}, Err: errors.New("not found")},
+	}
+	result := Reconcile(bug, prs, Branches{MasterRelease: "4.5"})
+	require.Nil(t, result.Transition)
+	require.Equal(t, []string{
+		"could not look up operator-framework/api#2: not found",
+		"not moving to MODIFIED without knowing the state of every linked PR",
+	}, result.Warnings)
+}
+
+func TestApply(t *testing.T) {
+	client := &bugzilla.Fake{Bugs: map[int]bugzilla.Bug{1: {ID: 1, Status: StatusNew}}, BugErrors: map[int]bool{}}
+	bug := client.Bugs[1]
+	transition := &Transition{Bug: &bug, From: StatusNew, Via: []string{StatusAssigned}, To: StatusPost, Reason: "operator-framework/api#2 linked"}
+	require.Equal(t, []string{StatusAssigned, StatusPost}, transition.Steps())
+	require.NoError(t, Apply(client, transition))
+	require.Len(t, client.Updates, 2)
+	require.Equal(t, StatusAssigned, client.Updates[0].Update.Status)
+	require.Nil(t, client.Updates[0].Update.Comment)
+	require.Equal(t, StatusPost, client.Updates[1].Update.Status)
+	require.Equal(t, "Moving to POST: operator-framework/api#2 linked.", client.Updates[1].Update.Comment.Body)
+
+	client.BugErrors[1] = true
+	require.EqualError(t, Apply(client, transition), "could not move to ASSIGNED: injected error updating bug")
+}
+
+func TestBackport(t *testing.T) {
+	require.Equal(t, "4.4", Backport(bugzilla.Bug{InternalWhiteboard: "backport-to: 4.4"}))
+	require.Equal(t, "", Backport(bugzilla.Bug{InternalWhiteboard: "needs triage"}))
+	require.Equal(t, "", Backport(bugzilla.Bug{}))
+}